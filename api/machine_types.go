@@ -8,19 +8,27 @@ import (
 )
 
 const (
-	MachineConfigMetadataKeyFlyManagedPostgres = "fly-managed-postgres"
-	MachineConfigMetadataKeyFlyPlatformVersion = "fly_platform_version"
-	MachineConfigMetadataKeyFlyReleaseId       = "fly_release_id"
-	MachineConfigMetadataKeyFlyReleaseVersion  = "fly_release_version"
-	MachineConfigMetadataKeyFlyProcessGroup    = "fly_process_group"
-	MachineConfigMetadataKeyFlyPreviousAlloc   = "fly_previous_alloc"
-	MachineFlyPlatformVersion2                 = "v2"
-	MachineProcessGroupApp                     = "app"
-	MachineProcessGroupFlyAppReleaseCommand    = "fly_app_release_command"
-	MachineStateDestroyed                      = "destroyed"
-	MachineStateDestroying                     = "destroying"
-	MachineStateStarted                        = "started"
-	MachineStateStopped                        = "stopped"
+	MachineConfigMetadataKeyFlyManagedPostgres  = "fly-managed-postgres"
+	MachineConfigMetadataKeyFlyPlatformVersion  = "fly_platform_version"
+	MachineConfigMetadataKeyFlyReleaseId        = "fly_release_id"
+	MachineConfigMetadataKeyFlyReleaseVersion   = "fly_release_version"
+	MachineConfigMetadataKeyFlyReleaseSource    = "fly_release_source_version"
+	MachineConfigMetadataKeyFlyProcessGroup     = "fly_process_group"
+	MachineConfigMetadataKeyFlyPreviousAlloc    = "fly_previous_alloc"
+	MachineConfigMetadataKeyFlyImageDigest      = "fly_image_digest"
+	MachineConfigMetadataKeyFlyImageTag         = "fly_image_tag"
+	MachineConfigMetadataKeyFlySourceHash       = "fly_source_hash"
+	MachineConfigMetadataKeyFlyDeployPinned     = "fly_deploy_pinned"
+	MachineConfigMetadataKeyFlySkipHealthChecks = "fly_skip_health_checks"
+	MachineConfigMetadataKeyFlyKeptForRollback  = "fly_kept_for_rollback"
+	MachineFlyPlatformVersion2                  = "v2"
+	MachineProcessGroupApp                      = "app"
+	MachineProcessGroupFlyAppReleaseCommand     = "fly_app_release_command"
+	MachineStateDestroyed                       = "destroyed"
+	MachineStateDestroying                      = "destroying"
+	MachineStateStarted                         = "started"
+	MachineStateStopped                         = "stopped"
+	MachineStateSuspended                       = "suspended"
 )
 
 type Machine struct {
@@ -91,6 +99,23 @@ func (m *Machine) ProcessGroup() string {
 	return m.Config.ProcessGroup()
 }
 
+// IsDeployPinned reports if the machine was pinned to skip updates from `fly deploy`
+func (m *Machine) IsDeployPinned() bool {
+	return m.Config != nil && m.Config.Metadata[MachineConfigMetadataKeyFlyDeployPinned] == "true"
+}
+
+// SkipsHealthChecks tells if deploys update the machine without waiting for its health checks,
+// for machines expected to fail them like maintenance ones
+func (m *Machine) SkipsHealthChecks() bool {
+	return m.Config != nil && m.Config.Metadata[MachineConfigMetadataKeyFlySkipHealthChecks] == "true"
+}
+
+// IsKeptForRollback tells if the machine was stopped by a deploy replacing it instead of being
+// destroyed, to roll back to its release. It isn't part of the app machines anymore.
+func (m *Machine) IsKeptForRollback() bool {
+	return m.Config != nil && m.Config.Metadata[MachineConfigMetadataKeyFlyKeptForRollback] == "true"
+}
+
 func (m *Machine) HasProcessGroup(desired string) bool {
 	return m.Config != nil && m.ProcessGroup() == desired
 }
@@ -200,6 +225,12 @@ type MachineExitEvent struct {
 	ExitedAt      time.Time `json:"exited_at,omitempty"`
 }
 
+// StopConfig is how a machine is asked to shut down gracefully before it is killed
+type StopConfig struct {
+	Timeout *Duration `json:"timeout,omitempty"`
+	Signal  *string   `json:"signal,omitempty"`
+}
+
 type StopMachineInput struct {
 	ID      string   `json:"id,omitempty"`
 	Signal  string   `json:"signal,omitempty"`
@@ -254,6 +285,7 @@ type MachineGuest struct {
 	CPUKind  string `json:"cpu_kind,omitempty"`
 	CPUs     int    `json:"cpus,omitempty"`
 	MemoryMB int    `json:"memory_mb,omitempty"`
+	GPUKind  string `json:"gpu_kind,omitempty"`
 
 	KernelArgs []string `json:"kernel_args,omitempty"`
 }
@@ -287,6 +319,23 @@ func (mg *MachineGuest) SetSize(size string) error {
 	return nil
 }
 
+// SetGPUKind attaches a GPU of kind, one of MachineGPUKinds or their short aliases, to the guest
+func (mg *MachineGuest) SetGPUKind(kind string) error {
+	if alias, ok := machineGPUKindAliases[kind]; ok {
+		kind = alias
+	}
+	if _, ok := MachineGPUKinds[kind]; !ok {
+		validKinds := []string{}
+		for kind := range MachineGPUKinds {
+			validKinds = append(validKinds, kind)
+		}
+		sort.Strings(validKinds)
+		return fmt.Errorf("'%s' is an invalid GPU kind, choose one of: %v", kind, validKinds)
+	}
+	mg.GPUKind = kind
+	return nil
+}
+
 // ToSize converts Guest into VMSize on a best effort way
 func (mg *MachineGuest) ToSize() string {
 	if mg == nil {
@@ -324,6 +373,19 @@ var MachinePresets map[string]*MachineGuest = map[string]*MachineGuest{
 	"performance-16x": {CPUKind: "performance", CPUs: 16, MemoryMB: 16 * MIN_MEMORY_MB_PER_CPU},
 }
 
+// MachineGPUKinds maps the GPU kinds machines can have to the regions offering them
+var MachineGPUKinds = map[string][]string{
+	"a10":            {"ord"},
+	"a100-pcie-40gb": {"ord"},
+	"a100-sxm4-80gb": {"ams", "iad", "mia", "sjc", "syd"},
+	"l40s":           {"ord"},
+}
+
+var machineGPUKindAliases = map[string]string{
+	"a100-40gb": "a100-pcie-40gb",
+	"a100-80gb": "a100-sxm4-80gb",
+}
+
 type MachineMetrics struct {
 	Port int    `toml:"port" json:"port,omitempty"`
 	Path string `toml:"path" json:"path,omitempty"`
@@ -443,6 +505,8 @@ type MachineConfig struct {
 	Metrics  *MachineMetrics         `json:"metrics,omitempty"`
 	Checks   map[string]MachineCheck `json:"checks,omitempty"`
 	Statics  []*Static               `json:"statics,omitempty"`
+	// StopConfig holds the kill_signal and kill_timeout from fly.toml
+	StopConfig *StopConfig `json:"stop_config,omitempty"`
 
 	// Set by fly deploy or fly machines commands
 	Image string `json:"image,omitempty"`
@@ -501,7 +565,16 @@ type MachineInit struct {
 }
 
 type DNSConfig struct {
-	SkipRegistration bool `json:"skip_registration,omitempty"`
+	SkipRegistration bool        `json:"skip_registration,omitempty"`
+	Nameservers      []string    `json:"nameservers,omitempty"`
+	Searches         []string    `json:"searches,omitempty"`
+	Options          []DNSOption `json:"options,omitempty"`
+}
+
+// DNSOption is a resolv.conf option of the machine, e.g. ndots:2
+type DNSOption struct {
+	Name  string `toml:"name" json:"name,omitempty"`
+	Value string `toml:"value,omitempty" json:"value,omitempty"`
 }
 
 type MachineLease struct {
@@ -530,6 +603,8 @@ type LaunchMachineInput struct {
 	OrgSlug string         `json:"organizationId,omitempty"`
 	Region  string         `json:"region,omitempty"`
 	Config  *MachineConfig `json:"config,omitempty"`
+	// SkipLaunch updates the config of a stopped machine without starting it
+	SkipLaunch bool `json:"skip_launch,omitempty"`
 	// Client side only
 	SkipHealthChecks bool
 }
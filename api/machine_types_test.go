@@ -157,3 +157,16 @@ func TestMachineGuest_ToSize(t *testing.T) {
 		t.Errorf("want 'unknown', got '%s'", got)
 	}
 }
+
+func TestMachineGuest_SetGPUKind(t *testing.T) {
+	guest := &MachineGuest{}
+	if err := guest.SetGPUKind("a100-40gb"); err != nil {
+		t.Errorf("got error for valid GPU kind alias: %v", err)
+	} else if guest.GPUKind != "a100-pcie-40gb" {
+		t.Errorf("Expected a100-pcie-40gb GPU kind, got: %v", guest.GPUKind)
+	}
+
+	if err := guest.SetGPUKind("h100"); err == nil {
+		t.Error("Expected an error for an unknown GPU kind")
+	}
+}
@@ -174,12 +174,18 @@ func (f *Client) Update(ctx context.Context, builder api.LaunchMachineInput, non
 	return out, nil
 }
 
-func (f *Client) Start(ctx context.Context, machineID string) (*api.MachineStartResponse, error) {
+// Start starts a stopped or suspended machine, nonce is the lease held on it if any
+func (f *Client) Start(ctx context.Context, machineID string, nonce string) (*api.MachineStartResponse, error) {
 	startEndpoint := fmt.Sprintf("/%s/start", machineID)
 
 	out := new(api.MachineStartResponse)
 
-	if err := f.sendRequest(ctx, http.MethodPost, startEndpoint, nil, out, nil); err != nil {
+	headers := make(map[string][]string)
+	if nonce != "" {
+		headers[NonceHeader] = []string{nonce}
+	}
+
+	if err := f.sendRequest(ctx, http.MethodPost, startEndpoint, nil, out, headers); err != nil {
 		return nil, fmt.Errorf("failed to start VM %s: %w", machineID, err)
 	}
 	return out, nil
@@ -333,7 +339,7 @@ func (f *Client) ListFlyAppsMachines(ctx context.Context) ([]*api.Machine, *api.
 	var releaseCmdMachine *api.Machine
 	machines := make([]*api.Machine, 0)
 	for _, m := range allMachines {
-		if m.IsFlyAppsPlatform() && m.IsActive() && !m.IsFlyAppsReleaseCommand() {
+		if m.IsFlyAppsPlatform() && m.IsActive() && !m.IsFlyAppsReleaseCommand() && !m.IsKeptForRollback() {
 			machines = append(machines, m)
 		} else if m.IsFlyAppsReleaseCommand() {
 			releaseCmdMachine = m
@@ -369,6 +375,30 @@ func (f *Client) Kill(ctx context.Context, machineID string) (err error) {
 	return
 }
 
+// Cordon stops the proxy from routing requests to a machine, it keeps running
+func (f *Client) Cordon(ctx context.Context, machineID string, nonce string) error {
+	headers := make(map[string][]string)
+	if nonce != "" {
+		headers[NonceHeader] = []string{nonce}
+	}
+	if err := f.sendRequest(ctx, http.MethodPost, fmt.Sprintf("/%s/cordon", machineID), nil, nil, headers); err != nil {
+		return fmt.Errorf("failed to cordon VM %s: %w", machineID, err)
+	}
+	return nil
+}
+
+// Uncordon lets the proxy route requests to a cordoned machine again
+func (f *Client) Uncordon(ctx context.Context, machineID string, nonce string) error {
+	headers := make(map[string][]string)
+	if nonce != "" {
+		headers[NonceHeader] = []string{nonce}
+	}
+	if err := f.sendRequest(ctx, http.MethodPost, fmt.Sprintf("/%s/uncordon", machineID), nil, nil, headers); err != nil {
+		return fmt.Errorf("failed to uncordon VM %s: %w", machineID, err)
+	}
+	return nil
+}
+
 func (f *Client) FindLease(ctx context.Context, machineID string) (*api.MachineLease, error) {
 	endpoint := fmt.Sprintf("/%s/lease", machineID)
 
@@ -426,6 +456,25 @@ func (f *Client) ReleaseLease(ctx context.Context, machineID, nonce string) erro
 	return f.sendRequest(ctx, http.MethodDelete, endpoint, nil, nil, headers)
 }
 
+// SetMetadata sets a metadata key of a machine without updating its config, so it isn't restarted
+func (f *Client) SetMetadata(ctx context.Context, machineID, key, value string) error {
+	endpoint := fmt.Sprintf("/%s/metadata/%s", machineID, key)
+	in := map[string]string{"value": value}
+	if err := f.sendRequest(ctx, http.MethodPost, endpoint, in, nil, nil); err != nil {
+		return fmt.Errorf("failed to set metadata %s on VM %s: %w", key, machineID, err)
+	}
+	return nil
+}
+
+// DeleteMetadata removes a metadata key of a machine without updating its config
+func (f *Client) DeleteMetadata(ctx context.Context, machineID, key string) error {
+	endpoint := fmt.Sprintf("/%s/metadata/%s", machineID, key)
+	if err := f.sendRequest(ctx, http.MethodDelete, endpoint, nil, nil, nil); err != nil {
+		return fmt.Errorf("failed to delete metadata %s on VM %s: %w", key, machineID, err)
+	}
+	return nil
+}
+
 func (f *Client) Exec(ctx context.Context, machineID string, in *api.MachineExecRequest) (*api.MachineExecResponse, error) {
 	endpoint := fmt.Sprintf("/%s/exec", machineID)
 
@@ -931,6 +931,115 @@ type FlyctlDeployGetLatestImageResponse struct {
 // GetApp returns FlyctlDeployGetLatestImageResponse.App, and is useful for accessing the field via an interface.
 func (v *FlyctlDeployGetLatestImageResponse) GetApp() FlyctlDeployGetLatestImageApp { return v.App }
 
+// FlyctlDeployGetRecentReleasesApp includes the requested fields of the GraphQL type App.
+type FlyctlDeployGetRecentReleasesApp struct {
+	// Individual releases for this application, without any config processing
+	ReleasesUnprocessed FlyctlDeployGetRecentReleasesAppReleasesUnprocessedReleaseUnprocessedConnection `json:"releasesUnprocessed"`
+}
+
+// GetReleasesUnprocessed returns FlyctlDeployGetRecentReleasesApp.ReleasesUnprocessed, and is useful for accessing the field via an interface.
+func (v *FlyctlDeployGetRecentReleasesApp) GetReleasesUnprocessed() FlyctlDeployGetRecentReleasesAppReleasesUnprocessedReleaseUnprocessedConnection {
+	return v.ReleasesUnprocessed
+}
+
+// FlyctlDeployGetRecentReleasesAppReleasesUnprocessedReleaseUnprocessedConnection includes the requested fields of the GraphQL type ReleaseUnprocessedConnection.
+// The GraphQL type's documentation follows.
+//
+// The connection type for ReleaseUnprocessed.
+type FlyctlDeployGetRecentReleasesAppReleasesUnprocessedReleaseUnprocessedConnection struct {
+	// A list of nodes.
+	Nodes []FlyctlDeployGetRecentReleasesAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed `json:"nodes"`
+}
+
+// GetNodes returns FlyctlDeployGetRecentReleasesAppReleasesUnprocessedReleaseUnprocessedConnection.Nodes, and is useful for accessing the field via an interface.
+func (v *FlyctlDeployGetRecentReleasesAppReleasesUnprocessedReleaseUnprocessedConnection) GetNodes() []FlyctlDeployGetRecentReleasesAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed {
+	return v.Nodes
+}
+
+// FlyctlDeployGetRecentReleasesAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed includes the requested fields of the GraphQL type ReleaseUnprocessed.
+type FlyctlDeployGetRecentReleasesAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed struct {
+	// Unique ID
+	Id string `json:"id"`
+	// The version of the release
+	Version int `json:"version"`
+	// The status of the release
+	Status string `json:"status"`
+}
+
+// GetId returns FlyctlDeployGetRecentReleasesAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed.Id, and is useful for accessing the field via an interface.
+func (v *FlyctlDeployGetRecentReleasesAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed) GetId() string {
+	return v.Id
+}
+
+// GetVersion returns FlyctlDeployGetRecentReleasesAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed.Version, and is useful for accessing the field via an interface.
+func (v *FlyctlDeployGetRecentReleasesAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed) GetVersion() int {
+	return v.Version
+}
+
+// GetStatus returns FlyctlDeployGetRecentReleasesAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed.Status, and is useful for accessing the field via an interface.
+func (v *FlyctlDeployGetRecentReleasesAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed) GetStatus() string {
+	return v.Status
+}
+
+// FlyctlDeployGetRecentReleasesResponse is returned by FlyctlDeployGetRecentReleases on success.
+type FlyctlDeployGetRecentReleasesResponse struct {
+	// Find an app by name
+	App FlyctlDeployGetRecentReleasesApp `json:"app"`
+}
+
+// GetApp returns FlyctlDeployGetRecentReleasesResponse.App, and is useful for accessing the field via an interface.
+func (v *FlyctlDeployGetRecentReleasesResponse) GetApp() FlyctlDeployGetRecentReleasesApp {
+	return v.App
+}
+
+// FlyctlDeployGetReleaseApp includes the requested fields of the GraphQL type App.
+type FlyctlDeployGetReleaseApp struct {
+	// Find a specific release
+	Release FlyctlDeployGetReleaseAppRelease `json:"release"`
+}
+
+// GetRelease returns FlyctlDeployGetReleaseApp.Release, and is useful for accessing the field via an interface.
+func (v *FlyctlDeployGetReleaseApp) GetRelease() FlyctlDeployGetReleaseAppRelease { return v.Release }
+
+// FlyctlDeployGetReleaseAppRelease includes the requested fields of the GraphQL type Release.
+type FlyctlDeployGetReleaseAppRelease struct {
+	// The version of the release
+	Version int `json:"version"`
+	// Docker image URI
+	ImageRef string                                          `json:"imageRef"`
+	Config   FlyctlDeployGetReleaseAppReleaseConfigAppConfig `json:"config"`
+}
+
+// GetVersion returns FlyctlDeployGetReleaseAppRelease.Version, and is useful for accessing the field via an interface.
+func (v *FlyctlDeployGetReleaseAppRelease) GetVersion() int { return v.Version }
+
+// GetImageRef returns FlyctlDeployGetReleaseAppRelease.ImageRef, and is useful for accessing the field via an interface.
+func (v *FlyctlDeployGetReleaseAppRelease) GetImageRef() string { return v.ImageRef }
+
+// GetConfig returns FlyctlDeployGetReleaseAppRelease.Config, and is useful for accessing the field via an interface.
+func (v *FlyctlDeployGetReleaseAppRelease) GetConfig() FlyctlDeployGetReleaseAppReleaseConfigAppConfig {
+	return v.Config
+}
+
+// FlyctlDeployGetReleaseAppReleaseConfigAppConfig includes the requested fields of the GraphQL type AppConfig.
+type FlyctlDeployGetReleaseAppReleaseConfigAppConfig struct {
+	Definition interface{} `json:"definition"`
+}
+
+// GetDefinition returns FlyctlDeployGetReleaseAppReleaseConfigAppConfig.Definition, and is useful for accessing the field via an interface.
+func (v *FlyctlDeployGetReleaseAppReleaseConfigAppConfig) GetDefinition() interface{} {
+	return v.Definition
+}
+
+// FlyctlDeployGetReleaseResponse is returned by FlyctlDeployGetRelease on success.
+type FlyctlDeployGetReleaseResponse struct {
+	// Find an app by name
+	App FlyctlDeployGetReleaseApp `json:"app"`
+}
+
+// GetApp returns FlyctlDeployGetReleaseResponse.App, and is useful for accessing the field via an interface.
+func (v *FlyctlDeployGetReleaseResponse) GetApp() FlyctlDeployGetReleaseApp { return v.App }
+
 // GetAddOnAddOn includes the requested fields of the GraphQL type AddOn.
 type GetAddOnAddOn struct {
 	Id string `json:"id"`
@@ -2203,6 +2312,30 @@ type __FlyctlDeployGetLatestImageInput struct {
 // GetAppName returns __FlyctlDeployGetLatestImageInput.AppName, and is useful for accessing the field via an interface.
 func (v *__FlyctlDeployGetLatestImageInput) GetAppName() string { return v.AppName }
 
+// __FlyctlDeployGetRecentReleasesInput is used internally by genqlient
+type __FlyctlDeployGetRecentReleasesInput struct {
+	AppName string `json:"appName"`
+	Count   int    `json:"count"`
+}
+
+// GetAppName returns __FlyctlDeployGetRecentReleasesInput.AppName, and is useful for accessing the field via an interface.
+func (v *__FlyctlDeployGetRecentReleasesInput) GetAppName() string { return v.AppName }
+
+// GetCount returns __FlyctlDeployGetRecentReleasesInput.Count, and is useful for accessing the field via an interface.
+func (v *__FlyctlDeployGetRecentReleasesInput) GetCount() int { return v.Count }
+
+// __FlyctlDeployGetReleaseInput is used internally by genqlient
+type __FlyctlDeployGetReleaseInput struct {
+	AppName string `json:"appName"`
+	Version int    `json:"version"`
+}
+
+// GetAppName returns __FlyctlDeployGetReleaseInput.AppName, and is useful for accessing the field via an interface.
+func (v *__FlyctlDeployGetReleaseInput) GetAppName() string { return v.AppName }
+
+// GetVersion returns __FlyctlDeployGetReleaseInput.Version, and is useful for accessing the field via an interface.
+func (v *__FlyctlDeployGetReleaseInput) GetVersion() int { return v.Version }
+
 // __GetAddOnInput is used internally by genqlient
 type __GetAddOnInput struct {
 	Name string `json:"name"`
@@ -2659,6 +2792,86 @@ query FlyctlDeployGetLatestImage ($appName: String!) {
 	return &data, err
 }
 
+func FlyctlDeployGetRecentReleases(
+	ctx context.Context,
+	client graphql.Client,
+	appName string,
+	count int,
+) (*FlyctlDeployGetRecentReleasesResponse, error) {
+	req := &graphql.Request{
+		OpName: "FlyctlDeployGetRecentReleases",
+		Query: `
+query FlyctlDeployGetRecentReleases ($appName: String!, $count: Int!) {
+	app(name: $appName) {
+		releasesUnprocessed(first: $count) {
+			nodes {
+				id
+				version
+				status
+			}
+		}
+	}
+}
+`,
+		Variables: &__FlyctlDeployGetRecentReleasesInput{
+			AppName: appName,
+			Count:   count,
+		},
+	}
+	var err error
+
+	var data FlyctlDeployGetRecentReleasesResponse
+	resp := &graphql.Response{Data: &data}
+
+	err = client.MakeRequest(
+		ctx,
+		req,
+		resp,
+	)
+
+	return &data, err
+}
+
+func FlyctlDeployGetRelease(
+	ctx context.Context,
+	client graphql.Client,
+	appName string,
+	version int,
+) (*FlyctlDeployGetReleaseResponse, error) {
+	req := &graphql.Request{
+		OpName: "FlyctlDeployGetRelease",
+		Query: `
+query FlyctlDeployGetRelease ($appName: String!, $version: Int!) {
+	app(name: $appName) {
+		release(version: $version) {
+			version
+			imageRef
+			config {
+				definition
+			}
+		}
+	}
+}
+`,
+		Variables: &__FlyctlDeployGetReleaseInput{
+			AppName: appName,
+			Version: version,
+		},
+	}
+	var err error
+
+	var data FlyctlDeployGetReleaseResponse
+	resp := &graphql.Response{Data: &data}
+
+	err = client.MakeRequest(
+		ctx,
+		req,
+		resp,
+	)
+
+	return &data, err
+}
+
 func GetAddOn(
 	ctx context.Context,
 	client graphql.Client,
@@ -29,10 +29,11 @@ func NewConfig() *Config {
 // Config wraps the properties of app configuration.
 // NOTE: If you any new setting here, please also add a value for it at testdata/rull-reference.toml
 type Config struct {
-	AppName       string  `toml:"app,omitempty" json:"app,omitempty"`
-	PrimaryRegion string  `toml:"primary_region,omitempty" json:"primary_region,omitempty"`
-	KillSignal    *string `toml:"kill_signal,omitempty" json:"kill_signal,omitempty"`
-	KillTimeout   *int    `toml:"kill_timeout,omitempty" json:"kill_timeout,omitempty"`
+	AppName       string   `toml:"app,omitempty" json:"app,omitempty"`
+	PrimaryRegion string   `toml:"primary_region,omitempty" json:"primary_region,omitempty"`
+	Regions       []string `toml:"regions,omitempty" json:"regions,omitempty"`
+	KillSignal    *string  `toml:"kill_signal,omitempty" json:"kill_signal,omitempty"`
+	KillTimeout   *int     `toml:"kill_timeout,omitempty" json:"kill_timeout,omitempty"`
 
 	// Sections that are typically short and benefit from being on top
 	Experimental *Experimental     `toml:"experimental,omitempty" json:"experimental,omitempty"`
@@ -50,6 +51,7 @@ type Config struct {
 	// Others, less important.
 	Statics []Static            `toml:"statics,omitempty" json:"statics,omitempty"`
 	Metrics *api.MachineMetrics `toml:"metrics,omitempty" json:"metrics,omitempty"`
+	DNS     *DNS                `toml:"dns,omitempty" json:"dns,omitempty"`
 
 	// RawDefinition contains fly.toml parsed as-is
 	// If you add any config field that is v2 specific, be sure to remove it in SanitizeDefinition()
@@ -70,8 +72,71 @@ type Config struct {
 }
 
 type Deploy struct {
-	ReleaseCommand string `toml:"release_command,omitempty" json:"release_command,omitempty"`
-	Strategy       string `toml:"strategy,omitempty" json:"strategy,omitempty"`
+	ReleaseCommand  string           `toml:"release_command,omitempty" json:"release_command,omitempty"`
+	ReleaseCommands []ReleaseCommand `toml:"release_commands,omitempty" json:"release_commands,omitempty"`
+	Strategy        string           `toml:"strategy,omitempty" json:"strategy,omitempty"`
+	WebhookURL      string           `toml:"webhook_url,omitempty" json:"webhook_url,omitempty"`
+	// FallbackRegions are tried in order for new machines whose region is out of capacity
+	FallbackRegions []string `toml:"fallback_regions,omitempty" json:"fallback_regions,omitempty"`
+	// MaintenancePage is the path, relative to fly.toml, of the HTML page served during deploys with --maintenance-page
+	MaintenancePage string `toml:"maintenance_page,omitempty" json:"maintenance_page,omitempty"`
+	// MachineNameTemplate names new machines, e.g. "{group}-{region}-{index}", instead of random names
+	MachineNameTemplate string `toml:"machine_name_template,omitempty" json:"machine_name_template,omitempty"`
+	// ReleaseReady is waited for after the release commands succeeded, before machines are updated
+	ReleaseReady *ReleaseReady `toml:"release_ready,omitempty" json:"release_ready,omitempty"`
+	// ReadinessCommands replace the health checks of the updated machines of their group
+	ReadinessCommands []ReadinessCommand `toml:"readiness_commands,omitempty" json:"readiness_commands,omitempty"`
+	// ConditionalGroups are process groups deployed only when enabled, disabled ones have no machines
+	ConditionalGroups []ConditionalGroup `toml:"conditional_groups,omitempty" json:"conditional_groups,omitempty"`
+	// MaxConcurrentPerGroup bounds the machines of a process group updated at once, as a number
+	// or a percentage of the group like "50%". Groups left out are updated one machine at a time.
+	MaxConcurrentPerGroup map[string]string `toml:"max_concurrent_per_group,omitempty" json:"max_concurrent_per_group,omitempty"`
+}
+
+// ReleaseReady confirms what the release commands started is done, e.g. migrations applied
+// asynchronously. Set either HTTPURL or Command.
+type ReleaseReady struct {
+	// HTTPURL is polled until it answers with a 2xx status
+	HTTPURL string `toml:"http_url,omitempty" json:"http_url,omitempty"`
+	// Command is run on a started machine of the app, still on the previous release, until it exits 0
+	Command  string        `toml:"command,omitempty" json:"command,omitempty"`
+	Interval *api.Duration `toml:"interval,omitempty" json:"interval,omitempty"`
+	Timeout  *api.Duration `toml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// MachinesDeployStrategies are the [deploy] strategies fly deploy supports for machines apps
+var MachinesDeployStrategies = []string{"rolling", "immediate"}
+
+// ReleaseCommand is one of the [[deploy.release_commands]], run in order before machines are updated
+type ReleaseCommand struct {
+	Command string `toml:"command" json:"command"`
+	// ProcessGroup sizes the machine running the command like the machines of the group
+	ProcessGroup string `toml:"process_group,omitempty" json:"process_group,omitempty"`
+}
+
+// ReadinessCommand is one of the [[deploy.readiness_commands]]. It is run inside each updated machine
+// of its process group until it exits 0, instead of waiting for the machine health checks.
+type ReadinessCommand struct {
+	ProcessGroup string        `toml:"process_group" json:"process_group"`
+	Command      string        `toml:"command" json:"command"`
+	Interval     *api.Duration `toml:"interval,omitempty" json:"interval,omitempty"`
+	Timeout      *api.Duration `toml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// ConditionalGroup is one of the [[deploy.conditional_groups]]. Its process group is disabled when
+// enabled is false, or when enabled_env is set and that variable is empty in the environment flyctl
+// runs in. Deploys destroy the machines of disabled groups and don't launch new ones.
+type ConditionalGroup struct {
+	ProcessGroup string `toml:"process_group" json:"process_group"`
+	Enabled      *bool  `toml:"enabled,omitempty" json:"enabled,omitempty"`
+	EnabledEnv   string `toml:"enabled_env,omitempty" json:"enabled_env,omitempty"`
+}
+
+// DNS sets the resolv.conf of the machines, e.g. a search domain for service discovery
+type DNS struct {
+	Nameservers []string        `toml:"nameservers,omitempty" json:"nameservers,omitempty"`
+	Searches    []string        `toml:"searches,omitempty" json:"searches,omitempty"`
+	Options     []api.DNSOption `toml:"options,omitempty" json:"options,omitempty"`
 }
 
 type Static struct {
@@ -36,6 +36,7 @@ func (c *Config) SanitizedDefinition() map[string]any {
 	delete(definition, "app")
 	delete(definition, "build")
 	delete(definition, "primary_region")
+	delete(definition, "regions")
 	delete(definition, "http_service")
 	return definition
 }
@@ -163,6 +163,7 @@ func TestToDefinition(t *testing.T) {
 	assert.Equal(t, &api.Definition{
 		"app":            "foo",
 		"primary_region": "sea",
+		"regions":        []any{"sea", "ams"},
 		"kill_signal":    "SIGTERM",
 		"kill_timeout":   int64(3),
 
@@ -204,8 +205,27 @@ func TestToDefinition(t *testing.T) {
 		},
 
 		"deploy": map[string]any{
-			"release_command": "release command",
-			"strategy":        "rolling-eyes",
+			"release_command":       "release command",
+			"strategy":              "rolling-eyes",
+			"webhook_url":           "https://example.com/deploys",
+			"maintenance_page":      "maintenance.html",
+			"machine_name_template": "{group}-{region}-{index}",
+			"fallback_regions":      []any{"ord", "iad"},
+			"release_commands": []map[string]any{
+				{"command": "migrate analytics", "process_group": "web"},
+			},
+			"release_ready": map[string]any{
+				"http_url": "https://example.com/migrations/ready",
+				"interval": "10s",
+				"timeout":  "3m0s",
+			},
+			"readiness_commands": []map[string]any{
+				{"process_group": "worker", "command": "bin/ready", "interval": "5s", "timeout": "1m0s"},
+			},
+			"conditional_groups": []map[string]any{
+				{"process_group": "worker", "enabled": true, "enabled_env": "DEPLOY_WORKER"},
+			},
+			"max_concurrent_per_group": map[string]any{"web": "50%", "worker": "1"},
 		},
 		"env": map[string]any{
 			"FOO": "BAR",
@@ -214,6 +234,12 @@ func TestToDefinition(t *testing.T) {
 			"port": int64(9999),
 			"path": "/metrics",
 		},
+		"dns": map[string]any{
+			"searches": []any{"service.internal"},
+			"options": []map[string]any{
+				{"name": "ndots", "value": "2"},
+			},
+		},
 		"statics": []map[string]any{
 			{
 				"guest_path": "/path/to/statics",
@@ -2,6 +2,9 @@ package appconfig
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/shlex"
 	"github.com/samber/lo"
@@ -18,7 +21,12 @@ func (c *Config) ToMachineConfig(processGroup string, src *api.MachineConfig) (*
 }
 
 func (c *Config) ToReleaseMachineConfig() (*api.MachineConfig, error) {
-	releaseCmd, err := shlex.Split(c.Deploy.ReleaseCommand)
+	return c.ToReleaseCommandMachineConfig(ReleaseCommand{Command: c.Deploy.ReleaseCommand})
+}
+
+// ToReleaseCommandMachineConfig returns the config of the ephemeral machine running rc
+func (c *Config) ToReleaseCommandMachineConfig(rc ReleaseCommand) (*api.MachineConfig, error) {
+	releaseCmd, err := shlex.Split(rc.Command)
 	if err != nil {
 		return nil, err
 	}
@@ -46,10 +54,101 @@ func (c *Config) ToReleaseMachineConfig() (*api.MachineConfig, error) {
 	if c.PrimaryRegion != "" {
 		mConfig.Env["PRIMARY_REGION"] = c.PrimaryRegion
 	}
+	if rc.ProcessGroup != "" {
+		mConfig.Env["RELEASE_COMMAND_PROCESS_GROUP"] = rc.ProcessGroup
+	}
 
 	return mConfig, nil
 }
 
+// ReleaseCommands returns the release commands to run before updating machines, in order.
+// The single release_command goes first.
+func (c *Config) ReleaseCommands() []ReleaseCommand {
+	if c.Deploy == nil {
+		return nil
+	}
+	var cmds []ReleaseCommand
+	if c.Deploy.ReleaseCommand != "" {
+		cmds = append(cmds, ReleaseCommand{Command: c.Deploy.ReleaseCommand})
+	}
+	return append(cmds, c.Deploy.ReleaseCommands...)
+}
+
+// ReadinessCommand returns the [[deploy.readiness_commands]] of a process group, nil when it has none
+func (c *Config) ReadinessCommand(group string) *ReadinessCommand {
+	if c.Deploy == nil {
+		return nil
+	}
+	for i := range c.Deploy.ReadinessCommands {
+		if c.Deploy.ReadinessCommands[i].ProcessGroup == group {
+			return &c.Deploy.ReadinessCommands[i]
+		}
+	}
+	return nil
+}
+
+// ProcessGroupEnabled tells if a process group is deployed according to its [[deploy.conditional_groups]],
+// getenv reads the environment enabled_env is looked up in. Groups without conditions are enabled.
+func (c *Config) ProcessGroupEnabled(group string, getenv func(string) string) bool {
+	if c.Deploy == nil {
+		return true
+	}
+	for _, cg := range c.Deploy.ConditionalGroups {
+		if cg.ProcessGroup != group {
+			continue
+		}
+		if cg.Enabled != nil && !*cg.Enabled {
+			return false
+		}
+		return cg.EnabledEnv == "" || getenv(cg.EnabledEnv) != ""
+	}
+	return true
+}
+
+// MaxConcurrentUpdates returns how many of the machines of a process group deploys update at once,
+// from its [deploy] max_concurrent_per_group limit and the machines it has. It's at least 1.
+func (c *Config) MaxConcurrentUpdates(group string, machines int) (int, error) {
+	if c.Deploy == nil || c.Deploy.MaxConcurrentPerGroup[group] == "" {
+		return 1, nil
+	}
+	limit := c.Deploy.MaxConcurrentPerGroup[group]
+	var n int
+	if percent, ok := strings.CutSuffix(limit, "%"); ok {
+		p, err := strconv.ParseFloat(percent, 64)
+		if err != nil || p <= 0 || p > 100 {
+			return 0, fmt.Errorf("invalid max_concurrent_per_group '%s' for group '%s', percentages go from 0%% to 100%%", limit, group)
+		}
+		n = int(float64(machines) * p / 100)
+	} else {
+		var err error
+		if n, err = strconv.Atoi(limit); err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid max_concurrent_per_group '%s' for group '%s', use a positive number of machines or a percentage like 50%%", limit, group)
+		}
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n, nil
+}
+
+// ToMachineDNS returns the machine DNS config with the resolver settings of fly.toml [dns].
+// fly.toml owns them, they are removed along with the section. Settings flyctl manages are kept from src.
+func (c *Config) ToMachineDNS(src *api.DNSConfig) *api.DNSConfig {
+	dns := &api.DNSConfig{}
+	if src != nil {
+		dns.SkipRegistration = src.SkipRegistration
+	}
+	if c.DNS != nil {
+		dns.Nameservers = c.DNS.Nameservers
+		dns.Searches = c.DNS.Searches
+		dns.Options = c.DNS.Options
+	}
+	if !dns.SkipRegistration && len(dns.Nameservers) == 0 && len(dns.Searches) == 0 && len(dns.Options) == 0 {
+		return nil
+	}
+	return dns
+}
+
 // updateMachineConfig applies configuration options from the optional MachineConfig passed in, then the base config, into a new MachineConfig
 func (c *Config) updateMachineConfig(src *api.MachineConfig) (*api.MachineConfig, error) {
 	// For flattened app configs there is only one proces name and it is the group it was flattened for
@@ -63,6 +162,9 @@ func (c *Config) updateMachineConfig(src *api.MachineConfig) (*api.MachineConfig
 	// Metrics
 	mConfig.Metrics = c.Metrics
 
+	// DNS
+	mConfig.DNS = c.ToMachineDNS(mConfig.DNS)
+
 	// Init
 	cmd, err := c.InitCmd(processGroup)
 	if err != nil {
@@ -131,5 +233,14 @@ func (c *Config) updateMachineConfig(src *api.MachineConfig) (*api.MachineConfig
 		})
 	}
 
+	// StopConfig
+	mConfig.StopConfig = nil
+	if c.KillSignal != nil || c.KillTimeout != nil {
+		mConfig.StopConfig = &api.StopConfig{Signal: c.KillSignal}
+		if c.KillTimeout != nil {
+			mConfig.StopConfig.Timeout = &api.Duration{Duration: time.Duration(*c.KillTimeout) * time.Second}
+		}
+	}
+
 	return mConfig, nil
 }
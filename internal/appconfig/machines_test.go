@@ -127,6 +127,29 @@ func TestToReleaseMachineConfig(t *testing.T) {
 	assert.Equal(t, want, got)
 }
 
+func TestToReleaseCommandMachineConfig_processGroup(t *testing.T) {
+	cfg := &Config{
+		PrimaryRegion: "mia",
+		Processes:     map[string]string{"web": "run web", "analytics": "run analytics"},
+		Deploy: &Deploy{
+			ReleaseCommand:  "migrate-db",
+			ReleaseCommands: []ReleaseCommand{{Command: "migrate-analytics --all", ProcessGroup: "analytics"}},
+		},
+	}
+
+	cmds := cfg.ReleaseCommands()
+	assert.Equal(t, []ReleaseCommand{
+		{Command: "migrate-db"},
+		{Command: "migrate-analytics --all", ProcessGroup: "analytics"},
+	}, cmds)
+
+	got, err := cfg.ToReleaseCommandMachineConfig(cmds[1])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"migrate-analytics", "--all"}, got.Init.Cmd)
+	assert.Equal(t, "analytics", got.Env["RELEASE_COMMAND_PROCESS_GROUP"])
+	assert.Equal(t, "fly_app_release_command", got.Metadata["fly_process_group"])
+}
+
 func TestToMachineConfig_multiProcessGroups(t *testing.T) {
 	cfg, err := LoadConfig("./testdata/tomachine-processgroups.toml")
 	require.NoError(t, err)
@@ -305,3 +328,80 @@ func TestToMachineConfig_services(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, want, got.Services)
 }
+
+func TestReadinessCommand(t *testing.T) {
+	cfg := &Config{
+		Processes: map[string]string{"app": "run app", "worker": "run worker"},
+		Deploy: &Deploy{ReadinessCommands: []ReadinessCommand{
+			{ProcessGroup: "worker", Command: "bin/ready"},
+		}},
+	}
+	require.NoError(t, cfg.SetMachinesPlatform())
+	assert.Equal(t, "bin/ready", cfg.ReadinessCommand("worker").Command)
+	assert.Nil(t, cfg.ReadinessCommand("app"))
+
+	cfg.Deploy.ReadinessCommands = append(cfg.Deploy.ReadinessCommands, ReadinessCommand{ProcessGroup: "worker", Command: "true"})
+	extraInfo, err := cfg.validateDeploySection()
+	assert.Error(t, err)
+	assert.Contains(t, extraInfo, "Process group 'worker' has more than one readiness command")
+}
+
+func TestProcessGroupEnabled(t *testing.T) {
+	cfg := &Config{
+		Processes: map[string]string{"app": "run app", "debug": "run debug", "worker": "run worker"},
+		Deploy: &Deploy{ConditionalGroups: []ConditionalGroup{
+			{ProcessGroup: "debug", EnabledEnv: "ENABLE_DEBUG"},
+			{ProcessGroup: "worker", Enabled: api.Pointer(false), EnabledEnv: "ENABLE_WORKER"},
+		}},
+	}
+	require.NoError(t, cfg.SetMachinesPlatform())
+	env := map[string]string{}
+	getenv := func(name string) string { return env[name] }
+
+	assert.True(t, cfg.ProcessGroupEnabled("app", getenv))
+	assert.False(t, cfg.ProcessGroupEnabled("debug", getenv))
+	assert.False(t, cfg.ProcessGroupEnabled("worker", getenv))
+
+	env["ENABLE_DEBUG"] = "1"
+	env["ENABLE_WORKER"] = "1"
+	assert.True(t, cfg.ProcessGroupEnabled("debug", getenv))
+	// enabled = false wins over the environment
+	assert.False(t, cfg.ProcessGroupEnabled("worker", getenv))
+
+	cfg.Deploy.ConditionalGroups = append(cfg.Deploy.ConditionalGroups, ConditionalGroup{ProcessGroup: "dbug"})
+	extraInfo, err := cfg.validateDeploySection()
+	assert.Error(t, err)
+	assert.Contains(t, extraInfo, "Conditional group 'dbug' in [[deploy.conditional_groups]] isn't defined in [processes]")
+}
+
+func TestMaxConcurrentUpdates(t *testing.T) {
+	cfg := &Config{
+		Processes: map[string]string{"web": "run web", "worker": "run worker", "cron": "run cron"},
+		Deploy: &Deploy{MaxConcurrentPerGroup: map[string]string{
+			"web":    "50%",
+			"worker": "2",
+		}},
+	}
+	require.NoError(t, cfg.SetMachinesPlatform())
+
+	n, err := cfg.MaxConcurrentUpdates("web", 9)
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+	// Percentages never go below a machine at a time
+	n, err = cfg.MaxConcurrentUpdates("web", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = cfg.MaxConcurrentUpdates("worker", 9)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	n, err = cfg.MaxConcurrentUpdates("cron", 9)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	cfg.Deploy.MaxConcurrentPerGroup["cron"] = "150%"
+	cfg.Deploy.MaxConcurrentPerGroup["wrker"] = "1"
+	extraInfo, err := cfg.validateDeploySection()
+	assert.Error(t, err)
+	assert.Contains(t, extraInfo, "invalid max_concurrent_per_group '150%' for group 'cron'")
+	assert.Contains(t, extraInfo, "Process group 'wrker' in [deploy.max_concurrent_per_group] isn't defined in [processes]")
+}
@@ -17,6 +17,7 @@ var configPatches = []patchFuncType{
 	patchExperimental,
 	patchTopLevelChecks,
 	patchMounts,
+	patchDeploy,
 }
 
 func applyPatches(cfgMap map[string]any) (*Config, error) {
@@ -72,6 +73,26 @@ func patchEnv(cfg map[string]any) (map[string]any, error) {
 	return cfg, nil
 }
 
+func patchDeploy(cfg map[string]any) (map[string]any, error) {
+	deploy, ok := cfg["deploy"].(map[string]any)
+	if !ok {
+		return cfg, nil
+	}
+	if raw, ok := deploy["max_concurrent_per_group"]; ok {
+		cast, ok := raw.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("Do not know how to process 'deploy.max_concurrent_per_group' of type: %T", raw)
+		}
+		// Limits are either numbers or percentages, keep them all as strings
+		limits := map[string]string{}
+		for group, v := range cast {
+			limits[group] = fmt.Sprintf("%v", v)
+		}
+		deploy["max_concurrent_per_group"] = limits
+	}
+	return cfg, nil
+}
+
 func patchProcesses(cfg map[string]any) (map[string]any, error) {
 	if raw, ok := cfg["processes"]; ok {
 		switch cast := raw.(type) {
@@ -286,6 +286,7 @@ func TestLoadTOMLAppConfigReferenceFormat(t *testing.T) {
 		KillSignal:       api.Pointer("SIGTERM"),
 		KillTimeout:      api.Pointer(3),
 		PrimaryRegion:    "sea",
+		Regions:          []string{"sea", "ams"},
 		Experimental: &Experimental{
 			Cmd:          []string{"cmd"},
 			Entrypoint:   []string{"entrypoint"},
@@ -315,8 +316,32 @@ func TestLoadTOMLAppConfigReferenceFormat(t *testing.T) {
 		},
 
 		Deploy: &Deploy{
-			ReleaseCommand: "release command",
-			Strategy:       "rolling-eyes",
+			ReleaseCommand:      "release command",
+			Strategy:            "rolling-eyes",
+			WebhookURL:          "https://example.com/deploys",
+			MaintenancePage:     "maintenance.html",
+			MachineNameTemplate: "{group}-{region}-{index}",
+			FallbackRegions:     []string{"ord", "iad"},
+			ReleaseCommands: []ReleaseCommand{
+				{Command: "migrate analytics", ProcessGroup: "web"},
+			},
+			ReleaseReady: &ReleaseReady{
+				HTTPURL:  "https://example.com/migrations/ready",
+				Interval: api.MustParseDuration("10s"),
+				Timeout:  api.MustParseDuration("3m"),
+			},
+			ReadinessCommands: []ReadinessCommand{{
+				ProcessGroup: "worker",
+				Command:      "bin/ready",
+				Interval:     api.MustParseDuration("5s"),
+				Timeout:      api.MustParseDuration("1m"),
+			}},
+			ConditionalGroups: []ConditionalGroup{{
+				ProcessGroup: "worker",
+				Enabled:      api.Pointer(true),
+				EnabledEnv:   "DEPLOY_WORKER",
+			}},
+			MaxConcurrentPerGroup: map[string]string{"web": "50%", "worker": "1"},
 		},
 
 		Env: map[string]string{
@@ -328,6 +353,11 @@ func TestLoadTOMLAppConfigReferenceFormat(t *testing.T) {
 			Path: "/metrics",
 		},
 
+		DNS: &DNS{
+			Searches: []string{"service.internal"},
+			Options:  []api.DNSOption{{Name: "ndots", Value: "2"}},
+		},
+
 		HTTPService: &HTTPService{
 			InternalPort: 8080,
 			ForceHTTPS:   true,
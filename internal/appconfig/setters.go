@@ -250,6 +250,42 @@ func (c *Config) v1SetProcess(name, value string) {
 	}
 }
 
+// RemoveServices drops the services and checks of apps serving no network traffic, like background workers
+func (c *Config) RemoveServices() {
+	c.v1RemoveServices()
+	c.HTTPService = nil
+	c.Services = nil
+	c.Checks = nil
+}
+
+func (c *Config) v1RemoveServices() {
+	delete(c.RawDefinition, "services")
+	delete(c.RawDefinition, "http_service")
+	delete(c.RawDefinition, "checks")
+}
+
+// SetGRPCService replaces the services of the app with one serving a gRPC server on port over
+// HTTP/2. The proxy terminates TLS and negotiates h2 through ALPN, then forwards cleartext HTTP/2.
+// Its checks can't speak the grpc.health.v1 protocol, a TCP check is used instead.
+func (c *Config) SetGRPCService(port int) {
+	c.RemoveServices()
+	c.Services = []Service{{
+		Protocol:     "tcp",
+		InternalPort: port,
+		Ports: []api.MachinePort{{
+			Port:       api.IntPointer(443),
+			Handlers:   []string{"tls"},
+			TlsOptions: &api.TlsOptions{Alpn: []string{"h2"}},
+		}},
+		TCPChecks: []*ServiceTCPCheck{{
+			Interval:    &api.Duration{Duration: 15 * time.Second},
+			Timeout:     &api.Duration{Duration: 2 * time.Second},
+			GracePeriod: &api.Duration{Duration: 5 * time.Second},
+		}},
+	}}
+	c.RawDefinition["services"] = c.Services
+}
+
 func (c *Config) SetStatics(statics []Static) {
 	c.RawDefinition["statics"] = statics
 	c.Statics = make([]Static, 0, len(statics))
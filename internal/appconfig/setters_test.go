@@ -101,6 +101,26 @@ func TestSettersWithouServices(t *testing.T) {
 	assert.Equal(t, cfg.RawDefinition, map[string]any{})
 }
 
+func TestRemoveServices(t *testing.T) {
+	cfg, err := LoadConfig("./testdata/setters-service.toml")
+	require.NoError(t, err)
+	cfg.SetHttpCheck("/status")
+
+	cfg.RemoveServices()
+	assert.Nil(t, cfg.Services)
+	assert.Nil(t, cfg.HTTPService)
+	assert.Nil(t, cfg.Checks)
+	assert.NotContains(t, cfg.RawDefinition, "services")
+
+	cfg, err = LoadConfig("./testdata/setters-httpservice.toml")
+	require.NoError(t, err)
+	cfg.SetHttpCheck("/status")
+
+	cfg.RemoveServices()
+	assert.Nil(t, cfg.HTTPService)
+	assert.Nil(t, cfg.Checks)
+}
+
 func TestSetEnvVariable(t *testing.T) {
 	cfg := NewConfig()
 	cfg.SetEnvVariable("a", "1")
@@ -254,3 +274,22 @@ func TestSetKillSignal(t *testing.T) {
 	assert.Equal(t, cfg.KillSignal, api.Pointer("TERM"))
 	assert.Equal(t, cfg.RawDefinition, map[string]any{"kill_signal": "TERM"})
 }
+
+func TestSetGRPCService(t *testing.T) {
+	cfg, err := LoadConfig("./testdata/setters-httpservice.toml")
+	require.NoError(t, err)
+	cfg.SetHttpCheck("/status")
+
+	cfg.SetGRPCService(50051)
+	assert.Nil(t, cfg.HTTPService)
+	assert.Nil(t, cfg.Checks)
+	require.Len(t, cfg.Services, 1)
+	service := cfg.Services[0]
+	assert.Equal(t, 50051, service.InternalPort)
+	require.Len(t, service.Ports, 1)
+	assert.Equal(t, []string{"tls"}, service.Ports[0].Handlers)
+	assert.Equal(t, []string{"h2"}, service.Ports[0].TlsOptions.Alpn)
+	assert.Len(t, service.TCPChecks, 1)
+	assert.Empty(t, service.HTTPChecks)
+	assert.Contains(t, cfg.RawDefinition, "services")
+}
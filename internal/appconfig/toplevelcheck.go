@@ -67,14 +67,23 @@ func (chk *ToplevelCheck) toMachineCheck() (*api.MachineCheck, error) {
 		res.HTTPMethod = api.Pointer(strings.ToUpper(*chk.HTTPMethod))
 	}
 	if len(chk.HTTPHeaders) > 0 {
-		res.HTTPHeaders = lo.MapToSlice(
-			chk.HTTPHeaders, func(k string, v string) api.MachineHTTPHeader {
-				return api.MachineHTTPHeader{Name: k, Values: []string{v}}
-			})
+		res.HTTPHeaders = machineHTTPHeaders(chk.HTTPHeaders)
 	}
 	return res, nil
 }
 
+// machineHTTPHeaders converts check headers to the machine format. They are sorted by name
+// so the same fly.toml always gives the same machine config.
+func machineHTTPHeaders(headers map[string]string) []api.MachineHTTPHeader {
+	res := lo.MapToSlice(headers, func(k string, v string) api.MachineHTTPHeader {
+		return api.MachineHTTPHeader{Name: k, Values: []string{v}}
+	})
+	slices.SortFunc(res, func(a, b api.MachineHTTPHeader) bool {
+		return a.Name < b.Name
+	})
+	return res
+}
+
 func (chk *ToplevelCheck) String() string {
 	chkType := "none"
 	if chk.Type != nil {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"strings"
 
 	"github.com/google/shlex"
@@ -135,6 +136,81 @@ func (cfg *Config) validateDeploySection() (extraInfo string, err error) {
 			extraInfo += fmt.Sprintf("Can't shell split release command: '%s'\n", cfg.Deploy.ReleaseCommand)
 			err = ValidationError
 		}
+		if s := cfg.Deploy.Strategy; s != "" && !slices.Contains(MachinesDeployStrategies, s) {
+			extraInfo += fmt.Sprintf("Unsupported [deploy] strategy '%s', use one of %s\n", s, strings.Join(MachinesDeployStrategies, ", "))
+			err = ValidationError
+		}
+		if rr := cfg.Deploy.ReleaseReady; rr != nil {
+			switch {
+			case (rr.HTTPURL == "") == (rr.Command == ""):
+				extraInfo += "[deploy.release_ready] must set either http_url or command\n"
+				err = ValidationError
+			case rr.HTTPURL != "":
+				if u, vErr := url.Parse(rr.HTTPURL); vErr != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+					extraInfo += fmt.Sprintf("[deploy.release_ready] http_url '%s' must be an http or https URL\n", rr.HTTPURL)
+					err = ValidationError
+				}
+			default:
+				if _, vErr := shlex.Split(rr.Command); vErr != nil {
+					extraInfo += fmt.Sprintf("Can't shell split [deploy.release_ready] command: '%s'\n", rr.Command)
+					err = ValidationError
+				}
+			}
+		}
+		for _, rc := range cfg.Deploy.ReleaseCommands {
+			if rc.Command == "" {
+				extraInfo += "Release commands in [[deploy.release_commands]] must set a command\n"
+				err = ValidationError
+			} else if _, vErr := shlex.Split(rc.Command); vErr != nil {
+				extraInfo += fmt.Sprintf("Can't shell split release command: '%s'\n", rc.Command)
+				err = ValidationError
+			}
+			if rc.ProcessGroup != "" && !slices.Contains(cfg.ProcessNames(), rc.ProcessGroup) {
+				extraInfo += fmt.Sprintf("Release command '%s' is for process group '%s' which isn't defined in [processes]\n", rc.Command, rc.ProcessGroup)
+				err = ValidationError
+			}
+		}
+		seenGroups := map[string]bool{}
+		for _, rc := range cfg.Deploy.ReadinessCommands {
+			switch {
+			case rc.Command == "":
+				extraInfo += "Readiness commands in [[deploy.readiness_commands]] must set a command\n"
+				err = ValidationError
+			case !slices.Contains(cfg.ProcessNames(), rc.ProcessGroup):
+				extraInfo += fmt.Sprintf("Readiness command '%s' is for process group '%s' which isn't defined in [processes]\n", rc.Command, rc.ProcessGroup)
+				err = ValidationError
+			case seenGroups[rc.ProcessGroup]:
+				extraInfo += fmt.Sprintf("Process group '%s' has more than one readiness command\n", rc.ProcessGroup)
+				err = ValidationError
+			default:
+				if _, vErr := shlex.Split(rc.Command); vErr != nil {
+					extraInfo += fmt.Sprintf("Can't shell split readiness command: '%s'\n", rc.Command)
+					err = ValidationError
+				}
+			}
+			seenGroups[rc.ProcessGroup] = true
+		}
+		conditionalGroups := map[string]bool{}
+		for _, cg := range cfg.Deploy.ConditionalGroups {
+			switch {
+			case !slices.Contains(cfg.ProcessNames(), cg.ProcessGroup):
+				extraInfo += fmt.Sprintf("Conditional group '%s' in [[deploy.conditional_groups]] isn't defined in [processes]\n", cg.ProcessGroup)
+				err = ValidationError
+			case conditionalGroups[cg.ProcessGroup]:
+				extraInfo += fmt.Sprintf("Process group '%s' has more than one condition in [[deploy.conditional_groups]]\n", cg.ProcessGroup)
+				err = ValidationError
+			}
+			conditionalGroups[cg.ProcessGroup] = true
+		}
+		for group := range cfg.Deploy.MaxConcurrentPerGroup {
+			if !slices.Contains(cfg.ProcessNames(), group) {
+				extraInfo += fmt.Sprintf("Process group '%s' in [deploy.max_concurrent_per_group] isn't defined in [processes]\n", group)
+				err = ValidationError
+			} else if _, vErr := cfg.MaxConcurrentUpdates(group, 1); vErr != nil {
+				extraInfo += vErr.Error() + "\n"
+				err = ValidationError
+			}
+		}
 	}
 	return
 }
@@ -136,8 +136,9 @@ func (*builtinBuilder) Run(ctx context.Context, dockerFactory *dockerClientFacto
 	fmt.Println(img)
 
 	return &DeploymentImage{
-		ID:   img.ID,
-		Tag:  opts.Tag,
-		Size: img.Size,
+		ID:       img.ID,
+		Tag:      opts.Tag,
+		Size:     img.Size,
+		Platform: imagePlatform(img),
 	}, "", nil
 }
@@ -420,6 +420,18 @@ func authConfigs() map[string]types.AuthConfig {
 	return authConfigs
 }
 
+// imagePlatform formats the os/arch[/variant] of an inspected image, empty when docker didn't report it
+func imagePlatform(img types.ImageInspect) string {
+	if img.Os == "" || img.Architecture == "" {
+		return ""
+	}
+	platform := img.Os + "/" + img.Architecture
+	if img.Variant != "" {
+		platform += "/" + img.Variant
+	}
+	return platform
+}
+
 func flyRegistryAuth() string {
 	accessToken := flyctl.GetAPIToken()
 	authConfig := registryAuth(accessToken)
@@ -225,9 +225,10 @@ func (*dockerfileBuilder) Run(ctx context.Context, dockerFactory *dockerClientFa
 	}
 
 	return &DeploymentImage{
-		ID:   img.ID,
-		Tag:  opts.Tag,
-		Size: img.Size,
+		ID:       img.ID,
+		Tag:      opts.Tag,
+		Size:     img.Size,
+		Platform: imagePlatform(img),
 	}, "", nil
 }
 
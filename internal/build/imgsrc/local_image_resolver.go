@@ -93,6 +93,11 @@ func (*localImageResolver) Run(ctx context.Context, dockerFactory *dockerClientF
 		Tag:  opts.Tag,
 		Size: img.Size,
 	}
+	if inspect, _, err := docker.ImageInspectWithRaw(ctx, img.ID); err == nil {
+		di.Platform = imagePlatform(inspect)
+	} else {
+		terminal.Debugf("could not inspect image %s for its platform: %v\n", img.ID, err)
+	}
 
 	return di, "", nil
 }
@@ -58,6 +58,13 @@ type DeploymentImage struct {
 	ID   string
 	Tag  string
 	Size int64
+	// SourceHash is the SourceHash of the sources the image was built from, when known
+	SourceHash string
+	// BuildDuration and PushDuration are how long building and pushing the image took, zero when it wasn't built
+	BuildDuration time.Duration
+	PushDuration  time.Duration
+	// Platform is the os/arch the image was built for, e.g. linux/amd64, empty when unknown
+	Platform string
 }
 
 type Resolver struct {
@@ -154,6 +161,8 @@ func (r *Resolver) BuildImage(ctx context.Context, streams *iostreams.IOStreams,
 			bld.BuildAndPushFinish()
 			bld.FinishStrategy(s, false /* success */, nil, note)
 			r.finishBuild(ctx, bld, false /* completed */, "", img)
+			img.BuildDuration = timingDuration(bld.Timings.BuildMs)
+			img.PushDuration = timingDuration(bld.Timings.PushMs)
 			return img, nil
 		}
 		bld.BuildAndPushFinish()
@@ -317,6 +326,14 @@ func (b *build) ResetTimings() {
 	}
 }
 
+// timingDuration converts a timing in milliseconds, -1 when it wasn't measured
+func timingDuration(ms int64) time.Duration {
+	if ms < 0 {
+		return 0
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
 func (b *build) BuildAndPushStart() {
 	b.StartTimes.BuildAndPushMs = time.Now().UnixMilli()
 }
@@ -0,0 +1,121 @@
+package imgsrc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/docker/pkg/fileutils"
+	"github.com/pkg/errors"
+)
+
+// SourceHash hashes what an image built with opts depends on: the files of the build context
+// docker doesn't ignore, the Dockerfile and the build settings, build args included.
+// The same source gives the same hash, so a deploy can tell nothing changed since the last build.
+func SourceHash(opts ImageOptions) (string, error) {
+	h := sha256.New()
+
+	settings, err := json.Marshal(struct {
+		BuildArgs       map[string]string
+		ExtraBuildArgs  map[string]string
+		BuildSecrets    map[string]string
+		Target          string
+		BuiltIn         string
+		BuiltInSettings map[string]interface{}
+		Builder         string
+		Buildpacks      []string
+	}{
+		opts.BuildArgs, opts.ExtraBuildArgs, opts.BuildSecrets, opts.Target,
+		opts.BuiltIn, opts.BuiltInSettings, opts.Builder, opts.Buildpacks,
+	})
+	if err != nil {
+		return "", err
+	}
+	h.Write(settings)
+
+	// The Dockerfile can live outside of the build context
+	if opts.DockerfilePath != "" {
+		if err := hashFile(h, "Dockerfile", opts.DockerfilePath); err != nil {
+			return "", errors.Wrap(err, "error reading Dockerfile")
+		}
+	}
+
+	excludes, err := readDockerignore(opts.WorkingDir, opts.IgnorefilePath)
+	if err != nil {
+		return "", errors.Wrap(err, "error reading .dockerignore")
+	}
+	matcher, err := fileutils.NewPatternMatcher(excludes)
+	if err != nil {
+		return "", err
+	}
+
+	err = filepath.WalkDir(opts.WorkingDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(opts.WorkingDir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		// Commits and the app config change without the image needing a rebuild
+		if rel == ".git" || rel == "fly.toml" {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if excluded, _ := matcher.Matches(rel); excluded {
+			// Exclusions may bring back files of an ignored directory
+			if d.IsDir() && !matcher.Exclusions() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		switch {
+		case d.IsDir():
+			return nil
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			io.WriteString(h, rel+"\x00"+target+"\x00")
+			return nil
+		case !d.Type().IsRegular():
+			return nil
+		}
+		return hashFile(h, rel, path)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashFile writes the name, permissions and content of a file to h
+func hashFile(h io.Writer, name, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close() //skipcq: GO-S2307
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	io.WriteString(h, name+"\x00"+info.Mode().Perm().String()+"\x00")
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	_, err = io.WriteString(h, "\x00")
+	return err
+}
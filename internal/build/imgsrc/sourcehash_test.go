@@ -0,0 +1,51 @@
+package imgsrc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceHash(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	write("Dockerfile", "FROM alpine\nCOPY . /app\n")
+	write("main.go", "package main")
+	write("node_modules/dep/index.js", "module.exports = 1")
+	write(".dockerignore", "node_modules\n")
+	write("fly.toml", "app = 'my-app'")
+
+	opts := ImageOptions{
+		WorkingDir:     dir,
+		DockerfilePath: filepath.Join(dir, "Dockerfile"),
+		BuildArgs:      map[string]string{"VERSION": "1"},
+	}
+	hash := func() string {
+		h, err := SourceHash(opts)
+		require.NoError(t, err)
+		return h
+	}
+	initial := hash()
+	assert.Equal(t, initial, hash())
+
+	// Ignored files and the app config don't matter
+	write("node_modules/dep/index.js", "module.exports = 2")
+	write("fly.toml", "app = 'my-app'\nprimary_region = 'ord'")
+	assert.Equal(t, initial, hash())
+
+	// Build args do
+	opts.BuildArgs = map[string]string{"VERSION": "2"}
+	withOtherArgs := hash()
+	assert.NotEqual(t, initial, withOtherArgs)
+
+	// And so do the sources
+	write("main.go", "package main\n\nfunc main() {}")
+	assert.NotEqual(t, withOtherArgs, hash())
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/superfly/flyctl/internal/build/imgsrc"
 	"github.com/superfly/flyctl/internal/command"
 	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/internal/machine"
 	"github.com/superfly/flyctl/internal/render"
 	"github.com/superfly/flyctl/internal/sentry"
 
@@ -31,6 +32,7 @@ var CommonFlags = flag.Set{
 	flag.Now(),
 	flag.RemoteOnly(false),
 	flag.LocalOnly(),
+	flag.Builder(),
 	flag.Push(),
 	flag.Detach(),
 	flag.Strategy(),
@@ -52,11 +54,33 @@ var CommonFlags = flag.Set{
 		Name:        "auto-confirm",
 		Description: "Will automatically confirm changes when running non-interactively.",
 	},
+	flag.Int{
+		Name:        "confirm-destroy-over",
+		Description: "Ask for confirmation, or require --auto-confirm when not interactive, before destroying more than this number of machines of process groups removed from fly.toml",
+		Default:     defaultConfirmDestroyOver,
+	},
+	flag.String{
+		Name:        "machine-name-template",
+		Description: "Name new machines after this template instead of randomly, e.g. '{group}-{region}-{index}'. {index} is the lowest number making the name unique",
+	},
+	flag.String{
+		Name:        "primary-region",
+		Description: "Launch new machines and release commands in this region for this deploy only, without changing the primary region of fly.toml",
+	},
+	flag.Int{
+		Name:        "region-concurrency",
+		Description: "Maximum number of machines of a single region the deploy works on at once, so no region's machines API gets rate limited",
+		Default:     defaultRegionConcurrency,
+	},
 	flag.Int{
 		Name:        "wait-timeout",
 		Description: "Seconds to wait for individual machines to transition states and become healthy.",
 		Default:     int(DefaultWaitTimeout.Seconds()),
 	},
+	flag.Int{
+		Name:        "new-machine-wait-timeout",
+		Description: "Seconds to wait for newly created machines to start and become healthy, they may need to pull the image first. Defaults to twice --wait-timeout",
+	},
 	flag.Int{
 		Name:        "lease-timeout",
 		Description: "Seconds to lease individual machines while running deployment. All machines are leased at the beginning and released at the end. The lease is refreshed periodically for this same time, which is why it is short. flyctl releases leases in most cases.",
@@ -76,6 +100,161 @@ var CommonFlags = flag.Set{
 		Name:        "vm-size",
 		Description: `The VM size to use when deploying for the first time. See "fly platform vm-sizes" for valid values`,
 	},
+	flag.String{
+		Name:        "vm-gpu-kind",
+		Description: "The kind of GPU new machines get, e.g. a100-40gb. Machines are performance-8x unless --vm-size is set",
+	},
+	flag.Duration{
+		Name:        "deploy-timeout",
+		Description: "Maximum time the whole deployment may take before it's aborted and marked as failed, e.g. 30m. No limit by default.",
+	},
+	flag.String{
+		Name:        "command",
+		Description: "Override the command run by the app machines for this deploy only, e.g. \"sleep infinity\" to debug crashing machines. Not saved to fly.toml.",
+	},
+	flag.StringSlice{
+		Name:        "set-env",
+		Description: "Set environment variables in the form of NAME=VALUE on the app machines for this deploy only, they take precedence over fly.toml [env] and --env. Not saved to fly.toml, the next deploy without them removes them. Can be specified multiple times.",
+	},
+	flag.String{
+		Name:        "env-file",
+		Description: "Load environment variables for the app machines from a dotenv file, for this deploy only. They take precedence over fly.toml [env] and --env, --set-env takes precedence over them. Not saved to fly.toml",
+	},
+	flag.Bool{
+		Name:        "only-changed",
+		Description: "Skip updating machines whose configuration already matches the one being deployed, useful to retry a partially failed deploy",
+		Default:     false,
+	},
+	flag.String{
+		Name:        "update-order",
+		Description: "Order to update machines relative to the primary region: primary-first or primary-last",
+		Default:     updateOrderPrimaryLast,
+	},
+	flag.String{
+		Name:        "image-pull-policy",
+		Description: "How machines track the image tag: auto or always. auto pins the tag to its digest when the registry resolves it and falls back to the tag otherwise. always requires resolving the digest, a registry round-trip per deploy, so an image pushed again under the same tag is always picked up",
+		Default:     imagePullPolicyAuto,
+	},
+	flag.Bool{
+		Name:        "detach-volumes",
+		Description: "Replace machines whose volume isn't mounted in fly.toml anymore. The volume is left detached with its data, without it the deploy stops",
+		Default:     false,
+	},
+	flag.Bool{
+		Name:        "auto-create-volumes",
+		Description: "Create the volumes new and replacement machines need to mount when the app doesn't have enough unattached ones, instead of failing the deploy",
+		Default:     false,
+	},
+	flag.Bool{
+		Name:        "fail-on-missing-machines",
+		Description: "Fail the deploy when a process group is left with fewer machines than expected once it's done, instead of only warning",
+		Default:     false,
+	},
+	flag.Bool{
+		Name:        "fail-on-image-mismatch",
+		Description: "Fail the deploy when a machine doesn't run the deployed image digest once it's done, instead of only warning",
+		Default:     false,
+	},
+	flag.StringSlice{
+		Name:        "enable-process-group",
+		Description: "Deploy this process group even if [[deploy.conditional_groups]] in fly.toml disables it. Can be specified multiple times",
+	},
+	flag.Bool{
+		Name:        "no-public-ips-wait",
+		Description: "Skip the service checks of [[services]] and [http_service] while waiting for machines to be healthy, only the machine checks of [checks] are waited on",
+		Default:     false,
+	},
+	flag.Bool{
+		Name:        "build-only-if-changed",
+		Description: "Skip the build and deploy the image of the latest release again when the sources and build args didn't change since it was built",
+		Default:     false,
+	},
+	flag.Bool{
+		Name:        "force-build",
+		Description: "Build the image even if --build-only-if-changed finds the sources unchanged",
+		Default:     false,
+	},
+	flag.Bool{
+		Name:        "require-secrets",
+		Description: "Fail the deploy before building when secrets it references aren't set on the app, instead of only warning",
+		Default:     false,
+	},
+	flag.Bool{
+		Name:        "force-lease",
+		Description: "Clear machine leases left behind by a deploy that is no longer running, e.g. one that crashed, instead of failing",
+		Default:     false,
+	},
+	flag.String{
+		Name:        "min-healthy",
+		Description: "Percentage of updated machines that must pass health checks for the deploy to succeed, e.g. 95%. Unhealthy machines are reported",
+		Default:     "100%",
+	},
+	flag.Int{
+		Name:        "immediate-max-errors",
+		Description: "Abort a deploy with the immediate strategy once this many machine errors happened, instead of going on. Defaults to no limit",
+	},
+	flag.Duration{
+		Name:        "max-poll-interval",
+		Description: "Longest pause between two status checks of a machine being waited on, e.g. 10s. Checks start 1s apart and back off up to 5s by default",
+	},
+	flag.Duration{
+		Name:        "drain-timeout",
+		Description: "Time given to machines about to be destroyed to finish in-flight requests after they stop receiving new ones, e.g. 30s. Machines are destroyed right away by default",
+	},
+	flag.Int{
+		Name:        "keep-previous",
+		Description: "Stop the machines replaced by the deploy instead of destroying them, keeping those of the last N releases for a fast rollback with --from-release, which starts them again. Kept machines get no requests and use machine slots, those of older releases are destroyed by later deploys",
+	},
+	flag.Duration{
+		Name:        "batch-delay",
+		Description: "Pause between the batches of machines updated by the rolling strategy, a machine each unless [deploy] max_concurrent_per_group is set, e.g. 30s, to watch metrics before going on. Ctrl-C during the pause aborts the rest of the deploy",
+	},
+	flag.String{
+		Name:        "config-override",
+		Description: "JSON or TOML file with a partial machine config deep merged onto the config computed for every machine. It wins over fly.toml, but not over the metadata fly manages",
+	},
+	flag.String{
+		Name:        "progress-file",
+		Description: "Keep the state of the deploy in this JSON file, updated atomically as it advances, e.g. for a dashboard to poll",
+	},
+	flag.Bool{
+		Name:        "zero-downtime",
+		Description: "Update process groups with a single machine by launching a new machine and destroying the old one once healthy. Briefly doubles their machine count",
+		Default:     false,
+	},
+	flag.Bool{
+		Name:        "scan-image",
+		Description: "Scan the image for vulnerabilities with trivy before deploying it and abort if it has more critical ones than --scan-max-critical",
+		Default:     false,
+	},
+	flag.Int{
+		Name:        "scan-max-critical",
+		Description: "Number of critical vulnerabilities tolerated by --scan-image",
+		Default:     0,
+	},
+	flag.Bool{
+		Name:        "expand-regions",
+		Description: "Launch a machine for every process group in each region listed in fly.toml 'regions' it isn't running in yet",
+		Default:     false,
+	},
+	flag.Int{
+		Name:        "healthy-polls-required",
+		Description: "Number of consecutive polls a machine health checks must pass before its update is considered successful",
+		Default:     1,
+	},
+	flag.String{
+		Name:        "webhook-url",
+		Description: "URL to POST deploy events to, e.g. to notify a chat channel. Overrides the [deploy] webhook_url setting in fly.toml",
+	},
+	flag.StringSlice{
+		Name:        "fallback-regions",
+		Description: "Regions to launch new machines in, in order, when their region has no capacity left. Overrides the [deploy] fallback_regions setting in fly.toml",
+	},
+	flag.Bool{
+		Name:        "validate-health-checks",
+		Description: "Probe the HTTP health checks of the first updated machine and fail fast if they return a 4xx or 5xx status",
+		Default:     false,
+	},
 }
 
 func New() (cmd *cobra.Command) {
@@ -83,6 +262,11 @@ func New() (cmd *cobra.Command) {
 		long = `Deploy Fly applications from source or an image using a local or remote builder.
 
 		To disable colorized output and show full Docker build output, set the environment variable NO_COLOR=1.
+
+		With --json, machines deploys end with a JSON object summarizing them: "machines" lists the
+		machines the deploy went through with their state, health and outcome, "timings" how long each
+		phase took in milliseconds, and "regions" the machines of each process group by region before
+		and after the deploy.
 	`
 		short = "Deploy Fly applications"
 	)
@@ -95,10 +279,47 @@ func New() (cmd *cobra.Command) {
 
 	cmd.Args = cobra.MaximumNArgs(1)
 
+	cmd.AddCommand(newLaunchInput())
+
 	flag.Add(cmd,
 		CommonFlags,
 		flag.App(),
 		flag.AppConfig(),
+		flag.JSONOutput(),
+		flag.Bool{
+			Name:        "cancel",
+			Description: "Cancel the release being deployed from another session. Its deploy stops before updating its next machine",
+			Default:     false,
+		},
+		flag.Int{
+			Name:        "from-release",
+			Description: "Deploy the image and app config of this previous release version again, in a new release. fly.toml is ignored. Machines of the release kept by --keep-previous are started again instead of launching new ones",
+		},
+		flag.Bool{
+			Name:        "no-release",
+			Description: "Update the machines without creating a release, for operational tweaks rather than app deploys. The release history and the release metadata of the machines won't reflect the change",
+			Default:     false,
+		},
+		flag.Bool{
+			Name:        "maintenance-page",
+			Description: "Serve the page set by [deploy] maintenance_page in fly.toml from a temporary machine while the app machines are updated, for apps that can't deploy without downtime. The app machines get no requests until the deploy is over",
+			Default:     false,
+		},
+		flag.Bool{
+			Name:        "release-command-on-existing",
+			Description: "Run the release commands on a started machine of the app, preferably in the primary region, instead of a new machine. Only for deploys keeping the image the machines run, e.g. config changes, it's refused when the image changes",
+			Default:     false,
+		},
+		flag.Bool{
+			Name:        "validate-only",
+			Description: "Check the app config against the platform constraints (regions, guest sizes, service ports, mounts) and exit, without building nor deploying",
+			Default:     false,
+		},
+		flag.Bool{
+			Name:        "quiet",
+			Description: "Only print warnings, errors and a final summary line. Output requested with --json is still printed",
+			Default:     false,
+		},
 	)
 
 	return
@@ -112,6 +333,21 @@ func run(ctx context.Context) error {
 	}
 	ctx = flaps.NewContext(ctx, flapsClient)
 
+	if flag.GetBool(ctx, "cancel") {
+		return cancelRunningReleases(ctx)
+	}
+
+	if flag.GetBool(ctx, "quiet") {
+		ctx = withQuietOutput(ctx)
+	}
+
+	if version := flag.GetInt(ctx, "from-release"); version > 0 {
+		if flag.GetBool(ctx, "no-release") {
+			return fmt.Errorf("--from-release deploys a previous release in a new release, it can't be used with --no-release")
+		}
+		return deployFromRelease(ctx, version)
+	}
+
 	appConfig, err := determineAppConfig(ctx)
 	if err != nil {
 		if strings.Contains(err.Error(), "Could not find App") {
@@ -131,9 +367,12 @@ type DeployWithConfigArgs struct {
 	ForceMachines bool
 	ForceNomad    bool
 	ForceYes      bool
+	// ExpectedSecrets are secrets the app needs, e.g. the ones requested by the launch scanner
+	ExpectedSecrets []string
 }
 
 func DeployWithConfig(ctx context.Context, appConfig *appconfig.Config, args DeployWithConfigArgs) (err error) {
+	startedAt := time.Now()
 	appName := appconfig.NameFromContext(ctx)
 	apiClient := client.FromContext(ctx).API()
 	appCompact, err := apiClient.GetAppCompact(ctx, appName)
@@ -141,12 +380,26 @@ func DeployWithConfig(ctx context.Context, appConfig *appconfig.Config, args Dep
 		return err
 	}
 
+	if err := checkSecrets(ctx, appConfig, args.ExpectedSecrets); err != nil {
+		return err
+	}
+
+	if flag.GetBool(ctx, "validate-only") {
+		return validateConfigOnly(ctx, appConfig, appCompact)
+	}
+
 	// Fetch an image ref or build from source to get the final image reference to deploy
 	img, err := determineImage(ctx, appConfig)
 	if err != nil {
 		return fmt.Errorf("failed to fetch an image or build from source: %w", err)
 	}
 
+	if flag.GetBool(ctx, "scan-image") {
+		if err := scanImage(ctx, img.Tag, flag.GetInt(ctx, "scan-max-critical")); err != nil {
+			return err
+		}
+	}
+
 	if flag.GetBuildOnly(ctx) {
 		return nil
 	}
@@ -158,26 +411,76 @@ func DeployWithConfig(ctx context.Context, appConfig *appconfig.Config, args Dep
 		if err := appConfig.EnsureV2Config(); err != nil {
 			return fmt.Errorf("Can't deploy an invalid v2 app config: %s", err)
 		}
-		return deployToMachines(ctx, appConfig, appCompact, img)
+		return deployToMachines(ctx, appConfig, appCompact, img, startedAt, 0)
 	default:
 		return deployToNomad(ctx, appConfig, appCompact, img)
 	}
 }
 
-func deployToMachines(ctx context.Context, appConfig *appconfig.Config, appCompact *api.AppCompact, img *imgsrc.DeploymentImage) error {
+// deployToMachines deploys img with appConfig. fromReleaseVersion is the release they were taken
+// from by --from-release, zero for regular deploys. The flag isn't read here since launch deploys
+// through DeployWithConfig without registering it.
+func deployToMachines(ctx context.Context, appConfig *appconfig.Config, appCompact *api.AppCompact, img *imgsrc.DeploymentImage, startedAt time.Time, fromReleaseVersion int) error {
 	// It's important to push appConfig into context because MachineDeployment will fetch it from there
 	ctx = appconfig.WithConfig(ctx, appConfig)
+	if maxPoll := flag.GetDuration(ctx, "max-poll-interval"); maxPoll > 0 {
+		ctx = machine.WithPollBackoff(ctx, machine.PollBackoff{Min: machine.DefaultPollBackoff.Min, Max: maxPoll})
+	}
 
 	md, err := NewMachineDeployment(ctx, MachineDeploymentArgs{
-		AppCompact:        appCompact,
-		DeploymentImage:   img.Tag,
-		Strategy:          flag.GetString(ctx, "strategy"),
-		EnvFromFlags:      flag.GetStringSlice(ctx, "env"),
-		PrimaryRegionFlag: appConfig.PrimaryRegion,
-		SkipHealthChecks:  flag.GetDetach(ctx),
-		WaitTimeout:       time.Duration(flag.GetInt(ctx, "wait-timeout")) * time.Second,
-		LeaseTimeout:      time.Duration(flag.GetInt(ctx, "lease-timeout")) * time.Second,
-		VMSize:            flag.GetString(ctx, "vm-size"),
+		AppCompact:            appCompact,
+		DeploymentImage:       img.Tag,
+		SourceHash:            img.SourceHash,
+		ImagePlatform:         img.Platform,
+		Strategy:              flag.GetString(ctx, "strategy"),
+		EnvFromFlags:          flag.GetStringSlice(ctx, "env"),
+		PrimaryRegionFlag:     appConfig.PrimaryRegion,
+		PrimaryRegionOverride: flag.GetString(ctx, "primary-region"),
+		MachineNameTemplate:   flag.GetString(ctx, "machine-name-template"),
+		SkipHealthChecks:      flag.GetDetach(ctx),
+		WaitTimeout:           time.Duration(flag.GetInt(ctx, "wait-timeout")) * time.Second,
+		NewMachineWaitTimeout: time.Duration(flag.GetInt(ctx, "new-machine-wait-timeout")) * time.Second,
+		LeaseTimeout:          time.Duration(flag.GetInt(ctx, "lease-timeout")) * time.Second,
+		VMSize:                flag.GetString(ctx, "vm-size"),
+		VMGPUKind:             flag.GetString(ctx, "vm-gpu-kind"),
+		ValidateHealthChecks:  flag.GetBool(ctx, "validate-health-checks"),
+		DeployTimeout:         flag.GetDuration(ctx, "deploy-timeout"),
+		InitCommand:           flag.GetString(ctx, "command"),
+		SetEnv:                flag.GetStringSlice(ctx, "set-env"),
+		EnvFile:               flag.GetString(ctx, "env-file"),
+		OnlyChanged:           flag.GetBool(ctx, "only-changed"),
+		UpdateOrder:           flag.GetString(ctx, "update-order"),
+		WebhookURL:            flag.GetString(ctx, "webhook-url"),
+		FallbackRegions:       flag.GetStringSlice(ctx, "fallback-regions"),
+		HealthyPollsRequired:  flag.GetInt(ctx, "healthy-polls-required"),
+		ExpandRegions:         flag.GetBool(ctx, "expand-regions"),
+		ZeroDowntime:          flag.GetBool(ctx, "zero-downtime"),
+		DrainTimeout:          flag.GetDuration(ctx, "drain-timeout"),
+		BatchDelay:            flag.GetDuration(ctx, "batch-delay"),
+		KeepPrevious:          flag.GetInt(ctx, "keep-previous"),
+		MinHealthy:            flag.GetString(ctx, "min-healthy"),
+		ImmediateMaxErrors:    flag.GetInt(ctx, "immediate-max-errors"),
+		ProgressFile:          flag.GetString(ctx, "progress-file"),
+		ConfigOverride:        flag.GetString(ctx, "config-override"),
+		ImagePullPolicy:       flag.GetString(ctx, "image-pull-policy"),
+		AutoCreateVolumes:     flag.GetBool(ctx, "auto-create-volumes"),
+		DetachVolumes:         flag.GetBool(ctx, "detach-volumes"),
+		FailOnMissingMachines: flag.GetBool(ctx, "fail-on-missing-machines"),
+		FailOnImageMismatch:   flag.GetBool(ctx, "fail-on-image-mismatch"),
+		NoPublicIPsWait:       flag.GetBool(ctx, "no-public-ips-wait"),
+		EnableProcessGroups:   flag.GetStringSlice(ctx, "enable-process-group"),
+		ForceLease:            flag.GetBool(ctx, "force-lease"),
+		FromReleaseVersion:    fromReleaseVersion,
+		NoRelease:             flag.GetBool(ctx, "no-release"),
+		ConfirmDestroyOver:    flag.GetInt(ctx, "confirm-destroy-over"),
+		RegionConcurrency:     flag.GetInt(ctx, "region-concurrency"),
+		AutoConfirm:           flag.GetBool(ctx, "auto-confirm"),
+		ValidateOnly:          flag.GetBool(ctx, "validate-only"),
+		MaintenancePage:       flag.GetBool(ctx, "maintenance-page"),
+		ReleaseCmdOnExisting:  flag.GetBool(ctx, "release-command-on-existing"),
+		BuildDuration:         img.BuildDuration,
+		PushDuration:          img.PushDuration,
+		StartedAt:             startedAt,
 	})
 	if err != nil {
 		sentry.CaptureExceptionWithAppInfo(err, "deploy", appCompact)
@@ -287,7 +590,7 @@ func determineAppConfig(ctx context.Context) (cfg *appconfig.Config, err error)
 
 	err, extraInfo := cfg.Validate(ctx)
 	if extraInfo != "" {
-		fmt.Fprintf(io.Out, extraInfo)
+		fmt.Fprint(io.ErrOut, extraInfo)
 	}
 	if err != nil {
 		return nil, err
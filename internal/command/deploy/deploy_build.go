@@ -23,7 +23,10 @@ import (
 // DeploymentImage struct
 func determineImage(ctx context.Context, appConfig *appconfig.Config) (img *imgsrc.DeploymentImage, err error) {
 	tb := render.NewTextBlock(ctx, "Building image")
-	daemonType := imgsrc.NewDockerDaemonType(!flag.GetRemoteOnly(ctx), !flag.GetLocalOnly(ctx), env.IsCI(), flag.GetBool(ctx, "nixpacks"))
+	daemonType, err := determineDaemonType(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	client := client.FromContext(ctx).API()
 	io := iostreams.FromContext(ctx)
@@ -106,6 +109,30 @@ func determineImage(ctx context.Context, appConfig *appconfig.Config) (img *imgs
 		opts.Target = target
 	}
 
+	var sourceHash string
+	if flag.GetBool(ctx, "build-only-if-changed") {
+		if sourceHash, err = imgsrc.SourceHash(opts); err != nil {
+			terminal.Warnf("failed to hash the sources, building the image: %v\n", err)
+			sourceHash = ""
+		}
+	}
+	if sourceHash != "" {
+		tb.Printf("source hash: %s\n", sourceHash)
+		lastImage, lastHash, lastVersion, err := lastBuiltImage(ctx, appConfig.AppName)
+		switch {
+		case err != nil:
+			terminal.Debugf("failed to find the image of the latest release: %v\n", err)
+			tb.Printf("the latest release image is unknown, building\n")
+		case flag.GetBool(ctx, "force-build"):
+			tb.Printf("--force-build set, building\n")
+		case lastHash != sourceHash || lastImage == "":
+			tb.Printf("sources changed since release v%d, building\n", lastVersion)
+		default:
+			tb.Printf("sources unchanged since release v%d, reusing image %s\n", lastVersion, lastImage)
+			return &imgsrc.DeploymentImage{Tag: lastImage, SourceHash: sourceHash}, nil
+		}
+	}
+
 	// finally, build the image
 	heartbeat, err := resolver.StartHeartbeat(ctx)
 	if err != nil {
@@ -118,6 +145,7 @@ func determineImage(ctx context.Context, appConfig *appconfig.Config) (img *imgs
 	}
 
 	if err == nil {
+		img.SourceHash = sourceHash
 		tb.Printf("image: %s\n", img.Tag)
 		tb.Printf("image size: %s\n", humanize.Bytes(uint64(img.Size)))
 	}
@@ -125,6 +153,40 @@ func determineImage(ctx context.Context, appConfig *appconfig.Config) (img *imgs
 	return
 }
 
+const (
+	builderLocal  = "local"
+	builderRemote = "remote"
+	builderDepot  = "depot"
+)
+
+// determineDaemonType picks the docker daemon to build with, from --builder when set
+// or from --local-only and --remote-only otherwise. The local builder falls back to the
+// remote one when docker isn't running. The depot builder isn't supported yet.
+func determineDaemonType(ctx context.Context) (imgsrc.DockerDaemonType, error) {
+	nixpacks := flag.GetBool(ctx, "nixpacks")
+
+	switch builder := flag.GetBuilder(ctx); builder {
+	case "":
+		return imgsrc.NewDockerDaemonType(!flag.GetRemoteOnly(ctx), !flag.GetLocalOnly(ctx), env.IsCI(), nixpacks), nil
+	case builderLocal:
+		c, err := imgsrc.NewLocalDockerClient()
+		if c == nil || err != nil {
+			terminal.Warnf("The local docker daemon is unavailable, falling back to the remote builder\n")
+			return imgsrc.NewDockerDaemonType(false, true, false, nixpacks), nil
+		}
+		c.Close()
+		terminal.Debugf("Using the local builder\n")
+		return imgsrc.NewDockerDaemonType(true, false, true, nixpacks), nil
+	case builderRemote:
+		terminal.Debugf("Using the remote builder\n")
+		return imgsrc.NewDockerDaemonType(false, true, false, nixpacks), nil
+	case builderDepot:
+		return imgsrc.DockerDaemonTypeNone, fmt.Errorf("the %s builder isn't available in this version of flyctl, use --builder %s or --builder %s", builderDepot, builderLocal, builderRemote)
+	default:
+		return imgsrc.DockerDaemonTypeNone, fmt.Errorf("invalid builder '%s', must be %s or %s", builder, builderLocal, builderRemote)
+	}
+}
+
 // resolveDockerfilePath returns the absolute path to the Dockerfile
 // if one was specified in the app config or a command line argument
 func resolveDockerfilePath(ctx context.Context, appConfig *appconfig.Config) (path string, err error) {
@@ -0,0 +1,47 @@
+package deploy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/superfly/flyctl/internal/build/imgsrc"
+	"github.com/superfly/flyctl/internal/env"
+	"github.com/superfly/flyctl/internal/flag"
+)
+
+func Test_determineDaemonType(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    imgsrc.DockerDaemonType
+		wantErr string
+	}{
+		{name: "default", want: imgsrc.NewDockerDaemonType(true, true, env.IsCI(), false)},
+		{name: "remote only", args: []string{"--remote-only"}, want: imgsrc.NewDockerDaemonType(false, true, env.IsCI(), false)},
+		{name: "local only", args: []string{"--local-only", "--nixpacks"}, want: imgsrc.NewDockerDaemonType(true, false, env.IsCI(), true)},
+		{name: "remote builder", args: []string{"--builder=remote", "--local-only"}, want: imgsrc.NewDockerDaemonType(false, true, false, false)},
+		{name: "depot builder", args: []string{"--builder=depot"}, wantErr: "the depot builder isn't available in this version of flyctl"},
+		{name: "invalid builder", args: []string{"--builder=kaniko"}, wantErr: "invalid builder 'kaniko', must be local or remote"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := pflag.NewFlagSet(tt.name, pflag.ContinueOnError)
+			fs.String("builder", "", "")
+			fs.Bool("local-only", false, "")
+			fs.Bool("remote-only", false, "")
+			fs.Bool("nixpacks", false, "")
+			assert.NoError(t, fs.Parse(tt.args))
+
+			got, err := determineDaemonType(flag.NewContext(context.Background(), fs))
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				assert.Equal(t, imgsrc.DockerDaemonTypeNone, got)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
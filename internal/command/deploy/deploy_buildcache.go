@@ -0,0 +1,39 @@
+package deploy
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/flaps"
+)
+
+// lastBuiltImage returns the image and source hash of the latest release, read from the metadata of
+// its machines. The hash is empty when that release wasn't built with --build-only-if-changed.
+func lastBuiltImage(ctx context.Context, appName string) (image, sourceHash string, version int, err error) {
+	flapsClient, err := flaps.NewFromAppName(ctx, appName)
+	if err != nil {
+		return "", "", 0, err
+	}
+	machines, err := flapsClient.ListActive(ctx)
+	if err != nil {
+		return "", "", 0, err
+	}
+	image, sourceHash, version = latestReleaseImage(machines)
+	return image, sourceHash, version, nil
+}
+
+// latestReleaseImage picks the image and source hash of the machines running the highest release version
+func latestReleaseImage(machines []*api.Machine) (image, sourceHash string, version int) {
+	for _, m := range machines {
+		if m.Config == nil {
+			continue
+		}
+		v, err := strconv.Atoi(m.Config.Metadata[api.MachineConfigMetadataKeyFlyReleaseVersion])
+		if err != nil || v <= version {
+			continue
+		}
+		image, sourceHash, version = m.Config.Image, m.Config.Metadata[api.MachineConfigMetadataKeyFlySourceHash], v
+	}
+	return image, sourceHash, version
+}
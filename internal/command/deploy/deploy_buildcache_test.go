@@ -0,0 +1,35 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/superfly/flyctl/api"
+)
+
+func Test_latestReleaseImage(t *testing.T) {
+	release := func(version, image, hash string) *api.Machine {
+		return &api.Machine{Config: &api.MachineConfig{
+			Image: image,
+			Metadata: map[string]string{
+				api.MachineConfigMetadataKeyFlyReleaseVersion: version,
+				api.MachineConfigMetadataKeyFlySourceHash:     hash,
+			},
+		}}
+	}
+
+	image, hash, version := latestReleaseImage([]*api.Machine{
+		release("3", "registry.fly.io/app:release-v3", "abc"),
+		release("4", "registry.fly.io/app:release-v4@sha256:1234", "def"),
+		{ID: "no-config"},
+		release("2", "registry.fly.io/app:release-v2", "abc"),
+	})
+	assert.Equal(t, "registry.fly.io/app:release-v4@sha256:1234", image)
+	assert.Equal(t, "def", hash)
+	assert.Equal(t, 4, version)
+
+	image, hash, version = latestReleaseImage(nil)
+	assert.Equal(t, "", image)
+	assert.Equal(t, "", hash)
+	assert.Equal(t, 0, version)
+}
@@ -51,31 +51,31 @@ func (md *machineDeployment) provisionIpsOnFirstDeploy(ctx context.Context) erro
 			if err != nil {
 				return err
 			}
-			fmt.Fprintf(md.io.Out, "Allocated dedicated ipv4: %s\n", v4Dedicated.Address)
+			fmt.Fprintf(md.io.ErrOut, "Allocated dedicated ipv4: %s\n", v4Dedicated.Address)
 
 			if !hasUdpService {
 				v6Dedicated, err := md.apiClient.AllocateIPAddress(ctx, md.app.Name, "v6", "", nil, "")
 				if err != nil {
 					return err
 				}
-				fmt.Fprintf(md.io.Out, "Allocated dedicated ipv6: %s\n", v6Dedicated.Address)
+				fmt.Fprintf(md.io.ErrOut, "Allocated dedicated ipv6: %s\n", v6Dedicated.Address)
 			}
 		}
 
 	case false:
-		fmt.Fprintf(md.io.Out, "Provisioning ips for %s\n", md.colorize.Bold(md.app.Name))
+		fmt.Fprintf(md.io.ErrOut, "Provisioning ips for %s\n", md.colorize.Bold(md.app.Name))
 		v6Addr, err := md.apiClient.AllocateIPAddress(ctx, md.app.Name, "v6", "", nil, "")
 		if err != nil {
 			return fmt.Errorf("error allocating ipv6 after detecting first deploy and presence of services: %w", err)
 		}
-		fmt.Fprintf(md.io.Out, "  Dedicated ipv6: %s\n", v6Addr.Address)
+		fmt.Fprintf(md.io.ErrOut, "  Dedicated ipv6: %s\n", v6Addr.Address)
 
 		v4Shared, err := md.apiClient.AllocateSharedIPAddress(ctx, md.app.Name)
 		if err != nil {
 			return fmt.Errorf("error allocating shared ipv4 after detecting first deploy and presence of services: %w", err)
 		}
-		fmt.Fprintf(md.io.Out, "  Shared ipv4: %s\n", v4Shared)
-		fmt.Fprintf(md.io.Out, "  Add a dedicated ipv4 with: fly ips allocate-v4\n")
+		fmt.Fprintf(md.io.ErrOut, "  Shared ipv4: %s\n", v4Shared)
+		fmt.Fprintf(md.io.ErrOut, "  Add a dedicated ipv4 with: fly ips allocate-v4\n")
 	}
 
 	return nil
@@ -95,12 +95,12 @@ func (md *machineDeployment) provisionVolumesOnFirstDeploy(ctx context.Context)
 		}
 
 		for _, m := range groupConfig.Mounts {
-			fmt.Fprintf(md.io.Out, "Creating 1GB volume '%s' for process group '%s'. See `fly vol extend` to increase its size\n", m.Source, groupName)
+			fmt.Fprintf(md.io.ErrOut, "Creating 1GB volume '%s' for process group '%s'. See `fly vol extend` to increase its size\n", m.Source, groupName)
 
 			input := api.CreateVolumeInput{
 				AppID:     md.app.ID,
 				Name:      m.Source,
-				Region:    groupConfig.PrimaryRegion,
+				Region:    md.primaryRegion(),
 				SizeGb:    1,
 				Encrypted: true,
 			}
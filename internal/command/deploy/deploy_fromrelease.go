@@ -0,0 +1,81 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/client"
+	"github.com/superfly/flyctl/gql"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/build/imgsrc"
+	"github.com/superfly/flyctl/internal/flag"
+)
+
+// deployFromRelease deploys the image and app config recorded by a previous release again,
+// through a new release. It's a rollback that doesn't require editing fly.toml.
+func deployFromRelease(ctx context.Context, version int) error {
+	startedAt := time.Now()
+	appName := appconfig.NameFromContext(ctx)
+	apiClient := client.FromContext(ctx).API()
+
+	if flag.GetString(ctx, flag.ImageName) != "" {
+		return fmt.Errorf("--from-release deploys the image of the release, it can't be used with --image")
+	}
+
+	appCompact, err := apiClient.GetAppCompact(ctx, appName)
+	if err != nil {
+		return err
+	}
+	if appCompact.PlatformVersion != appconfig.MachinesPlatform {
+		return fmt.Errorf("--from-release is only supported by apps running on machines")
+	}
+
+	_ = `# @genqlient
+	query FlyctlDeployGetRelease($appName:String!, $version:Int!) {
+		app(name:$appName) {
+			release(version:$version) {
+				version
+				imageRef
+				config {
+					definition
+				}
+			}
+		}
+	}
+	`
+	resp, err := gql.FlyctlDeployGetRelease(ctx, apiClient.GenqClient, appName, version)
+	if err != nil {
+		return fmt.Errorf("failed to get release v%d of app %s: %w", version, appName, err)
+	}
+	release := resp.App.Release
+	if release.Version == 0 {
+		return fmt.Errorf("release v%d of app %s not found", version, appName)
+	}
+	if release.ImageRef == "" {
+		return fmt.Errorf("release v%d of app %s has no image recorded, it can't be deployed again", version, appName)
+	}
+	appConfig, err := appConfigFromReleaseDefinition(release.Config.Definition)
+	if err != nil {
+		return fmt.Errorf("release v%d of app %s: %w", version, appName, err)
+	}
+	appConfig.AppName = appName
+
+	return deployToMachines(ctx, appConfig, appCompact, &imgsrc.DeploymentImage{Tag: release.ImageRef}, startedAt, version)
+}
+
+func appConfigFromReleaseDefinition(definition any) (*appconfig.Config, error) {
+	definitionMap, ok := definition.(map[string]any)
+	if !ok || len(definitionMap) == 0 {
+		return nil, fmt.Errorf("no app config recorded, it can't be deployed again")
+	}
+	appConfig, err := appconfig.FromDefinition(api.DefinitionPtr(definitionMap))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load its app config: %w", err)
+	}
+	if err := appConfig.SetMachinesPlatform(); err != nil {
+		return nil, fmt.Errorf("its app config isn't valid for machines: %w", err)
+	}
+	return appConfig, nil
+}
@@ -0,0 +1,45 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/appconfig"
+)
+
+func Test_appConfigFromReleaseDefinition(t *testing.T) {
+	appConfig, err := appConfigFromReleaseDefinition(map[string]any{
+		"primary_region": "ord",
+		"env":            map[string]any{"FOO": "BAR"},
+		"http_service":   map[string]any{"internal_port": int64(8080)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ord", appConfig.PrimaryRegion)
+	assert.Equal(t, map[string]string{"FOO": "BAR"}, appConfig.Env)
+	assert.Equal(t, 8080, appConfig.HTTPService.InternalPort)
+
+	_, err = appConfigFromReleaseDefinition(nil)
+	assert.ErrorContains(t, err, "no app config recorded")
+}
+
+// Test machines deployed from a previous release note its version
+func Test_setMachineReleaseData_fromRelease(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{AppName: "my-cool-app"})
+	require.NoError(t, err)
+	md.releaseVersion = 50
+	md.fromReleaseVersion = 42
+
+	mConfig := &api.MachineConfig{}
+	md.setMachineReleaseData(mConfig)
+	assert.Equal(t, "50", mConfig.Metadata[api.MachineConfigMetadataKeyFlyReleaseVersion])
+	assert.Equal(t, "42", mConfig.Metadata[api.MachineConfigMetadataKeyFlyReleaseSource])
+
+	// The next regular deploy drops it
+	md.releaseVersion = 51
+	md.fromReleaseVersion = 0
+	md.setMachineReleaseData(mConfig)
+	assert.Equal(t, "51", mConfig.Metadata[api.MachineConfigMetadataKeyFlyReleaseVersion])
+	assert.NotContains(t, mConfig.Metadata, api.MachineConfigMetadataKeyFlyReleaseSource)
+}
@@ -0,0 +1,181 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/superfly/flyctl/client"
+	"github.com/superfly/flyctl/flaps"
+	"github.com/superfly/flyctl/gql"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/command"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/internal/render"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+// newLaunchInput is a debugging aid for the deploy code: it prints the launch input a deploy
+// would send for an existing machine, computed by the very same launchInputForUpdate with the
+// deploy flags that change it
+func newLaunchInput() *cobra.Command {
+	const (
+		short = "Show the launch input a deploy would use for a machine"
+		long  = short + `. Nothing is updated, the output can be diffed against the machine's
+current config to find out why a deploy updates or replaces it.
+`
+		usage = "launch-input <machine-id>"
+	)
+
+	cmd := command.New(usage, short, long, runLaunchInput,
+		command.RequireSession,
+		command.RequireAppName,
+	)
+
+	cmd.Hidden = true
+	cmd.Args = cobra.ExactArgs(1)
+
+	flag.Add(cmd,
+		flag.App(),
+		flag.AppConfig(),
+		flag.String{
+			Name:        flag.ImageName,
+			Shorthand:   "i",
+			Description: "The image to compute the launch input for, defaults to the machine's current image",
+		},
+		flag.StringSlice{
+			Name:        "env",
+			Shorthand:   "e",
+			Description: "Set of environment variables in the form of NAME=VALUE pairs. Can be specified multiple times.",
+		},
+		flag.String{
+			Name:        "config-override",
+			Description: "JSON or TOML file with a partial machine config deep merged onto the computed config",
+		},
+		flag.String{
+			Name:        "command",
+			Description: "Override the command run by the machine, as deploy --command does",
+		},
+		flag.StringSlice{
+			Name:        "set-env",
+			Description: "Set environment variables in the form of NAME=VALUE over the computed env, as deploy --set-env does. Can be specified multiple times.",
+		},
+		flag.String{
+			Name:        "env-file",
+			Description: "Load environment variables from a dotenv file over the computed env, as deploy --env-file does",
+		},
+		flag.Bool{
+			Name:        "detach-volumes",
+			Description: "Compute the replacement of a machine whose volume isn't mounted in fly.toml anymore, as deploy --detach-volumes does",
+			Default:     false,
+		},
+		flag.Bool{
+			Name:        "no-release",
+			Description: "Keep the release metadata of the machine, as deploy --no-release does",
+			Default:     false,
+		},
+	)
+
+	return cmd
+}
+
+func runLaunchInput(ctx context.Context) error {
+	var (
+		io        = iostreams.FromContext(ctx)
+		apiClient = client.FromContext(ctx).API()
+		appName   = appconfig.NameFromContext(ctx)
+		machineID = flag.FirstArg(ctx)
+	)
+
+	appCompact, err := apiClient.GetAppCompact(ctx, appName)
+	if err != nil {
+		return err
+	}
+	flapsClient, err := flaps.New(ctx, appCompact)
+	if err != nil {
+		return fmt.Errorf("could not create flaps client: %w", err)
+	}
+
+	appConfig := appconfig.ConfigFromContext(ctx)
+	if appConfig == nil {
+		if appConfig, err = appconfig.FromRemoteApp(ctx, appName); err != nil {
+			return err
+		}
+	}
+	if err := appConfig.EnsureV2Config(); err != nil {
+		return fmt.Errorf("Can't compute a launch input from an invalid v2 app config: %s", err)
+	}
+	ctx = appconfig.WithConfig(ctx, appConfig)
+	appConfig, err = determineAppConfigForMachines(ctx, flag.GetStringSlice(ctx, "env"), "")
+	if err != nil {
+		return err
+	}
+
+	origMachineRaw, err := flapsClient.Get(ctx, machineID)
+	if err != nil {
+		return fmt.Errorf("could not get machine %s: %w", machineID, err)
+	}
+
+	img := flag.GetString(ctx, flag.ImageName)
+	if img == "" {
+		img = origMachineRaw.Config.Image
+	}
+
+	md := &machineDeployment{
+		apiClient:     apiClient,
+		gqlClient:     apiClient.GenqClient,
+		flapsClient:   flapsClient,
+		io:            io,
+		colorize:      io.ColorScheme(),
+		alertOut:      io.ErrOut,
+		app:           appCompact,
+		appConfig:     appConfig,
+		img:           img,
+		detachVolumes: flag.GetBool(ctx, "detach-volumes"),
+		noRelease:     flag.GetBool(ctx, "no-release"),
+	}
+	if err := md.setConfigOverride(flag.GetString(ctx, "config-override")); err != nil {
+		return err
+	}
+	if err := md.setInitCommand(flag.GetString(ctx, "command")); err != nil {
+		return err
+	}
+	if err := md.setTransientEnv(flag.GetStringSlice(ctx, "set-env")); err != nil {
+		return err
+	}
+	if err := md.setEnvFile(flag.GetString(ctx, "env-file")); err != nil {
+		return err
+	}
+	if err := md.setNextReleaseData(ctx); err != nil {
+		return err
+	}
+	if err := md.resolveImgDigest(ctx); err != nil {
+		return err
+	}
+	if err := md.setVolumeConfig(ctx); err != nil {
+		return err
+	}
+
+	launchInput, err := md.launchInputForUpdate(origMachineRaw)
+	if err != nil {
+		return err
+	}
+	return render.JSON(io.Out, launchInput)
+}
+
+// setNextReleaseData sets the release the launch input claims to the one a deploy would create
+// next. Its ID is only known once the deploy creates it, a placeholder stands for it.
+func (md *machineDeployment) setNextReleaseData(ctx context.Context) error {
+	if md.noRelease {
+		return nil
+	}
+	resp, err := gql.FlyctlDeployGetLatestImage(ctx, md.gqlClient, md.app.Name)
+	if err != nil {
+		return fmt.Errorf("could not determine the next release of app %s: %w", md.app.Name, err)
+	}
+	md.releaseId = nextReleaseIdPlaceholder
+	md.releaseVersion = resp.App.CurrentReleaseUnprocessed.Version + 1
+	return nil
+}
+
+const nextReleaseIdPlaceholder = "<next release>"
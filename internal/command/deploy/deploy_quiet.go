@@ -0,0 +1,45 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/superfly/flyctl/internal/config"
+	"github.com/superfly/flyctl/iostreams"
+	"github.com/superfly/flyctl/terminal"
+)
+
+type loudStreamsKey struct{}
+
+// withQuietOutput silences the progress output of the deploy, for `fly deploy --quiet`.
+// Warnings, errors and the final summary line still go to the streams loudStreams returns.
+// With --json, Out carries the machine readable output and is kept.
+func withQuietOutput(ctx context.Context) context.Context {
+	io := iostreams.FromContext(ctx)
+	quiet := io.Quiet()
+	if config.FromContext(ctx).JSONOutput {
+		quiet.Out = io.Out
+	}
+	terminal.DefaultLogger.SetLogLevel(terminal.LevelWarn)
+
+	ctx = context.WithValue(ctx, loudStreamsKey{}, io)
+	return iostreams.NewContext(ctx, quiet)
+}
+
+// loudStreams returns the streams the deploy must never silence
+func loudStreams(ctx context.Context) *iostreams.IOStreams {
+	if io, ok := ctx.Value(loudStreamsKey{}).(*iostreams.IOStreams); ok {
+		return io
+	}
+	return iostreams.FromContext(ctx)
+}
+
+func isQuiet(ctx context.Context) bool {
+	_, ok := ctx.Value(loudStreamsKey{}).(*iostreams.IOStreams)
+	return ok
+}
+
+// warnf prints warnings and errors, which --quiet keeps
+func (md *machineDeployment) warnf(format string, args ...any) {
+	fmt.Fprintf(md.alertOut, format, args...)
+}
@@ -0,0 +1,90 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/config"
+	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/iostreams"
+	"github.com/superfly/flyctl/terminal"
+)
+
+func Test_withQuietOutput(t *testing.T) {
+	defer terminal.DefaultLogger.SetLogLevel(terminal.LevelInfo)
+
+	for _, jsonOutput := range []bool{false, true} {
+		t.Run(fmt.Sprintf("json=%v", jsonOutput), func(t *testing.T) {
+			ios, _, out, errOut := iostreams.Test()
+			ctx := iostreams.NewContext(context.Background(), ios)
+			ctx = config.NewContext(ctx, &config.Config{JSONOutput: jsonOutput})
+			assert.False(t, isQuiet(ctx))
+
+			ctx = withQuietOutput(ctx)
+			assert.True(t, isQuiet(ctx))
+			assert.Same(t, ios, loudStreams(ctx))
+
+			quiet := iostreams.FromContext(ctx)
+			assert.False(t, quiet.IsInteractive())
+			fmt.Fprintln(quiet.ErrOut, "progress")
+			fmt.Fprintln(quiet.Out, "output")
+
+			md := &machineDeployment{io: quiet, alertOut: loudStreams(ctx).ErrOut}
+			md.warnf("warning\n")
+
+			assert.Equal(t, "warning\n", errOut.String())
+			if jsonOutput {
+				assert.Equal(t, "output\n", out.String())
+			} else {
+				assert.Empty(t, out.String())
+			}
+		})
+	}
+}
+
+func Test_jsonOutput_onlyPrintsJSONToStdout(t *testing.T) {
+	cfg := &appconfig.Config{
+		Processes: map[string]string{"app": "run app", "worker": "run worker"},
+		Deploy: &appconfig.Deploy{ConditionalGroups: []appconfig.ConditionalGroup{
+			{ProcessGroup: "worker", Enabled: api.Pointer(false)},
+		}},
+	}
+	require.NoError(t, cfg.SetMachinesPlatform())
+	md, err := stabMachineDeployment(cfg)
+	require.NoError(t, err)
+	ios, _, out, errOut := iostreams.Test()
+	md.io = ios
+	md.colorize = ios.ColorScheme()
+	md.alertOut = ios.ErrOut
+	md.jsonOutput = true
+	md.strategy = "immediate"
+	md.machineSet = machine.NewMachineSet(nil, ios, []*api.Machine{
+		groupMachine("m1", "app", "ord"),
+		groupMachine("w1", "worker", "ord"),
+	})
+
+	require.NoError(t, md.setDisabledGroups(nil))
+	md.warnAboutProcessGroupChanges(context.Background(), md.resolveProcessGroupChanges())
+	m := groupMachine("m1", "app", "ord")
+	entries := []*machineUpdateEntry{{
+		leasableMachine: &concurrentMachine{m: m, inFlight: &inFlightCounter{}},
+		launchInput:     &api.LaunchMachineInput{ID: m.ID, Config: m.Config},
+	}}
+	require.NoError(t, md.updateExistingMachines(context.Background(), entries))
+	md.printMachineSummary(context.Background())
+
+	// Progress goes to stderr, stdout parses as the deploy summary alone
+	assert.Contains(t, errOut.String(), "Process groups disabled by [[deploy.conditional_groups]]: worker")
+	assert.Contains(t, errOut.String(), "Updating existing machines")
+	assert.Contains(t, errOut.String(), "Process groups have changed")
+	var summary deploySummaryJSON
+	require.NoError(t, json.Unmarshal(out.Bytes(), &summary), out.String())
+	require.Len(t, summary.Machines, 1)
+	assert.Equal(t, "m1", summary.Machines[0].ID)
+}
@@ -0,0 +1,133 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/superfly/flyctl/internal/config"
+	"github.com/superfly/flyctl/internal/render"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+// imageScanReport summarizes the vulnerabilities found in the image being deployed
+type imageScanReport struct {
+	Image           string                   `json:"image"`
+	Critical        int                      `json:"critical"`
+	High            int                      `json:"high"`
+	MaxCritical     int                      `json:"max_critical"`
+	Vulnerabilities []imageScanVulnerability `json:"vulnerabilities"`
+}
+
+type imageScanVulnerability struct {
+	VulnerabilityID  string `json:"VulnerabilityID"`
+	PkgName          string `json:"PkgName"`
+	InstalledVersion string `json:"InstalledVersion"`
+	FixedVersion     string `json:"FixedVersion,omitempty"`
+	Severity         string `json:"Severity"`
+	Title            string `json:"Title,omitempty"`
+}
+
+// trivyOutput is the subset of `trivy image --format json` output we care about
+type trivyOutput struct {
+	Results []struct {
+		Target          string                   `json:"Target"`
+		Vulnerabilities []imageScanVulnerability `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+// scanImage runs trivy against the image about to be deployed and fails when it has more
+// critical vulnerabilities than allowed. It runs once per deploy, before any machine is touched.
+func scanImage(ctx context.Context, imageRef string, maxCritical int) error {
+	io := iostreams.FromContext(ctx)
+	cfg := config.FromContext(ctx)
+
+	binary, err := exec.LookPath("trivy")
+	if err != nil {
+		return fmt.Errorf("--scan-image requires trivy, install it from https://aquasecurity.github.io/trivy: %w", err)
+	}
+
+	fmt.Fprintf(io.ErrOut, "Scanning image %s for vulnerabilities\n", imageRef)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, binary, "image", "--quiet", "--format", "json", "--severity", "CRITICAL,HIGH", imageRef)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = os.Environ()
+	// Credentials to pull images from the Fly registry, the token must not reach other registries
+	if imageRegistryHost(imageRef) == cfg.RegistryHost {
+		cmd.Env = append(cmd.Env, "TRIVY_USERNAME=x", "TRIVY_PASSWORD="+cfg.AccessToken)
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to scan image %s: %w: %s", imageRef, err, strings.TrimSpace(stderr.String()))
+	}
+
+	report, err := summarizeImageScan(imageRef, stdout.Bytes())
+	if err != nil {
+		return err
+	}
+	report.MaxCritical = maxCritical
+
+	if cfg.JSONOutput {
+		if err := render.JSON(io.Out, report); err != nil {
+			return err
+		}
+	} else {
+		printImageScanReport(io, report)
+	}
+
+	if report.Critical > maxCritical {
+		return fmt.Errorf("image %s has %d critical vulnerabilities, more than the %d allowed by --scan-max-critical", imageRef, report.Critical, maxCritical)
+	}
+	return nil
+}
+
+// imageRegistryHost returns the registry host of an image reference, docker.io for references without one
+func imageRegistryHost(imageRef string) string {
+	host, _, found := strings.Cut(imageRef, "/")
+	if !found || (!strings.ContainsAny(host, ".:") && host != "localhost") {
+		return "docker.io"
+	}
+	return host
+}
+
+func summarizeImageScan(imageRef string, output []byte) (*imageScanReport, error) {
+	var parsed trivyOutput
+	if err := json.Unmarshal(output, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse the image scan results: %w", err)
+	}
+	report := &imageScanReport{Image: imageRef, Vulnerabilities: []imageScanVulnerability{}}
+	for _, result := range parsed.Results {
+		for _, v := range result.Vulnerabilities {
+			switch v.Severity {
+			case "CRITICAL":
+				report.Critical++
+			case "HIGH":
+				report.High++
+			}
+			report.Vulnerabilities = append(report.Vulnerabilities, v)
+		}
+	}
+	return report, nil
+}
+
+func printImageScanReport(io *iostreams.IOStreams, report *imageScanReport) {
+	colorize := io.ColorScheme()
+	fmt.Fprintf(io.ErrOut, "Found %s and %s vulnerabilities\n",
+		colorize.Red(fmt.Sprintf("%d critical", report.Critical)),
+		colorize.Yellow(fmt.Sprintf("%d high", report.High)),
+	)
+	for _, v := range report.Vulnerabilities {
+		if v.Severity != "CRITICAL" {
+			continue
+		}
+		fixed := "no fix available"
+		if v.FixedVersion != "" {
+			fixed = "fixed in " + v.FixedVersion
+		}
+		fmt.Fprintf(io.ErrOut, "  * %s %s %s (%s)\n", v.VulnerabilityID, v.PkgName, v.InstalledVersion, fixed)
+	}
+}
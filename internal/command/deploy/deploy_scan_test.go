@@ -0,0 +1,37 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_summarizeImageScan(t *testing.T) {
+	output := `{
+  "Results": [
+    {"Target": "debian 11", "Vulnerabilities": [
+      {"VulnerabilityID": "CVE-2023-0001", "PkgName": "openssl", "InstalledVersion": "1.1.1", "FixedVersion": "1.1.2", "Severity": "CRITICAL"},
+      {"VulnerabilityID": "CVE-2023-0002", "PkgName": "zlib", "InstalledVersion": "1.2", "Severity": "HIGH"}
+    ]},
+    {"Target": "app/package-lock.json"}
+  ]
+}`
+	report, err := summarizeImageScan("registry.fly.io/my-app:release-v3", []byte(output))
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Critical)
+	assert.Equal(t, 1, report.High)
+	assert.Len(t, report.Vulnerabilities, 2)
+	assert.Equal(t, "openssl", report.Vulnerabilities[0].PkgName)
+
+	_, err = summarizeImageScan("registry.fly.io/my-app:release-v3", []byte("not json"))
+	assert.Error(t, err)
+}
+
+func Test_imageRegistryHost(t *testing.T) {
+	assert.Equal(t, "registry.fly.io", imageRegistryHost("registry.fly.io/my-app:release-v3"))
+	assert.Equal(t, "localhost:5000", imageRegistryHost("localhost:5000/my-app"))
+	assert.Equal(t, "docker.io", imageRegistryHost("docker.io/library/nginx"))
+	assert.Equal(t, "docker.io", imageRegistryHost("library/nginx"))
+	assert.Equal(t, "docker.io", imageRegistryHost("nginx:latest"))
+}
@@ -0,0 +1,89 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/superfly/flyctl/client"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/terminal"
+)
+
+var secretReferenceRegexp = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// checkSecrets warns about the secrets the deploy relies on that aren't set on the app, machines
+// missing them usually crash on boot and the deploy fails later on at health checks.
+// With --require-secrets the deploy stops before anything is built or launched.
+func checkSecrets(ctx context.Context, appConfig *appconfig.Config, expected []string) error {
+	referenced := referencedSecrets(appConfig, expected)
+	if len(referenced) == 0 {
+		return nil
+	}
+
+	appSecrets, err := client.FromContext(ctx).API().GetAppSecrets(ctx, appConfig.AppName)
+	if err != nil {
+		terminal.Debugf("failed to list the secrets of app %s: %v\n", appConfig.AppName, err)
+		return nil
+	}
+	set := make(map[string]bool, len(appSecrets))
+	for _, s := range appSecrets {
+		set[s.Name] = true
+	}
+
+	var missing []string
+	for _, name := range referenced {
+		if !set[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	msg := fmt.Sprintf("app %s is missing secrets the deploy references: %s. Set them with 'fly secrets set'", appConfig.AppName, strings.Join(missing, ", "))
+	if flag.GetBool(ctx, "require-secrets") {
+		return fmt.Errorf("%s", msg)
+	}
+	terminal.Warnf("%s, machines may fail to boot without them\n", msg)
+	return nil
+}
+
+// referencedSecrets lists, sorted, the expected secrets and the ${NAME} references of the env,
+// processes and release commands of appConfig that aren't defined in its [env] section
+func referencedSecrets(appConfig *appconfig.Config, expected []string) []string {
+	var values []string
+	for _, v := range appConfig.Env {
+		values = append(values, v)
+	}
+	for _, cmd := range appConfig.Processes {
+		values = append(values, cmd)
+	}
+	for _, rc := range appConfig.ReleaseCommands() {
+		values = append(values, rc.Command)
+	}
+
+	names := map[string]bool{}
+	for _, name := range expected {
+		names[name] = true
+	}
+	for _, v := range values {
+		for _, match := range secretReferenceRegexp.FindAllStringSubmatch(v, -1) {
+			names[match[1]] = true
+		}
+	}
+
+	var referenced []string
+	for name := range names {
+		// Fly sets its own variables on every machine
+		if _, ok := appConfig.Env[name]; ok || strings.HasPrefix(name, "FLY_") || name == "PRIMARY_REGION" {
+			continue
+		}
+		referenced = append(referenced, name)
+	}
+	sort.Strings(referenced)
+	return referenced
+}
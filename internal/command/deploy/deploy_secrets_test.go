@@ -0,0 +1,24 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/superfly/flyctl/internal/appconfig"
+)
+
+func Test_referencedSecrets(t *testing.T) {
+	appConfig := &appconfig.Config{
+		Env: map[string]string{
+			"DATABASE_URL": "postgres://${DB_USER}:${DB_PASSWORD}@${FLY_APP_NAME}.internal",
+			"DB_USER":      "app",
+			"REGION":       "${PRIMARY_REGION}",
+		},
+		Processes: map[string]string{
+			"web": "bin/server --key ${API_KEY}",
+		},
+	}
+
+	assert.Equal(t, []string{"API_KEY", "DB_PASSWORD", "SECRET_KEY_BASE"}, referencedSecrets(appConfig, []string{"SECRET_KEY_BASE"}))
+	assert.Empty(t, referencedSecrets(&appconfig.Config{}, nil))
+}
@@ -0,0 +1,116 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/superfly/flyctl/internal/machine"
+)
+
+// ReleaseCommandError is returned when the release command couldn't run or didn't succeed
+type ReleaseCommandError struct {
+	MachineID string
+	// ExitCode is the release command exit code, or -1 if it didn't exit
+	ExitCode int
+	err      error
+}
+
+func (e *ReleaseCommandError) Error() string {
+	return fmt.Sprintf("release command failed - aborting deployment. %v", e.err)
+}
+
+func (e *ReleaseCommandError) Unwrap() error {
+	return e.err
+}
+
+// HealthCheckTimeoutError is returned when a deployed machine health checks didn't pass in time
+type HealthCheckTimeoutError struct {
+	MachineID string
+	err       error
+}
+
+func (e *HealthCheckTimeoutError) Error() string {
+	return e.err.Error()
+}
+
+func (e *HealthCheckTimeoutError) Unwrap() error {
+	return e.err
+}
+
+// ImagePlatformError is returned when the deployment image wasn't built for the platform machines run on
+type ImagePlatformError struct {
+	Image    string
+	Platform string
+	Expected string
+}
+
+func (e *ImagePlatformError) Error() string {
+	return fmt.Sprintf("image %s was built for %s but machines run %s, its machines would crash on boot. "+
+		"Rebuild it with `docker build --platform %s` or deploy with --remote-only to build it on a Fly builder",
+		e.Image, e.Platform, e.Expected, e.Expected)
+}
+
+// ImmediateStrategyError is returned when the immediate strategy reached --immediate-max-errors,
+// it carries every machine error seen until then
+type ImmediateStrategyError struct {
+	Errors []error
+}
+
+func (e *ImmediateStrategyError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "aborting deployment after %d machine errors with the immediate strategy:", len(e.Errors))
+	for _, err := range e.Errors {
+		fmt.Fprintf(&b, "\n  * %v", err)
+	}
+	return b.String()
+}
+
+func (e *ImmediateStrategyError) Unwrap() []error {
+	return e.Errors
+}
+
+// PlatformValidationError is returned when the app config breaks platform constraints,
+// it carries every problem found instead of the first one
+type PlatformValidationError struct {
+	Errors []error
+}
+
+func (e *PlatformValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "the app config doesn't validate against the platform, %d problem(s) found:", len(e.Errors))
+	for _, err := range e.Errors {
+		fmt.Fprintf(&b, "\n  * %v", err)
+	}
+	return b.String()
+}
+
+func (e *PlatformValidationError) Unwrap() []error {
+	return e.Errors
+}
+
+// MachineLaunchError is returned when a new machine couldn't be created, e.g. when
+// the organization reached its machines quota
+type MachineLaunchError struct {
+	Group  string
+	Region string
+	err    error
+}
+
+func (e *MachineLaunchError) Error() string {
+	return e.err.Error()
+}
+
+func (e *MachineLaunchError) Unwrap() error {
+	return e.err
+}
+
+// healthCheckError classifies the error of waiting for a machine health checks to pass
+func healthCheckError(machineID string, err error) error {
+	var timeoutErr *machine.HealthChecksTimeoutError
+	if errors.As(err, &timeoutErr) || errors.Is(err, context.DeadlineExceeded) {
+		return &HealthCheckTimeoutError{MachineID: machineID, err: err}
+	}
+	return err
+}
@@ -0,0 +1,47 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_healthCheckError(t *testing.T) {
+	timeout := fmt.Errorf("timeout reached waiting for healthchecks to pass for machine ab1234567890 %w", context.DeadlineExceeded)
+	err := healthCheckError("ab1234567890", timeout)
+	var healthErr *HealthCheckTimeoutError
+	assert.ErrorAs(t, err, &healthErr)
+	assert.Equal(t, "ab1234567890", healthErr.MachineID)
+	assert.Equal(t, timeout.Error(), err.Error())
+
+	apiErr := errors.New("error getting machine ab1234567890 from api")
+	err = healthCheckError("ab1234567890", apiErr)
+	assert.False(t, errors.As(err, &healthErr))
+	assert.Equal(t, apiErr, err)
+}
+
+func Test_ReleaseCommandError(t *testing.T) {
+	var err error = &ReleaseCommandError{MachineID: "ab1234567890", ExitCode: 1, err: errors.New("exited with non-zero status of 1")}
+	err = fmt.Errorf("deploy failed: %w", err)
+
+	var releaseErr *ReleaseCommandError
+	assert.ErrorAs(t, err, &releaseErr)
+	assert.Equal(t, 1, releaseErr.ExitCode)
+	assert.Equal(t, "deploy failed: release command failed - aborting deployment. exited with non-zero status of 1", err.Error())
+}
+
+func Test_ImmediateStrategyError(t *testing.T) {
+	updateErr := errors.New("failed to update VM m2: 422")
+	var err error = &ImmediateStrategyError{Errors: []error{
+		fmt.Errorf("machine m1: %w", errors.New("failed to launch VM: capacity")),
+		fmt.Errorf("machine m2: %w", updateErr),
+	}}
+
+	assert.ErrorIs(t, err, updateErr)
+	assert.Equal(t, "aborting deployment after 2 machine errors with the immediate strategy:\n"+
+		"  * machine m1: failed to launch VM: capacity\n"+
+		"  * machine m2: failed to update VM m2: 422", err.Error())
+}
@@ -3,7 +3,13 @@ package deploy
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Khan/genqlient/graphql"
@@ -16,6 +22,7 @@ import (
 	"github.com/superfly/flyctl/gql"
 	"github.com/superfly/flyctl/internal/appconfig"
 	"github.com/superfly/flyctl/internal/cmdutil"
+	"github.com/superfly/flyctl/internal/config"
 	"github.com/superfly/flyctl/internal/machine"
 	"github.com/superfly/flyctl/iostreams"
 	"github.com/superfly/flyctl/terminal"
@@ -24,6 +31,26 @@ import (
 const (
 	DefaultWaitTimeout = 120 * time.Second
 	DefaultLeaseTtl    = 13 * time.Second
+
+	// newMachineWaitTimeoutFactor scales the wait timeout of machines that were just created,
+	// pulling the image on a cold host can take a while
+	newMachineWaitTimeoutFactor = 2
+
+	// defaultConfirmDestroyOver is the number of machines of removed process groups destroyed without confirmation
+	defaultConfirmDestroyOver = 3
+
+	// defaultRegionConcurrency is the number of machines of a region the deploy works on at once
+	defaultRegionConcurrency = 4
+)
+
+const (
+	updateOrderPrimaryFirst = "primary-first"
+	updateOrderPrimaryLast  = "primary-last"
+)
+
+const (
+	imagePullPolicyAuto   = "auto"
+	imagePullPolicyAlways = "always"
 )
 
 type MachineDeployment interface {
@@ -39,8 +66,102 @@ type MachineDeploymentArgs struct {
 	SkipHealthChecks  bool
 	RestartOnly       bool
 	WaitTimeout       time.Duration
-	LeaseTimeout      time.Duration
-	VMSize            string
+	// NewMachineWaitTimeout is the wait timeout of newly created machines, defaults to twice WaitTimeout
+	NewMachineWaitTimeout time.Duration
+	LeaseTimeout          time.Duration
+	VMSize                string
+	VMGPUKind             string
+	// ValidateHealthChecks probes the health checks of the first updated
+	// machine and aborts early when they report an HTTP error status
+	ValidateHealthChecks bool
+	// DeployTimeout caps the duration of the whole deployment, zero means no limit
+	DeployTimeout time.Duration
+	// InitCommand overrides the command of app machines without persisting it to fly.toml
+	InitCommand string
+	// SetEnv are NAME=VALUE pairs set on the env of app machines without persisting them to fly.toml
+	SetEnv []string
+	// EnvFile is a dotenv file whose vars are set like SetEnv, SetEnv takes precedence over them
+	EnvFile string
+	// OnlyChanged skips machines already running the configuration being deployed
+	OnlyChanged bool
+	// UpdateOrder is either primary-first or primary-last, defaults to primary-last
+	UpdateOrder string
+	// WebhookURL receives deploy events, defaults to the [deploy] webhook_url in fly.toml
+	WebhookURL string
+	// FallbackRegions are tried in order for new machines whose region is out of capacity, defaults to the [deploy] fallback_regions in fly.toml
+	FallbackRegions []string
+	// HealthyPollsRequired is the number of consecutive passing health check polls
+	// needed to consider a machine updated, defaults to 1
+	HealthyPollsRequired int
+	// ExpandRegions launches machines in the fly.toml regions process groups aren't running in
+	ExpandRegions bool
+	// ZeroDowntime replaces the machine of single machine groups instead of updating it in place
+	ZeroDowntime bool
+	// DrainTimeout is the time machines get to finish in-flight requests before being destroyed
+	DrainTimeout time.Duration
+	// BatchDelay is the pause between the batches of machines updated by the rolling strategy
+	BatchDelay time.Duration
+	// KeepPrevious is how many releases the machines replaced by deploys are kept stopped for
+	KeepPrevious int
+	// MinHealthy is the percentage of updated machines that must pass health checks, defaults to 100%
+	MinHealthy string
+	// ImmediateMaxErrors is how many machine errors the immediate strategy goes on after, zero for no limit
+	ImmediateMaxErrors int
+	// ProgressFile is the path of a JSON file kept up to date with the deploy state
+	ProgressFile string
+	// ConfigOverride is the path of a partial machine config merged onto every machine config
+	ConfigOverride string
+	// ImagePullPolicy is either auto or always, defaults to auto
+	ImagePullPolicy string
+	// DetachVolumes allows replacing machines whose volume isn't mounted by fly.toml anymore
+	DetachVolumes bool
+	// AutoCreateVolumes creates the volumes new and replacement machines need and the app doesn't have
+	AutoCreateVolumes bool
+	// FailOnMissingMachines fails deploys leaving a process group with fewer machines than expected
+	FailOnMissingMachines bool
+	// FailOnImageMismatch fails deploys leaving a machine on another image digest than the deployed one
+	FailOnImageMismatch bool
+	// NoPublicIPsWait skips the service checks while waiting for machines, only machine checks are waited on
+	NoPublicIPsWait bool
+	// ForceLease clears leases held by deploys that stopped refreshing them instead of failing
+	ForceLease bool
+	// SourceHash identifies the sources the image was built from, see imgsrc.SourceHash
+	SourceHash string
+	// ImagePlatform is the os/arch DeploymentImage was built for, checked against the machines platform when known
+	ImagePlatform string
+	// FromReleaseVersion is the release whose image and config are deployed again, if any
+	FromReleaseVersion int
+	// NoRelease updates the machines without creating a release nor changing their release metadata
+	NoRelease bool
+	// ConfirmDestroyOver is the number of machines of removed process groups a deploy destroys
+	// without asking for confirmation, defaults to 3
+	ConfirmDestroyOver int
+	// RegionConcurrency bounds the machines of a single region the deploy works on at once, defaults to 4
+	RegionConcurrency int
+	// MachineNameTemplate names new machines, e.g. "{group}-{region}-{index}", over [deploy] machine_name_template
+	MachineNameTemplate string
+	// PrimaryRegionOverride launches new machines and release commands in this region for this deploy only,
+	// unlike PrimaryRegionFlag it isn't saved as the app primary region
+	PrimaryRegionOverride string
+	// AutoConfirm confirms destroying machines without asking
+	AutoConfirm bool
+	// EnableProcessGroups deploys these process groups even if their [[deploy.conditional_groups]] disables them
+	EnableProcessGroups []string
+	// ReleaseCmdOnExisting runs the release commands on a started machine of the app instead of launching
+	// a machine for them, the machine must already run DeploymentImage
+	ReleaseCmdOnExisting bool
+	// MaintenancePage serves the page set by [deploy] maintenance_page while the machines are updated
+	MaintenancePage bool
+	// ValidateOnly checks the app config against the platform constraints and deploys nothing,
+	// DeploymentImage isn't required
+	ValidateOnly bool
+	// BuildDuration and PushDuration are how long building and pushing the image took, reported with the deploy timings
+	BuildDuration time.Duration
+	PushDuration  time.Duration
+	// StartedAt is when the deploy started, for its total time. It defaults to when the deployment is created
+	StartedAt time.Time
+	// Hooks are run at points of the deployment, for programs embedding flyctl
+	Hooks *DeployHooks
 }
 
 type machineDeployment struct {
@@ -49,9 +170,19 @@ type machineDeployment struct {
 	flapsClient           *flaps.Client
 	io                    *iostreams.IOStreams
 	colorize              *iostreams.ColorScheme
+	alertOut              io.Writer
+	quiet                 bool
+	jsonOutput            bool
+	deployed              []deployedMachine
+	waitForSkip           func(context.Context) bool
 	app                   *api.AppCompact
 	appConfig             *appconfig.Config
 	img                   string
+	imgDigest             string
+	imgTag                string
+	imgPlatform           string
+	sourceHash            string
+	hooks                 *DeployHooks
 	machineSet            machine.MachineSet
 	releaseCommandMachine machine.MachineSet
 	volumes               map[string][]api.Volume
@@ -61,14 +192,65 @@ type machineDeployment struct {
 	skipHealthChecks      bool
 	restartOnly           bool
 	waitTimeout           time.Duration
+	newMachineWaitTimeout time.Duration
 	leaseTimeout          time.Duration
 	leaseDelayBetween     time.Duration
 	isFirstDeploy         bool
 	machineGuest          *api.MachineGuest
+	validateHealthChecks  bool
+	deployTimeout         time.Duration
+	initCommand           []string
+	transientEnv          map[string]string
+	envFileEnv            map[string]string
+	onlyChanged           bool
+	updateOrder           string
+	deployLock            machine.LeasableMachine
+	webhookURL            string
+	fallbackRegions       []string
+	primaryRegionOverride string
+	machineNameTemplate   string
+	// machineNames are the names taken in the app, for machineName to skip them
+	machineNames          map[string]bool
+	machineNamesMu        sync.Mutex
+	healthyPollsRequired  int
+	immediateMaxErrors    int
+	expandRegions         bool
+	zeroDowntime          bool
+	drainTimeout          time.Duration
+	batchDelay            time.Duration
+	keepPrevious          int
+	maxUnhealthyRatio     float64
+	progress              *deployProgress
+	configOverride        map[string]any
+	canceled              atomic.Bool
+	canceledCh            chan struct{}
+	imagePullPolicy       string
+	detachVolumes         bool
+	autoCreateVolumes     bool
+	volumeSizes           map[string]int
+	failOnMissingMachines bool
+	failOnImageMismatch   bool
+	noPublicIPsWait       bool
+	forceLease            bool
+	expected              machineTopology
+	fromReleaseVersion    int
+	keptMachines          []*api.Machine
+	noRelease             bool
+	validateOnly          bool
+	confirmDestroyOver    int
+	regionConcurrency     int
+	regions               *regionLimiter
+	autoConfirm           bool
+	maintenanceHTML       string
+	releaseOnExisting     bool
+	disabledGroups        map[string]bool
+	regionChanges         []regionChange
+	startedAt             time.Time
+	timings               deployTimings
 }
 
 func NewMachineDeployment(ctx context.Context, args MachineDeploymentArgs) (MachineDeployment, error) {
-	if !args.RestartOnly && args.DeploymentImage == "" {
+	if !args.RestartOnly && !args.ValidateOnly && args.DeploymentImage == "" {
 		return nil, fmt.Errorf("BUG: machines deployment created without specifying the image")
 	}
 	if args.RestartOnly && args.DeploymentImage != "" {
@@ -89,16 +271,22 @@ func NewMachineDeployment(ctx context.Context, args MachineDeploymentArgs) (Mach
 	if err != nil {
 		return nil, err
 	}
-	if appConfig.Deploy != nil {
-		_, err = shlex.Split(appConfig.Deploy.ReleaseCommand)
-		if err != nil {
+	for _, rc := range appConfig.ReleaseCommands() {
+		if _, err := shlex.Split(rc.Command); err != nil {
 			return nil, err
 		}
+		if rc.ProcessGroup != "" && !lo.Contains(appConfig.ProcessNames(), rc.ProcessGroup) {
+			return nil, fmt.Errorf("release command '%s' is for process group '%s' which isn't defined in fly.toml", rc.Command, rc.ProcessGroup)
+		}
 	}
 	waitTimeout := args.WaitTimeout
 	if waitTimeout == 0 {
 		waitTimeout = DefaultWaitTimeout
 	}
+	newMachineWaitTimeout := args.NewMachineWaitTimeout
+	if newMachineWaitTimeout == 0 {
+		newMachineWaitTimeout = waitTimeout * newMachineWaitTimeoutFactor
+	}
 	leaseTimeout := args.LeaseTimeout
 	if leaseTimeout == 0 {
 		leaseTimeout = DefaultLeaseTtl
@@ -107,29 +295,134 @@ func NewMachineDeployment(ctx context.Context, args MachineDeploymentArgs) (Mach
 	if waitTimeout != DefaultWaitTimeout || leaseTimeout != DefaultLeaseTtl || args.WaitTimeout == 0 || args.LeaseTimeout == 0 {
 		terminal.Infof("Using wait timeout: %s lease timeout: %s delay between lease refreshes: %s\n", waitTimeout, leaseTimeout, leaseDelayBetween)
 	}
+	// Lease and other diagnostic logs are debug level, show them with --verbose too
+	if config.FromContext(ctx).VerboseOutput {
+		terminal.DefaultLogger.SetLogLevel(terminal.LevelDebug)
+	}
 	io := iostreams.FromContext(ctx)
+	loud := loudStreams(ctx)
 	apiClient := client.FromContext(ctx).API()
 	md := &machineDeployment{
-		apiClient:         apiClient,
-		gqlClient:         apiClient.GenqClient,
-		flapsClient:       flapsClient,
-		io:                io,
-		colorize:          io.ColorScheme(),
-		app:               args.AppCompact,
-		appConfig:         appConfig,
-		img:               args.DeploymentImage,
-		skipHealthChecks:  args.SkipHealthChecks,
-		restartOnly:       args.RestartOnly,
-		waitTimeout:       waitTimeout,
-		leaseTimeout:      leaseTimeout,
-		leaseDelayBetween: leaseDelayBetween,
+		apiClient:             apiClient,
+		gqlClient:             apiClient.GenqClient,
+		flapsClient:           flapsClient,
+		io:                    io,
+		colorize:              loud.ColorScheme(),
+		alertOut:              loud.ErrOut,
+		quiet:                 isQuiet(ctx),
+		jsonOutput:            config.FromContext(ctx).JSONOutput,
+		canceledCh:            make(chan struct{}),
+		app:                   args.AppCompact,
+		appConfig:             appConfig,
+		img:                   args.DeploymentImage,
+		imgPlatform:           args.ImagePlatform,
+		sourceHash:            args.SourceHash,
+		hooks:                 args.Hooks,
+		skipHealthChecks:      args.SkipHealthChecks,
+		restartOnly:           args.RestartOnly,
+		waitTimeout:           waitTimeout,
+		newMachineWaitTimeout: newMachineWaitTimeout,
+		leaseTimeout:          leaseTimeout,
+		leaseDelayBetween:     leaseDelayBetween,
+		validateHealthChecks:  args.ValidateHealthChecks,
+		deployTimeout:         args.DeployTimeout,
+		onlyChanged:           args.OnlyChanged,
+		expandRegions:         args.ExpandRegions,
+		zeroDowntime:          args.ZeroDowntime,
+		drainTimeout:          args.DrainTimeout,
+		detachVolumes:         args.DetachVolumes,
+		autoCreateVolumes:     args.AutoCreateVolumes,
+		failOnMissingMachines: args.FailOnMissingMachines,
+		failOnImageMismatch:   args.FailOnImageMismatch,
+		noPublicIPsWait:       args.NoPublicIPsWait,
+		forceLease:            args.ForceLease,
+		fromReleaseVersion:    args.FromReleaseVersion,
+		noRelease:             args.NoRelease,
+		validateOnly:          args.ValidateOnly,
+		autoConfirm:           args.AutoConfirm,
+		releaseOnExisting:     args.ReleaseCmdOnExisting,
+		startedAt:             args.StartedAt,
+		timings:               deployTimings{Build: args.BuildDuration, Push: args.PushDuration},
+		progress:              newDeployProgress(args.ProgressFile, args.AppCompact.Name),
+	}
+	if md.startedAt.IsZero() {
+		md.startedAt = time.Now()
 	}
 	if err := md.setStrategy(args.Strategy); err != nil {
 		return nil, err
 	}
-	if err := md.setMachineGuest(args.VMSize); err != nil {
+	if err := md.setPrimaryRegionOverride(args.PrimaryRegionOverride); err != nil {
+		return nil, err
+	}
+	if err := md.setMachineNameTemplate(args.MachineNameTemplate); err != nil {
+		return nil, err
+	}
+	if err := md.setDisabledGroups(args.EnableProcessGroups); err != nil {
+		return nil, err
+	}
+	if err := md.setMachineGuest(args.VMSize, args.VMGPUKind); err != nil {
+		return nil, err
+	}
+	if err := md.setInitCommand(args.InitCommand); err != nil {
+		return nil, err
+	}
+	if err := md.setTransientEnv(args.SetEnv); err != nil {
+		return nil, err
+	}
+	if err := md.setEnvFile(args.EnvFile); err != nil {
+		return nil, err
+	}
+	if err := md.setUpdateOrder(args.UpdateOrder); err != nil {
+		return nil, err
+	}
+	if err := md.setImagePullPolicy(args.ImagePullPolicy); err != nil {
+		return nil, err
+	}
+	if err := md.setWebhookURL(args.WebhookURL); err != nil {
 		return nil, err
 	}
+	md.setFallbackRegions(args.FallbackRegions)
+	if err := md.setHealthyPollsRequired(args.HealthyPollsRequired); err != nil {
+		return nil, err
+	}
+	if err := md.setImmediateMaxErrors(args.ImmediateMaxErrors); err != nil {
+		return nil, err
+	}
+	if err := md.setMinHealthy(args.MinHealthy); err != nil {
+		return nil, err
+	}
+	if err := md.setBatchDelay(args.BatchDelay); err != nil {
+		return nil, err
+	}
+	if err := md.setMaintenancePage(args.MaintenancePage); err != nil {
+		return nil, err
+	}
+	if args.KeepPrevious < 0 {
+		return nil, fmt.Errorf("error invalid keep previous '%d'; it must be a number of releases", args.KeepPrevious)
+	}
+	md.keepPrevious = args.KeepPrevious
+	if args.ConfirmDestroyOver < 0 {
+		return nil, fmt.Errorf("error invalid confirm destroy over '%d'; it must be a positive number of machines", args.ConfirmDestroyOver)
+	}
+	md.confirmDestroyOver = args.ConfirmDestroyOver
+	if md.confirmDestroyOver == 0 {
+		md.confirmDestroyOver = defaultConfirmDestroyOver
+	}
+	if args.RegionConcurrency < 0 {
+		return nil, fmt.Errorf("error invalid region concurrency '%d'; it must be a positive number of machines", args.RegionConcurrency)
+	}
+	md.regionConcurrency = args.RegionConcurrency
+	if md.regionConcurrency == 0 {
+		md.regionConcurrency = defaultRegionConcurrency
+	}
+	md.regions = newRegionLimiter(md.regionConcurrency)
+	if err := md.setConfigOverride(args.ConfigOverride); err != nil {
+		return nil, err
+	}
+	if md.validateOnly {
+		// Nothing is deployed, the checks only need the app config and the flags
+		return md, nil
+	}
 	if err := md.setMachinesForDeployment(ctx); err != nil {
 		return nil, err
 	}
@@ -142,13 +435,18 @@ func NewMachineDeployment(ctx context.Context, args MachineDeploymentArgs) (Mach
 	if err := md.setImg(ctx); err != nil {
 		return nil, err
 	}
+	if err := md.resolveImgDigest(ctx); err != nil {
+		return nil, err
+	}
 	if err := md.setVolumeConfig(ctx); err != nil {
 		return nil, err
 	}
 	if err := md.validateVolumeConfig(); err != nil {
 		return nil, err
 	}
-	if err = md.createReleaseInBackend(ctx); err != nil {
+	if md.noRelease {
+		terminal.Warnf("Updating the machines without a release: the release history and the release metadata of the machines won't reflect this change\n")
+	} else if err = md.createReleaseInBackend(ctx); err != nil {
 		return nil, err
 	}
 	return md, nil
@@ -212,6 +510,14 @@ func (md *machineDeployment) setVolumeConfig(ctx context.Context) error {
 		return fmt.Errorf("Error fetching application volumes: %w", err)
 	}
 
+	// Volumes created for new machines are sized like the largest volume of the same name
+	md.volumeSizes = map[string]int{}
+	for _, v := range volumes {
+		if v.SizeGb > md.volumeSizes[v.Name] {
+			md.volumeSizes[v.Name] = v.SizeGb
+		}
+	}
+
 	unattached := lo.Filter(volumes, func(v api.Volume, _ int) bool {
 		return v.AttachedAllocation == nil && v.AttachedMachine == nil
 	})
@@ -278,12 +584,18 @@ func (md *machineDeployment) validateVolumeConfig() error {
 			}
 
 		case false:
-			// Check if there are unattached volumes for new groups with mounts
+			// Check if there are enough unattached volumes for new groups with mounts,
+			// every mount needs a volume of its own
+			needed := map[string]int{}
+			for _, m := range groupConfig.Mounts {
+				needed[m.Source]++
+			}
 			for _, m := range groupConfig.Mounts {
-				if vs := md.volumes[m.Source]; len(vs) == 0 {
+				if vs := md.volumes[m.Source]; len(vs) < needed[m.Source] && !md.autoCreateVolumes {
 					return fmt.Errorf(
-						"creating a new machine in group '%s' requires an unattached '%s' volume. Create it with `fly volume create %s`",
-						groupName, m.Source, m.Source)
+						"creating a new machine in group '%s' requires %d unattached '%s' volume(s) but found %d. "+
+							"Create them with `fly volume create %s` or deploy with --auto-create-volumes",
+						groupName, needed[m.Source], m.Source, len(vs), m.Source)
 				}
 			}
 		}
@@ -308,6 +620,47 @@ func (md *machineDeployment) setImg(ctx context.Context) error {
 	return fmt.Errorf("could not find image to use for deployment; backend error was: %w", err)
 }
 
+// resolveImgDigest pins the deployment image to its current digest so every machine
+// in the release runs the same bits even if the tag is moved while deploying.
+func (md *machineDeployment) resolveImgDigest(ctx context.Context) error {
+	if md.restartOnly || md.img == "" {
+		return nil
+	}
+	md.imgTag = imageTag(md.img)
+	if _, digest, found := strings.Cut(md.img, "@"); found {
+		md.imgDigest = digest
+		return nil
+	}
+	// Pinning the digest is what makes machines pull an image pushed again under the same tag,
+	// with the always pull policy the deploy can't go on without it
+	img, err := md.apiClient.ResolveImageForApp(ctx, md.app.Name, md.img)
+	switch {
+	case err != nil && md.imagePullPolicy == imagePullPolicyAlways:
+		return fmt.Errorf("could not resolve digest for image %s required by --image-pull-policy=%s: %w", md.img, imagePullPolicyAlways, err)
+	case err != nil:
+		terminal.Warnf("could not resolve digest for image %s, machines will track the tag: %v\n", md.img, err)
+		return nil
+	case (img == nil || img.Digest == "") && md.imagePullPolicy == imagePullPolicyAlways:
+		return fmt.Errorf("no digest found for image %s, required by --image-pull-policy=%s", md.img, imagePullPolicyAlways)
+	case img == nil || img.Digest == "":
+		terminal.Debugf("no digest found for image %s\n", md.img)
+		return nil
+	}
+	md.imgDigest = img.Digest
+	md.img = fmt.Sprintf("%s@%s", md.img, img.Digest)
+	terminal.Debugf("resolved deployment image to %s\n", md.img)
+	return nil
+}
+
+// imageTag returns the tag of an image reference like registry.fly.io/app:deployment-01H5ZKD4V0X1Y2Z3@sha256:...
+func imageTag(ref string) string {
+	ref, _, _ = strings.Cut(ref, "@")
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		return ref[i+1:]
+	}
+	return ""
+}
+
 func (md *machineDeployment) latestImage(ctx context.Context) (string, error) {
 	_ = `# @genqlient
 	       query FlyctlDeployGetLatestImage($appName:String!) {
@@ -330,24 +683,161 @@ func (md *machineDeployment) latestImage(ctx context.Context) (string, error) {
 	return resp.App.CurrentReleaseUnprocessed.ImageRef, nil
 }
 
-func (md *machineDeployment) setMachineGuest(vmSize string) error {
-	if vmSize == "" {
+func (md *machineDeployment) setMachineGuest(vmSize, gpuKind string) error {
+	if vmSize == "" && gpuKind == "" {
 		return nil
 	}
+	if vmSize == "" {
+		vmSize = defaultGPUVMSize
+	}
 	md.machineGuest = &api.MachineGuest{}
-	return md.machineGuest.SetSize(vmSize)
+	if err := md.machineGuest.SetSize(vmSize); err != nil {
+		return err
+	}
+	if gpuKind == "" {
+		return nil
+	}
+	if err := md.machineGuest.SetGPUKind(gpuKind); err != nil {
+		return err
+	}
+	// New machines launch in the primary region, better fail now than after building and releasing
+	return checkGPURegion(md.machineGuest, md.primaryRegion())
+}
+
+func (md *machineDeployment) setInitCommand(command string) error {
+	if command == "" {
+		return nil
+	}
+	initCmd, err := shlex.Split(command)
+	if err != nil {
+		return fmt.Errorf("failed parsing command override: %w", err)
+	}
+	md.initCommand = initCmd
+	md.warnf("%s %s\n", md.colorize.WarningIcon(), md.colorize.Yellow(fmt.Sprintf(
+		"All app machines will run `%s` instead of their configured command. "+
+			"This override is transient, it isn't saved to %s and the next deploy reverts it",
+		command, appconfig.DefaultConfigFileName)))
+	return nil
+}
+
+func (md *machineDeployment) setTransientEnv(env []string) error {
+	if len(env) == 0 {
+		return nil
+	}
+	parsedEnv, err := cmdutil.ParseKVStringsToMap(env)
+	if err != nil {
+		return fmt.Errorf("failed parsing --set-env: %w", err)
+	}
+	md.transientEnv = parsedEnv
+	names := lo.Keys(parsedEnv)
+	sort.Strings(names)
+	md.warnf("%s %s\n", md.colorize.WarningIcon(), md.colorize.Yellow(fmt.Sprintf(
+		"All app machines will have %s set over their configured env. "+
+			"This is transient, it isn't saved to %s and the next deploy without --set-env removes it",
+		strings.Join(names, ", "), appconfig.DefaultConfigFileName)))
+	return nil
+}
+
+func (md *machineDeployment) setUpdateOrder(order string) error {
+	switch order {
+	case "":
+		md.updateOrder = updateOrderPrimaryLast
+	case updateOrderPrimaryFirst, updateOrderPrimaryLast:
+		md.updateOrder = order
+	default:
+		return fmt.Errorf("error unsupported update order '%s'; use %s or %s", order, updateOrderPrimaryFirst, updateOrderPrimaryLast)
+	}
+	return nil
 }
 
+func (md *machineDeployment) setImagePullPolicy(policy string) error {
+	switch policy {
+	case "":
+		md.imagePullPolicy = imagePullPolicyAuto
+	case imagePullPolicyAuto, imagePullPolicyAlways:
+		md.imagePullPolicy = policy
+	default:
+		return fmt.Errorf("error unsupported image pull policy '%s'; use %s or %s", policy, imagePullPolicyAuto, imagePullPolicyAlways)
+	}
+	return nil
+}
+
+func (md *machineDeployment) setHealthyPollsRequired(polls int) error {
+	switch {
+	case polls == 0:
+		md.healthyPollsRequired = 1
+	case polls < 0:
+		return fmt.Errorf("error invalid number of healthy polls required '%d'; it must be at least 1", polls)
+	default:
+		md.healthyPollsRequired = polls
+	}
+	return nil
+}
+
+func (md *machineDeployment) setImmediateMaxErrors(maxErrors int) error {
+	switch {
+	case maxErrors < 0:
+		return fmt.Errorf("error invalid immediate max errors '%d'; it must be at least 1, or 0 for no limit", maxErrors)
+	case maxErrors > 0 && md.strategy != "immediate":
+		terminal.Warnf("--immediate-max-errors only applies to the immediate strategy, the %s strategy stops at the first error\n", md.strategy)
+	}
+	md.immediateMaxErrors = maxErrors
+	return nil
+}
+
+func (md *machineDeployment) setBatchDelay(delay time.Duration) error {
+	switch {
+	case delay < 0:
+		return fmt.Errorf("error invalid batch delay '%s'; it must be positive", delay)
+	case delay > 0 && md.strategy != "rolling":
+		terminal.Warnf("--batch-delay only applies to the rolling strategy, the %s strategy doesn't pause between machines\n", md.strategy)
+	}
+	md.batchDelay = delay
+	return nil
+}
+
+func (md *machineDeployment) setMinHealthy(minHealthy string) error {
+	if minHealthy == "" {
+		md.maxUnhealthyRatio = 0
+		return nil
+	}
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(minHealthy), "%"), 64)
+	if err != nil || percent <= 0 || percent > 100 {
+		return fmt.Errorf("error invalid min healthy '%s'; it must be a percentage between 0%% and 100%%, e.g. 95%%", minHealthy)
+	}
+	md.maxUnhealthyRatio = (100 - percent) / 100
+	return nil
+}
+
+func (md *machineDeployment) setWebhookURL(webhookURL string) error {
+	if webhookURL == "" && md.appConfig.Deploy != nil {
+		webhookURL = md.appConfig.Deploy.WebhookURL
+	}
+	if webhookURL == "" {
+		return nil
+	}
+	u, err := url.Parse(webhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("error invalid webhook url '%s'; it must be an http or https URL", webhookURL)
+	}
+	md.webhookURL = webhookURL
+	return nil
+}
+
+// setStrategy picks the --strategy flag, then the fly.toml [deploy] strategy, then rolling
 func (md *machineDeployment) setStrategy(passedInStrategy string) error {
+	source := "--strategy"
 	if passedInStrategy != "" {
 		md.strategy = passedInStrategy
 	} else if md.appConfig.Deploy != nil && md.appConfig.Deploy.Strategy != "" {
 		md.strategy = md.appConfig.Deploy.Strategy
+		source = "fly.toml [deploy] strategy"
 	} else {
 		md.strategy = "rolling"
 	}
-	if md.strategy != "rolling" && md.strategy != "immediate" {
-		return fmt.Errorf("error unsupported deployment strategy '%s'; fly deploy for machines supports rolling and immediate strategies", md.strategy)
+	if !lo.Contains(appconfig.MachinesDeployStrategies, md.strategy) {
+		return fmt.Errorf("error unsupported deployment strategy '%s' from %s; fly deploy for machines supports %s strategies",
+			md.strategy, source, strings.Join(appconfig.MachinesDeployStrategies, " and "))
 	}
 	return nil
 }
@@ -380,6 +870,9 @@ func (md *machineDeployment) createReleaseInBackend(ctx context.Context) error {
 }
 
 func (md *machineDeployment) updateReleaseInBackend(ctx context.Context, status string) error {
+	if md.noRelease {
+		return nil
+	}
 	_ = `# @genqlient
 	mutation MachinesUpdateRelease($input:UpdateReleaseInput!) {
 		updateRelease(input:$input) {
@@ -0,0 +1,86 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/superfly/flyctl/api"
+)
+
+// enablesAutostop tells if desired turns autostop on for a service of current that didn't have it.
+// Services are matched by protocol and internal port, new services don't count as the proxy
+// doesn't route them to machines yet.
+func enablesAutostop(current, desired *api.MachineConfig) bool {
+	if current == nil || desired == nil {
+		return false
+	}
+	for _, d := range desired.Services {
+		if d.Autostop == nil || !*d.Autostop {
+			continue
+		}
+		for _, c := range current.Services {
+			if c.Protocol == d.Protocol && c.InternalPort == d.InternalPort && (c.Autostop == nil || !*c.Autostop) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// groupPeers are the other entries updated in the process group of e, the ones already updated first
+// as they run the new config
+func groupPeers(e *machineUpdateEntry, entries []*machineUpdateEntry) []*machineUpdateEntry {
+	group := e.launchInput.Config.ProcessGroup()
+	var updated, pending []*machineUpdateEntry
+	seen := false
+	for _, other := range entries {
+		switch {
+		case other == e:
+			seen = true
+		case other.launchInput.Config.ProcessGroup() != group:
+		case seen:
+			pending = append(pending, other)
+		default:
+			updated = append(updated, other)
+		}
+	}
+	return append(updated, pending...)
+}
+
+// keepGroupMachineUp makes sure another machine of e's process group is started before e is updated
+// with autostop enabled. The proxy may stop the machines already on the new config while the app
+// is idle, updating the last running one would then leave the whole group stopped mid-deploy.
+func (md *machineDeployment) keepGroupMachineUp(ctx context.Context, e *machineUpdateEntry, entries []*machineUpdateEntry, logPrefix string) error {
+	peers := groupPeers(e, entries)
+	if len(peers) == 0 {
+		return nil
+	}
+
+	var stopped []*machineUpdateEntry
+	for _, peer := range peers {
+		m, err := md.flapsClient.Get(ctx, peer.leasableMachine.Machine().ID)
+		if err != nil {
+			// Machines replaced earlier in the deploy are gone, the entry has the original one
+			continue
+		}
+		switch m.State {
+		case api.MachineStateStarted:
+			return nil
+		case api.MachineStateStopped, api.MachineStateSuspended:
+			stopped = append(stopped, peer)
+		}
+	}
+	if len(stopped) == 0 {
+		md.warnf("No other machine of group '%s' could be kept running while enabling autostop on %s\n",
+			e.launchInput.Config.ProcessGroup(), e.leasableMachine.Machine().ID)
+		return nil
+	}
+
+	lm := stopped[0].leasableMachine
+	fmt.Fprintf(md.io.ErrOut, "  %s Starting %s to keep group '%s' running while autostop is enabled\n",
+		logPrefix, md.colorize.Bold(lm.FormattedMachineId()), e.launchInput.Config.ProcessGroup())
+	if err := lm.Resume(ctx); err != nil {
+		return fmt.Errorf("failed to start machine %s to keep group '%s' running: %w", lm.Machine().ID, e.launchInput.Config.ProcessGroup(), err)
+	}
+	return lm.WaitForState(ctx, api.MachineStateStarted, md.waitTimeout, logPrefix)
+}
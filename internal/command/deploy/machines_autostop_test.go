@@ -0,0 +1,46 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func Test_enablesAutostop(t *testing.T) {
+	config := func(autostop *bool) *api.MachineConfig {
+		return &api.MachineConfig{Services: []api.MachineService{{Protocol: "tcp", InternalPort: 8080, Autostop: autostop}}}
+	}
+	assert.True(t, enablesAutostop(config(nil), config(api.Pointer(true))))
+	assert.True(t, enablesAutostop(config(api.Pointer(false)), config(api.Pointer(true))))
+	assert.False(t, enablesAutostop(config(api.Pointer(true)), config(api.Pointer(true))))
+	assert.False(t, enablesAutostop(config(nil), config(api.Pointer(false))))
+	assert.False(t, enablesAutostop(config(api.Pointer(true)), nil))
+
+	// A new service isn't routed to the machine yet
+	desired := config(api.Pointer(true))
+	desired.Services[0].InternalPort = 9090
+	assert.False(t, enablesAutostop(config(nil), desired))
+}
+
+func Test_groupPeers(t *testing.T) {
+	ios, _, _, _ := iostreams.Test()
+	entry := func(id, group string) *machineUpdateEntry {
+		m := groupMachine(id, group, "ord")
+		return &machineUpdateEntry{
+			leasableMachine: machine.NewLeasableMachine(nil, ios, m),
+			launchInput:     &api.LaunchMachineInput{ID: id, Config: m.Config},
+		}
+	}
+	entries := []*machineUpdateEntry{entry("m1", "app"), entry("w1", "worker"), entry("m2", "app"), entry("m3", "app")}
+
+	ids := func(entries []*machineUpdateEntry) []string {
+		return lo.Map(entries, func(e *machineUpdateEntry, _ int) string { return e.launchInput.ID })
+	}
+	assert.Equal(t, []string{"m1", "m3"}, ids(groupPeers(entries[2], entries)))
+	assert.Equal(t, []string{"m2", "m3"}, ids(groupPeers(entries[0], entries)))
+	assert.Empty(t, groupPeers(entries[1], entries))
+}
@@ -0,0 +1,28 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// waitBatchDelay pauses the rolling strategy between two batches of updated machines for
+// --batch-delay, so the effect of the last updates shows up in metrics before the next ones.
+// Canceling ctx, e.g. with Ctrl-C, or the release with `fly deploy --cancel` ends the pause with
+// an error aborting the rest of the deploy.
+func (md *machineDeployment) waitBatchDelay(ctx context.Context) error {
+	if md.batchDelay <= 0 || md.strategy != "rolling" {
+		return nil
+	}
+	fmt.Fprintf(md.io.ErrOut, "  Waiting %s before updating the next machine\n", md.batchDelay)
+	timer := time.NewTimer(md.batchDelay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("deploy interrupted during the batch delay: %w", ctx.Err())
+	case <-md.canceledCh:
+		return errDeployCanceled
+	}
+}
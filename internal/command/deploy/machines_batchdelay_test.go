@@ -0,0 +1,74 @@
+package deploy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func Test_waitBatchDelay(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	md.io, _, _, _ = iostreams.Test()
+	md.strategy = "rolling"
+
+	assert.NoError(t, md.waitBatchDelay(context.Background()))
+
+	md.batchDelay = time.Millisecond
+	assert.NoError(t, md.waitBatchDelay(context.Background()))
+
+	md.batchDelay = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, md.waitBatchDelay(ctx), context.Canceled)
+
+	// So does canceling the release from another session
+	md.canceledCh = make(chan struct{})
+	close(md.canceledCh)
+	assert.ErrorIs(t, md.waitBatchDelay(context.Background()), errDeployCanceled)
+
+	// The immediate strategy never pauses
+	md.strategy = "immediate"
+	assert.NoError(t, md.waitBatchDelay(ctx))
+}
+
+func Test_setBatchDelay(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	md.strategy = "rolling"
+
+	assert.Error(t, md.setBatchDelay(-time.Second))
+	require.NoError(t, md.setBatchDelay(30*time.Second))
+	assert.Equal(t, 30*time.Second, md.batchDelay)
+}
+
+func Test_updateExistingMachines_batchDelaySkipsUpToDate(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	ios, _, _, errOut := iostreams.Test()
+	md.io = ios
+	md.colorize = ios.ColorScheme()
+	md.strategy = "rolling"
+	md.batchDelay = time.Millisecond
+
+	entry := func(id string) *machineUpdateEntry {
+		m := groupMachine(id, "app", "ord")
+		m.State = api.MachineStateStopped
+		return &machineUpdateEntry{
+			leasableMachine: machine.NewLeasableMachine(nil, ios, m),
+			launchInput:     &api.LaunchMachineInput{ID: id, Config: m.Config},
+			upToDate:        true,
+		}
+	}
+
+	// Machines already up to date aren't updated, there's nothing to pace
+	require.NoError(t, md.updateExistingMachines(context.Background(), []*machineUpdateEntry{entry("m1"), entry("m2")}))
+	assert.NotContains(t, errOut.String(), "Waiting")
+}
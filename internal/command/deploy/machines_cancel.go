@@ -0,0 +1,132 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Khan/genqlient/graphql"
+	"github.com/superfly/flyctl/client"
+	"github.com/superfly/flyctl/gql"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/iostreams"
+	"github.com/superfly/flyctl/terminal"
+)
+
+const (
+	releaseStatusRunning = "running"
+	releaseStatusFailed  = "failed"
+
+	// cancelPollInterval is how often a deploy checks if its release was canceled
+	cancelPollInterval = 10 * time.Second
+	// recentReleasesCount is how many of the latest releases are looked at for running ones
+	recentReleasesCount = 10
+)
+
+// errDeployCanceled is returned by deploys that noticed their release was canceled with `fly deploy --cancel`
+var errDeployCanceled = fmt.Errorf("the release was canceled with 'fly deploy --cancel', stopped before updating more machines")
+
+type recentRelease = gql.FlyctlDeployGetRecentReleasesAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed
+
+func recentReleases(ctx context.Context, gqlClient graphql.Client, appName string) ([]recentRelease, error) {
+	_ = `# @genqlient
+	query FlyctlDeployGetRecentReleases($appName:String!, $count:Int!) {
+		app(name:$appName) {
+			releasesUnprocessed(first:$count) {
+				nodes {
+					id
+					version
+					status
+				}
+			}
+		}
+	}
+	`
+	resp, err := gql.FlyctlDeployGetRecentReleases(ctx, gqlClient, appName, recentReleasesCount)
+	if err != nil {
+		return nil, err
+	}
+	return resp.App.ReleasesUnprocessed.Nodes, nil
+}
+
+// cancelRunningReleases marks the running releases of the app as failed. The deploys running them
+// notice it and stop before updating their next machine, releasing their machine leases.
+func cancelRunningReleases(ctx context.Context) error {
+	var (
+		io        = iostreams.FromContext(ctx)
+		gqlClient = client.FromContext(ctx).API().GenqClient
+		appName   = appconfig.NameFromContext(ctx)
+	)
+
+	releases, err := recentReleases(ctx, gqlClient, appName)
+	if err != nil {
+		return fmt.Errorf("failed to list the releases of app %s: %w", appName, err)
+	}
+
+	canceled := 0
+	for _, r := range releases {
+		if r.Status != releaseStatusRunning {
+			continue
+		}
+		input := gql.UpdateReleaseInput{ReleaseId: r.Id, Status: releaseStatusFailed}
+		if _, err := gql.MachinesUpdateRelease(ctx, gqlClient, input); err != nil {
+			return fmt.Errorf("failed to cancel release v%d: %w", r.Version, err)
+		}
+		fmt.Fprintf(io.Out, "Canceled release v%d, its deploy stops before updating its next machine\n", r.Version)
+		canceled++
+	}
+	if canceled == 0 {
+		fmt.Fprintf(io.Out, "No release in progress for app %s\n", appName)
+	}
+	return nil
+}
+
+// watchForCancellation polls the status of the release being deployed until the returned func is called.
+// Once it was canceled from another session, checkCanceled fails and canceledCh is closed.
+func (md *machineDeployment) watchForCancellation(ctx context.Context) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(cancelPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				releases, err := recentReleases(ctx, md.gqlClient, md.app.Name)
+				if err != nil {
+					terminal.Debugf("failed to check if release %s was canceled: %v\n", md.releaseId, err)
+					continue
+				}
+				if releaseCanceled(releases, md.releaseId) {
+					md.canceled.Store(true)
+					if md.canceledCh != nil {
+						close(md.canceledCh)
+					}
+					terminal.Warnf("Release v%d was canceled, stopping before the next machine\n", md.releaseVersion)
+					return
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+func releaseCanceled(releases []recentRelease, releaseID string) bool {
+	for _, r := range releases {
+		if r.Id == releaseID {
+			return r.Status == releaseStatusFailed
+		}
+	}
+	return false
+}
+
+// checkCanceled is called between machines so a canceled deploy doesn't leave one half updated
+func (md *machineDeployment) checkCanceled() error {
+	if md.canceled.Load() {
+		return errDeployCanceled
+	}
+	return nil
+}
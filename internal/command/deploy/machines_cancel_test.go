@@ -0,0 +1,31 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/internal/appconfig"
+)
+
+func Test_releaseCanceled(t *testing.T) {
+	releases := []recentRelease{
+		{Id: "release_3", Version: 3, Status: releaseStatusFailed},
+		{Id: "release_2", Version: 2, Status: releaseStatusRunning},
+		{Id: "release_1", Version: 1, Status: "complete"},
+	}
+	assert.True(t, releaseCanceled(releases, "release_3"))
+	assert.False(t, releaseCanceled(releases, "release_2"))
+	assert.False(t, releaseCanceled(releases, "release_1"))
+	// Releases too old to be listed are never considered canceled
+	assert.False(t, releaseCanceled(releases, "release_0"))
+}
+
+func Test_checkCanceled(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+
+	assert.NoError(t, md.checkCanceled())
+	md.canceled.Store(true)
+	assert.ErrorIs(t, md.checkCanceled(), errDeployCanceled)
+}
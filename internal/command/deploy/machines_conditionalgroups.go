@@ -0,0 +1,46 @@
+package deploy
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/samber/lo"
+	"golang.org/x/exp/slices"
+)
+
+// setDisabledGroups disables the process groups whose [[deploy.conditional_groups]] condition doesn't
+// hold, except the ones enabled for this deploy with --enable-process-group. Disabled groups are
+// treated as groups that should have no machines: theirs are destroyed and none is launched.
+func (md *machineDeployment) setDisabledGroups(enabled []string) error {
+	groups := md.appConfig.ProcessNames()
+	for _, name := range enabled {
+		if !slices.Contains(groups, name) {
+			return fmt.Errorf("invalid --enable-process-group '%s', fly.toml defines the process groups %s", name, strings.Join(groups, ", "))
+		}
+	}
+	for _, name := range groups {
+		if slices.Contains(enabled, name) || md.appConfig.ProcessGroupEnabled(name, os.Getenv) {
+			continue
+		}
+		if md.disabledGroups == nil {
+			md.disabledGroups = map[string]bool{}
+		}
+		md.disabledGroups[name] = true
+	}
+	if len(md.disabledGroups) == 0 || md.restartOnly {
+		return nil
+	}
+	if len(md.disabledGroups) == len(groups) {
+		return fmt.Errorf("every process group is disabled by [[deploy.conditional_groups]] in fly.toml, enable one with --enable-process-group")
+	}
+	disabled := lo.Keys(md.disabledGroups)
+	slices.Sort(disabled)
+	fmt.Fprintf(md.io.ErrOut, "Process groups disabled by [[deploy.conditional_groups]]: %s\n", strings.Join(disabled, ", "))
+	return nil
+}
+
+// enabledProcessNames are the process groups of fly.toml the deploy runs machines for
+func (md *machineDeployment) enabledProcessNames() []string {
+	return lo.Reject(md.appConfig.ProcessNames(), func(name string, _ int) bool { return md.disabledGroups[name] })
+}
@@ -0,0 +1,64 @@
+package deploy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func TestConditionalGroups(t *testing.T) {
+	appConfig := &appconfig.Config{
+		PrimaryRegion: "scl",
+		Processes: map[string]string{
+			"web":    "run web",
+			"debug":  "run debug",
+			"worker": "run worker",
+		},
+		Deploy: &appconfig.Deploy{ConditionalGroups: []appconfig.ConditionalGroup{
+			{ProcessGroup: "debug", EnabledEnv: "FLYCTL_TEST_ENABLE_DEBUG"},
+			{ProcessGroup: "worker", Enabled: api.Pointer(false)},
+		}},
+	}
+	require.NoError(t, appConfig.SetMachinesPlatform())
+	md, err := stabMachineDeployment(appConfig)
+	require.NoError(t, err)
+	ios, _, _, errOut := iostreams.Test()
+	md.io = ios
+	md.machineSet = machine.NewMachineSet(nil, ios, []*api.Machine{
+		groupMachine("web1", "web", "scl"),
+		groupMachine("debug1", "debug", "scl"),
+	})
+
+	require.NoError(t, md.setDisabledGroups(nil))
+	assert.Equal(t, map[string]bool{"debug": true, "worker": true}, md.disabledGroups)
+	assert.Equal(t, []string{"web"}, md.enabledProcessNames())
+	assert.Contains(t, errOut.String(), "Process groups disabled by [[deploy.conditional_groups]]: debug, worker")
+
+	// Disabled groups lose their machines and don't get new ones
+	diff := md.resolveProcessGroupChanges()
+	assert.Equal(t, map[string]int{"debug": 1}, diff.groupsToRemove)
+	assert.Empty(t, diff.groupsNeedingMachines)
+	assert.Equal(t, machineTopology{"web": {"scl": 1}}, md.expectedTopology(diff))
+	assert.ErrorContains(t, md.spawnMachineInGroup(context.Background(), "worker", "", 0, 1), "disables it")
+
+	// The environment and --enable-process-group turn them back on
+	t.Setenv("FLYCTL_TEST_ENABLE_DEBUG", "1")
+	md.disabledGroups = nil
+	require.NoError(t, md.setDisabledGroups([]string{"worker"}))
+	assert.Empty(t, md.disabledGroups)
+	diff = md.resolveProcessGroupChanges()
+	assert.Empty(t, diff.groupsToRemove)
+	assert.Equal(t, map[string]bool{"worker": true}, diff.groupsNeedingMachines)
+
+	assert.ErrorContains(t, md.setDisabledGroups([]string{"dbug"}), "invalid --enable-process-group 'dbug'")
+	md.disabledGroups = nil
+	appConfig.Deploy.ConditionalGroups = append(appConfig.Deploy.ConditionalGroups, appconfig.ConditionalGroup{ProcessGroup: "web", Enabled: api.Pointer(false)})
+	t.Setenv("FLYCTL_TEST_ENABLE_DEBUG", "")
+	assert.ErrorContains(t, md.setDisabledGroups(nil), "every process group is disabled")
+}
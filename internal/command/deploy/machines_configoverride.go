@@ -0,0 +1,97 @@
+package deploy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/superfly/flyctl/api"
+)
+
+// setConfigOverride loads the partial machine config given with --config-override.
+// It is a JSON or TOML (by file extension) document using the machine config field names.
+func (md *machineDeployment) setConfigOverride(path string) error {
+	if path == "" {
+		return nil
+	}
+	override, err := loadMachineConfigOverride(path)
+	if err != nil {
+		return fmt.Errorf("error invalid config override '%s'; %w", path, err)
+	}
+	md.configOverride = override
+	return nil
+}
+
+func loadMachineConfigOverride(path string) (map[string]any, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	override := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		err = toml.Unmarshal(buf, &override)
+	default:
+		err = json.Unmarshal(buf, &override)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Be sure it is a valid machine config before any machine is touched
+	buf, err = json.Marshal(override)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&api.MachineConfig{}); err != nil {
+		return nil, fmt.Errorf("it doesn't match the machine config format: %w", err)
+	}
+	return override, nil
+}
+
+// applyConfigOverride deep merges the config override onto mConfig, the override wins.
+// Objects are merged key by key, any other value (lists included) is replaced.
+// It must run before setMachineReleaseData so fly managed metadata still wins over the override.
+func (md *machineDeployment) applyConfigOverride(mConfig *api.MachineConfig) (*api.MachineConfig, error) {
+	if len(md.configOverride) == 0 {
+		return mConfig, nil
+	}
+
+	buf, err := json.Marshal(mConfig)
+	if err != nil {
+		return nil, err
+	}
+	merged := map[string]any{}
+	if err := json.Unmarshal(buf, &merged); err != nil {
+		return nil, err
+	}
+	deepMerge(merged, md.configOverride)
+
+	if buf, err = json.Marshal(merged); err != nil {
+		return nil, err
+	}
+	result := &api.MachineConfig{}
+	if err := json.Unmarshal(buf, result); err != nil {
+		return nil, fmt.Errorf("failed to apply the config override: %w", err)
+	}
+	return result, nil
+}
+
+func deepMerge(dst, src map[string]any) {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]any)
+		dstMap, dstIsMap := dst[key].(map[string]any)
+		if srcIsMap && dstIsMap {
+			deepMerge(dstMap, srcMap)
+			continue
+		}
+		dst[key] = value
+	}
+}
@@ -0,0 +1,72 @@
+package deploy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/appconfig"
+)
+
+func writeOverride(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func Test_setConfigOverride(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{
+		AppName: "my-cool-app",
+		Env:     map[string]string{"OTHER": "value"},
+	})
+	require.NoError(t, err)
+	md.releaseId = "release_id"
+	md.releaseVersion = 3
+
+	path := writeOverride(t, "override.json", `{
+		"env": {"DEBUG": "1"},
+		"guest": {"cpu_kind": "performance", "cpus": 2, "memory_mb": 4096},
+		"metadata": {"fly_release_id": "not me", "team": "infra"}
+	}`)
+	require.NoError(t, md.setConfigOverride(path))
+
+	li, err := md.launchInputForUpdate(&api.Machine{
+		ID:     "ab1234567890",
+		Config: &api.MachineConfig{Guest: &api.MachineGuest{CPUKind: "shared", CPUs: 1, MemoryMB: 256}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"OTHER": "value", "DEBUG": "1", "FLY_PROCESS_GROUP": "app"}, li.Config.Env)
+	assert.Equal(t, &api.MachineGuest{CPUKind: "performance", CPUs: 2, MemoryMB: 4096}, li.Config.Guest)
+	// Fly managed metadata wins over the override
+	assert.Equal(t, "release_id", li.Config.Metadata["fly_release_id"])
+	assert.Equal(t, "infra", li.Config.Metadata["team"])
+
+	li, err = md.launchInputForLaunch("", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "1", li.Config.Env["DEBUG"])
+}
+
+func Test_setConfigOverride_toml(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{AppName: "my-cool-app"})
+	require.NoError(t, err)
+
+	path := writeOverride(t, "override.toml", "[env]\nDEBUG = \"1\"\n")
+	require.NoError(t, md.setConfigOverride(path))
+
+	li, err := md.launchInputForLaunch("", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "1", li.Config.Env["DEBUG"])
+}
+
+func Test_setConfigOverride_invalid(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{AppName: "my-cool-app"})
+	require.NoError(t, err)
+
+	assert.Error(t, md.setConfigOverride(writeOverride(t, "unknown.json", `{"not_a_field": true}`)))
+	assert.Error(t, md.setConfigOverride(writeOverride(t, "badtype.json", `{"env": ["DEBUG=1"]}`)))
+	assert.Error(t, md.setConfigOverride(writeOverride(t, "broken.toml", `[env`)))
+	assert.Error(t, md.setConfigOverride(filepath.Join(t.TempDir(), "missing.json")))
+}
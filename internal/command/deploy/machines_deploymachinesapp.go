@@ -1,31 +1,83 @@
 package deploy
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/samber/lo"
 	"github.com/superfly/flyctl/api"
 	"github.com/superfly/flyctl/flaps"
 	machcmd "github.com/superfly/flyctl/internal/command/machine"
 	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/internal/prompt"
 	"github.com/superfly/flyctl/terminal"
 	"golang.org/x/exp/slices"
 )
 
+// maxConcurrentDestroys bounds the machines destroyed at once when removing process groups
+const maxConcurrentDestroys = 8
+
 type ProcessGroupsDiff struct {
 	machinesToRemove      []machine.LeasableMachine
 	groupsToRemove        map[string]int
 	groupsNeedingMachines map[string]bool
+	// regionsNeedingMachines lists, by group, the declared regions the group has no machine in
+	regionsNeedingMachines map[string][]string
 }
 
 func (md *machineDeployment) DeployMachinesApp(ctx context.Context) error {
 	ctx = flaps.NewContext(ctx, md.flapsClient)
 
+	// Restarts don't apply the app config, there's nothing to validate
+	if !md.restartOnly {
+		if err := md.validatePlatformConstraints(ctx); err != nil {
+			return err
+		}
+	}
+	if md.validateOnly {
+		fmt.Fprintf(md.io.ErrOut, "%s The app config of %s validates against the platform\n", md.colorize.SuccessIcon(), md.app.Name)
+		return nil
+	}
+
+	// --deploy-timeout bounds the whole deploy, waits for the locks included. The original context
+	// is kept around to record the final status after the deploy timeout expired.
+	statusCtx := ctx
+	if md.deployTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, md.deployTimeout)
+		defer cancel()
+	}
+
+	unlockLocal, lockErr := lockLocalDeploy(ctx, md.app.Name)
+	if lockErr != nil {
+		return lockErr
+	}
+	defer unlockLocal()
+
+	if err := md.acquireLeasesOrClearStale(ctx, func() error { return md.acquireDeployLock(ctx) }); err != nil {
+		return err
+	}
+	defer md.releaseDeployLock(statusCtx)
+
 	if err := md.updateReleaseInBackend(ctx, "running"); err != nil {
 		return fmt.Errorf("failed to set release status to 'running': %w", err)
 	}
+	md.notifyWebhook(ctx, webhookPayload{Event: webhookEventReleaseStarted})
+	md.progress.start(md.releaseId, md.releaseVersion)
+	stopWatching := md.watchForCancellation(ctx)
+	defer stopWatching()
+	stopSkipRequests := md.watchForSkipRequests()
+	defer stopSkipRequests()
+
+	ctx = md.withoutPublicChecks(ctx)
 
 	var err error
 	if md.restartOnly {
@@ -33,64 +85,140 @@ func (md *machineDeployment) DeployMachinesApp(ctx context.Context) error {
 	} else {
 		err = md.deployMachinesApp(ctx)
 	}
+	if err == nil {
+		err = md.verifyDeployment(ctx)
+	}
+	if err == nil && !md.restartOnly {
+		md.cleanupKeptMachines(ctx)
+	}
+	md.printMachineSummary(statusCtx)
 	status := "complete"
 	if err != nil {
 		status = "failed"
 	}
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("deploy timeout of %s reached, aborting deployment: %w", md.deployTimeout, err)
+	}
 
-	if updateErr := md.updateReleaseInBackend(ctx, status); updateErr != nil {
+	if updateErr := md.updateReleaseInBackend(statusCtx, status); updateErr != nil {
 		if err == nil {
 			err = fmt.Errorf("failed to set final release status: %w", updateErr)
 		} else {
 			terminal.Warnf("failed to set final release status after deployment failure: %v\n", updateErr)
 		}
 	}
+
+	event := webhookPayload{Event: webhookEventDeployComplete}
+	if err != nil {
+		event = webhookPayload{Event: webhookEventDeployFailed, Error: err.Error()}
+	}
+	md.notifyWebhook(statusCtx, event)
+	md.progress.finish(err)
+	if err == nil && md.quiet {
+		if md.noRelease {
+			fmt.Fprintf(md.alertOut, "Updated the machines of %s without a release\n", md.app.Name)
+		} else {
+			fmt.Fprintf(md.alertOut, "Deployed release v%d of %s\n", md.releaseVersion, md.app.Name)
+		}
+	}
 	return err
 }
 
 // restartMachinesApp only restarts existing machines but updates their release metadata
 func (md *machineDeployment) restartMachinesApp(ctx context.Context) error {
-	if err := md.machineSet.AcquireLeases(ctx, md.leaseTimeout); err != nil {
+	if err := md.hooks.beforeAcquireLeases(ctx); err != nil {
 		return err
 	}
-	defer md.machineSet.ReleaseLeases(ctx) // skipcq: GO-S2307
-	md.machineSet.StartBackgroundLeaseRefresh(ctx, md.leaseTimeout, md.leaseDelayBetween)
+	leased := md.leasedMachines()
+	if err := md.acquireLeasesOrClearStale(ctx, func() error { return leased.AcquireLeases(ctx, md.leaseTimeout) }); err != nil {
+		return err
+	}
+	defer leased.ReleaseLeases(ctx) // skipcq: GO-S2307
+	leased.StartBackgroundLeaseRefresh(ctx, md.leaseTimeout, md.leaseDelayBetween)
 
-	machineUpdateEntries := lo.Map(md.machineSet.GetMachines(), func(lm machine.LeasableMachine, _ int) *machineUpdateEntry {
-		return &machineUpdateEntry{leasableMachine: lm, launchInput: md.launchInputForRestart(lm.Machine())}
-	})
+	var machineUpdateEntries []*machineUpdateEntry
+	for _, lm := range md.unpinnedMachines() {
+		machineUpdateEntries = append(machineUpdateEntries, &machineUpdateEntry{leasableMachine: lm, launchInput: md.launchInputForRestart(lm.Machine())})
+	}
 
 	return md.updateExistingMachines(ctx, machineUpdateEntries)
 }
 
 // deployMachinesApp executes the following flow:
 //   - Run release command
+//   - Wait for [deploy.release_ready], if set
 //   - Remove spare machines from removed groups
 //   - Launch new machines on new groups
+//   - Start the maintenance page, if requested
 //   - Update existing machines
 func (md *machineDeployment) deployMachinesApp(ctx context.Context) error {
-	if err := md.runReleaseCommand(ctx); err != nil {
-		return fmt.Errorf("release command failed - aborting deployment. %w", err)
+	defer md.reportRegionThrottling()
+	if err := md.checkImagePlatform(); err != nil {
+		return err
 	}
 
-	if err := md.machineSet.AcquireLeases(ctx, md.leaseTimeout); err != nil {
+	releaseCommands := md.appConfig.ReleaseCommands()
+	if len(releaseCommands) > 0 {
+		md.progress.setPhase(progressPhaseReleaseCommand)
+	}
+	releaseStarted := time.Now()
+	err := md.runReleaseCommands(ctx)
+	if len(releaseCommands) > 0 {
+		md.timings.ReleaseCommand = time.Since(releaseStarted)
+	}
+	if err != nil {
+		var releaseErr *ReleaseCommandError
+		if !errors.As(err, &releaseErr) {
+			releaseErr = &ReleaseCommandError{ExitCode: -1, err: err}
+		}
+		return releaseErr
+	}
+	if len(releaseCommands) > 0 {
+		md.notifyWebhook(ctx, webhookPayload{Event: webhookEventReleaseCommandFinished})
+	}
+	if err := md.waitForReleaseReady(ctx); err != nil {
+		return err
+	}
+	if err := md.hooks.afterReleaseCommand(ctx); err != nil {
+		return err
+	}
+
+	if err := md.hooks.beforeAcquireLeases(ctx); err != nil {
+		return err
+	}
+	leased := md.leasedMachines()
+	if err := md.acquireLeasesOrClearStale(ctx, func() error { return leased.AcquireLeases(ctx, md.leaseTimeout) }); err != nil {
+		return err
+	}
+	defer leased.ReleaseLeases(ctx) // skipcq: GO-S2307
+	leased.StartBackgroundLeaseRefresh(ctx, md.leaseTimeout, md.leaseDelayBetween)
+
+	md.uncordonAfterMaintenance(ctx)
+	if err := md.loadMachineNames(ctx); err != nil {
 		return err
 	}
-	defer md.machineSet.ReleaseLeases(ctx) // skipcq: GO-S2307
-	md.machineSet.StartBackgroundLeaseRefresh(ctx, md.leaseTimeout, md.leaseDelayBetween)
 
 	processGroupMachineDiff := md.resolveProcessGroupChanges()
 	md.warnAboutProcessGroupChanges(ctx, processGroupMachineDiff)
+	md.expected = md.expectedTopology(processGroupMachineDiff)
+	md.printRegionChanges(processGroupMachineDiff)
 
 	if len(processGroupMachineDiff.machinesToRemove) > 0 {
+		if err := md.confirmDestroy(ctx, len(processGroupMachineDiff.machinesToRemove)); err != nil {
+			return err
+		}
 		// Destroy machines that don't fit the current process groups
 		if err := md.machineSet.RemoveMachines(ctx, processGroupMachineDiff.machinesToRemove); err != nil {
 			return err
 		}
-		for _, mach := range processGroupMachineDiff.machinesToRemove {
-			if err := machcmd.Destroy(ctx, md.app, mach.Machine(), true); err != nil {
-				return err
-			}
+		if err := md.destroyMachines(ctx, processGroupMachineDiff.machinesToRemove); err != nil {
+			return err
+		}
+	}
+
+	if md.autoCreateVolumes {
+		if err := md.createMissingVolumes(ctx, processGroupMachineDiff); err != nil {
+			return err
 		}
 	}
 
@@ -98,7 +226,10 @@ func (md *machineDeployment) deployMachinesApp(ctx context.Context) error {
 	if len(processGroupMachineDiff.groupsNeedingMachines) > 0 {
 		i := 0
 		for name := range processGroupMachineDiff.groupsNeedingMachines {
-			if err := md.spawnMachineInGroup(ctx, name, i, len(processGroupMachineDiff.groupsNeedingMachines)); err != nil {
+			if err := md.checkCanceled(); err != nil {
+				return err
+			}
+			if err := md.spawnMachineInGroup(ctx, name, "", i, len(processGroupMachineDiff.groupsNeedingMachines)); err != nil {
 				return err
 			}
 			i++
@@ -106,24 +237,167 @@ func (md *machineDeployment) deployMachinesApp(ctx context.Context) error {
 		fmt.Fprintf(md.io.ErrOut, "Finished launching new machines\n")
 	}
 
+	// Create machines in the regions declared in fly.toml that groups aren't running in yet
+	if total := countRegionsNeedingMachines(processGroupMachineDiff.regionsNeedingMachines); total > 0 {
+		i := 0
+		for name, regions := range processGroupMachineDiff.regionsNeedingMachines {
+			for _, region := range regions {
+				if err := md.checkCanceled(); err != nil {
+					return err
+				}
+				if err := md.spawnMachineInGroup(ctx, name, region, i, total); err != nil {
+					return err
+				}
+				i++
+			}
+		}
+		fmt.Fprintf(md.io.ErrOut, "Finished launching machines in new regions\n")
+	}
+
 	var machineUpdateEntries []*machineUpdateEntry
-	for _, lm := range md.machineSet.GetMachines() {
+	for _, lm := range md.unpinnedMachines() {
 		li, err := md.launchInputForUpdate(lm.Machine())
 		if err != nil {
 			return fmt.Errorf("failed to update machine configuration for %s: %w", lm.FormattedMachineId(), err)
 		}
-		machineUpdateEntries = append(machineUpdateEntries, &machineUpdateEntry{leasableMachine: lm, launchInput: li})
+		upToDate := md.onlyChanged && li.ID == lm.Machine().ID && sameMachineConfig(lm.Machine().Config, li.Config)
+		machineUpdateEntries = append(machineUpdateEntries, &machineUpdateEntry{
+			leasableMachine: lm,
+			launchInput:     li,
+			upToDate:        upToDate,
+			enablesAutostop: enablesAutostop(lm.Machine().Config, li.Config),
+		})
+	}
+
+	if err := md.loadKeptMachines(ctx); err != nil {
+		return err
+	}
+	if md.zeroDowntime {
+		var err error
+		if machineUpdateEntries, err = md.replaceSingleMachineGroups(ctx, machineUpdateEntries); err != nil {
+			return err
+		}
+	}
+
+	if md.maintenanceHTML != "" {
+		stopMaintenance, err := md.startMaintenancePage(ctx)
+		if err != nil {
+			return err
+		}
+		defer stopMaintenance()
 	}
 
 	return md.updateExistingMachines(ctx, machineUpdateEntries)
 }
 
+// sameMachineConfig compares two machine configs ignoring the release metadata,
+// which changes on every deploy even if nothing else does
+func sameMachineConfig(current, desired *api.MachineConfig) bool {
+	normalize := func(c *api.MachineConfig) []byte {
+		c = machine.CloneConfig(c)
+		if c == nil {
+			return nil
+		}
+		delete(c.Metadata, api.MachineConfigMetadataKeyFlyReleaseId)
+		delete(c.Metadata, api.MachineConfigMetadataKeyFlyReleaseVersion)
+		b, _ := json.Marshal(c)
+		return b
+	}
+	return bytes.Equal(normalize(current), normalize(desired))
+}
+
+// destroyMachines destroys the machines using a bounded pool of workers, so removing
+// large groups is fast, and reports every failure instead of stopping at the first one.
+// No more than --region-concurrency machines of a region are destroyed at once.
+func (md *machineDeployment) destroyMachines(ctx context.Context, machines []machine.LeasableMachine) error {
+	var (
+		wg      sync.WaitGroup
+		workers = make(chan struct{}, maxConcurrentDestroys)
+		results = make(chan error, len(machines))
+	)
+	for _, lm := range machines {
+		wg.Add(1)
+		go func(lm machine.LeasableMachine) {
+			defer wg.Done()
+			// Wait for the region first, not to hold a worker other regions could use
+			release, err := md.regions.acquire(ctx, lm.Machine().Region)
+			if err != nil {
+				results <- err
+				return
+			}
+			defer release()
+			workers <- struct{}{}
+			defer func() { <-workers }()
+			md.drainMachine(ctx, lm)
+			results <- machcmd.Destroy(ctx, md.app, lm.Machine(), true)
+		}(lm)
+	}
+	wg.Wait()
+	close(results)
+
+	var errs []error
+	for err := range results {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	fmt.Fprintf(md.io.ErrOut, "Destroyed %d of %d machines", len(machines)-len(errs), len(machines))
+	if len(errs) > 0 {
+		fmt.Fprintf(md.io.ErrOut, ", %s", md.colorize.Red(fmt.Sprintf("%d failed", len(errs))))
+	}
+	fmt.Fprintln(md.io.ErrOut)
+	return errors.Join(errs...)
+}
+
+// unpinnedMachines returns the machines to update, reporting the ones pinned
+// with the fly_deploy_pinned=true metadata as intentionally skipped
+func (md *machineDeployment) unpinnedMachines() []machine.LeasableMachine {
+	var machines []machine.LeasableMachine
+	for _, lm := range md.machineSet.GetMachines() {
+		if lm.Machine().IsDeployPinned() {
+			fmt.Fprintf(md.io.ErrOut, "Skipping machine %s because it is pinned with %s=true\n",
+				md.colorize.Bold(lm.FormattedMachineId()), api.MachineConfigMetadataKeyFlyDeployPinned)
+			md.recordMachine(lm, machineOutcomePinned)
+			continue
+		}
+		machines = append(machines, lm)
+	}
+	return machines
+}
+
 type machineUpdateEntry struct {
 	leasableMachine machine.LeasableMachine
 	launchInput     *api.LaunchMachineInput
+	// upToDate is set when the machine already runs launchInput's config
+	upToDate bool
+	// enablesAutostop is set when launchInput turns autostop on for a service of the machine
+	enablesAutostop bool
+	// err is the error the immediate strategy went on after while updating the machine
+	err error
+}
+
+// sortUpdateEntries moves the machines in the primary region to the front or the back of
+// the update, keeping the original order otherwise. Updating the primary region last limits
+// the blast radius of a bad release on the region serving most of the traffic.
+func sortUpdateEntries(entries []*machineUpdateEntry, primaryRegion, order string) {
+	if primaryRegion == "" {
+		return
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		iPrimary := entries[i].launchInput.Region == primaryRegion
+		jPrimary := entries[j].launchInput.Region == primaryRegion
+		if order == updateOrderPrimaryFirst {
+			return iPrimary && !jPrimary
+		}
+		return !iPrimary && jPrimary
+	})
 }
 
 func formatIndex(n, total int) string {
+	// Nothing to count, avoid printing a bogus [1/0]
+	if total <= 0 {
+		return "[0/0]"
+	}
 	pad := 0
 	for i := total; i != 0; i /= 10 {
 		pad++
@@ -131,134 +405,508 @@ func formatIndex(n, total int) string {
 	return fmt.Sprintf("[%0*d/%d]", pad, n+1, total)
 }
 
-func (md *machineDeployment) updateExistingMachines(ctx context.Context, updateEntries []*machineUpdateEntry) error {
-	// FIXME: handle deploy strategy: rolling, immediate, canary, bluegreen
-	fmt.Fprintf(md.io.Out, "Updating existing machines in '%s' with %s strategy\n", md.colorize.Bold(md.app.Name), md.strategy)
-	for i, e := range updateEntries {
-		lm := e.leasableMachine
+func (md *machineDeployment) updateExistingMachines(ctx context.Context, updateEntries []*machineUpdateEntry) (err error) {
+	defer md.timeMachineUpdates()()
+	completed := 0
+	defer func() {
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("%d of %d machines were updated: %w", completed, len(updateEntries), err)
+		}
+		// Errors aborting the immediate strategy list the skipped errors already
+		var immediateErr *ImmediateStrategyError
+		if err != nil && !errors.As(err, &immediateErr) {
+			md.printSkippedErrors(updateEntries)
+		}
+	}()
+
+	// mu guards the state shared by the machines of a batch updated at once
+	var mu sync.Mutex
+	var unhealthy []*HealthCheckTimeoutError
+	// The immediate strategy goes on after machine errors, up to --immediate-max-errors of them
+	var immediateErrs []error
+	continueAfterError := func(e *machineUpdateEntry, lm machine.LeasableMachine, err error) error {
+		mu.Lock()
+		defer mu.Unlock()
+		md.recordMachine(lm, machineOutcomeFailed)
+		e.err = err
+		err = fmt.Errorf("machine %s: %w", lm.Machine().ID, err)
+		immediateErrs = append(immediateErrs, err)
+		if md.immediateMaxErrors > 0 && len(immediateErrs) >= md.immediateMaxErrors {
+			return &ImmediateStrategyError{Errors: immediateErrs}
+		}
+		md.warnf("Continuing after error: %s\n", err)
+		return nil
+	}
+	pendingByGroup := map[string]int{}
+	for _, e := range updateEntries {
+		pendingByGroup[e.launchInput.Config.ProcessGroup()]++
+	}
+	for group, count := range pendingByGroup {
+		md.progress.addMachines(group, count)
+	}
+	md.progress.setPhase(progressPhaseUpdating)
+	markCompleted := func(e *machineUpdateEntry, lm machine.LeasableMachine, outcome string) error {
+		mu.Lock()
+		md.recordMachine(lm, outcome)
+		completed++
+		group := e.launchInput.Config.ProcessGroup()
+		md.progress.machineDone(group)
+		pendingByGroup[group]--
+		groupDone, completedNow := pendingByGroup[group] == 0, completed
+		mu.Unlock()
+		// The webhook can take a while to answer, the other machines of the batch don't wait on it
+		if groupDone {
+			md.notifyWebhook(ctx, webhookPayload{
+				Event:     webhookEventGroupCompleted,
+				Group:     group,
+				Machines:  len(updateEntries),
+				Completed: completedNow,
+			})
+		}
+		return md.hooks.afterMachineUpdate(ctx, lm.Machine())
+	}
+	// firstToValidate tells whether the health check endpoints are still to be validated, on the
+	// first machine actually updated. Machines up to date, jobs and ones skipping health checks don't count.
+	validated := false
+	firstToValidate := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		first := !validated
+		validated = true
+		return first
+	}
+	// tolerateUnhealthy counts healthErr in when --min-healthy allows one more unhealthy machine
+	tolerateUnhealthy := func(healthErr *HealthCheckTimeoutError) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(unhealthy) >= md.allowedUnhealthy(len(updateEntries)) {
+			return false
+		}
+		unhealthy = append(unhealthy, healthErr)
+		return true
+	}
+
+	sortUpdateEntries(updateEntries, md.appConfig.PrimaryRegion, md.updateOrder)
+	batches, err := md.updateBatches(updateEntries)
+	if err != nil {
+		return err
+	}
+
+	// updateMachine updates the machine of the entry at index i. It returns the machine it worked
+	// on last, the one replacing it if any, to show as failed on errors. Machines updated along with
+	// others get no progress lines overwritten in place, nor manual skips of their health checks.
+	updateMachine := func(ctx context.Context, i int, e *machineUpdateEntry, concurrent bool) (lm machine.LeasableMachine, err error) {
+		if concurrent {
+			ctx = machine.WithoutProgress(ctx)
+		}
+		clearProgress := func() {
+			if !concurrent {
+				md.logClearLinesAbove(1)
+			}
+		}
+		lm = e.leasableMachine
 		launchInput := e.launchInput
 		indexStr := formatIndex(i, len(updateEntries))
+		waitTimeout := md.waitTimeoutFor(e)
+		md.progress.startGroup(launchInput.Config.ProcessGroup())
+
+		if e.upToDate {
+			fmt.Fprintf(md.io.ErrOut, "  %s Machine %s is already up to date\n", indexStr, md.colorize.Bold(lm.FormattedMachineId()))
+			// It may come from a failed deploy, be sure it is healthy before moving on
+			if md.strategy != "immediate" && !md.skipHealthChecks && lm.Machine().State == api.MachineStateStarted &&
+				!runsToCompletion(lm.Machine().Config) && !lm.Machine().SkipsHealthChecks() {
+				if err := lm.WaitForConsecutiveHealthchecksToPass(ctx, md.waitTimeout, md.healthyPollsRequired, indexStr); err != nil {
+					return lm, healthCheckError(lm.Machine().ID, err)
+				}
+			}
+			if err := markCompleted(e, lm, machineOutcomeUpToDate); err != nil {
+				return lm, err
+			}
+			return lm, nil
+		}
+
+		// Interactive sessions show a line per step, overwritten by the progress of the waits.
+		// Non-interactive ones, like CI, only get a single line per machine with its outcome.
+		interactive := md.io.IsInteractive() && !concurrent
+		if err := md.hooks.beforeMachineUpdate(ctx, lm.Machine()); err != nil {
+			return lm, err
+		}
+		// Enabling autostop goes one machine at a time, with another one of the group up meanwhile
+		if e.enablesAutostop && md.strategy != "immediate" {
+			if err := md.keepGroupMachineUp(ctx, e, updateEntries, indexStr); err != nil {
+				return lm, err
+			}
+		}
 
-		if launchInput.ID != lm.Machine().ID {
+		// Batch jobs are done once they exit after the update, not with an exit from before
+		exitedSince := latestEventTimestamp(lm.Machine())
+		var summary string
+		// Deploys of a release again start its machines kept for rollback in place of these
+		mu.Lock()
+		kept := md.takeKeptMachine(launchInput)
+		mu.Unlock()
+		if kept != nil || launchInput.ID != lm.Machine().ID {
 			// If IDs don't match, destroy the original machine and launch a new one
 			// This can be the case for machines that changes its volumes or any other immutable config
-			fmt.Fprintf(md.io.ErrOut, "  %s Replacing %s by new machine\n", indexStr, md.colorize.Bold(lm.FormattedMachineId()))
-			if err := lm.Destroy(ctx, true); err != nil {
-				if md.strategy != "immediate" {
-					return err
+			if interactive {
+				fmt.Fprintf(md.io.ErrOut, "  %s Replacing %s by new machine\n", indexStr, md.colorize.Bold(lm.FormattedMachineId()))
+			}
+			if !md.keepForRollback(ctx, lm) {
+				md.drainMachine(ctx, lm)
+				err := md.inRegion(ctx, lm.Machine().Region, func() error { return lm.Destroy(ctx, true) })
+				if err != nil {
+					if md.strategy != "immediate" {
+						return lm, err
+					}
+					if err := continueAfterError(e, lm, err); err != nil {
+						return lm, err
+					}
 				}
-				fmt.Fprintf(md.io.ErrOut, "Continuing after error: %s\n", err)
 			}
 
-			newMachineRaw, err := md.flapsClient.Launch(ctx, *launchInput)
+			newMachineRaw, err := md.launchOrReuse(ctx, *launchInput, kept)
 			if err != nil {
 				if md.strategy != "immediate" {
-					return err
+					return lm, &MachineLaunchError{Group: launchInput.Config.ProcessGroup(), Region: launchInput.Region, err: err}
 				}
-				fmt.Fprintf(md.io.ErrOut, "Continuing after error: %s\n", err)
-				continue
+				if err := continueAfterError(e, lm, err); err != nil {
+					return lm, err
+				}
+				return lm, nil
 			}
 
+			oldMachineID := lm.FormattedMachineId()
 			lm = machine.NewLeasableMachine(md.flapsClient, md.io, newMachineRaw)
-			fmt.Fprintf(md.io.ErrOut, "  %s Created machine %s\n", indexStr, md.colorize.Bold(lm.FormattedMachineId()))
+			exitedSince = 0
+			if interactive {
+				fmt.Fprintf(md.io.ErrOut, "  %s Created machine %s\n", indexStr, md.colorize.Bold(lm.FormattedMachineId()))
+			}
+			summary = fmt.Sprintf("Machine %s replaced by %s", md.colorize.Bold(oldMachineID), md.colorize.Bold(lm.FormattedMachineId()))
 
 		} else {
-			fmt.Fprintf(md.io.ErrOut, "  %s Updating %s\n", indexStr, md.colorize.Bold(lm.FormattedMachineId()))
-			if err := lm.Update(ctx, *launchInput); err != nil {
+			if interactive {
+				fmt.Fprintf(md.io.ErrOut, "  %s Updating %s\n", indexStr, md.colorize.Bold(lm.FormattedMachineId()))
+			}
+			priorRelease := releaseMetadataOf(lm.Machine())
+			wasSuspended := lm.Machine().State == api.MachineStateSuspended
+			err := md.inRegion(ctx, lm.Machine().Region, func() error { return lm.Update(ctx, *launchInput) })
+			if err != nil {
 				if md.strategy != "immediate" {
-					return err
+					return lm, err
+				}
+				md.restoreReleaseMetadata(ctx, lm.Machine().ID, priorRelease)
+				if err := continueAfterError(e, lm, err); err != nil {
+					return lm, err
+				}
+				fmt.Fprintf(md.io.ErrOut, "  %s Machine %s failed to update\n", indexStr, md.colorize.Bold(lm.FormattedMachineId()))
+				return lm, nil
+			}
+			summary = fmt.Sprintf("Machine %s updated", md.colorize.Bold(lm.FormattedMachineId()))
+			// Suspended machines are resumed to run the new config. The state the update returns may be
+			// a transitional one, it doesn't tell whether the machine is left suspended.
+			if wasSuspended {
+				if err := lm.Resume(ctx); err != nil {
+					if md.strategy != "immediate" {
+						return lm, fmt.Errorf("failed to resume suspended machine %s: %w", lm.Machine().ID, err)
+					}
+					if err := continueAfterError(e, lm, err); err != nil {
+						return lm, err
+					}
+					fmt.Fprintf(md.io.ErrOut, "  %s Machine %s updated but failed to resume\n", indexStr, md.colorize.Bold(lm.FormattedMachineId()))
+					return lm, nil
 				}
-				fmt.Fprintf(md.io.ErrOut, "Continuing after error: %s\n", err)
+			}
+			if wasSuspended {
+				summary = fmt.Sprintf("Machine %s resumed and updated", md.colorize.Bold(lm.FormattedMachineId()))
 			}
 		}
 
 		if md.strategy == "immediate" {
-			continue
+			if !interactive {
+				fmt.Fprintf(md.io.ErrOut, "  %s %s\n", indexStr, summary)
+			}
+			if err := markCompleted(e, lm, machineOutcomeUpdated); err != nil {
+				return lm, err
+			}
+			return lm, nil
 		}
 
-		if err := lm.WaitForState(ctx, api.MachineStateStarted, md.waitTimeout, indexStr); err != nil {
-			return err
+		switch {
+		case runsOnSchedule(launchInput.Config):
+			// Its next run may be hours away, there's nothing to wait for
+		case runsToCompletion(launchInput.Config):
+			if err := lm.WaitForExit(ctx, exitedSince, waitTimeout, indexStr); err != nil {
+				return lm, err
+			}
+		default:
+			if err := lm.WaitForState(ctx, api.MachineStateStarted, waitTimeout, indexStr); err != nil {
+				return lm, err
+			}
 		}
 
-		if !md.skipHealthChecks {
-			if err := lm.WaitForHealthchecksToPass(ctx, md.waitTimeout, indexStr); err != nil {
-				return err
+		if !md.skipHealthChecks && !runsToCompletion(launchInput.Config) && lm.Machine().SkipsHealthChecks() {
+			clearProgress()
+			fmt.Fprintf(md.io.ErrOut, "  %s %s: health checks skipped by label %s=true\n",
+				indexStr, summary, api.MachineConfigMetadataKeyFlySkipHealthChecks)
+			if err := markCompleted(e, lm, machineOutcomeHealthSkipped); err != nil {
+				return lm, err
+			}
+			return lm, nil
+		}
+		if !md.skipHealthChecks && !runsToCompletion(launchInput.Config) {
+			if md.validateHealthChecks && firstToValidate() {
+				if err := md.validateHealthCheckEndpoints(ctx, lm); err != nil {
+					return lm, err
+				}
 			}
-			// FIXME: combine this wait with the wait for start as one update line (or two per in noninteractive case)
-			md.logClearLinesAbove(1)
+			waitHealthy := func(ctx context.Context) error {
+				// Groups with a [[deploy.readiness_commands]] are ready once it exits 0, whatever their health checks
+				if rc := md.appConfig.ReadinessCommand(launchInput.Config.ProcessGroup()); rc != nil {
+					return md.waitForReadinessCommand(ctx, lm, rc, waitTimeout, indexStr)
+				}
+				return lm.WaitForConsecutiveHealthchecksToPass(ctx, waitTimeout, md.healthyPollsRequired, indexStr)
+			}
+			// Keys can only skip the wait of a single machine
+			var skipped bool
+			if concurrent {
+				err = waitHealthy(ctx)
+			} else {
+				skipped, err = md.skippableWait(ctx, waitHealthy)
+			}
+			if skipped {
+				clearProgress()
+				md.warnf("  %s %s: health checks skipped manually\n", indexStr, summary)
+				if err := markCompleted(e, lm, machineOutcomeHealthSkipped); err != nil {
+					return lm, err
+				}
+				return lm, nil
+			}
+			if err != nil {
+				err = healthCheckError(lm.Machine().ID, err)
+				var healthErr *HealthCheckTimeoutError
+				if !errors.As(err, &healthErr) || !tolerateUnhealthy(healthErr) {
+					return lm, err
+				}
+				md.warnf("  %s Machine %s is %s, continuing within the --min-healthy tolerance\n",
+					indexStr, md.colorize.Bold(lm.FormattedMachineId()), md.colorize.Red("unhealthy"))
+				if err := markCompleted(e, lm, machineOutcomeUnhealthy); err != nil {
+					return lm, err
+				}
+				return lm, nil
+			}
+		}
+		clearProgress()
+		if interactive {
 			fmt.Fprintf(md.io.ErrOut, "  %s Machine %s update finished: %s\n",
 				indexStr,
 				md.colorize.Bold(lm.FormattedMachineId()),
 				md.colorize.Green("success"),
 			)
+		} else {
+			fmt.Fprintf(md.io.ErrOut, "  %s %s: %s\n", indexStr, summary, md.colorize.Green("success"))
 		}
+		if err := markCompleted(e, lm, machineOutcomeUpdated); err != nil {
+			return lm, err
+		}
+		return lm, nil
 	}
 
+	// FIXME: handle deploy strategy: rolling, immediate, canary, bluegreen
+	fmt.Fprintf(md.io.ErrOut, "Updating existing machines in '%s' with %s strategy\n", md.colorize.Bold(md.app.Name), md.strategy)
+	updatedBefore := false
+	for _, batch := range batches {
+		if err := md.checkCanceled(); err != nil {
+			return fmt.Errorf("%d of %d machines were updated: %w", completed, len(updateEntries), err)
+		}
+		// --batch-delay paces the batches updating machines, the ones already up to date go on their own
+		updates := !updateEntries[batch[0]].upToDate
+		if updates && updatedBefore {
+			if err := md.waitBatchDelay(ctx); err != nil {
+				return fmt.Errorf("%d of %d machines were updated: %w", completed, len(updateEntries), err)
+			}
+		}
+		updatedBefore = updatedBefore || updates
+		concurrent := len(batch) > 1
+		if concurrent {
+			fmt.Fprintf(md.io.ErrOut, "  Updating %d machines of group '%s' at once\n",
+				len(batch), updateEntries[batch[0]].launchInput.Config.ProcessGroup())
+		}
+
+		errs := make([]error, len(batch))
+		update := func(j int) {
+			i := batch[j]
+			lm, err := updateMachine(ctx, i, updateEntries[i], concurrent)
+			if err != nil {
+				mu.Lock()
+				md.recordMachine(lm, machineOutcomeFailed)
+				mu.Unlock()
+			}
+			errs[j] = err
+		}
+		if concurrent {
+			restoreOutput := md.serializeOutput()
+			var wg sync.WaitGroup
+			for j := range batch {
+				wg.Add(1)
+				go func(j int) {
+					defer wg.Done()
+					update(j)
+				}(j)
+			}
+			wg.Wait()
+			restoreOutput()
+		} else {
+			update(0)
+		}
+		// The other machines of the batch are done updating, the first error aborts the deploy
+		for _, err := range errs {
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(unhealthy) > 0 {
+		terminal.Warnf("%d of %d machines didn't pass health checks and need follow-up:\n", len(unhealthy), len(updateEntries))
+		for _, healthErr := range unhealthy {
+			md.warnf("  * %s: %s\n", healthErr.MachineID, healthErr)
+		}
+	}
+	md.printSkippedErrors(updateEntries)
 	fmt.Fprintf(md.io.ErrOut, "  Finished deploying\n")
 	return nil
 }
 
-func (md *machineDeployment) spawnMachineInGroup(ctx context.Context, groupName string, i, total int) error {
+// printSkippedErrors reports in one place the machine errors the immediate strategy went on after,
+// instead of leaving them scattered in the output of the update
+func (md *machineDeployment) printSkippedErrors(entries []*machineUpdateEntry) {
+	failed := lo.Filter(entries, func(e *machineUpdateEntry, _ int) bool { return e.err != nil })
+	if len(failed) == 0 {
+		return
+	}
+	terminal.Warnf("%d machine errors were skipped by the immediate strategy:\n", len(failed))
+	for _, e := range failed {
+		m := e.leasableMachine.Machine()
+		md.warnf("  * %s (group %s, region %s): %s\n", m.ID, m.ProcessGroup(), m.Region, e.err)
+	}
+}
+
+// waitTimeoutFor returns how long to wait for the machine of e to start and pass its health checks.
+// Replacement machines are new and get the longer new machine timeout.
+func (md *machineDeployment) waitTimeoutFor(e *machineUpdateEntry) time.Duration {
+	if e.launchInput.ID != e.leasableMachine.Machine().ID {
+		return md.newMachineWaitTimeout
+	}
+	return md.waitTimeout
+}
+
+// allowedUnhealthy returns how many of total updated machines may fail their health checks
+// while staying above the --min-healthy ratio
+func (md *machineDeployment) allowedUnhealthy(total int) int {
+	return int(math.Floor(float64(total) * md.maxUnhealthyRatio))
+}
+
+// spawnMachineInGroup launches a machine for groupName in region, or in the primary region when empty
+func (md *machineDeployment) spawnMachineInGroup(ctx context.Context, groupName, region string, i, total int) error {
 	if groupName == "" {
 		// If the group is unspecified, it should have been translated to "app" by this point
 		panic("spawnMachineInGroup requires a non-empty group name. this is a bug!")
 	}
-	fmt.Fprintf(md.io.Out, "No machines in group '%s', launching one new machine\n", md.colorize.Bold(groupName))
-	launchInput, err := md.launchInputForLaunch(groupName, md.machineGuest)
+	if md.disabledGroups[groupName] {
+		return fmt.Errorf("BUG: can't launch a machine in process group %s, [[deploy.conditional_groups]] disables it", groupName)
+	}
+	if region == "" {
+		fmt.Fprintf(md.io.ErrOut, "No machines in group '%s', launching one new machine\n", md.colorize.Bold(groupName))
+	} else {
+		fmt.Fprintf(md.io.ErrOut, "No machines in group '%s' in region '%s', launching one new machine\n", md.colorize.Bold(groupName), region)
+	}
+	md.progress.setPhase(progressPhaseLaunching)
+	md.progress.addMachines(groupName, 1)
+	md.progress.startGroup(groupName)
+	launchInput, err := md.launchInputForLaunch(groupName, region, md.machineGuest)
 	if err != nil {
 		return fmt.Errorf("error creating machine configuration: %w", err)
 	}
 
-	newMachineRaw, err := md.flapsClient.Launch(ctx, *launchInput)
+	newMachineRaw, err := md.launchWithFallback(ctx, launchInput)
 	if err != nil {
 		relCmdWarning := ""
-		if strings.Contains(err.Error(), "please add a payment method") && !md.releaseCommandMachine.IsEmpty() {
+		if strings.Contains(err.Error(), "please add a payment method") && len(md.appConfig.ReleaseCommands()) > 0 {
 			relCmdWarning = "\nPlease note that release commands run in their own ephemeral machine, and therefore count towards the machine limit."
 		}
-		return fmt.Errorf("error creating a new machine: %w%s", err, relCmdWarning)
+		return &MachineLaunchError{
+			Group:  groupName,
+			Region: launchInput.Region,
+			err:    fmt.Errorf("error creating a new machine: %w%s", err, relCmdWarning),
+		}
 	}
 
 	newMachine := machine.NewLeasableMachine(md.flapsClient, md.io, newMachineRaw)
+	md.recordMachine(newMachine, machineOutcomeLaunched)
 
 	indexStr := formatIndex(i, total)
 
-	// FIXME: dry this up with release commands and non-empty update
-	fmt.Fprintf(md.io.ErrOut, "  Created release_command machine %s\n", md.colorize.Bold(newMachineRaw.ID))
-	if md.strategy != "immediate" {
-		err := newMachine.WaitForState(ctx, api.MachineStateStarted, md.waitTimeout, indexStr)
-		if err != nil {
+	interactive := md.io.IsInteractive()
+	if interactive {
+		fmt.Fprintf(md.io.ErrOut, "  %s Created machine %s\n", indexStr, md.colorize.Bold(newMachine.FormattedMachineId()))
+	}
+	jobMachine := runsToCompletion(launchInput.Config)
+	switch {
+	case md.strategy == "immediate" || runsOnSchedule(launchInput.Config):
+		// Scheduled machines may not run before their next scheduled time
+	case jobMachine:
+		if err := newMachine.WaitForExit(ctx, 0, md.newMachineWaitTimeout, indexStr); err != nil {
 			return err
 		}
-	}
-	if md.strategy != "immediate" && !md.skipHealthChecks {
-		err := newMachine.WaitForHealthchecksToPass(ctx, md.waitTimeout, indexStr)
-		// FIXME: combine this wait with the wait for start as one update line (or two per in noninteractive case)
+	default:
+		err := newMachine.WaitForState(ctx, api.MachineStateStarted, md.newMachineWaitTimeout, indexStr)
 		if err != nil {
 			return err
-		} else {
-			md.logClearLinesAbove(1)
-			fmt.Fprintf(md.io.ErrOut, "  Machine %s update finished: %s\n",
+		}
+	}
+	if md.strategy != "immediate" && !md.skipHealthChecks && !jobMachine {
+		if err := newMachine.WaitForHealthchecksToPass(ctx, md.newMachineWaitTimeout, indexStr); err != nil {
+			return healthCheckError(newMachineRaw.ID, err)
+		}
+		md.logClearLinesAbove(1)
+		if interactive {
+			fmt.Fprintf(md.io.ErrOut, "  %s Machine %s update finished: %s\n",
+				indexStr,
 				md.colorize.Bold(newMachine.FormattedMachineId()),
 				md.colorize.Green("success"),
 			)
 		}
 	}
+	if !interactive {
+		fmt.Fprintf(md.io.ErrOut, "  %s Machine %s created: %s\n", indexStr, md.colorize.Bold(newMachine.FormattedMachineId()), md.colorize.Green("success"))
+	}
+	md.progress.machineDone(groupName)
 	return nil
 }
 
 func (md *machineDeployment) resolveProcessGroupChanges() ProcessGroupsDiff {
 	output := ProcessGroupsDiff{
-		groupsToRemove:        map[string]int{},
-		groupsNeedingMachines: map[string]bool{},
+		groupsToRemove:         map[string]int{},
+		groupsNeedingMachines:  map[string]bool{},
+		regionsNeedingMachines: map[string][]string{},
 	}
 
-	groupsInConfig := md.appConfig.ProcessNames()
+	// Disabled groups are handled like groups removed from fly.toml
+	groupsInConfig := md.enabledProcessNames()
 	groupHasMachine := map[string]bool{}
+	groupRegions := map[string]map[string]bool{}
 
 	for _, leasableMachine := range md.machineSet.GetMachines() {
 		name := leasableMachine.Machine().ProcessGroup()
 		if slices.Contains(groupsInConfig, name) {
 			groupHasMachine[name] = true
+			if groupRegions[name] == nil {
+				groupRegions[name] = map[string]bool{}
+			}
+			groupRegions[name][leasableMachine.Machine().Region] = true
+		} else if leasableMachine.Machine().IsDeployPinned() {
+			// Pinned machines are left alone even if their group is gone
+			continue
 		} else {
 			output.groupsToRemove[name] += 1
 			output.machinesToRemove = append(output.machinesToRemove, leasableMachine)
@@ -271,31 +919,84 @@ func (md *machineDeployment) resolveProcessGroupChanges() ProcessGroupsDiff {
 		}
 	}
 
+	if md.expandRegions {
+		for _, name := range groupsInConfig {
+			for _, region := range md.appConfig.Regions {
+				if groupRegions[name][region] {
+					continue
+				}
+				// The machine launched for a new group already lands in the primary region
+				if output.groupsNeedingMachines[name] && region == md.primaryRegion() {
+					continue
+				}
+				if !slices.Contains(output.regionsNeedingMachines[name], region) {
+					output.regionsNeedingMachines[name] = append(output.regionsNeedingMachines[name], region)
+				}
+			}
+		}
+	}
+
 	return output
 }
 
+func countRegionsNeedingMachines(regionsNeedingMachines map[string][]string) (total int) {
+	for _, regions := range regionsNeedingMachines {
+		total += len(regions)
+	}
+	return total
+}
+
+// confirmDestroy asks before destroying more than --confirm-destroy-over machines of removed
+// process groups, a typo in a process group name of fly.toml would otherwise destroy all its machines
+func (md *machineDeployment) confirmDestroy(ctx context.Context, count int) error {
+	if count <= md.confirmDestroyOver || md.autoConfirm {
+		return nil
+	}
+	if !md.io.CanPrompt() {
+		return fmt.Errorf("this deploy would destroy %d machines of process groups removed from fly.toml, more than --confirm-destroy-over=%d; "+
+			"check the process group names in fly.toml or deploy with --auto-confirm", count, md.confirmDestroyOver)
+	}
+	confirmed, err := prompt.Confirmf(ctx, "Destroy %d machines of process groups removed from fly.toml?", count)
+	switch {
+	case err != nil:
+		return err
+	case !confirmed:
+		return fmt.Errorf("deploy aborted, no machine was destroyed")
+	}
+	return nil
+}
+
 func (md *machineDeployment) warnAboutProcessGroupChanges(ctx context.Context, diff ProcessGroupsDiff) {
 	willAddMachines := len(diff.groupsNeedingMachines) != 0
 	willRemoveMachines := diff.machinesToRemove != nil
+	willExpandRegions := countRegionsNeedingMachines(diff.regionsNeedingMachines) != 0
 
-	if !willAddMachines && !willRemoveMachines {
+	if !willAddMachines && !willRemoveMachines && !willExpandRegions {
 		return
 	}
 
-	fmt.Fprintln(md.io.Out, "Process groups have changed. This will:")
+	fmt.Fprintln(md.io.ErrOut, "Process groups have changed. This will:")
 
 	if willRemoveMachines {
 		bullet := md.colorize.Red("*")
 		for grp, numMach := range diff.groupsToRemove {
 			pluralS := lo.Ternary(numMach == 1, "", "s")
-			fmt.Fprintf(md.io.Out, " %s destroy %d \"%s\" machine%s\n", bullet, numMach, grp, pluralS)
+			fmt.Fprintf(md.io.ErrOut, " %s destroy %d \"%s\" machine%s\n", bullet, numMach, grp, pluralS)
 		}
 	}
 	if willAddMachines {
 		bullet := md.colorize.Green("*")
 		for name := range diff.groupsNeedingMachines {
-			fmt.Fprintf(md.io.Out, " %s create 1 \"%s\" machine\n", bullet, name)
+			fmt.Fprintf(md.io.ErrOut, " %s create 1 \"%s\" machine\n", bullet, name)
+		}
+	}
+	if willExpandRegions {
+		bullet := md.colorize.Green("*")
+		for name, regions := range diff.regionsNeedingMachines {
+			for _, region := range regions {
+				fmt.Fprintf(md.io.ErrOut, " %s create 1 \"%s\" machine in %s\n", bullet, name, region)
+			}
 		}
 	}
-	fmt.Fprint(md.io.Out, "\n")
+	fmt.Fprint(md.io.ErrOut, "\n")
 }
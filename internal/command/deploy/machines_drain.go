@@ -0,0 +1,42 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/terminal"
+)
+
+// drainStopMargin is how long to wait on top of the drain timeout for the stop to be reported
+const drainStopMargin = 10 * time.Second
+
+// drainMachine gracefully stops a machine about to be destroyed. The proxy stops routing new
+// connections to it and in-flight requests get up to the drain timeout to finish.
+// Draining is best effort, the machine is force destroyed anyway afterwards.
+func (md *machineDeployment) drainMachine(ctx context.Context, lm machine.LeasableMachine) {
+	if md.drainTimeout <= 0 || lm.Machine().State != api.MachineStateStarted {
+		return
+	}
+	fmt.Fprintf(md.io.ErrOut, "  Draining machine %s for up to %s\n", md.colorize.Bold(lm.FormattedMachineId()), md.drainTimeout)
+	if err := lm.Stop(ctx, md.drainTimeout); err != nil {
+		terminal.Warnf("failed to drain machine %s, destroying it right away: %v\n", lm.Machine().ID, err)
+		return
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, md.drainTimeout+drainStopMargin)
+	defer cancel()
+	for {
+		err := md.flapsClient.Wait(waitCtx, lm.Machine(), api.MachineStateStopped, md.drainTimeout+drainStopMargin)
+		switch {
+		case err == nil:
+			return
+		case waitCtx.Err() != nil:
+			terminal.Warnf("machine %s didn't stop after draining for %s, destroying it anyway\n", lm.Machine().ID, md.drainTimeout)
+			return
+		}
+		time.Sleep(time.Second)
+	}
+}
@@ -0,0 +1,76 @@
+package deploy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/flaps"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/logger"
+	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+// stoppingMachine fakes a machine recording the timeouts it's stopped with
+type stoppingMachine struct {
+	machine.LeasableMachine
+	m     *api.Machine
+	stops []time.Duration
+}
+
+func (s *stoppingMachine) Machine() *api.Machine      { return s.m }
+func (s *stoppingMachine) FormattedMachineId() string { return s.m.ID }
+
+func (s *stoppingMachine) Stop(_ context.Context, timeout time.Duration) error {
+	s.stops = append(s.stops, timeout)
+	return nil
+}
+
+func Test_drainMachine(t *testing.T) {
+	var waits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/apps/my-app/machines/m1/wait", r.URL.Path)
+		assert.Equal(t, api.MachineStateStopped, r.URL.Query().Get("state"))
+		waits.Add(1)
+	}))
+	defer server.Close()
+	t.Setenv("FLY_FLAPS_BASE_URL", server.URL)
+	t.Setenv("FLY_ACCESS_TOKEN", "token")
+	ios, _, _, errOut := iostreams.Test()
+	ctx := logger.NewContext(context.Background(), logger.FromEnv(ios.ErrOut))
+
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	md.io = ios
+	md.colorize = ios.ColorScheme()
+	md.flapsClient, err = flaps.NewFromAppName(ctx, "my-app")
+	require.NoError(t, err)
+
+	started := groupMachine("m1", "app", "ord")
+	started.State = api.MachineStateStarted
+	stopped := groupMachine("m1", "app", "ord")
+	stopped.State = api.MachineStateStopped
+
+	// Without a drain timeout, or a started machine, there's nothing to drain
+	lm := &stoppingMachine{m: started}
+	md.drainMachine(ctx, lm)
+	md.drainTimeout = 5 * time.Second
+	lm2 := &stoppingMachine{m: stopped}
+	md.drainMachine(ctx, lm2)
+	assert.Empty(t, lm.stops)
+	assert.Empty(t, lm2.stops)
+	assert.Zero(t, waits.Load())
+
+	// Started machines are stopped with the drain timeout, then waited on until they're stopped
+	md.drainMachine(ctx, lm)
+	assert.Equal(t, []time.Duration{5 * time.Second}, lm.stops)
+	assert.EqualValues(t, 1, waits.Load())
+	assert.Contains(t, errOut.String(), "Draining machine m1 for up to 5s")
+}
@@ -0,0 +1,120 @@
+package deploy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/superfly/flyctl/internal/appconfig"
+)
+
+var (
+	dotEnvNameRegexp       = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+	dotEnvCommentRegexp    = regexp.MustCompile(`\s+#`)
+	secretLookingEnvRegexp = regexp.MustCompile(`(?i)(_KEY$|SECRET|PASSWORD|TOKEN)`)
+	dotEnvUnescaper        = strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\n`, "\n", `\r`, "\r", `\t`, "\t")
+)
+
+// setEnvFile loads the env of the app machines for this deploy from a dotenv file.
+// It takes precedence over fly.toml [env], --env and the config override, --set-env wins over it.
+func (md *machineDeployment) setEnvFile(path string) error {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed opening --env-file: %w", err)
+	}
+	defer f.Close() // skipcq: GO-S2307
+	env, err := parseDotEnv(f)
+	if err != nil {
+		return fmt.Errorf("failed parsing --env-file %s: %w", path, err)
+	}
+	md.envFileEnv = env
+
+	names := lo.Keys(env)
+	sort.Strings(names)
+	md.warnf("%s %s\n", md.colorize.WarningIcon(), md.colorize.Yellow(fmt.Sprintf(
+		"All app machines will have %s from %s set over their configured env. "+
+			"This is transient, it isn't saved to %s and the next deploy without --env-file removes it",
+		strings.Join(names, ", "), path, appconfig.DefaultConfigFileName)))
+	if secretLooking := lo.Filter(names, func(name string, _ int) bool { return secretLookingEnvRegexp.MatchString(name) }); len(secretLooking) > 0 {
+		md.warnf("%s %s\n", md.colorize.WarningIcon(), md.colorize.Yellow(fmt.Sprintf(
+			"%s look like secrets, env values are visible in the machine config. Consider `fly secrets set` instead",
+			strings.Join(secretLooking, ", "))))
+	}
+	return nil
+}
+
+// parseDotEnv reads NAME=VALUE lines in the dotenv format: blank lines and # comments are
+// skipped, an `export ` prefix is allowed, single quoted values are taken as is, double quoted
+// values support \n, \r, \t, \" and \\ escapes, and quoted values can span several lines.
+// Unquoted values end at a # preceded by whitespace.
+func parseDotEnv(r io.Reader) (map[string]string, error) {
+	env := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !dotEnvNameRegexp.MatchString(name) {
+			return nil, fmt.Errorf("line %d: must be in the format NAME=VALUE", lineNo)
+		}
+		value = strings.TrimSpace(value)
+
+		if value == "" || (value[0] != '"' && value[0] != '\'') {
+			if loc := dotEnvCommentRegexp.FindStringIndex(value); loc != nil {
+				value = value[:loc[0]]
+			}
+			env[name] = value
+			continue
+		}
+
+		quote, startLine := value[0], lineNo
+		value = value[1:]
+		for {
+			if end := closingQuoteIndex(value, quote); end >= 0 {
+				if rest := strings.TrimSpace(value[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+					return nil, fmt.Errorf("line %d: unexpected %q after the closing quote of %s", lineNo, rest, name)
+				}
+				value = value[:end]
+				break
+			}
+			if !scanner.Scan() {
+				return nil, fmt.Errorf("line %d: the quoted value of %s is never closed", startLine, name)
+			}
+			lineNo++
+			value += "\n" + scanner.Text()
+		}
+		if quote == '"' {
+			value = dotEnvUnescaper.Replace(value)
+		}
+		env[name] = value
+	}
+	return env, scanner.Err()
+}
+
+// closingQuoteIndex finds the quote closing a value, double quotes can be escaped by a backslash
+func closingQuoteIndex(value string, quote byte) int {
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && quote == '"':
+			i++
+		case value[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,70 @@
+package deploy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func Test_parseDotEnv(t *testing.T) {
+	env, err := parseDotEnv(strings.NewReader(`
+# a comment
+LOG_LEVEL=debug
+export REGION = ord
+EMPTY=
+INLINE=value # a comment
+HASH=value#not-a-comment
+SINGLE='literal \n "as is"'
+DOUBLE="line\nbreak \"quoted\"" # a comment
+MULTI="first
+second"
+`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"LOG_LEVEL": "debug",
+		"REGION":    "ord",
+		"EMPTY":     "",
+		"INLINE":    "value",
+		"HASH":      "value#not-a-comment",
+		"SINGLE":    `literal \n "as is"`,
+		"DOUBLE":    "line\nbreak \"quoted\"",
+		"MULTI":     "first\nsecond",
+	}, env)
+
+	_, err = parseDotEnv(strings.NewReader("OK=1\nNOVALUE\n"))
+	assert.EqualError(t, err, "line 2: must be in the format NAME=VALUE")
+	_, err = parseDotEnv(strings.NewReader("OPEN=\"never closed\nOTHER=1\n"))
+	assert.EqualError(t, err, "line 1: the quoted value of OPEN is never closed")
+}
+
+func Test_setEnvFile(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{
+		Env: map[string]string{"LOG_LEVEL": "info", "OTHER": "value"},
+	})
+	require.NoError(t, err)
+	ios, _, _, errOut := iostreams.Test()
+	md.io = ios
+	md.alertOut = ios.ErrOut
+	md.colorize = ios.ColorScheme()
+
+	path := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(path, []byte("LOG_LEVEL=warn\nTRACE=1\nSTRIPE_API_KEY=sk_test\n"), 0o600))
+	require.NoError(t, md.setEnvFile(path))
+	assert.Contains(t, errOut.String(), "STRIPE_API_KEY look like secrets")
+	require.NoError(t, md.setTransientEnv([]string{"TRACE=2"}))
+
+	// fly.toml < --env-file < --set-env
+	li, err := md.launchInputForLaunch("", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "warn", li.Config.Env["LOG_LEVEL"])
+	assert.Equal(t, "2", li.Config.Env["TRACE"])
+	assert.Equal(t, "value", li.Config.Env["OTHER"])
+
+	assert.Error(t, md.setEnvFile(filepath.Join(t.TempDir(), "missing.env")))
+}
@@ -0,0 +1,73 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/flaps"
+)
+
+// capacityErrorRegexp matches the errors of machines launched in a region without room for them
+var capacityErrorRegexp = regexp.MustCompile(`(?i)(no capacity|insufficient (resources|capacity|memory)|could not reserve resource|not enough capacity)`)
+
+// isCapacityError tells whether a machine launch failed because its region has no room left for
+// it, as opposed to failures launching it in another region would hit too
+func isCapacityError(err error) bool {
+	var flapsErr *flaps.FlapsError
+	return errors.As(err, &flapsErr) && capacityErrorRegexp.MatchString(flapsErr.Error())
+}
+
+func (md *machineDeployment) setFallbackRegions(fallbackRegions []string) {
+	if len(fallbackRegions) == 0 && md.appConfig.Deploy != nil {
+		fallbackRegions = md.appConfig.Deploy.FallbackRegions
+	}
+	md.fallbackRegions = fallbackRegions
+}
+
+// launchWithFallback launches a new machine and, when its region is out of capacity, tries the
+// fallback regions in order. launchInput is updated with the region the machine landed in, and
+// the name it got there.
+// Machines with volumes stay in their region, their volume can't follow them.
+func (md *machineDeployment) launchWithFallback(ctx context.Context, launchInput *api.LaunchMachineInput) (*api.Machine, error) {
+	newMachine, err := md.launchMachine(ctx, *launchInput)
+	if err == nil || !isCapacityError(err) || len(launchInput.Config.Mounts) > 0 {
+		return newMachine, err
+	}
+
+	region := launchInput.Region
+	for _, fallback := range md.fallbackRegions {
+		if fallback == region || checkGPURegion(launchInput.Config.Guest, fallback) != nil {
+			continue
+		}
+		md.warnf("No capacity for a new machine in region %s, trying region %s: %s\n", region, fallback, err)
+		input := md.fallbackInput(launchInput, fallback)
+		newMachine, err = md.launchMachine(ctx, input)
+		if err == nil {
+			md.warnf("Placement fell back from region %s to %s for machine %s\n", launchInput.Region, fallback, newMachine.ID)
+			// The deploy is verified against the region the machine landed in
+			if md.expected != nil {
+				md.expected.move(input.Config.ProcessGroup(), launchInput.Region, fallback)
+			}
+			*launchInput = input
+			return newMachine, nil
+		}
+		if !isCapacityError(err) {
+			return nil, err
+		}
+		region = fallback
+	}
+	return nil, err
+}
+
+// fallbackInput returns launchInput moved to region, named after it when machines are named
+// by a template
+func (md *machineDeployment) fallbackInput(launchInput *api.LaunchMachineInput, region string) api.LaunchMachineInput {
+	input := *launchInput
+	input.Region = region
+	if input.Name != "" {
+		input.Name = md.machineName(input.Config.ProcessGroup(), region)
+	}
+	return input
+}
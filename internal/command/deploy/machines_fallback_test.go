@@ -0,0 +1,68 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/flaps"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func Test_isCapacityError(t *testing.T) {
+	capacityErr := &flaps.FlapsError{OriginalError: errors.New("no capacity available in region ams"), ResponseStatusCode: 412}
+	assert.True(t, isCapacityError(capacityErr))
+	assert.True(t, isCapacityError(fmt.Errorf("error creating a new machine: %w", capacityErr)))
+
+	assert.False(t, isCapacityError(&flaps.FlapsError{OriginalError: errors.New("invalid image"), ResponseStatusCode: 422}))
+	// Only errors returned by the machines API are considered
+	assert.False(t, isCapacityError(errors.New("no capacity")))
+}
+
+func Test_setFallbackRegions(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{
+		Deploy: &appconfig.Deploy{FallbackRegions: []string{"ord", "iad"}},
+	})
+	require.NoError(t, err)
+
+	md.setFallbackRegions(nil)
+	assert.Equal(t, []string{"ord", "iad"}, md.fallbackRegions)
+	// The flag wins over fly.toml
+	md.setFallbackRegions([]string{"fra"})
+	assert.Equal(t, []string{"fra"}, md.fallbackRegions)
+}
+
+func Test_fallbackInput(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	ios, _, _, _ := iostreams.Test()
+	md.machineSet = machine.NewMachineSet(nil, ios, nil)
+
+	launchInput := &api.LaunchMachineInput{
+		Region: "ams",
+		Config: &api.MachineConfig{Metadata: map[string]string{api.MachineConfigMetadataKeyFlyProcessGroup: "app"}},
+	}
+	assert.Equal(t, "ord", md.fallbackInput(launchInput, "ord").Region)
+	assert.Empty(t, md.fallbackInput(launchInput, "ord").Name)
+
+	require.NoError(t, md.setMachineNameTemplate("{group}-{region}-{index}"))
+	launchInput.Name = md.machineName("app", "ams")
+	input := md.fallbackInput(launchInput, "ord")
+	assert.Equal(t, "app-ord-1", input.Name)
+	assert.Equal(t, "ams", launchInput.Region)
+}
+
+func Test_machineTopologyMove(t *testing.T) {
+	expected := machineTopology{"app": {"ams": 2}}
+	expected.move("app", "ams", "ord")
+	assert.Equal(t, machineTopology{"app": {"ams": 1, "ord": 1}}, expected)
+	assert.Empty(t, topologyShortfalls(expected, []*api.Machine{
+		groupMachine("m1", "app", "ams"),
+		groupMachine("m2", "app", "ord"),
+	}))
+}
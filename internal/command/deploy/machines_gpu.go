@@ -0,0 +1,28 @@
+package deploy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/superfly/flyctl/api"
+	"golang.org/x/exp/slices"
+)
+
+// defaultGPUVMSize is the size of GPU machines deployed without --vm-size
+const defaultGPUVMSize = "performance-8x"
+
+// checkGPURegion fails when guest has a GPU region doesn't offer, naming the regions that do.
+// An empty region is left to the platform to pick.
+func checkGPURegion(guest *api.MachineGuest, region string) error {
+	if guest == nil || guest.GPUKind == "" || region == "" {
+		return nil
+	}
+	regions, ok := api.MachineGPUKinds[guest.GPUKind]
+	if !ok || slices.Contains(regions, region) {
+		return nil
+	}
+	return fmt.Errorf(
+		"GPU kind %s isn't available in region %s, it is in: %s. Set primary_region in fly.toml to one of them",
+		guest.GPUKind, region, strings.Join(regions, ", "),
+	)
+}
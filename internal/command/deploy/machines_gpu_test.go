@@ -0,0 +1,41 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/appconfig"
+)
+
+func Test_setMachineGuest_gpu(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{PrimaryRegion: "ord"})
+	require.NoError(t, err)
+
+	require.NoError(t, md.setMachineGuest("", "a100-40gb"))
+	assert.Equal(t, &api.MachineGuest{CPUKind: "performance", CPUs: 8, MemoryMB: 16384, GPUKind: "a100-pcie-40gb"}, md.machineGuest)
+
+	md.appConfig.PrimaryRegion = "cdg"
+	assert.ErrorContains(t, md.setMachineGuest("performance-4x", "a100-80gb"),
+		"GPU kind a100-sxm4-80gb isn't available in region cdg, it is in: ams, iad, mia, sjc, syd")
+}
+
+func Test_launchInputFor_gpu(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{PrimaryRegion: "ord"})
+	require.NoError(t, err)
+	require.NoError(t, md.setMachineGuest("", "l40s"))
+
+	li, err := md.launchInputForLaunch("", "", md.machineGuest)
+	require.NoError(t, err)
+	assert.Equal(t, "l40s", li.Config.Guest.GPUKind)
+
+	// Spawning in a region without the GPU fails before launching
+	_, err = md.launchInputForLaunch("", "ams", md.machineGuest)
+	assert.ErrorContains(t, err, "isn't available in region ams")
+
+	// Updates keep the GPU of the machine
+	li, err = md.launchInputForUpdate(&api.Machine{ID: "ab1234567890", Region: "ord", Config: li.Config})
+	require.NoError(t, err)
+	assert.Equal(t, "l40s", li.Config.Guest.GPUKind)
+}
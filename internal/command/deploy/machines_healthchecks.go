@@ -0,0 +1,72 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/machine"
+)
+
+const (
+	healthCheckValidationTimeout  = 15 * time.Second
+	healthCheckValidationInterval = 1 * time.Second
+)
+
+// validateHealthCheckEndpoints briefly polls the checks of a freshly updated machine
+// and fails fast when an HTTP check is already answering with a 4xx or 5xx status,
+// instead of letting the deployment wait the whole wait timeout.
+// Inconclusive results are ignored and left to the regular health checks wait.
+func (md *machineDeployment) validateHealthCheckEndpoints(ctx context.Context, lm machine.LeasableMachine) error {
+	waitCtx, cancel := context.WithTimeout(ctx, healthCheckValidationTimeout)
+	defer cancel()
+
+	for {
+		m, err := md.flapsClient.Get(waitCtx, lm.Machine().ID)
+		switch {
+		case waitCtx.Err() != nil:
+			return nil
+		case err != nil:
+			return nil
+		case m.HealthCheckStatus().AllPassing():
+			return nil
+		}
+
+		if check, path, code := failingHttpCheck(m); check != "" {
+			return fmt.Errorf(
+				"your %s health check '%s' returns %d on machine %s; fix the check in %s or the app before deploying again",
+				path, check, code, lm.FormattedMachineId(), appconfig.DefaultConfigFileName,
+			)
+		}
+
+		select {
+		case <-waitCtx.Done():
+			return nil
+		case <-time.After(healthCheckValidationInterval):
+		}
+	}
+}
+
+// failingHttpCheck returns the name, path and status code of the first critical
+// HTTP check reporting an error status, or an empty name when there is none.
+func failingHttpCheck(m *api.Machine) (string, string, int) {
+	for _, cs := range m.Checks {
+		if cs == nil || cs.Status != "critical" || machine.CheckType(m, cs.Name) != "http" {
+			continue
+		}
+		code := machine.HTTPErrorStatus(cs.Output)
+		if code == 0 {
+			continue
+		}
+		path := "the check path"
+		if m.Config != nil {
+			if def, ok := m.Config.Checks[cs.Name]; ok && def.HTTPPath != nil {
+				path = *def.HTTPPath
+			}
+		}
+		return cs.Name, path, code
+	}
+	return "", "", 0
+}
@@ -0,0 +1,42 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/superfly/flyctl/api"
+)
+
+func Test_failingHttpCheck(t *testing.T) {
+	m := &api.Machine{
+		Config: &api.MachineConfig{
+			Checks: map[string]api.MachineCheck{
+				"web": {Type: api.Pointer("http"), HTTPPath: api.Pointer("/health")},
+				"tcp": {Type: api.Pointer("tcp")},
+				"nop": {},
+			},
+		},
+		Checks: []*api.MachineCheckStatus{
+			{Name: "tcp", Status: "critical", Output: "connection refused on port 500"},
+			{Name: "servicecheck-00-tcp-8080", Status: "critical", Output: "dial tcp 172.19.0.2:8080: 503 attempts"},
+			{Name: "nop", Status: "critical", Output: "exit status 404"},
+			{Name: "web", Status: "passing", Output: "200 OK"},
+		},
+	}
+
+	name, _, _ := failingHttpCheck(m)
+	assert.Equal(t, "", name)
+
+	m.Checks[3] = &api.MachineCheckStatus{Name: "web", Status: "critical", Output: "GET /health: 404 Not Found"}
+	name, path, code := failingHttpCheck(m)
+	assert.Equal(t, "web", name)
+	assert.Equal(t, "/health", path)
+	assert.Equal(t, 404, code)
+
+	// Service checks tell their type by name
+	m.Checks = []*api.MachineCheckStatus{{Name: "servicecheck-01-http-8080", Status: "critical", Output: "500 Internal Server Error"}}
+	name, path, code = failingHttpCheck(m)
+	assert.Equal(t, "servicecheck-01-http-8080", name)
+	assert.Equal(t, "the check path", path)
+	assert.Equal(t, 500, code)
+}
@@ -0,0 +1,59 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/superfly/flyctl/api"
+)
+
+// DeployHooks lets programs embedding flyctl run their own steps, like extra validation or
+// notifications, at points of a machines deployment. Every hook is optional and the first
+// one returning an error aborts the deployment.
+type DeployHooks struct {
+	// AfterReleaseCommand runs once the release commands succeeded, there may be none
+	AfterReleaseCommand func(ctx context.Context) error
+	// BeforeAcquireLeases runs before the machines of the app are leased to be updated
+	BeforeAcquireLeases func(ctx context.Context) error
+	// BeforeMachineUpdate runs before a machine is updated or replaced
+	BeforeMachineUpdate func(ctx context.Context, m *api.Machine) error
+	// AfterMachineUpdate runs once a machine is done, it gets the replacement for replaced machines
+	AfterMachineUpdate func(ctx context.Context, m *api.Machine) error
+}
+
+// The methods below are safe to call on a nil *DeployHooks, which is what deploys without hooks get
+
+func (h *DeployHooks) afterReleaseCommand(ctx context.Context) error {
+	if h == nil || h.AfterReleaseCommand == nil {
+		return nil
+	}
+	return hookError("after release command", h.AfterReleaseCommand(ctx))
+}
+
+func (h *DeployHooks) beforeAcquireLeases(ctx context.Context) error {
+	if h == nil || h.BeforeAcquireLeases == nil {
+		return nil
+	}
+	return hookError("before acquiring leases", h.BeforeAcquireLeases(ctx))
+}
+
+func (h *DeployHooks) beforeMachineUpdate(ctx context.Context, m *api.Machine) error {
+	if h == nil || h.BeforeMachineUpdate == nil {
+		return nil
+	}
+	return hookError(fmt.Sprintf("before updating machine %s", m.ID), h.BeforeMachineUpdate(ctx, m))
+}
+
+func (h *DeployHooks) afterMachineUpdate(ctx context.Context, m *api.Machine) error {
+	if h == nil || h.AfterMachineUpdate == nil {
+		return nil
+	}
+	return hookError(fmt.Sprintf("after updating machine %s", m.ID), h.AfterMachineUpdate(ctx, m))
+}
+
+func hookError(point string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("deploy hook %s aborted the deployment: %w", point, err)
+}
@@ -0,0 +1,69 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func Test_DeployHooks_nil(t *testing.T) {
+	var hooks *DeployHooks
+	ctx := context.Background()
+	assert.NoError(t, hooks.afterReleaseCommand(ctx))
+	assert.NoError(t, hooks.beforeAcquireLeases(ctx))
+	assert.NoError(t, hooks.beforeMachineUpdate(ctx, &api.Machine{ID: "m1"}))
+	assert.NoError(t, hooks.afterMachineUpdate(ctx, &api.Machine{ID: "m1"}))
+	assert.NoError(t, (&DeployHooks{}).afterMachineUpdate(ctx, &api.Machine{ID: "m1"}))
+}
+
+func Test_restartMachinesApp_beforeAcquireLeasesAborts(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	hookErr := errors.New("maintenance window is closed")
+	md.hooks = &DeployHooks{
+		BeforeAcquireLeases: func(ctx context.Context) error { return hookErr },
+	}
+
+	err = md.restartMachinesApp(context.Background())
+	assert.ErrorIs(t, err, hookErr)
+	assert.EqualError(t, err, "deploy hook before acquiring leases aborted the deployment: maintenance window is closed")
+}
+
+func Test_updateExistingMachines_afterMachineUpdate(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	ios, _, _, _ := iostreams.Test()
+	md.io = ios
+	md.colorize = ios.ColorScheme()
+
+	var updated []string
+	md.hooks = &DeployHooks{
+		AfterMachineUpdate: func(ctx context.Context, m *api.Machine) error {
+			updated = append(updated, m.ID)
+			if m.ID == "m2" {
+				return errors.New("smoke test failed")
+			}
+			return nil
+		},
+	}
+	entry := func(id string) *machineUpdateEntry {
+		m := groupMachine(id, "app", "ord")
+		m.State = api.MachineStateStopped
+		return &machineUpdateEntry{
+			leasableMachine: machine.NewLeasableMachine(nil, ios, m),
+			launchInput:     &api.LaunchMachineInput{ID: id, Config: m.Config},
+			upToDate:        true,
+		}
+	}
+
+	err = md.updateExistingMachines(context.Background(), []*machineUpdateEntry{entry("m1"), entry("m2"), entry("m3")})
+	assert.EqualError(t, err, "deploy hook after updating machine m2 aborted the deployment: smoke test failed")
+	assert.Equal(t, []string{"m1", "m2"}, updated)
+}
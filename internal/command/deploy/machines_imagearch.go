@@ -0,0 +1,38 @@
+package deploy
+
+import (
+	"strings"
+
+	"github.com/superfly/flyctl/terminal"
+)
+
+// machinePlatform is the os/arch Fly Machines run images on
+const machinePlatform = "linux/amd64"
+
+// checkImagePlatform fails when the deployment image was built for another platform than the machines run on,
+// such an image crashes on boot and the deploy would only fail later on health checks.
+// The check is skipped when the image platform is unknown, as for images resolved from a registry.
+func (md *machineDeployment) checkImagePlatform() error {
+	if md.imgPlatform == "" {
+		terminal.Debugf("image platform of %s is unknown, skipping the platform check\n", md.img)
+		return nil
+	}
+	if samePlatform(md.imgPlatform, machinePlatform) {
+		return nil
+	}
+	return &ImagePlatformError{
+		Image:    md.img,
+		Platform: md.imgPlatform,
+		Expected: machinePlatform,
+	}
+}
+
+// samePlatform compares the os and architecture of two os/arch[/variant] platforms, ignoring the variant
+func samePlatform(a, b string) bool {
+	aParts := strings.SplitN(a, "/", 3)
+	bParts := strings.SplitN(b, "/", 3)
+	if len(aParts) < 2 || len(bParts) < 2 {
+		return a == b
+	}
+	return aParts[0] == bParts[0] && aParts[1] == bParts[1]
+}
@@ -0,0 +1,35 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/internal/appconfig"
+)
+
+func Test_checkImagePlatform(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	md.img = "registry.fly.io/my-cool-app:deployment-1"
+
+	md.imgPlatform = ""
+	assert.NoError(t, md.checkImagePlatform())
+
+	md.imgPlatform = "linux/amd64"
+	assert.NoError(t, md.checkImagePlatform())
+
+	md.imgPlatform = "linux/arm64/v8"
+	err = md.checkImagePlatform()
+	var platformErr *ImagePlatformError
+	require.ErrorAs(t, err, &platformErr)
+	assert.Equal(t, "linux/arm64/v8", platformErr.Platform)
+	assert.ErrorContains(t, err, "--platform linux/amd64")
+}
+
+func Test_samePlatform(t *testing.T) {
+	assert.True(t, samePlatform("linux/amd64", "linux/amd64"))
+	assert.True(t, samePlatform("linux/amd64/v3", "linux/amd64"))
+	assert.False(t, samePlatform("linux/arm64", "linux/amd64"))
+	assert.False(t, samePlatform("windows/amd64", "linux/amd64"))
+}
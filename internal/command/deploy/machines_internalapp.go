@@ -0,0 +1,19 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/superfly/flyctl/internal/machine"
+)
+
+// withoutPublicChecks returns a context making the health check waits skip the service checks
+// with --no-public-ips-wait. The platform runs them against the private address of the machines
+// like the machine checks, skipping them leaves the machine checks as the only health gating.
+func (md *machineDeployment) withoutPublicChecks(ctx context.Context) context.Context {
+	if !md.noPublicIPsWait || len(md.appConfig.AllServices()) == 0 {
+		return ctx
+	}
+	fmt.Fprintf(md.io.ErrOut, "Skipping service checks with --no-public-ips-wait, only machine checks are waited on\n")
+	return machine.WithoutServiceChecks(ctx)
+}
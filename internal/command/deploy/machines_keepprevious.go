@@ -0,0 +1,156 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/samber/lo"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/helpers"
+	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/terminal"
+)
+
+// keepForRollback stops a machine replaced by the deploy and tags it to be kept, instead of
+// destroying it, when --keep-previous is set. Tagged machines lose their services, are left out of
+// later deploys and keep the release metadata of the release they run. It reports whether the
+// machine was kept, the caller destroys it otherwise.
+func (md *machineDeployment) keepForRollback(ctx context.Context, lm machine.LeasableMachine) bool {
+	if md.keepPrevious <= 0 {
+		return false
+	}
+	m := lm.Machine()
+	if m.Config != nil && len(m.Config.Mounts) > 0 {
+		terminal.Debugf("machine %s has a volume, destroying it instead of keeping it for rollback\n", m.ID)
+		return false
+	}
+
+	if m.State == api.MachineStateStarted {
+		// Same graceful stop as draining, in-flight requests get the drain timeout to finish
+		if err := lm.Stop(ctx, md.drainTimeout); err != nil {
+			md.warnf("Failed to stop machine %s to keep it for rollback, destroying it: %s\n", m.ID, err)
+			return false
+		}
+		if err := lm.WaitForState(ctx, api.MachineStateStopped, md.drainTimeout+drainStopMargin, ""); err != nil {
+			md.warnf("Machine %s didn't stop to be kept for rollback, destroying it: %s\n", m.ID, err)
+			return false
+		}
+	}
+	// Without services the proxy can neither route requests to it nor start it on demand
+	keptConfig := helpers.Clone(m.Config)
+	keptConfig.Services = nil
+	keptConfig.Metadata[api.MachineConfigMetadataKeyFlyKeptForRollback] = "true"
+	input := api.LaunchMachineInput{
+		ID:         m.ID,
+		Region:     m.Region,
+		Config:     keptConfig,
+		SkipLaunch: true,
+	}
+	if err := md.inRegion(ctx, m.Region, func() error { return lm.Update(ctx, input) }); err != nil {
+		md.warnf("Failed to tag machine %s to keep it for rollback, destroying it: %s\n", m.ID, err)
+		return false
+	}
+	fmt.Fprintf(md.io.ErrOut, "  Stopped machine %s, kept for a rollback to release v%s\n",
+		md.colorize.Bold(lm.FormattedMachineId()), m.Config.Metadata[api.MachineConfigMetadataKeyFlyReleaseVersion])
+	return true
+}
+
+// cleanupKeptMachines destroys the machines kept for rollback by previous deploys that run a
+// release older than the last --keep-previous ones. It's best effort, failures are only reported.
+func (md *machineDeployment) cleanupKeptMachines(ctx context.Context) {
+	machines, err := md.flapsClient.List(ctx, "")
+	if err != nil {
+		terminal.Debugf("failed to list machines kept for rollback: %v\n", err)
+		return
+	}
+	for _, m := range keptMachinesToDestroy(machines, md.releaseVersion, md.keepPrevious) {
+		input := api.RemoveMachineInput{ID: m.ID, Kill: true}
+		if err := md.flapsClient.Destroy(ctx, input, ""); err != nil {
+			md.warnf("Failed to destroy machine %s kept for rollback: %s\n", m.ID, err)
+			continue
+		}
+		fmt.Fprintf(md.io.ErrOut, "  Destroyed machine %s kept for a rollback to release v%s\n",
+			md.colorize.Bold(m.ID), m.Config.Metadata[api.MachineConfigMetadataKeyFlyReleaseVersion])
+	}
+}
+
+// keptMachinesToDestroy returns the machines kept for rollback that run a release older than the
+// keep releases before releaseVersion. Machines with no release version recorded are left alone.
+func keptMachinesToDestroy(machines []*api.Machine, releaseVersion, keep int) []*api.Machine {
+	var toDestroy []*api.Machine
+	for _, m := range machines {
+		if !m.IsKeptForRollback() || !m.IsActive() {
+			continue
+		}
+		version, err := strconv.Atoi(m.Config.Metadata[api.MachineConfigMetadataKeyFlyReleaseVersion])
+		if err != nil {
+			continue
+		}
+		if version < releaseVersion-keep {
+			toDestroy = append(toDestroy, m)
+		}
+	}
+	return toDestroy
+}
+
+// loadKeptMachines lists the machines kept for rollback that run the release deployed again by
+// --from-release, to be started instead of launching new machines.
+func (md *machineDeployment) loadKeptMachines(ctx context.Context) error {
+	if md.fromReleaseVersion <= 0 {
+		return nil
+	}
+	machines, err := md.flapsClient.List(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed to list the machines kept for rollback: %w", err)
+	}
+	md.keptMachines = keptMachinesOfRelease(machines, md.fromReleaseVersion)
+	return nil
+}
+
+// keptMachinesOfRelease returns the machines kept for rollback that run release version.
+func keptMachinesOfRelease(machines []*api.Machine, version int) []*api.Machine {
+	return lo.Filter(machines, func(m *api.Machine, _ int) bool {
+		return m.IsKeptForRollback() && m.IsActive() &&
+			m.Config.Metadata[api.MachineConfigMetadataKeyFlyReleaseVersion] == strconv.Itoa(version)
+	})
+}
+
+// takeKeptMachine returns a machine kept for rollback that can run launchInput, and removes it
+// from the ones available. Kept machines have no volume, inputs with mounts never reuse them.
+func (md *machineDeployment) takeKeptMachine(launchInput *api.LaunchMachineInput) *api.Machine {
+	if len(launchInput.Config.Mounts) > 0 {
+		return nil
+	}
+	_, i, found := lo.FindIndexOf(md.keptMachines, func(m *api.Machine) bool {
+		return m.Region == launchInput.Region && m.ProcessGroup() == launchInput.Config.ProcessGroup()
+	})
+	if !found {
+		return nil
+	}
+	m := md.keptMachines[i]
+	md.keptMachines = append(md.keptMachines[:i:i], md.keptMachines[i+1:]...)
+	return m
+}
+
+// launchOrReuse launches a machine with launchInput, or starts the kept machine taken by
+// takeKeptMachine with it when there's one, since it already has the image on its host.
+func (md *machineDeployment) launchOrReuse(ctx context.Context, launchInput api.LaunchMachineInput, kept *api.Machine) (*api.Machine, error) {
+	if kept == nil {
+		return md.launchMachine(ctx, launchInput)
+	}
+	lm := machine.NewLeasableMachine(md.flapsClient, md.io, kept)
+	if err := lm.AcquireLease(ctx, md.leaseTimeout); err != nil {
+		terminal.Debugf("failed to lease machine %s kept for rollback, launching a new one: %v\n", kept.ID, err)
+		return md.launchMachine(ctx, launchInput)
+	}
+	defer lm.ReleaseLease(ctx)
+
+	// The update restores the services and drops the kept tag along with the rest of the config
+	launchInput.ID = kept.ID
+	if err := md.inRegion(ctx, kept.Region, func() error { return lm.Update(ctx, launchInput) }); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(md.io.ErrOut, "  Started machine %s kept for rollback\n", md.colorize.Bold(lm.FormattedMachineId()))
+	return lm.Machine(), nil
+}
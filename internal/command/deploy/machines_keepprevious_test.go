@@ -0,0 +1,75 @@
+package deploy
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/superfly/flyctl/api"
+)
+
+func Test_keptMachinesToDestroy(t *testing.T) {
+	kept := func(id string, version int) *api.Machine {
+		return &api.Machine{
+			ID:    id,
+			State: api.MachineStateStopped,
+			Config: &api.MachineConfig{Metadata: map[string]string{
+				api.MachineConfigMetadataKeyFlyKeptForRollback: "true",
+				api.MachineConfigMetadataKeyFlyReleaseVersion:  strconv.Itoa(version),
+			}},
+		}
+	}
+	running := kept("running", 3)
+	delete(running.Config.Metadata, api.MachineConfigMetadataKeyFlyKeptForRollback)
+	destroyed := kept("destroyed", 3)
+	destroyed.State = api.MachineStateDestroyed
+
+	machines := []*api.Machine{kept("v9", 9), kept("v8", 8), kept("v7", 7), running, destroyed}
+
+	// Deploying release 10 and keeping 2 releases keeps the machines of 8 and 9
+	ids := func(machines []*api.Machine) (ids []string) {
+		for _, m := range machines {
+			ids = append(ids, m.ID)
+		}
+		return ids
+	}
+	assert.Equal(t, []string{"v7"}, ids(keptMachinesToDestroy(machines, 10, 2)))
+	// Deploys without --keep-previous clean up all of them
+	assert.Equal(t, []string{"v9", "v8", "v7"}, ids(keptMachinesToDestroy(machines, 10, 0)))
+}
+
+func Test_takeKeptMachine(t *testing.T) {
+	kept := func(id, group, region string, version int) *api.Machine {
+		return &api.Machine{
+			ID:     id,
+			Region: region,
+			State:  api.MachineStateStopped,
+			Config: &api.MachineConfig{Metadata: map[string]string{
+				api.MachineConfigMetadataKeyFlyKeptForRollback: "true",
+				api.MachineConfigMetadataKeyFlyReleaseVersion:  strconv.Itoa(version),
+				api.MachineConfigMetadataKeyFlyProcessGroup:    group,
+			}},
+		}
+	}
+	machines := []*api.Machine{kept("v4", "app", "ord", 4), kept("worker", "worker", "ord", 3), kept("app", "app", "ord", 3), kept("ams", "app", "ams", 3)}
+
+	md := &machineDeployment{keptMachines: keptMachinesOfRelease(machines, 3)}
+	assert.Len(t, md.keptMachines, 3)
+
+	input := func(group, region string) *api.LaunchMachineInput {
+		return &api.LaunchMachineInput{
+			Region: region,
+			Config: &api.MachineConfig{Metadata: map[string]string{api.MachineConfigMetadataKeyFlyProcessGroup: group}},
+		}
+	}
+	assert.Equal(t, "app", md.takeKeptMachine(input("app", "ord")).ID)
+	// Each kept machine is only started once
+	assert.Nil(t, md.takeKeptMachine(input("app", "ord")))
+	// Kept machines have no volume to attach
+	withMount := input("app", "ams")
+	withMount.Config.Mounts = []api.MachineMount{{Volume: "vol_1", Path: "/data"}}
+	assert.Nil(t, md.takeKeptMachine(withMount))
+	assert.Equal(t, "ams", md.takeKeptMachine(input("app", "ams")).ID)
+	assert.Equal(t, "worker", md.takeKeptMachine(input("worker", "ord")).ID)
+	assert.Empty(t, md.keptMachines)
+}
@@ -11,7 +11,10 @@ import (
 )
 
 func (md *machineDeployment) launchInputForRestart(origMachineRaw *api.Machine) *api.LaunchMachineInput {
+	origMachineRaw = upgradeLegacyMachine(origMachineRaw)
 	Config := machine.CloneConfig(origMachineRaw.Config)
+	// Keep the machines resolving names as fly.toml says across restarts
+	Config.DNS = md.appConfig.ToMachineDNS(Config.DNS)
 	md.setMachineReleaseData(Config)
 
 	return &api.LaunchMachineInput{
@@ -23,34 +26,52 @@ func (md *machineDeployment) launchInputForRestart(origMachineRaw *api.Machine)
 	}
 }
 
-func (md *machineDeployment) launchInputForLaunch(processGroup string, guest *api.MachineGuest) (*api.LaunchMachineInput, error) {
+// launchInputForLaunch configures a new machine for processGroup in region, or in the primary region when empty
+func (md *machineDeployment) launchInputForLaunch(processGroup, region string, guest *api.MachineGuest) (*api.LaunchMachineInput, error) {
 	mConfig, err := md.appConfig.ToMachineConfig(processGroup, nil)
 	if err != nil {
 		return nil, err
 	}
 	mConfig.Guest = guest
 	mConfig.Image = md.img
+	if len(md.initCommand) > 0 {
+		mConfig.Init.Exec = md.initCommand
+	}
+	if mConfig, err = md.applyConfigOverride(mConfig); err != nil {
+		return nil, err
+	}
+	// --env-file and then --set-env come last, they win over fly.toml, --env and the config override
+	mConfig.Env = lo.Assign(mConfig.Env, md.envFileEnv, md.transientEnv)
 	md.setMachineReleaseData(mConfig)
 	// Get the final process group and prevent empty string
 	processGroup = mConfig.ProcessGroup()
 
-	if len(mConfig.Mounts) > 0 {
-		mount0 := &mConfig.Mounts[0]
-		if len(md.volumes[mount0.Name]) == 0 {
-			return nil, fmt.Errorf("New machine in group '%s' needs an unattached volume named '%s'", processGroup, mount0.Name)
+	if region == "" {
+		region = md.primaryRegion()
+	}
+	if err := checkGPURegion(mConfig.Guest, region); err != nil {
+		return nil, err
+	}
+	for i := range mConfig.Mounts {
+		mount := &mConfig.Mounts[i]
+		volume, ok := md.popVolume(mount.Name, region)
+		if !ok {
+			return nil, md.missingVolumeError("New machine", processGroup, *mount, region)
 		}
-		mount0.Volume = md.volumes[mount0.Name][0].ID
+		mount.Volume = volume.ID
 	}
 
 	return &api.LaunchMachineInput{
 		AppID:   md.app.Name,
+		Name:    md.machineName(processGroup, region),
 		OrgSlug: md.app.Organization.ID,
-		Region:  md.appConfig.PrimaryRegion,
+		Region:  region,
 		Config:  mConfig,
 	}, nil
 }
 
 func (md *machineDeployment) launchInputForUpdate(origMachineRaw *api.Machine) (*api.LaunchMachineInput, error) {
+	origMachineRaw = upgradeLegacyMachine(origMachineRaw)
 	mID := origMachineRaw.ID
 	processGroup := origMachineRaw.Config.ProcessGroup()
 
@@ -59,6 +80,14 @@ func (md *machineDeployment) launchInputForUpdate(origMachineRaw *api.Machine) (
 		return nil, err
 	}
 	mConfig.Image = md.img
+	if len(md.initCommand) > 0 {
+		mConfig.Init.Exec = md.initCommand
+	}
+	if mConfig, err = md.applyConfigOverride(mConfig); err != nil {
+		return nil, err
+	}
+	// --env-file and then --set-env come last, they win over fly.toml, --env and the config override
+	mConfig.Env = lo.Assign(mConfig.Env, md.envFileEnv, md.transientEnv)
 	md.setMachineReleaseData(mConfig)
 	// Get the final process group and prevent empty string
 	processGroup = mConfig.ProcessGroup()
@@ -72,9 +101,18 @@ func (md *machineDeployment) launchInputForUpdate(origMachineRaw *api.Machine) (
 	if len(oMounts) != 0 {
 		switch {
 		case len(mMounts) == 0:
-			// The mounts section was removed from fly.toml
+			// The mounts section was removed from fly.toml, replacing the machine leaves its volume
+			// and data behind. Require an explicit opt-in as it is rarely what was wanted.
+			if !md.detachVolumes {
+				return nil, fmt.Errorf(
+					"machine %s has volume '%s' (%s) attached but fly.toml doesn't mount it in group '%s'. "+
+						"Replacing the machine would detach the volume and leave its data behind, "+
+						"add the [mounts] section back or deploy with --detach-volumes",
+					mID, oMounts[0].Name, oMounts[0].Volume, processGroup)
+			}
+			terminal.Warnf("Machine %s has volume '%s' (%s) attached but fly.toml doesn't have a [mounts] section, replacing it and detaching the volume\n",
+				mID, oMounts[0].Name, oMounts[0].Volume)
 			mID = "" // Forces machine replacement
-			terminal.Warnf("Machine %s has a volume attached but fly.toml doesn't have a [mounts] section\n", mID)
 		case oMounts[0].Name == "":
 			// It's rare but can happen, we don't know the mounted volume name
 			// so can't be sure it matches the mounts defined in fly.toml, in this
@@ -85,10 +123,11 @@ func (md *machineDeployment) launchInputForUpdate(origMachineRaw *api.Machine) (
 			// As we can't change the volume for a running machine, the only
 			// way is to destroy the current machine and launch a new one with the new volume attached
 			terminal.Warnf("Machine %s has volume '%s' attached but fly.toml have a different name: '%s'\n", mID, oMounts[0].Name, mMounts[0].Name)
-			if len(md.volumes[mMounts[0].Name]) == 0 {
-				return nil, fmt.Errorf("machine in group '%s' needs an unattached volume named '%s'", processGroup, mMounts[0].Name)
+			volume, ok := md.popVolume(mMounts[0].Name, origMachineRaw.Region)
+			if !ok {
+				return nil, md.missingVolumeError("Machine "+mID, processGroup, mMounts[0], origMachineRaw.Region)
 			}
-			mMounts[0].Volume = md.volumes[mMounts[0].Name][0].ID
+			mMounts[0].Volume = volume.ID
 			mID = "" // Forces machine replacement
 		case mMounts[0].Path != oMounts[0].Path:
 			// The volume is the same but its mount path changed. Not a big deal.
@@ -106,11 +145,11 @@ func (md *machineDeployment) launchInputForUpdate(origMachineRaw *api.Machine) (
 		// Replace the machine because [mounts] section was added to fly.toml
 		// and it is not possible to attach a volume to an existing machine.
 		// The volume could be in a different zone than the machine.
-		mount0 := &mMounts[0]
-		if len(md.volumes[mount0.Name]) == 0 {
-			return nil, fmt.Errorf("machine in group '%s' needs an unattached volume named '%s'", processGroup, mMounts[0].Name)
+		volume, ok := md.popVolume(mMounts[0].Name, origMachineRaw.Region)
+		if !ok {
+			return nil, md.missingVolumeError("Machine "+mID, processGroup, mMounts[0], origMachineRaw.Region)
 		}
-		mount0.Volume = md.volumes[mount0.Name][0].ID
+		mMounts[0].Volume = volume.ID
 		mID = "" // Forces machine replacement
 	}
 
@@ -123,20 +162,50 @@ func (md *machineDeployment) launchInputForUpdate(origMachineRaw *api.Machine) (
 	}, nil
 }
 
+// popVolume takes an unattached volume named name, preferably one in region as the machine
+// mounting it runs where the volume is
+func (md *machineDeployment) popVolume(name, region string) (api.Volume, bool) {
+	volumes := md.volumes[name]
+	if len(volumes) == 0 {
+		return api.Volume{}, false
+	}
+	i := lo.IndexOf(lo.Map(volumes, func(v api.Volume, _ int) string { return v.Region }), region)
+	if i < 0 {
+		i = 0
+	}
+	volume := volumes[i]
+	md.volumes[name] = append(volumes[:i:i], volumes[i+1:]...)
+	return volume, true
+}
+
 func (md *machineDeployment) setMachineReleaseData(mConfig *api.MachineConfig) {
-	mConfig.Metadata = lo.Assign(mConfig.Metadata, map[string]string{
-		api.MachineConfigMetadataKeyFlyReleaseId:      md.releaseId,
-		api.MachineConfigMetadataKeyFlyReleaseVersion: strconv.Itoa(md.releaseVersion),
-	})
+	// Without a release, machines keep claiming the release they were on
+	if !md.noRelease {
+		mConfig.Metadata = lo.Assign(mConfig.Metadata, map[string]string{
+			api.MachineConfigMetadataKeyFlyReleaseId:      md.releaseId,
+			api.MachineConfigMetadataKeyFlyReleaseVersion: strconv.Itoa(md.releaseVersion),
+		})
+	}
+	// Releases deploying a previous release again note which one they come from
+	switch {
+	case md.fromReleaseVersion > 0:
+		mConfig.Metadata[api.MachineConfigMetadataKeyFlyReleaseSource] = strconv.Itoa(md.fromReleaseVersion)
+	case !md.restartOnly && !md.noRelease:
+		delete(mConfig.Metadata, api.MachineConfigMetadataKeyFlyReleaseSource)
+	}
 
-	// These defaults should come from appConfig.ToMachineConfig() and set on launch;
-	// leave them here for the moment becase very old machines may not have them
-	// and we want to set in case of simple app restarts
-	if _, ok := mConfig.Metadata[api.MachineConfigMetadataKeyFlyPlatformVersion]; !ok {
-		mConfig.Metadata[api.MachineConfigMetadataKeyFlyPlatformVersion] = api.MachineFlyPlatformVersion2
-	}
-	if _, ok := mConfig.Metadata[api.MachineConfigMetadataKeyFlyProcessGroup]; !ok {
-		mConfig.Metadata[api.MachineConfigMetadataKeyFlyProcessGroup] = api.MachineProcessGroupApp
+	// Don't keep a digest, tag or source hash that doesn't belong to the image being deployed
+	for key, value := range map[string]string{
+		api.MachineConfigMetadataKeyFlyImageDigest: md.imgDigest,
+		api.MachineConfigMetadataKeyFlyImageTag:    md.imgTag,
+		api.MachineConfigMetadataKeyFlySourceHash:  md.sourceHash,
+	} {
+		switch {
+		case value != "":
+			mConfig.Metadata[key] = value
+		case !md.restartOnly:
+			delete(mConfig.Metadata, key)
+		}
 	}
 
 	// FIXME: Move this as extra metadata read from a machineDeployment argument
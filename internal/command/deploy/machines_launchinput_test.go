@@ -1,13 +1,16 @@
 package deploy
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/superfly/flyctl/api"
 	"github.com/superfly/flyctl/helpers"
 	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/iostreams"
 )
 
 // Test the basic flow of launching, restarting and updating a machine for default process group
@@ -42,7 +45,7 @@ func Test_launchInputFor_Basic(t *testing.T) {
 			},
 		},
 	}
-	li, err := md.launchInputForLaunch("", nil)
+	li, err := md.launchInputForLaunch("", "", nil)
 	require.NoError(t, err)
 	assert.Equal(t, want, li)
 
@@ -89,11 +92,11 @@ func Test_launchInputFor_onMounts(t *testing.T) {
 	})
 	assert.NoError(t, err)
 	md.volumes = map[string][]api.Volume{
-		"data": {{ID: "vol_12345", Name: "data"}},
+		"data": {{ID: "vol_12345", Name: "data"}, {ID: "vol_67890", Name: "data"}},
 	}
 
 	// New machine must get a volume attached
-	li, err := md.launchInputForLaunch("", nil)
+	li, err := md.launchInputForLaunch("", "", nil)
 	require.NoError(t, err)
 	require.NotEmpty(t, li.Config.Mounts)
 	assert.Equal(t, api.MachineMount{Volume: "vol_12345", Path: "/data", Name: "data"}, li.Config.Mounts[0])
@@ -144,21 +147,55 @@ func Test_launchInputFor_onMounts(t *testing.T) {
 	require.NoError(t, err)
 	require.NotEmpty(t, li.Config.Mounts)
 	assert.Equal(t, "", li.ID)
-	assert.Equal(t, api.MachineMount{Volume: "vol_12345", Path: "/data", Name: "data"}, li.Config.Mounts[0])
+	// vol_12345 was taken by the new machine launched above
+	assert.Equal(t, api.MachineMount{Volume: "vol_67890", Path: "/data", Name: "data"}, li.Config.Mounts[0])
 
-	// Updating a machine with an attached volume should trigger a replacement if fly.toml doesn't define one.
+	// Updating a machine with an attached volume fails if fly.toml doesn't define one, it would detach the volume
 	md.appConfig.Mounts = nil
-	li, err = md.launchInputForUpdate(&api.Machine{
+	detached := &api.Machine{
 		ID: "ab1234567890",
 		Config: &api.MachineConfig{
 			Mounts: []api.MachineMount{{Volume: "vol_attached", Path: "/replace-me", Name: "replace-me"}},
 		},
-	})
+	}
+	_, err = md.launchInputForUpdate(detached)
+	assert.ErrorContains(t, err, "volume 'replace-me' (vol_attached)")
+	assert.ErrorContains(t, err, "--detach-volumes")
+
+	// ...unless detaching is confirmed, then it triggers a replacement
+	md.detachVolumes = true
+	li, err = md.launchInputForUpdate(detached)
 	require.NoError(t, err)
 	assert.Equal(t, "", li.ID)
 	assert.Empty(t, li.Config.Mounts)
 }
 
+// Test every mount of a new machine gets a volume of its own
+func Test_launchInputForLaunch_multipleMounts(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{
+		Mounts: []appconfig.Mount{
+			{Source: "data", Destination: "/data"},
+			{Source: "cache", Destination: "/cache"},
+		},
+	})
+	require.NoError(t, err)
+	md.volumes = map[string][]api.Volume{
+		"data":  {{ID: "vol_data1", Name: "data"}, {ID: "vol_data2", Name: "data"}},
+		"cache": {{ID: "vol_cache1", Name: "cache"}},
+	}
+
+	li, err := md.launchInputForLaunch("", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []api.MachineMount{
+		{Volume: "vol_data1", Path: "/data", Name: "data"},
+		{Volume: "vol_cache1", Path: "/cache", Name: "cache"},
+	}, li.Config.Mounts)
+
+	// The volumes were taken, a second machine can't get a cache volume
+	_, err = md.launchInputForLaunch("", "", nil)
+	assert.ErrorContains(t, err, "needs an unattached volume named 'cache' to mount at /cache")
+}
+
 // Test restart or updating a machine propagates fields not under fly.toml control
 func Test_launchInputForUpdate_keepUnmanagedFields(t *testing.T) {
 	md, err := stabMachineDeployment(&appconfig.Config{
@@ -210,3 +247,228 @@ func Test_launchInputForUpdate_keepUnmanagedFields(t *testing.T) {
 	assert.Equal(t, &api.DNSConfig{SkipRegistration: true}, li.Config.DNS)
 	assert.Equal(t, []api.MachineProcess{{CmdOverride: []string{"foo"}}}, li.Config.Processes)
 }
+
+// Test the resolved image digest is recorded on launched and updated machines
+func Test_launchInputFor_imageDigest(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	md.img = "super/balloon@sha256:1234"
+	md.imgDigest = "sha256:1234"
+
+	li, err := md.launchInputForLaunch("", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "super/balloon@sha256:1234", li.Config.Image)
+	assert.Equal(t, "sha256:1234", li.Config.Metadata["fly_image_digest"])
+
+	// A stale digest must be dropped when the new image couldn't be resolved
+	md.img = "super/globe"
+	md.imgDigest = ""
+	li, err = md.launchInputForUpdate(&api.Machine{
+		ID: "ab1234567890",
+		Config: &api.MachineConfig{
+			Metadata: map[string]string{"fly_image_digest": "sha256:1234"},
+		},
+	})
+	require.NoError(t, err)
+	assert.NotContains(t, li.Config.Metadata, "fly_image_digest")
+}
+
+// Test the command override is applied to app machines but not to release command machines
+func Test_launchInputFor_initCommand(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{
+		Deploy: &appconfig.Deploy{ReleaseCommand: "touch sky"},
+	})
+	require.NoError(t, err)
+	md.initCommand = []string{"sleep", "infinity"}
+
+	li, err := md.launchInputForLaunch("", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sleep", "infinity"}, li.Config.Init.Exec)
+
+	li, err = md.launchInputForUpdate(&api.Machine{ID: "ab1234567890", Config: &api.MachineConfig{}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sleep", "infinity"}, li.Config.Init.Exec)
+
+	li = md.launchInputForReleaseCommand(nil, md.appConfig.ReleaseCommands()[0])
+	assert.Empty(t, li.Config.Init.Exec)
+	assert.Equal(t, []string{"touch", "sky"}, li.Config.Init.Cmd)
+}
+
+// Test a restart policy set out of band (e.g. `fly machine update --restart on-failure`) survives
+// the updates and restarts applying the rest of the new config, only a config override changes it
+func Test_launchInputFor_keepRestartPolicy(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{
+		AppName: "my-cool-app",
+		Env:     map[string]string{"LOG_LEVEL": "debug"},
+	})
+	require.NoError(t, err)
+	md.releaseId = "release_id"
+	md.releaseVersion = 4
+
+	restart := api.MachineRestart{Policy: api.MachineRestartPolicyOnFailure, MaxRetries: 5}
+	origMachine := &api.Machine{
+		ID: "ab1234567890",
+		Config: &api.MachineConfig{
+			Env:      map[string]string{"LOG_LEVEL": "info"},
+			Restart:  restart,
+			Metadata: map[string]string{api.MachineConfigMetadataKeyFlyReleaseVersion: "3"},
+		},
+	}
+
+	li, err := md.launchInputForUpdate(origMachine)
+	require.NoError(t, err)
+	assert.Equal(t, "debug", li.Config.Env["LOG_LEVEL"])
+	assert.Equal(t, "4", li.Config.Metadata[api.MachineConfigMetadataKeyFlyReleaseVersion])
+	assert.Equal(t, restart, li.Config.Restart)
+
+	li = md.launchInputForRestart(origMachine)
+	assert.Equal(t, "4", li.Config.Metadata[api.MachineConfigMetadataKeyFlyReleaseVersion])
+	assert.Equal(t, restart, li.Config.Restart)
+
+	require.NoError(t, md.setConfigOverride(writeOverride(t, "override.json", `{"restart": {"policy": "always"}}`)))
+	li, err = md.launchInputForUpdate(origMachine)
+	require.NoError(t, err)
+	assert.Equal(t, api.MachineRestartPolicyAlways, li.Config.Restart.Policy)
+}
+
+// Test kill_signal and kill_timeout end up in the machine stop config
+func Test_launchInputFor_stopConfig(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{
+		AppName:     "my-cool-app",
+		KillSignal:  api.Pointer("SIGINT"),
+		KillTimeout: api.Pointer(30),
+	})
+	require.NoError(t, err)
+
+	want := &api.StopConfig{
+		Signal:  api.Pointer("SIGINT"),
+		Timeout: &api.Duration{Duration: 30 * time.Second},
+	}
+	li, err := md.launchInputForLaunch("", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, want, li.Config.StopConfig)
+
+	li, err = md.launchInputForUpdate(&api.Machine{
+		ID:     "ab1234567890",
+		Config: &api.MachineConfig{StopConfig: &api.StopConfig{Signal: api.Pointer("SIGTERM")}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, want, li.Config.StopConfig)
+}
+
+// Test fly.toml [dns] is set on launched, updated and restarted machines
+func Test_launchInputFor_DNS(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{
+		AppName: "my-cool-app",
+		DNS: &appconfig.DNS{
+			Searches: []string{"service.internal"},
+			Options:  []api.DNSOption{{Name: "ndots", Value: "2"}},
+		},
+	})
+	require.NoError(t, err)
+	want := &api.DNSConfig{
+		Searches: []string{"service.internal"},
+		Options:  []api.DNSOption{{Name: "ndots", Value: "2"}},
+	}
+
+	li, err := md.launchInputForLaunch("", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, want, li.Config.DNS)
+
+	li, err = md.launchInputForUpdate(&api.Machine{ID: "ab1234567890", Config: &api.MachineConfig{}})
+	require.NoError(t, err)
+	assert.Equal(t, want, li.Config.DNS)
+
+	li = md.launchInputForRestart(&api.Machine{ID: "ab1234567890", Config: &api.MachineConfig{}})
+	assert.Equal(t, want, li.Config.DNS)
+
+	// Removing [dns] from fly.toml removes the settings it owns
+	md.appConfig.DNS = nil
+	li, err = md.launchInputForUpdate(&api.Machine{ID: "ab1234567890", Config: &api.MachineConfig{DNS: want}})
+	require.NoError(t, err)
+	assert.Nil(t, li.Config.DNS)
+}
+
+func Test_launchInputFor_checkHeaders(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{
+		AppName: "my-cool-app",
+		Checks: map[string]*appconfig.ToplevelCheck{
+			"status": {
+				Type:     api.Pointer("http"),
+				Port:     api.Pointer(8080),
+				HTTPPath: api.Pointer("/status"),
+				HTTPHeaders: map[string]string{
+					"X-Tenant":      "checks",
+					"Authorization": "Bearer secret",
+				},
+			},
+		},
+		Services: []appconfig.Service{{
+			Protocol:     "tcp",
+			InternalPort: 8080,
+			HTTPChecks: []*appconfig.ServiceHTTPCheck{{
+				HTTPPath:    api.Pointer("/health"),
+				HTTPHeaders: map[string]string{"Authorization": "Bearer secret"},
+			}},
+		}},
+	})
+	require.NoError(t, err)
+
+	li, err := md.launchInputForLaunch("", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []api.MachineHTTPHeader{
+		{Name: "Authorization", Values: []string{"Bearer secret"}},
+		{Name: "X-Tenant", Values: []string{"checks"}},
+	}, li.Config.Checks["status"].HTTPHeaders)
+	require.Len(t, li.Config.Services, 1)
+	require.Len(t, li.Config.Services[0].Checks, 1)
+	assert.Equal(t, []api.MachineHTTPHeader{
+		{Name: "Authorization", Values: []string{"Bearer secret"}},
+	}, li.Config.Services[0].Checks[0].HTTPHeaders)
+}
+
+func Test_launchInputFor_transientEnv(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{
+		Env: map[string]string{"LOG_LEVEL": "info", "OTHER": "value"},
+	})
+	require.NoError(t, err)
+	ios, _, _, _ := iostreams.Test()
+	md.io = ios
+	md.alertOut = ios.ErrOut
+	md.colorize = ios.ColorScheme()
+
+	assert.Error(t, md.setTransientEnv([]string{"NOVALUE"}))
+	require.NoError(t, md.setTransientEnv([]string{"LOG_LEVEL=debug", "TRACE=1"}))
+
+	li, err := md.launchInputForLaunch("", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "debug", li.Config.Env["LOG_LEVEL"])
+	assert.Equal(t, "1", li.Config.Env["TRACE"])
+	assert.Equal(t, "value", li.Config.Env["OTHER"])
+
+	// The next deploy without --set-env drops them
+	md.transientEnv = nil
+	li, err = md.launchInputForUpdate(&api.Machine{ID: "ab1234567890", Config: li.Config})
+	require.NoError(t, err)
+	assert.Equal(t, "info", li.Config.Env["LOG_LEVEL"])
+	assert.NotContains(t, li.Config.Env, "TRACE")
+}
+
+// Test machines updated without a release keep claiming the release they were on
+func Test_setMachineReleaseData_noRelease(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{AppName: "my-cool-app"})
+	require.NoError(t, err)
+	md.noRelease = true
+
+	mConfig := &api.MachineConfig{Metadata: map[string]string{
+		api.MachineConfigMetadataKeyFlyReleaseId:      "release_42",
+		api.MachineConfigMetadataKeyFlyReleaseVersion: "42",
+		api.MachineConfigMetadataKeyFlyReleaseSource:  "40",
+	}}
+	md.setMachineReleaseData(mConfig)
+	assert.Equal(t, "release_42", mConfig.Metadata[api.MachineConfigMetadataKeyFlyReleaseId])
+	assert.Equal(t, "42", mConfig.Metadata[api.MachineConfigMetadataKeyFlyReleaseVersion])
+	assert.Equal(t, "40", mConfig.Metadata[api.MachineConfigMetadataKeyFlyReleaseSource])
+
+	assert.NoError(t, md.updateReleaseInBackend(context.Background(), "complete"))
+}
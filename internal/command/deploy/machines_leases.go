@@ -0,0 +1,182 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/flaps"
+	"github.com/superfly/flyctl/terminal"
+)
+
+// leaseExpiryGrace is how long after a held lease expires it is looked at again, leaving its
+// session the time to refresh it right at the expiry
+var leaseExpiryGrace = 2 * time.Second
+
+// heldLease is a lease on one of the app machines that this deploy doesn't hold
+type heldLease struct {
+	machineID string
+	lease     *api.MachineLeaseData
+}
+
+// acquireLeasesOrClearStale runs acquire and, when it fails, looks at who holds the machine leases.
+// Leases nobody refreshes anymore were left behind by a deploy that is gone, they are cleared
+// with --force-lease and acquire runs once more.
+func (md *machineDeployment) acquireLeasesOrClearStale(ctx context.Context, acquire func() error) error {
+	err := acquire()
+	if err == nil {
+		return nil
+	}
+
+	before, inspectErr := md.findHeldLeases(ctx)
+	if inspectErr != nil {
+		terminal.Debugf("failed to inspect machine leases: %v\n", inspectErr)
+		return err
+	}
+	if len(before) == 0 {
+		return err
+	}
+
+	// A live session refreshes its lease before the lease expires, whatever --lease-timeout it uses,
+	// so a lease still unchanged past its own expiry was left behind
+	if delay := staleLeaseCheckDelay(before, time.Now()); delay > 0 {
+		fmt.Fprintf(md.io.ErrOut, "Machines %s are leased (%s), waiting %s for the leases to expire or be refreshed\n",
+			leasedMachineIDs(sortedLeases(before)), leaseOwners(sortedLeases(before)), delay.Round(time.Second))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+	after, inspectErr := md.findHeldLeases(ctx)
+	if inspectErr != nil {
+		terminal.Debugf("failed to inspect machine leases: %v\n", inspectErr)
+		return err
+	}
+
+	stale, active := classifyLeases(before, after, time.Now())
+	switch {
+	case len(active) > 0:
+		return fmt.Errorf("machines %s are leased by a deploy that is still running (%s), wait for it to finish or cancel it with 'fly deploy --cancel': %w", leasedMachineIDs(active), leaseOwners(active), err)
+	case len(stale) == 0:
+		// The leases were released in the meantime
+		return acquire()
+	case !md.forceLease:
+		return fmt.Errorf("machines %s are leased by a deploy that is no longer running (%s), run the deploy again with --force-lease to clear the leases: %w", leasedMachineIDs(stale), leaseOwners(stale), err)
+	}
+
+	for _, l := range stale {
+		md.warnf("Clearing stale lease on machine %s held by %s\n", l.machineID, leaseOwner(l))
+		if err := md.flapsClient.ReleaseLease(ctx, l.machineID, l.lease.Nonce); err != nil {
+			return fmt.Errorf("failed to clear stale lease on machine %s: %w", l.machineID, err)
+		}
+	}
+	return acquire()
+}
+
+// findHeldLeases lists the leases on the app machines this deploy doesn't hold itself
+func (md *machineDeployment) findHeldLeases(ctx context.Context) (map[string]heldLease, error) {
+	held := map[string]heldLease{}
+	for _, lm := range md.machineSet.GetMachines() {
+		if lm.HasLease() {
+			continue
+		}
+		id := lm.Machine().ID
+		lease, err := md.flapsClient.FindLease(ctx, id)
+		if err != nil {
+			var flapsErr *flaps.FlapsError
+			if errors.As(err, &flapsErr) && flapsErr.ResponseStatusCode == http.StatusNotFound {
+				continue
+			}
+			return nil, err
+		}
+		if lease == nil || lease.Data == nil {
+			continue
+		}
+		held[id] = heldLease{machineID: id, lease: lease.Data}
+	}
+	return held, nil
+}
+
+// classifyLeases compares the leases seen before and after they expired. A lease that expired,
+// or wasn't refreshed since, is stale. One that was refreshed or taken over by another
+// nonce belongs to a live session. Leases released meanwhile are in neither list.
+func classifyLeases(before, after map[string]heldLease, now time.Time) (stale, active []heldLease) {
+	for id, b := range before {
+		a, ok := after[id]
+		switch {
+		case !ok:
+			continue
+		case a.lease.Nonce != b.lease.Nonce:
+			active = append(active, a)
+		case a.lease.ExpiresAt > b.lease.ExpiresAt && a.lease.ExpiresAt > now.Unix():
+			active = append(active, a)
+		default:
+			stale = append(stale, a)
+		}
+	}
+	byMachineID := func(leases []heldLease) {
+		sort.Slice(leases, func(i, j int) bool { return leases[i].machineID < leases[j].machineID })
+	}
+	byMachineID(stale)
+	byMachineID(active)
+	return stale, active
+}
+
+// staleLeaseCheckDelay is how long to wait for the latest expiry of leases, and the grace after it,
+// before telling stale leases from refreshed ones. It is zero when all of them already expired.
+func staleLeaseCheckDelay(leases map[string]heldLease, now time.Time) time.Duration {
+	var latest int64
+	for _, l := range leases {
+		if l.lease.ExpiresAt > latest {
+			latest = l.lease.ExpiresAt
+		}
+	}
+	if latest <= now.Unix() {
+		return 0
+	}
+	return time.Unix(latest, 0).Sub(now) + leaseExpiryGrace
+}
+
+func sortedLeases(leases map[string]heldLease) []heldLease {
+	sorted := make([]heldLease, 0, len(leases))
+	for _, l := range leases {
+		sorted = append(sorted, l)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].machineID < sorted[j].machineID })
+	return sorted
+}
+
+func leasedMachineIDs(leases []heldLease) string {
+	ids := make([]string, 0, len(leases))
+	for _, l := range leases {
+		ids = append(ids, l.machineID)
+	}
+	return strings.Join(ids, ", ")
+}
+
+// leaseOwners lists the distinct owners of leases, in order
+func leaseOwners(leases []heldLease) string {
+	var owners []string
+	seen := map[string]bool{}
+	for _, l := range leases {
+		owner := leaseOwner(l)
+		if !seen[owner] {
+			seen[owner] = true
+			owners = append(owners, owner)
+		}
+	}
+	return "held by " + strings.Join(owners, ", ")
+}
+
+func leaseOwner(l heldLease) string {
+	if l.lease.Owner == "" {
+		return "an unknown owner"
+	}
+	return l.lease.Owner
+}
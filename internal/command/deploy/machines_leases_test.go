@@ -0,0 +1,68 @@
+package deploy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/superfly/flyctl/api"
+)
+
+func testLease(id, nonce, owner string, expiresAt int64) heldLease {
+	return heldLease{machineID: id, lease: &api.MachineLeaseData{Nonce: nonce, Owner: owner, ExpiresAt: expiresAt}}
+}
+
+func Test_classifyLeases(t *testing.T) {
+	now := time.Unix(1000, 0)
+	before := map[string]heldLease{
+		"m1": testLease("m1", "n1", "alice@example.com", 1005),
+		"m2": testLease("m2", "n2", "bob@example.com", 1005),
+		"m3": testLease("m3", "n3", "bob@example.com", 1005),
+		"m4": testLease("m4", "n4", "bob@example.com", 990),
+		"m5": testLease("m5", "n5", "bob@example.com", 1005),
+	}
+	after := map[string]heldLease{
+		// refreshed by its deploy
+		"m1": testLease("m1", "n1", "alice@example.com", 1013),
+		// nobody refreshed it
+		"m2": testLease("m2", "n2", "bob@example.com", 1005),
+		// released and leased again by another session
+		"m3": testLease("m3", "other", "carol@example.com", 1013),
+		// expired before it was looked at
+		"m4": testLease("m4", "n4", "bob@example.com", 990),
+		// m5 was released meanwhile
+	}
+
+	stale, active := classifyLeases(before, after, now)
+	assert.Equal(t, []heldLease{after["m2"], after["m4"]}, stale)
+	assert.Equal(t, []heldLease{after["m1"], after["m3"]}, active)
+	assert.Equal(t, "m2, m4", leasedMachineIDs(stale))
+	assert.Equal(t, "held by alice@example.com, carol@example.com", leaseOwners(active))
+}
+
+func Test_staleLeaseCheckDelay(t *testing.T) {
+	now := time.Unix(1000, 0)
+	assert.Zero(t, staleLeaseCheckDelay(map[string]heldLease{"m1": testLease("m1", "n1", "", 990)}, now))
+	// Leases of a long --lease-timeout are watched until they expire
+	assert.Equal(t, 3600*time.Second+leaseExpiryGrace, staleLeaseCheckDelay(map[string]heldLease{
+		"m1": testLease("m1", "n1", "", 990),
+		"m2": testLease("m2", "n2", "", 4600),
+		"m3": testLease("m3", "n3", "", 1010),
+	}, now))
+}
+
+func Test_leaseOwners_unknownOwner(t *testing.T) {
+	assert.Equal(t, "held by an unknown owner", leaseOwners([]heldLease{testLease("m1", "n1", "", 990)}))
+}
+
+func Test_acquireLeasesOrClearStale_noInspectionOnSuccess(t *testing.T) {
+	md := &machineDeployment{}
+	calls := 0
+	err := md.acquireLeasesOrClearStale(context.Background(), func() error {
+		calls++
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,41 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/superfly/flyctl/internal/filemu"
+)
+
+// localDeployLockPath is the file locked by the deploys of appName running on this host
+func localDeployLockPath(appName string) string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("flyctl.deploy.%s.lock", appName))
+}
+
+// lockLocalDeploy makes a second deploy of the same app from this host fail right away, instead of
+// racing the first one for the machine leases. The OS releases the lock when the process holding it
+// exits, so a crashed deploy never leaves a stale lock behind.
+func lockLocalDeploy(ctx context.Context, appName string) (filemu.UnlockFunc, error) {
+	path := localDeployLockPath(appName)
+	unlock, err := filemu.Lock(ctx, path)
+	switch {
+	case err == nil:
+		// Recorded to tell the deploys finding the lock taken which process holds it
+		_ = os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())), 0o600)
+		return unlock, nil
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	}
+
+	holder := "another flyctl process"
+	if data, err := os.ReadFile(path); err == nil {
+		if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+			holder = fmt.Sprintf("flyctl process %d", pid)
+		}
+	}
+	return nil, fmt.Errorf("%s is already deploying app %s from this host, wait for it to finish before deploying again", holder, appName)
+}
@@ -0,0 +1,34 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_lockLocalDeploy(t *testing.T) {
+	ctx := context.Background()
+	appName := fmt.Sprintf("test-app-%d", os.Getpid())
+	defer os.Remove(localDeployLockPath(appName))
+
+	unlock, err := lockLocalDeploy(ctx, appName)
+	require.NoError(t, err)
+
+	_, err = lockLocalDeploy(ctx, appName)
+	assert.ErrorContains(t, err, fmt.Sprintf("flyctl process %d is already deploying app %s", os.Getpid(), appName))
+
+	// Other apps can be deployed at the same time
+	unlockOther, err := lockLocalDeploy(ctx, appName+"-other")
+	require.NoError(t, err)
+	require.NoError(t, unlockOther())
+	os.Remove(localDeployLockPath(appName + "-other"))
+
+	require.NoError(t, unlock())
+	unlock, err = lockLocalDeploy(ctx, appName)
+	require.NoError(t, err)
+	require.NoError(t, unlock())
+}
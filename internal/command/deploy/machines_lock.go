@@ -0,0 +1,55 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samber/lo"
+	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/terminal"
+	"golang.org/x/exp/slices"
+)
+
+// acquireDeployLock takes an advisory app lock before the release is marked as running,
+// so concurrent deploys fail fast instead of stomping on each other's releases.
+// The lock is the lease of the machine with the lowest ID, which every deploy agrees on,
+// and it is kept when the rest of the machines are leased later on.
+func (md *machineDeployment) acquireDeployLock(ctx context.Context) error {
+	if md.machineSet.IsEmpty() {
+		return nil
+	}
+	machines := slices.Clone(md.machineSet.GetMachines())
+	slices.SortFunc(machines, func(a, b machine.LeasableMachine) bool {
+		return a.Machine().ID < b.Machine().ID
+	})
+	lockMachine := machines[0]
+
+	if err := lockMachine.AcquireLease(ctx, md.leaseTimeout); err != nil {
+		return fmt.Errorf("another deploy is in progress for app %s, could not lease machine %s: %w", md.app.Name, lockMachine.Machine().ID, err)
+	}
+	lockMachine.StartBackgroundLeaseRefresh(ctx, md.leaseTimeout, md.leaseDelayBetween)
+	md.deployLock = lockMachine
+	return nil
+}
+
+// releaseDeployLock releases the advisory app lock, it is a noop if it was already released
+func (md *machineDeployment) releaseDeployLock(ctx context.Context) {
+	if md.deployLock == nil {
+		return
+	}
+	if err := md.deployLock.ReleaseLease(ctx); err != nil {
+		terminal.Warnf("failed to release deploy lock: %v\n", err)
+	}
+	md.deployLock = nil
+}
+
+// leasedMachines returns the machines of the deploy but the one holding the deploy lock. Its lease
+// is refreshed and released on its own, the lock is held until the final release status is recorded.
+func (md *machineDeployment) leasedMachines() machine.MachineSet {
+	if md.deployLock == nil {
+		return md.machineSet
+	}
+	return machine.NewMachineSetOf(lo.Filter(md.machineSet.GetMachines(), func(lm machine.LeasableMachine, _ int) bool {
+		return lm != md.deployLock
+	}))
+}
@@ -0,0 +1,29 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func Test_leasedMachines_leavesDeployLockOut(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	ios, _, _, _ := iostreams.Test()
+	md.machineSet = machine.NewMachineSet(nil, ios, []*api.Machine{
+		groupMachine("m1", "app", "ord"),
+		groupMachine("m2", "app", "ord"),
+		groupMachine("m3", "app", "ord"),
+	})
+	assert.Same(t, md.machineSet, md.leasedMachines())
+
+	// The lock lease outlives the ones of the machines, released before the final release status
+	machines := md.machineSet.GetMachines()
+	md.deployLock = machines[0]
+	assert.Equal(t, machines[1:], md.leasedMachines().GetMachines())
+}
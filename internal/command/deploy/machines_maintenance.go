@@ -0,0 +1,210 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/helpers"
+	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/terminal"
+)
+
+const (
+	// maintenanceProcessGroup is the process group of the machine serving the maintenance page.
+	// It isn't in fly.toml, so the next deploy destroys the machine if a deploy failed to.
+	maintenanceProcessGroup = "fly_app_maintenance"
+	maintenanceImage        = "nginx:stable-alpine"
+	maintenancePort         = 8080
+	maintenanceDestroyWait  = time.Minute
+	// maintenanceDefaultTTL is how long the maintenance page machine lives without --deploy-timeout,
+	// it destroys itself afterwards in case the deploy couldn't
+	maintenanceDefaultTTL = time.Hour
+)
+
+// maintenanceNginxConf answers every request with the maintenance page and a 503 status
+var maintenanceNginxConf = fmt.Sprintf(`server {
+    listen %[1]d;
+    listen [::]:%[1]d;
+    root /usr/share/nginx/html;
+    error_page 503 /maintenance.html;
+    location = /maintenance.html { internal; }
+    location / { return 503; }
+}
+`, maintenancePort)
+
+const maintenanceScript = `printf '%s' "$MAINTENANCE_HTML" > /usr/share/nginx/html/maintenance.html && ` +
+	`printf '%s' "$MAINTENANCE_NGINX_CONF" > /etc/nginx/conf.d/default.conf && ` +
+	`exec timeout -s TERM "$MAINTENANCE_TTL" nginx -g 'daemon off;'`
+
+// setMaintenancePage loads the page set by [deploy] maintenance_page, its path is relative to fly.toml
+func (md *machineDeployment) setMaintenancePage(enabled bool) error {
+	if !enabled {
+		return nil
+	}
+	if md.appConfig.Deploy == nil || md.appConfig.Deploy.MaintenancePage == "" {
+		return fmt.Errorf("--maintenance-page needs the path of the page to serve in the [deploy] maintenance_page of fly.toml")
+	}
+	path := md.appConfig.Deploy.MaintenancePage
+	if configPath := md.appConfig.ConfigFilePath(); !filepath.IsAbs(path) && configPath != "" {
+		path = filepath.Join(filepath.Dir(configPath), path)
+	}
+	html, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed reading the maintenance page: %w", err)
+	}
+	md.maintenanceHTML = string(html)
+	return nil
+}
+
+// startMaintenancePage launches a machine answering on the public HTTP services of the app with
+// the maintenance page, and cordons the app machines so the proxy routes every request to it
+// while they're updated. The returned func uncordons the app machines and destroys the maintenance
+// machine, it must be called whether the deploy succeeded or not. Should flyctl be killed before,
+// the maintenance machine destroys itself after its TTL and the next deploy uncordons the app
+// machines, see uncordonAfterMaintenance.
+func (md *machineDeployment) startMaintenancePage(ctx context.Context) (stop func(), err error) {
+	launchInput, err := md.launchInputForMaintenance()
+	if err != nil {
+		return nil, err
+	}
+	if len(launchInput.Config.Services) == 0 {
+		md.warnf("No public HTTP service to serve the maintenance page on, deploying without it\n")
+		return func() {}, nil
+	}
+
+	fmt.Fprintf(md.io.ErrOut, "Starting the maintenance page\n")
+	newMachine, err := md.flapsClient.Launch(ctx, *launchInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to launch the maintenance page machine: %w", err)
+	}
+	var cordoned []machine.LeasableMachine
+	stop = func() {
+		ctx, cancel := context.WithTimeout(context.Background(), maintenanceDestroyWait)
+		defer cancel()
+		fmt.Fprintf(md.io.ErrOut, "Removing the maintenance page\n")
+		// The app machines get requests back before the maintenance page goes away
+		for _, lm := range cordoned {
+			if err := lm.Uncordon(ctx); err != nil {
+				terminal.Warnf("failed to put machine %s back in the proxy routing, the next deploy does it: %v\n", lm.Machine().ID, err)
+			}
+		}
+		if err := md.flapsClient.Destroy(ctx, api.RemoveMachineInput{ID: newMachine.ID, Kill: true}, ""); err != nil {
+			terminal.Warnf("failed to destroy the maintenance page machine %s, the next deploy destroys it or run `fly machine destroy --force %s`: %v\n",
+				newMachine.ID, newMachine.ID, err)
+		}
+	}
+
+	lm := machine.NewLeasableMachine(md.flapsClient, md.io, newMachine)
+	if err := lm.WaitForState(ctx, api.MachineStateStarted, md.newMachineWaitTimeout, ""); err != nil {
+		stop()
+		return nil, fmt.Errorf("the maintenance page machine didn't start: %w", err)
+	}
+	for _, appMachine := range md.machineSet.GetMachines() {
+		if err := appMachine.Cordon(ctx); err != nil {
+			stop()
+			return nil, fmt.Errorf("failed to take machine %s out of the proxy routing for the maintenance page: %w", appMachine.Machine().ID, err)
+		}
+		cordoned = append(cordoned, appMachine)
+	}
+	fmt.Fprintf(md.io.ErrOut, "  Maintenance page served by machine %s\n", lm.FormattedMachineId())
+	return stop, nil
+}
+
+// uncordonAfterMaintenance puts the app machines back in the proxy routing when a maintenance page
+// machine is left by a deploy that couldn't remove it, flyctl was killed before it could uncordon them
+func (md *machineDeployment) uncordonAfterMaintenance(ctx context.Context) {
+	machines := md.machineSet.GetMachines()
+	if !lo.ContainsBy(machines, func(lm machine.LeasableMachine) bool { return lm.Machine().ProcessGroup() == maintenanceProcessGroup }) {
+		return
+	}
+	fmt.Fprintf(md.io.ErrOut, "Found the maintenance page of a previous deploy, putting the app machines back in the proxy routing\n")
+	for _, lm := range machines {
+		if lm.Machine().ProcessGroup() == maintenanceProcessGroup {
+			continue
+		}
+		if err := lm.Uncordon(ctx); err != nil {
+			terminal.Warnf("failed to put machine %s back in the proxy routing: %v\n", lm.Machine().ID, err)
+		}
+	}
+}
+
+// maintenanceTTL is how long the maintenance page machine lives at most, the deploy timeout when set
+func (md *machineDeployment) maintenanceTTL() time.Duration {
+	if md.deployTimeout > 0 {
+		return md.deployTimeout + maintenanceDestroyWait
+	}
+	return maintenanceDefaultTTL
+}
+
+// launchInputForMaintenance configures the machine serving the maintenance page in the primary
+// region, with the HTTP services of the app pointed to nginx. nginx exits after maintenanceTTL
+// and the machine is destroyed once it did.
+func (md *machineDeployment) launchInputForMaintenance() (*api.LaunchMachineInput, error) {
+	var services []api.MachineService
+	seen := map[string]bool{}
+	for _, group := range md.appConfig.ProcessNames() {
+		mConfig, err := md.appConfig.ToMachineConfig(group, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range mConfig.Services {
+			if !isHTTPService(s) {
+				continue
+			}
+			key := fmt.Sprintf("%s %v", s.Protocol, lo.Map(s.Ports, func(p api.MachinePort, _ int) string {
+				return fmt.Sprint(lo.FromPtr(p.Port), lo.FromPtr(p.StartPort), lo.FromPtr(p.EndPort))
+			}))
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			s.InternalPort = maintenancePort
+			s.Checks = nil
+			s.Autostop = api.Pointer(false)
+			services = append(services, s)
+		}
+	}
+
+	guest := helpers.Clone(api.MachinePresets["shared-cpu-1x"])
+	return &api.LaunchMachineInput{
+		AppID:   md.app.Name,
+		OrgSlug: md.app.Organization.ID,
+		Region:  md.primaryRegion(),
+		Config: &api.MachineConfig{
+			Image: maintenanceImage,
+			Init: api.MachineInit{
+				Entrypoint: []string{"/bin/sh", "-c"},
+				Cmd:        []string{maintenanceScript},
+			},
+			Env: map[string]string{
+				"MAINTENANCE_HTML":       md.maintenanceHTML,
+				"MAINTENANCE_NGINX_CONF": maintenanceNginxConf,
+				"MAINTENANCE_TTL":        strconv.Itoa(int(md.maintenanceTTL().Seconds())),
+			},
+			AutoDestroy: true,
+			Restart:     api.MachineRestart{Policy: api.MachineRestartPolicyNo},
+			Guest:       guest,
+			Services:    services,
+			Metadata: map[string]string{
+				api.MachineConfigMetadataKeyFlyPlatformVersion: api.MachineFlyPlatformVersion2,
+				api.MachineConfigMetadataKeyFlyProcessGroup:    maintenanceProcessGroup,
+			},
+		},
+	}, nil
+}
+
+// isHTTPService tells if a service has a port handled as HTTP by the proxy
+func isHTTPService(s api.MachineService) bool {
+	for _, p := range s.Ports {
+		if lo.Contains(p.Handlers, "http") {
+			return true
+		}
+	}
+	return false
+}
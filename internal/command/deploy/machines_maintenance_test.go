@@ -0,0 +1,111 @@
+package deploy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func Test_setMaintenancePage(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	require.NoError(t, md.setMaintenancePage(false))
+	assert.ErrorContains(t, md.setMaintenancePage(true), "[deploy] maintenance_page")
+
+	path := filepath.Join(t.TempDir(), "maintenance.html")
+	require.NoError(t, os.WriteFile(path, []byte("<h1>Back soon</h1>"), 0o600))
+	md.appConfig.Deploy = &appconfig.Deploy{MaintenancePage: path}
+	require.NoError(t, md.setMaintenancePage(true))
+	assert.Equal(t, "<h1>Back soon</h1>", md.maintenanceHTML)
+}
+
+func Test_launchInputForMaintenance(t *testing.T) {
+	cfg := &appconfig.Config{
+		AppName:       "my-cool-app",
+		PrimaryRegion: "ord",
+		Processes:     map[string]string{"web": "run web", "worker": "run worker"},
+		HTTPService: &appconfig.HTTPService{
+			InternalPort: 3000,
+			Processes:    []string{"web"},
+		},
+		Services: []appconfig.Service{{
+			Protocol:     "tcp",
+			InternalPort: 5432,
+			Ports:        []api.MachinePort{{Port: lo.ToPtr(5432)}},
+			Processes:    []string{"worker"},
+		}},
+	}
+	require.NoError(t, cfg.SetMachinesPlatform())
+	md, err := stabMachineDeployment(cfg)
+	require.NoError(t, err)
+	md.maintenanceHTML = "<h1>Back soon</h1>"
+
+	li, err := md.launchInputForMaintenance()
+	require.NoError(t, err)
+	assert.Equal(t, "ord", li.Region)
+	assert.Equal(t, maintenanceImage, li.Config.Image)
+	assert.Equal(t, maintenanceProcessGroup, li.Config.ProcessGroup())
+	assert.Equal(t, "<h1>Back soon</h1>", li.Config.Env["MAINTENANCE_HTML"])
+	// Only the HTTP service is answered, by nginx
+	require.Len(t, li.Config.Services, 1)
+	assert.Equal(t, maintenancePort, li.Config.Services[0].InternalPort)
+	assert.Empty(t, li.Config.Services[0].Checks)
+	// The machine destroys itself after its TTL if the deploy couldn't
+	assert.True(t, li.Config.AutoDestroy)
+	assert.Equal(t, api.MachineRestartPolicyNo, li.Config.Restart.Policy)
+	assert.Equal(t, "3600", li.Config.Env["MAINTENANCE_TTL"])
+	md.deployTimeout = 10 * time.Minute
+	li, err = md.launchInputForMaintenance()
+	require.NoError(t, err)
+	assert.Equal(t, "660", li.Config.Env["MAINTENANCE_TTL"])
+}
+
+// cordonedMachine fakes a machine recording when it's taken out of the proxy routing and put back
+type cordonedMachine struct {
+	machine.LeasableMachine
+	m     *api.Machine
+	calls []string
+}
+
+func (c *cordonedMachine) Machine() *api.Machine { return c.m }
+
+func (c *cordonedMachine) Uncordon(context.Context) error {
+	c.calls = append(c.calls, "uncordon")
+	return nil
+}
+
+// fixedMachineSet is a machine set of fake machines
+type fixedMachineSet struct {
+	machine.MachineSet
+	machines []machine.LeasableMachine
+}
+
+func (s *fixedMachineSet) GetMachines() []machine.LeasableMachine { return s.machines }
+
+func Test_uncordonAfterMaintenance(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	ios, _, _, _ := iostreams.Test()
+	md.io = ios
+	app := &cordonedMachine{m: groupMachine("m1", "app", "ord")}
+	md.machineSet = &fixedMachineSet{machines: []machine.LeasableMachine{app}}
+
+	md.uncordonAfterMaintenance(context.Background())
+	assert.Empty(t, app.calls)
+
+	leftover := &cordonedMachine{m: groupMachine("m2", maintenanceProcessGroup, "ord")}
+	md.machineSet = &fixedMachineSet{machines: []machine.LeasableMachine{app, leftover}}
+	md.uncordonAfterMaintenance(context.Background())
+	assert.Equal(t, []string{"uncordon"}, app.calls)
+	assert.Empty(t, leftover.calls)
+}
@@ -0,0 +1,106 @@
+package deploy
+
+import (
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/terminal"
+)
+
+// legacyMetadataKeyProcessGroup is what fly_process_group was called by early machines apps
+const legacyMetadataKeyProcessGroup = "process_group"
+
+// machineConfigMigration upgrades one legacy shape of machine config to the current schema
+type machineConfigMigration struct {
+	description string
+	applies     func(*api.MachineConfig) bool
+	migrate     func(*api.MachineConfig)
+}
+
+// machineConfigMigrations are applied in order to the config of machines being updated or
+// restarted, machines created by old flyctl versions or out of band lack what deploys rely on
+var machineConfigMigrations = []machineConfigMigration{
+	{
+		description: "add the missing metadata",
+		applies:     func(c *api.MachineConfig) bool { return c.Metadata == nil },
+		migrate:     func(c *api.MachineConfig) { c.Metadata = map[string]string{} },
+	},
+	{
+		description: "rename the process_group metadata to fly_process_group",
+		applies: func(c *api.MachineConfig) bool {
+			_, ok := c.Metadata[legacyMetadataKeyProcessGroup]
+			return ok
+		},
+		migrate: func(c *api.MachineConfig) {
+			if c.Metadata[api.MachineConfigMetadataKeyFlyProcessGroup] == "" {
+				c.Metadata[api.MachineConfigMetadataKeyFlyProcessGroup] = c.Metadata[legacyMetadataKeyProcessGroup]
+			}
+			delete(c.Metadata, legacyMetadataKeyProcessGroup)
+		},
+	},
+	{
+		description: "mark it as a platform v2 machine",
+		applies: func(c *api.MachineConfig) bool {
+			return c.Metadata[api.MachineConfigMetadataKeyFlyPlatformVersion] == ""
+		},
+		migrate: func(c *api.MachineConfig) {
+			c.Metadata[api.MachineConfigMetadataKeyFlyPlatformVersion] = api.MachineFlyPlatformVersion2
+		},
+	},
+	{
+		description: "put it in the app process group",
+		applies: func(c *api.MachineConfig) bool {
+			return c.Metadata[api.MachineConfigMetadataKeyFlyProcessGroup] == ""
+		},
+		migrate: func(c *api.MachineConfig) {
+			c.Metadata[api.MachineConfigMetadataKeyFlyProcessGroup] = api.MachineProcessGroupApp
+		},
+	},
+	{
+		description: "set the protocol of services without one to tcp",
+		applies: func(c *api.MachineConfig) bool {
+			for _, s := range c.Services {
+				if s.Protocol == "" {
+					return true
+				}
+			}
+			return false
+		},
+		migrate: func(c *api.MachineConfig) {
+			for i := range c.Services {
+				if c.Services[i].Protocol == "" {
+					c.Services[i].Protocol = "tcp"
+				}
+			}
+		},
+	},
+}
+
+// migrateMachineConfig upgrades mConfig to the current schema in place and describes each upgrade made
+func migrateMachineConfig(mConfig *api.MachineConfig) (upgrades []string) {
+	if mConfig == nil {
+		return nil
+	}
+	for _, m := range machineConfigMigrations {
+		if m.applies(mConfig) {
+			m.migrate(mConfig)
+			upgrades = append(upgrades, m.description)
+		}
+	}
+	return upgrades
+}
+
+// upgradeLegacyMachine returns m with its config upgraded to the current schema, or m itself
+// when it's up to date. The machine of the machine set isn't modified.
+func upgradeLegacyMachine(m *api.Machine) *api.Machine {
+	mConfig := machine.CloneConfig(m.Config)
+	upgrades := migrateMachineConfig(mConfig)
+	if len(upgrades) == 0 {
+		return m
+	}
+	for _, upgrade := range upgrades {
+		terminal.Infof("Upgrading the config of legacy machine %s: %s\n", m.ID, upgrade)
+	}
+	upgraded := *m
+	upgraded.Config = mConfig
+	return &upgraded
+}
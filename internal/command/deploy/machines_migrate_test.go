@@ -0,0 +1,89 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/appconfig"
+)
+
+func Test_migrateMachineConfig(t *testing.T) {
+	// Machines created before metadata existed
+	mConfig := &api.MachineConfig{Image: "old/image"}
+	assert.Equal(t, []string{
+		"add the missing metadata",
+		"mark it as a platform v2 machine",
+		"put it in the app process group",
+	}, migrateMachineConfig(mConfig))
+	assert.Equal(t, map[string]string{
+		"fly_platform_version": "v2",
+		"fly_process_group":    "app",
+	}, mConfig.Metadata)
+
+	// Early machines apps named the process group metadata differently
+	mConfig = &api.MachineConfig{
+		Metadata: map[string]string{"process_group": "worker"},
+		Services: []api.MachineService{{InternalPort: 8080}, {Protocol: "udp", InternalPort: 5353}},
+	}
+	assert.Equal(t, []string{
+		"rename the process_group metadata to fly_process_group",
+		"mark it as a platform v2 machine",
+		"set the protocol of services without one to tcp",
+	}, migrateMachineConfig(mConfig))
+	assert.Equal(t, map[string]string{
+		"fly_platform_version": "v2",
+		"fly_process_group":    "worker",
+	}, mConfig.Metadata)
+	assert.Equal(t, "tcp", mConfig.Services[0].Protocol)
+	assert.Equal(t, "udp", mConfig.Services[1].Protocol)
+
+	// Both keys, fly_process_group wins
+	mConfig = &api.MachineConfig{Metadata: map[string]string{
+		"process_group":        "old",
+		"fly_process_group":    "web",
+		"fly_platform_version": "v2",
+	}}
+	assert.Len(t, migrateMachineConfig(mConfig), 1)
+	assert.Equal(t, "web", mConfig.ProcessGroup())
+	assert.NotContains(t, mConfig.Metadata, "process_group")
+
+	// Up to date configs are left alone
+	assert.Empty(t, migrateMachineConfig(mConfig))
+	assert.Empty(t, migrateMachineConfig(nil))
+}
+
+func Test_upgradeLegacyMachine(t *testing.T) {
+	current := &api.Machine{ID: "current", Config: &api.MachineConfig{Metadata: map[string]string{
+		"fly_process_group":    "app",
+		"fly_platform_version": "v2",
+	}}}
+	assert.Same(t, current, upgradeLegacyMachine(current))
+
+	legacy := &api.Machine{ID: "legacy", Config: &api.MachineConfig{Metadata: map[string]string{"process_group": "app"}}}
+	upgraded := upgradeLegacyMachine(legacy)
+	assert.Equal(t, "v2", upgraded.Config.Metadata["fly_platform_version"])
+	// The machine of the machine set isn't changed
+	assert.Equal(t, map[string]string{"process_group": "app"}, legacy.Config.Metadata)
+}
+
+func Test_launchInputForUpdate_legacyMachine(t *testing.T) {
+	cfg := &appconfig.Config{
+		AppName:   "my-cool-app",
+		Processes: map[string]string{"worker": "work"},
+	}
+	require.NoError(t, cfg.SetMachinesPlatform())
+	md, err := stabMachineDeployment(cfg)
+	require.NoError(t, err)
+
+	li, err := md.launchInputForUpdate(&api.Machine{
+		ID:     "ab1234567890",
+		Config: &api.MachineConfig{Image: "old/image", Metadata: map[string]string{"process_group": "worker"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ab1234567890", li.ID)
+	assert.Equal(t, "worker", li.Config.Metadata["fly_process_group"])
+	assert.Equal(t, "v2", li.Config.Metadata["fly_platform_version"])
+	assert.NotContains(t, li.Config.Metadata, "process_group")
+}
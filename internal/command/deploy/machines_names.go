@@ -0,0 +1,123 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/flaps"
+	"github.com/superfly/flyctl/terminal"
+)
+
+var (
+	machineNamePlaceholderRegexp = regexp.MustCompile(`\{[^}]*\}`)
+	machineNameCharsRegexp       = regexp.MustCompile(`^[a-zA-Z0-9_-]*$`)
+)
+
+// setMachineNameTemplate names the machines launched by the deploy after template, or the
+// [deploy] machine_name_template of fly.toml. {group} and {region} are the machine ones, {index}
+// is the lowest number making the name unique within the app.
+func (md *machineDeployment) setMachineNameTemplate(template string) error {
+	if template == "" && md.appConfig.Deploy != nil {
+		template = md.appConfig.Deploy.MachineNameTemplate
+	}
+	if template == "" {
+		return nil
+	}
+	for _, placeholder := range machineNamePlaceholderRegexp.FindAllString(template, -1) {
+		switch placeholder {
+		case "{group}", "{region}", "{index}":
+		default:
+			return fmt.Errorf("invalid machine name template '%s': unknown placeholder %s, use {group}, {region} and {index}", template, placeholder)
+		}
+	}
+	if !strings.Contains(template, "{index}") {
+		return fmt.Errorf("invalid machine name template '%s': it must contain {index} to keep machine names unique", template)
+	}
+	if literal := machineNamePlaceholderRegexp.ReplaceAllString(template, ""); !machineNameCharsRegexp.MatchString(literal) {
+		return fmt.Errorf("invalid machine name template '%s': only letters, digits, '-' and '_' are allowed besides the placeholders", template)
+	}
+	md.machineNameTemplate = template
+	return nil
+}
+
+// maxNameConflicts bounds the names tried for a new machine whose names keep being taken
+const maxNameConflicts = 5
+
+// loadMachineNames takes the names of every machine of the app for machineName to skip them,
+// including the ones deploys leave alone like machines kept for rollback.
+func (md *machineDeployment) loadMachineNames(ctx context.Context) error {
+	if md.machineNameTemplate == "" {
+		return nil
+	}
+	machines, err := md.flapsClient.List(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed to list the machines of the app to name new ones: %w", err)
+	}
+	md.machineNamesMu.Lock()
+	defer md.machineNamesMu.Unlock()
+	md.machineNames = map[string]bool{}
+	for _, m := range machines {
+		if m.State != api.MachineStateDestroyed {
+			md.machineNames[m.Name] = true
+		}
+	}
+	return nil
+}
+
+// machineName returns the name of a new machine of group in region, empty to let the platform
+// pick a random one. Names of the app machines and of the ones named before are skipped.
+func (md *machineDeployment) machineName(group, region string) string {
+	if md.machineNameTemplate == "" {
+		return ""
+	}
+	md.machineNamesMu.Lock()
+	defer md.machineNamesMu.Unlock()
+	if md.machineNames == nil {
+		md.machineNames = map[string]bool{}
+		for _, lm := range md.machineSet.GetMachines() {
+			md.machineNames[lm.Machine().Name] = true
+		}
+	}
+	prefix := strings.NewReplacer("{group}", group, "{region}", region).Replace(md.machineNameTemplate)
+	for index := 1; ; index++ {
+		name := strings.ReplaceAll(prefix, "{index}", strconv.Itoa(index))
+		if !md.machineNames[name] {
+			md.machineNames[name] = true
+			return name
+		}
+	}
+}
+
+// launchMachine launches a machine with launchInput. When its name was taken meanwhile, e.g. by a
+// machine created by hand, it's launched again with the next free name.
+func (md *machineDeployment) launchMachine(ctx context.Context, launchInput api.LaunchMachineInput) (*api.Machine, error) {
+	for tries := 1; ; tries++ {
+		var m *api.Machine
+		err := md.inRegion(ctx, launchInput.Region, func() (err error) {
+			m, err = md.flapsClient.Launch(ctx, launchInput)
+			return err
+		})
+		if err == nil || launchInput.Name == "" || md.machineNameTemplate == "" || !isNameConflictError(err) || tries == maxNameConflicts {
+			return m, err
+		}
+		name := launchInput.Name
+		launchInput.Name = md.machineName(launchInput.Config.ProcessGroup(), launchInput.Region)
+		terminal.Debugf("machine name %s is taken, launching the machine as %s\n", name, launchInput.Name)
+	}
+}
+
+// isNameConflictError tells whether a machine launch failed because its name is taken
+func isNameConflictError(err error) bool {
+	var flapsErr *flaps.FlapsError
+	if !errors.As(err, &flapsErr) {
+		return false
+	}
+	return flapsErr.ResponseStatusCode == http.StatusConflict ||
+		(flapsErr.ResponseStatusCode == http.StatusUnprocessableEntity && strings.Contains(flapsErr.Error(), "already"))
+}
@@ -0,0 +1,54 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/flaps"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func Test_setMachineNameTemplate(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+
+	assert.ErrorContains(t, md.setMachineNameTemplate("{group}-{zone}-{index}"), "unknown placeholder {zone}")
+	assert.ErrorContains(t, md.setMachineNameTemplate("{group}-{region}"), "it must contain {index}")
+	assert.ErrorContains(t, md.setMachineNameTemplate("{group}.{index}"), "only letters, digits")
+
+	md.appConfig.Deploy = &appconfig.Deploy{MachineNameTemplate: "{group}-{index}"}
+	require.NoError(t, md.setMachineNameTemplate(""))
+	assert.Equal(t, "{group}-{index}", md.machineNameTemplate)
+	require.NoError(t, md.setMachineNameTemplate("web-{region}-{index}"))
+	assert.Equal(t, "web-{region}-{index}", md.machineNameTemplate)
+}
+
+func Test_machineName(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	assert.Empty(t, md.machineName("app", "ord"))
+
+	ios, _, _, _ := iostreams.Test()
+	existing := groupMachine("m1", "app", "ord")
+	existing.Name = "app-ord-1"
+	md.machineSet = machine.NewMachineSet(nil, ios, []*api.Machine{existing})
+	require.NoError(t, md.setMachineNameTemplate("{group}-{region}-{index}"))
+
+	assert.Equal(t, "app-ord-2", md.machineName("app", "ord"))
+	assert.Equal(t, "app-ord-3", md.machineName("app", "ord"))
+	assert.Equal(t, "worker-syd-1", md.machineName("worker", "syd"))
+}
+
+func Test_isNameConflictError(t *testing.T) {
+	assert.True(t, isNameConflictError(&flaps.FlapsError{OriginalError: errors.New("conflict"), ResponseStatusCode: http.StatusConflict}))
+	assert.True(t, isNameConflictError(fmt.Errorf("launch: %w", &flaps.FlapsError{OriginalError: errors.New("name app-ord-1 is already taken"), ResponseStatusCode: http.StatusUnprocessableEntity})))
+	assert.False(t, isNameConflictError(&flaps.FlapsError{OriginalError: errors.New("invalid image"), ResponseStatusCode: http.StatusUnprocessableEntity}))
+	assert.False(t, isNameConflictError(errors.New("name already taken")))
+}
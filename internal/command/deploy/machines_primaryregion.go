@@ -0,0 +1,36 @@
+package deploy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var regionCodeRegexp = regexp.MustCompile(`^[a-z]{3}$`)
+
+// setPrimaryRegionOverride launches the new machines of this deploy, and its release commands,
+// in region instead of the primary region of fly.toml. It isn't saved with the release config,
+// existing machines stay where they are.
+func (md *machineDeployment) setPrimaryRegionOverride(region string) error {
+	region = strings.ToLower(strings.TrimSpace(region))
+	if region == "" {
+		return nil
+	}
+	if !regionCodeRegexp.MatchString(region) {
+		return fmt.Errorf("invalid --primary-region '%s', it must be a region code like 'ord', see `fly platform regions`", region)
+	}
+	md.primaryRegionOverride = region
+	if region != md.appConfig.PrimaryRegion {
+		md.warnf("New machines and release commands run in region %s instead of the primary region %s for this deploy only, fly.toml is left as is\n",
+			region, md.appConfig.PrimaryRegion)
+	}
+	return nil
+}
+
+// primaryRegion is where new machines launch, --primary-region or the primary region of fly.toml
+func (md *machineDeployment) primaryRegion() string {
+	if md.primaryRegionOverride != "" {
+		return md.primaryRegionOverride
+	}
+	return md.appConfig.PrimaryRegion
+}
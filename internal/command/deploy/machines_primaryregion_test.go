@@ -0,0 +1,35 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func Test_setPrimaryRegionOverride(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{AppName: "my-cool-app", PrimaryRegion: "scl"})
+	require.NoError(t, err)
+	ios, _, _, errOut := iostreams.Test()
+	md.io = ios
+	md.alertOut = ios.ErrOut
+	md.colorize = ios.ColorScheme()
+
+	require.NoError(t, md.setPrimaryRegionOverride(""))
+	assert.Equal(t, "scl", md.primaryRegion())
+
+	assert.ErrorContains(t, md.setPrimaryRegionOverride("chicago"), "invalid --primary-region 'chicago'")
+
+	require.NoError(t, md.setPrimaryRegionOverride(" ORD "))
+	assert.Equal(t, "ord", md.primaryRegion())
+	assert.Contains(t, errOut.String(), "instead of the primary region scl for this deploy only")
+
+	li, err := md.launchInputForLaunch("", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "ord", li.Region)
+	assert.Equal(t, "ord", md.launchInputForReleaseCommand(nil, appconfig.ReleaseCommand{Command: "migrate"}).Region)
+	// fly.toml isn't changed
+	assert.Equal(t, "scl", md.appConfig.PrimaryRegion)
+}
@@ -0,0 +1,177 @@
+package deploy
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/superfly/flyctl/terminal"
+)
+
+const (
+	progressPhaseReleaseCommand = "release_command"
+	progressPhaseLaunching      = "launching"
+	progressPhaseUpdating       = "updating"
+	progressPhaseComplete       = "complete"
+	progressPhaseFailed         = "failed"
+
+	progressGroupPending    = "pending"
+	progressGroupInProgress = "in_progress"
+	progressGroupCompleted  = "completed"
+	progressGroupFailed     = "failed"
+)
+
+// deployProgress keeps the state of a deploy in a JSON file a dashboard can poll.
+// A nil *deployProgress is valid and does nothing, it's what deploys without
+// --progress-file get.
+type deployProgress struct {
+	mu    sync.Mutex
+	path  string
+	state progressState
+}
+
+type progressState struct {
+	App            string                    `json:"app"`
+	ReleaseID      string                    `json:"release_id"`
+	ReleaseVersion int                       `json:"release_version"`
+	Phase          string                    `json:"phase"`
+	Total          int                       `json:"total_machines"`
+	Completed      int                       `json:"completed_machines"`
+	Groups         map[string]*progressGroup `json:"groups"`
+	Error          string                    `json:"error,omitempty"`
+	UpdatedAt      time.Time                 `json:"updated_at"`
+}
+
+type progressGroup struct {
+	Status    string `json:"status"`
+	Total     int    `json:"total_machines"`
+	Completed int    `json:"completed_machines"`
+}
+
+func newDeployProgress(path, appName string) *deployProgress {
+	if path == "" {
+		return nil
+	}
+	return &deployProgress{
+		path:  path,
+		state: progressState{App: appName, Groups: map[string]*progressGroup{}},
+	}
+}
+
+// start records the release being deployed
+func (p *deployProgress) start(releaseID string, releaseVersion int) {
+	p.update(func(s *progressState) {
+		s.ReleaseID = releaseID
+		s.ReleaseVersion = releaseVersion
+	})
+}
+
+func (p *deployProgress) setPhase(phase string) {
+	p.update(func(s *progressState) {
+		s.Phase = phase
+	})
+}
+
+// addMachines registers count more machines to deploy in group
+func (p *deployProgress) addMachines(group string, count int) {
+	p.update(func(s *progressState) {
+		g := s.group(group)
+		g.Total += count
+		s.Total += count
+		if g.Completed < g.Total {
+			g.Status = progressGroupPending
+		}
+	})
+}
+
+// startGroup marks group as being worked on
+func (p *deployProgress) startGroup(group string) {
+	p.update(func(s *progressState) {
+		if g := s.group(group); g.Status == progressGroupPending {
+			g.Status = progressGroupInProgress
+		}
+	})
+}
+
+// machineDone records a machine of group as deployed
+func (p *deployProgress) machineDone(group string) {
+	p.update(func(s *progressState) {
+		g := s.group(group)
+		g.Completed++
+		s.Completed++
+		if g.Completed >= g.Total {
+			g.Status = progressGroupCompleted
+		}
+	})
+}
+
+// finish records the outcome of the deploy, groups left unfinished are failed
+func (p *deployProgress) finish(err error) {
+	p.update(func(s *progressState) {
+		s.Phase = progressPhaseComplete
+		if err == nil {
+			return
+		}
+		s.Phase = progressPhaseFailed
+		s.Error = err.Error()
+		for _, g := range s.Groups {
+			if g.Status != progressGroupCompleted {
+				g.Status = progressGroupFailed
+			}
+		}
+	})
+}
+
+func (s *progressState) group(name string) *progressGroup {
+	g, ok := s.Groups[name]
+	if !ok {
+		g = &progressGroup{Status: progressGroupPending}
+		s.Groups[name] = g
+	}
+	return g
+}
+
+// update applies fn to the state and writes the progress file. Writing is best effort,
+// failures are only logged and never abort the deployment.
+func (p *deployProgress) update(fn func(*progressState)) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fn(&p.state)
+	p.state.UpdatedAt = time.Now().UTC()
+	if err := writeFileAtomic(p.path, p.state); err != nil {
+		terminal.Warnf("failed to write the deploy progress file: %v\n", err)
+	}
+}
+
+// writeFileAtomic writes v as JSON next to path and renames it over path,
+// so readers never see a partially written file
+func writeFileAtomic(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // skipcq: GO-S2307
+	// CreateTemp makes the file private, let other users' dashboards read it
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,65 @@
+package deploy
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readProgress(t *testing.T, path string) progressState {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var state progressState
+	require.NoError(t, json.Unmarshal(data, &state))
+	return state
+}
+
+func Test_deployProgress(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deploy.json")
+	p := newDeployProgress(path, "my-cool-app")
+
+	p.start("release_id", 3)
+	p.addMachines("app", 2)
+	p.addMachines("worker", 1)
+	p.setPhase(progressPhaseUpdating)
+	p.startGroup("app")
+	p.machineDone("app")
+
+	state := readProgress(t, path)
+	assert.Equal(t, "my-cool-app", state.App)
+	assert.Equal(t, "release_id", state.ReleaseID)
+	assert.Equal(t, 3, state.ReleaseVersion)
+	assert.Equal(t, progressPhaseUpdating, state.Phase)
+	assert.Equal(t, 3, state.Total)
+	assert.Equal(t, 1, state.Completed)
+	assert.Equal(t, &progressGroup{Status: progressGroupInProgress, Total: 2, Completed: 1}, state.Groups["app"])
+	assert.Equal(t, &progressGroup{Status: progressGroupPending, Total: 1}, state.Groups["worker"])
+
+	p.machineDone("app")
+	p.finish(errors.New("boom"))
+
+	state = readProgress(t, path)
+	assert.Equal(t, progressPhaseFailed, state.Phase)
+	assert.Equal(t, "boom", state.Error)
+	assert.Equal(t, progressGroupCompleted, state.Groups["app"].Status)
+	assert.Equal(t, progressGroupFailed, state.Groups["worker"].Status)
+
+	// Only the progress file is left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func Test_deployProgress_disabled(t *testing.T) {
+	p := newDeployProgress("", "my-cool-app")
+	assert.Nil(t, p)
+	// Calls on a nil progress are no-ops
+	p.setPhase(progressPhaseUpdating)
+	p.machineDone("app")
+	p.finish(nil)
+}
@@ -0,0 +1,43 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/terminal"
+)
+
+const defaultReadinessInterval = 2 * time.Second
+
+// waitForReadinessCommand runs the readiness command of the machine group inside it until it exits 0.
+// It replaces waiting for the health checks of groups without network checks, like workers.
+// Timing out fails like health checks not passing, within the --min-healthy tolerance.
+func (md *machineDeployment) waitForReadinessCommand(ctx context.Context, lm machine.LeasableMachine, rc *appconfig.ReadinessCommand, defaultTimeout time.Duration, logPrefix string) error {
+	interval, timeout := defaultReadinessInterval, defaultTimeout
+	if rc.Interval != nil && rc.Interval.Duration > 0 {
+		interval = rc.Interval.Duration
+	}
+	if rc.Timeout != nil && rc.Timeout.Duration > 0 {
+		timeout = rc.Timeout.Duration
+	}
+
+	md.logClearLinesAbove(1)
+	fmt.Fprintf(md.io.ErrOut, "  %s Waiting for `%s` to succeed on machine %s\n", logPrefix, rc.Command, md.colorize.Bold(lm.FormattedMachineId()))
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		err := md.execCheckCommand(ctx, lm.Machine().ID, rc.Command)
+		if err == nil {
+			return nil
+		}
+		terminal.Debugf("machine %s isn't ready yet: %v\n", lm.Machine().ID, err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("machine %s wasn't ready after %s: %w, last error: %v", lm.Machine().ID, timeout, ctx.Err(), err)
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package deploy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+	"golang.org/x/exp/slices"
+)
+
+// regionChange is the number of machines of a process group in a region before and after the deploy
+type regionChange struct {
+	ProcessGroup string `json:"process_group"`
+	Region       string `json:"region"`
+	Before       int    `json:"before"`
+	After        int    `json:"after"`
+}
+
+// currentTopology counts the machines the deploy starts from: the ones of the deployed process
+// groups and the ones diff destroys. Pinned machines of removed groups are left out, like in
+// expectedTopology, since the deploy doesn't touch them.
+func (md *machineDeployment) currentTopology(diff ProcessGroupsDiff) machineTopology {
+	current := machineTopology{}
+	groups := md.enabledProcessNames()
+	for _, lm := range md.machineSet.GetMachines() {
+		m := lm.Machine()
+		if slices.Contains(groups, m.ProcessGroup()) || lo.Contains(diff.machinesToRemove, lm) {
+			current.add(m.ProcessGroup(), m.Region, 1)
+		}
+	}
+	return current
+}
+
+// regionChanges lists the regions of every process group in before or after, sorted by group and region
+func regionChanges(before, after machineTopology) []regionChange {
+	var changes []regionChange
+	for _, t := range []machineTopology{before, after} {
+		for group, regions := range t {
+			for region := range regions {
+				if !lo.ContainsBy(changes, func(c regionChange) bool { return c.ProcessGroup == group && c.Region == region }) {
+					changes = append(changes, regionChange{ProcessGroup: group, Region: region, Before: before[group][region], After: after[group][region]})
+				}
+			}
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].ProcessGroup != changes[j].ProcessGroup {
+			return changes[i].ProcessGroup < changes[j].ProcessGroup
+		}
+		return changes[i].Region < changes[j].Region
+	})
+	return changes
+}
+
+// printRegionChanges shows, for the process groups whose machines move, how many machines each
+// of their regions has before and after the deploy, e.g. "ord: 2→1, iad: 0→1". With --json the
+// changes are part of the deploy summary instead.
+func (md *machineDeployment) printRegionChanges(diff ProcessGroupsDiff) {
+	md.regionChanges = regionChanges(md.currentTopology(diff), md.expected)
+	if md.jsonOutput {
+		return
+	}
+
+	byGroup := lo.GroupBy(md.regionChanges, func(c regionChange) string { return c.ProcessGroup })
+	groups := lo.Keys(byGroup)
+	sort.Strings(groups)
+	var lines []string
+	for _, group := range groups {
+		changes := byGroup[group]
+		if lo.EveryBy(changes, func(c regionChange) bool { return c.Before == c.After }) {
+			continue
+		}
+		counts := lo.Map(changes, func(c regionChange, _ int) string {
+			return fmt.Sprintf("%s: %d→%d", c.Region, c.Before, c.After)
+		})
+		lines = append(lines, fmt.Sprintf("  %s  %s\n", md.colorize.Bold(group), strings.Join(counts, ", ")))
+	}
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintln(md.io.Out, "Machines by region, before and after this deploy:")
+	fmt.Fprint(md.io.Out, strings.Join(lines, ""))
+	fmt.Fprint(md.io.Out, "\n")
+}
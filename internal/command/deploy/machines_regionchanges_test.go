@@ -0,0 +1,56 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func TestRegionChanges(t *testing.T) {
+	appConfig := &appconfig.Config{
+		PrimaryRegion: "iad",
+		Regions:       []string{"iad"},
+		Processes: map[string]string{
+			"web":    "run web",
+			"worker": "run worker",
+		},
+	}
+	require.NoError(t, appConfig.SetMachinesPlatform())
+	md, err := stabMachineDeployment(appConfig)
+	require.NoError(t, err)
+	ios, _, out, _ := iostreams.Test()
+	md.io = ios
+	md.colorize = ios.ColorScheme()
+	md.expandRegions = true
+	md.machineSet = machine.NewMachineSet(nil, ios, []*api.Machine{
+		groupMachine("web1", "web", "ord"),
+		groupMachine("web2", "web", "ord"),
+		groupMachine("worker1", "worker", "iad"),
+		groupMachine("old1", "old", "ord"),
+	})
+
+	diff := md.resolveProcessGroupChanges()
+	md.expected = md.expectedTopology(diff)
+	md.printRegionChanges(diff)
+	assert.Equal(t, []regionChange{
+		{ProcessGroup: "old", Region: "ord", Before: 1, After: 0},
+		{ProcessGroup: "web", Region: "iad", Before: 0, After: 1},
+		{ProcessGroup: "web", Region: "ord", Before: 2, After: 2},
+		{ProcessGroup: "worker", Region: "iad", Before: 1, After: 1},
+	}, md.regionChanges)
+	assert.Equal(t, "Machines by region, before and after this deploy:\n"+
+		"  old  ord: 1→0\n"+
+		"  web  iad: 0→1, ord: 2→2\n\n", out.String())
+
+	// With --json the changes are only part of the summary
+	out.Reset()
+	md.jsonOutput = true
+	md.printRegionChanges(diff)
+	assert.Empty(t, out.String())
+	assert.Len(t, md.regionChanges, 4)
+}
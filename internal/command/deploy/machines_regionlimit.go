@@ -0,0 +1,74 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// regionLimiter bounds the machines of a single region worked on at once,
+// so a deploy doesn't get rate limited by the machines API of one region
+type regionLimiter struct {
+	limit int
+
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+	// throttled counts the machines that waited for a slot of their region
+	throttled atomic.Int32
+}
+
+func newRegionLimiter(limit int) *regionLimiter {
+	return &regionLimiter{limit: limit, slots: map[string]chan struct{}{}}
+}
+
+// acquire waits for a slot in region, the returned func gives it back.
+// A nil limiter doesn't limit anything.
+func (l *regionLimiter) acquire(ctx context.Context, region string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	l.mu.Lock()
+	slots, ok := l.slots[region]
+	if !ok {
+		slots = make(chan struct{}, l.limit)
+		l.slots[region] = slots
+	}
+	l.mu.Unlock()
+
+	release := func() { <-slots }
+	select {
+	case slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	l.throttled.Add(1)
+	select {
+	case slots <- struct{}{}:
+		return release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// inRegion runs work, a call to the machines API on a machine of region, in one of the region's slots
+func (md *machineDeployment) inRegion(ctx context.Context, region string, work func() error) error {
+	release, err := md.regions.acquire(ctx, region)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return work()
+}
+
+// reportRegionThrottling tells how many machines waited for their region during the deploy
+func (md *machineDeployment) reportRegionThrottling() {
+	if md.regions == nil {
+		return
+	}
+	if n := md.regions.throttled.Load(); n > 0 {
+		fmt.Fprintf(md.io.ErrOut, "%d machine operations waited for others in their region, --region-concurrency=%d keeps each region under the machines API rate limit\n",
+			n, md.regionConcurrency)
+	}
+}
@@ -0,0 +1,66 @@
+package deploy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func Test_regionLimiter(t *testing.T) {
+	l := newRegionLimiter(1)
+	ctx := context.Background()
+
+	release, err := l.acquire(ctx, "ord")
+	require.NoError(t, err)
+	// Other regions aren't held up
+	releaseSyd, err := l.acquire(ctx, "syd")
+	require.NoError(t, err)
+	releaseSyd()
+	assert.Zero(t, l.throttled.Load())
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = l.acquire(timeout, "ord")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.EqualValues(t, 1, l.throttled.Load())
+
+	release()
+	release, err = l.acquire(ctx, "ord")
+	require.NoError(t, err)
+	release()
+	assert.EqualValues(t, 1, l.throttled.Load())
+}
+
+func Test_updateExistingMachines_regionConcurrency(t *testing.T) {
+	cfg := &appconfig.Config{Deploy: &appconfig.Deploy{MaxConcurrentPerGroup: map[string]string{"app": "3"}}}
+	md, err := stabMachineDeployment(cfg)
+	require.NoError(t, err)
+	ios, _, _, errOut := iostreams.Test()
+	md.io = ios
+	md.colorize = ios.ColorScheme()
+	md.strategy = "immediate"
+	md.regionConcurrency = 1
+	md.regions = newRegionLimiter(md.regionConcurrency)
+
+	inFlight := &inFlightCounter{}
+	var entries []*machineUpdateEntry
+	for _, id := range []string{"m1", "m2", "m3"} {
+		m := groupMachine(id, "app", "ord")
+		entries = append(entries, &machineUpdateEntry{
+			leasableMachine: &concurrentMachine{m: m, inFlight: inFlight},
+			launchInput:     &api.LaunchMachineInput{ID: id, Config: m.Config},
+		})
+	}
+
+	// The batch updates the 3 machines at once but their region takes one at a time
+	require.NoError(t, md.updateExistingMachines(context.Background(), entries))
+	assert.Equal(t, 1, inFlight.maxSoFar)
+	md.reportRegionThrottling()
+	assert.Contains(t, errOut.String(), "2 machine operations waited for others in their region, --region-concurrency=1")
+}
@@ -12,21 +12,81 @@ import (
 	"github.com/superfly/flyctl/api"
 	"github.com/superfly/flyctl/flaps"
 	"github.com/superfly/flyctl/helpers"
+	"github.com/superfly/flyctl/internal/appconfig"
 	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/terminal"
 )
 
-func (md *machineDeployment) runReleaseCommand(ctx context.Context) error {
-	if md.appConfig.Deploy == nil || md.appConfig.Deploy.ReleaseCommand == "" {
-		return nil
+const (
+	// releaseCommandAttempts bounds the runs of a release command whose machine failed to launch or be monitored
+	releaseCommandAttempts   = 3
+	releaseCommandRetryDelay = 5 * time.Second
+)
+
+// runReleaseCommands runs the release commands in order, the first failure aborts the deploy
+func (md *machineDeployment) runReleaseCommands(ctx context.Context) error {
+	for _, rc := range md.appConfig.ReleaseCommands() {
+		if err := md.runReleaseCommand(ctx, rc); err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+// releaseMachineError is a failure launching the release command machine, before it started and
+// so before the command could run. Only those are retried: once started, the command may have
+// applied migrations, running it again or killing it midway isn't safe.
+type releaseMachineError struct {
+	err error
+}
+
+func (e *releaseMachineError) Error() string {
+	return e.err.Error()
+}
+
+func (e *releaseMachineError) Unwrap() error {
+	return e.err
+}
+
+// retryableReleaseCommandError tells if err comes from the release command machine rather than
+// from the command itself, and the deploy wasn't canceled meanwhile
+func retryableReleaseCommandError(ctx context.Context, err error) bool {
+	var machineErr *releaseMachineError
+	return ctx.Err() == nil && errors.As(err, &machineErr)
+}
 
-	fmt.Fprintf(md.io.ErrOut, "Running %s release_command: %s\n",
-		md.colorize.Bold(md.app.Name),
-		md.appConfig.Deploy.ReleaseCommand,
-	)
-	err := md.createOrUpdateReleaseCmdMachine(ctx)
+func (md *machineDeployment) runReleaseCommand(ctx context.Context, rc appconfig.ReleaseCommand) error {
+	if rc.ProcessGroup != "" {
+		fmt.Fprintf(md.io.ErrOut, "Running %s release_command for process group '%s': %s\n",
+			md.colorize.Bold(md.app.Name), rc.ProcessGroup, rc.Command)
+	} else {
+		fmt.Fprintf(md.io.ErrOut, "Running %s release_command: %s\n", md.colorize.Bold(md.app.Name), rc.Command)
+	}
+	for attempt := 1; ; attempt++ {
+		err := md.runReleaseCommandOnce(ctx, rc)
+		if err == nil || attempt >= releaseCommandAttempts || !retryableReleaseCommandError(ctx, err) {
+			return err
+		}
+		md.warnf("release_command machine failed, retrying in %s (attempt %d of %d): %v\n",
+			releaseCommandRetryDelay, attempt+1, releaseCommandAttempts, err)
+		md.discardReleaseCommandMachine(ctx)
+		select {
+		case <-time.After(releaseCommandRetryDelay):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// runReleaseCommandOnce runs rc on the release command machine, failing to launch the machine or
+// to see it start fails with a releaseMachineError
+func (md *machineDeployment) runReleaseCommandOnce(ctx context.Context, rc appconfig.ReleaseCommand) error {
+	if md.releaseOnExisting {
+		return md.execReleaseCommand(ctx, rc)
+	}
+	err := md.createOrUpdateReleaseCmdMachine(ctx, rc)
 	if err != nil {
-		return fmt.Errorf("error running release_command machine: %w", err)
+		return &releaseMachineError{fmt.Errorf("error running release_command machine: %w", err)}
 	}
 	releaseCmdMachine := md.releaseCommandMachine.GetMachines()[0]
 	// FIXME: consolidate this wait stuff with deploy waits? Especially once we improve the outpu
@@ -44,33 +104,51 @@ func (md *machineDeployment) runReleaseCommand(ctx context.Context) error {
 	}
 	if exitCode != 0 {
 		time.Sleep(2 * time.Second) // Wait 2 secs to be sure logs have reached OpenSearch
-		fmt.Fprintf(md.io.ErrOut, "Error release_command failed running on machine %s with exit code %s.\n",
+		md.warnf("Error release_command failed running on machine %s with exit code %s.\n",
 			md.colorize.Bold(releaseCmdMachine.Machine().ID), md.colorize.Red(strconv.Itoa(exitCode)))
-		fmt.Fprintf(md.io.ErrOut, "Check its logs: here's the last 100 lines below, or run 'fly logs -i %s':\n",
+		md.warnf("Check its logs: here's the last 100 lines below, or run 'fly logs -i %s':\n",
 			releaseCmdMachine.Machine().ID)
-		releaseCmdLogs, _, err := md.apiClient.GetAppLogs(ctx, md.app.Name, "", md.appConfig.PrimaryRegion, releaseCmdMachine.Machine().ID)
+		releaseCmdLogs, _, err := md.apiClient.GetAppLogs(ctx, md.app.Name, "", md.primaryRegion(), releaseCmdMachine.Machine().ID)
 		if err != nil {
 			return fmt.Errorf("error getting release_command logs: %w", err)
 		}
 		for _, l := range releaseCmdLogs {
-			fmt.Fprintf(md.io.ErrOut, "  %s\n", l.Message)
+			md.warnf("  %s\n", l.Message)
+		}
+		return &ReleaseCommandError{
+			MachineID: releaseCmdMachine.Machine().ID,
+			ExitCode:  exitCode,
+			err:       fmt.Errorf("error release_command machine %s exited with non-zero status of %d", releaseCmdMachine.Machine().ID, exitCode),
 		}
-		return fmt.Errorf("error release_command machine %s exited with non-zero status of %d", releaseCmdMachine.Machine().ID, exitCode)
 	}
 	md.logClearLinesAbove(1)
 	fmt.Fprintf(md.io.ErrOut, "  release_command %s completed successfully\n", md.colorize.Bold(releaseCmdMachine.Machine().ID))
+	// The machine destroyed itself once done, the next release command gets a new one
+	md.releaseCommandMachine = machine.NewMachineSet(md.flapsClient, md.io, nil)
 	return nil
 }
 
-func (md *machineDeployment) createOrUpdateReleaseCmdMachine(ctx context.Context) error {
+// discardReleaseCommandMachine destroys the machine of a failed release command attempt, if any,
+// the next attempt launches a new one
+func (md *machineDeployment) discardReleaseCommandMachine(ctx context.Context) {
+	if !md.releaseCommandMachine.IsEmpty() {
+		id := md.releaseCommandMachine.GetMachines()[0].Machine().ID
+		if err := md.flapsClient.Destroy(ctx, api.RemoveMachineInput{AppID: md.app.Name, ID: id, Kill: true}, ""); err != nil {
+			terminal.Debugf("failed to destroy release_command machine %s: %v\n", id, err)
+		}
+	}
+	md.releaseCommandMachine = machine.NewMachineSet(md.flapsClient, md.io, nil)
+}
+
+func (md *machineDeployment) createOrUpdateReleaseCmdMachine(ctx context.Context, rc appconfig.ReleaseCommand) error {
 	if md.releaseCommandMachine.IsEmpty() {
-		return md.createReleaseCommandMachine(ctx)
+		return md.createReleaseCommandMachine(ctx, rc)
 	}
-	return md.updateReleaseCommandMachine(ctx)
+	return md.updateReleaseCommandMachine(ctx, rc)
 }
 
-func (md *machineDeployment) createReleaseCommandMachine(ctx context.Context) error {
-	launchInput := md.launchInputForReleaseCommand(nil)
+func (md *machineDeployment) createReleaseCommandMachine(ctx context.Context, rc appconfig.ReleaseCommand) error {
+	launchInput := md.launchInputForReleaseCommand(nil, rc)
 	releaseCmdMachine, err := md.flapsClient.Launch(ctx, *launchInput)
 	if err != nil {
 		return fmt.Errorf("error creating a release_command machine: %w", err)
@@ -81,7 +159,7 @@ func (md *machineDeployment) createReleaseCommandMachine(ctx context.Context) er
 	return nil
 }
 
-func (md *machineDeployment) updateReleaseCommandMachine(ctx context.Context) error {
+func (md *machineDeployment) updateReleaseCommandMachine(ctx context.Context, rc appconfig.ReleaseCommand) error {
 	releaseCmdMachine := md.releaseCommandMachine.GetMachines()[0]
 	fmt.Fprintf(md.io.ErrOut, "  Updating release_command machine %s\n", md.colorize.Bold(releaseCmdMachine.Machine().ID))
 
@@ -95,7 +173,7 @@ func (md *machineDeployment) updateReleaseCommandMachine(ctx context.Context) er
 	defer md.releaseCommandMachine.ReleaseLeases(ctx) // skipcq: GO-S2307
 	md.releaseCommandMachine.StartBackgroundLeaseRefresh(ctx, md.leaseTimeout, md.leaseDelayBetween)
 
-	launchInput := md.launchInputForReleaseCommand(releaseCmdMachine.Machine())
+	launchInput := md.launchInputForReleaseCommand(releaseCmdMachine.Machine(), rc)
 	if err := releaseCmdMachine.Update(ctx, *launchInput); err != nil {
 		return fmt.Errorf("error updating release_command machine: %w", err)
 	}
@@ -103,16 +181,16 @@ func (md *machineDeployment) updateReleaseCommandMachine(ctx context.Context) er
 	return nil
 }
 
-func (md *machineDeployment) launchInputForReleaseCommand(origMachineRaw *api.Machine) *api.LaunchMachineInput {
+func (md *machineDeployment) launchInputForReleaseCommand(origMachineRaw *api.Machine, rc appconfig.ReleaseCommand) *api.LaunchMachineInput {
 	if origMachineRaw == nil {
 		origMachineRaw = &api.Machine{
-			Region: md.appConfig.PrimaryRegion,
+			Region: md.primaryRegion(),
 		}
 	}
-	// We can ignore the error because ToReleaseMachineConfig fails only
+	// We can ignore the error because ToReleaseCommandMachineConfig fails only
 	// if it can't split the command and we test that at initialization
-	mConfig, _ := md.appConfig.ToReleaseMachineConfig()
-	mConfig.Guest = md.inferReleaseCommandGuest()
+	mConfig, _ := md.appConfig.ToReleaseCommandMachineConfig(rc)
+	mConfig.Guest = md.inferReleaseCommandGuest(rc.ProcessGroup)
 	mConfig.Image = md.img
 	md.setMachineReleaseData(mConfig)
 
@@ -125,10 +203,14 @@ func (md *machineDeployment) launchInputForReleaseCommand(origMachineRaw *api.Ma
 	}
 }
 
-func (md *machineDeployment) inferReleaseCommandGuest() *api.MachineGuest {
+// inferReleaseCommandGuest sizes the release command machine like the biggest machine of group,
+// the default process group when empty
+func (md *machineDeployment) inferReleaseCommandGuest(group string) *api.MachineGuest {
 	desiredGuest := api.MachinePresets["shared-cpu-2x"]
 	if !md.machineSet.IsEmpty() {
-		group := md.appConfig.DefaultProcessName()
+		if group == "" {
+			group = md.appConfig.DefaultProcessName()
+		}
 		ram := func(m *api.Machine) int {
 			if m != nil && m.Config != nil && m.Config.Guest != nil {
 				return m.Config.Guest.MemoryMB
@@ -158,7 +240,8 @@ func (md *machineDeployment) waitForReleaseCommandToFinish(ctx context.Context,
 			// The machine exited and was destroyed quickly.
 			return nil
 		}
-		return fmt.Errorf("error waiting for release_command machine %s to start: %w", releaseCmdMachine.Machine().ID, err)
+		// The command didn't start, launching another machine is safe
+		return &releaseMachineError{fmt.Errorf("error waiting for release_command machine %s to start: %w", releaseCmdMachine.Machine().ID, err)}
 	}
 	err = releaseCmdMachine.WaitForState(ctx, api.MachineStateDestroyed, md.waitTimeout, "")
 	if err != nil {
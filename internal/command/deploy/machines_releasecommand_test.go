@@ -0,0 +1,25 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_retryableReleaseCommandError(t *testing.T) {
+	ctx := context.Background()
+	machineErr := &releaseMachineError{errors.New("failed to launch VM: 503")}
+	assert.True(t, retryableReleaseCommandError(ctx, machineErr))
+	assert.True(t, retryableReleaseCommandError(ctx, fmt.Errorf("wrapped: %w", machineErr)))
+
+	// The command itself failing is never retried
+	assert.False(t, retryableReleaseCommandError(ctx, &ReleaseCommandError{ExitCode: 1, err: errors.New("exit 1")}))
+	assert.False(t, retryableReleaseCommandError(ctx, errors.New("error getting release_command logs")))
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.False(t, retryableReleaseCommandError(canceled, machineErr))
+}
@@ -0,0 +1,78 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/machine"
+)
+
+// execReleaseCommand runs rc through exec on a started machine of the app, for --release-command-on-existing.
+// It saves launching a release command machine but the command runs in the machine as it is, so it's
+// refused unless the machine already runs the image being deployed: a new image may bring migrations
+// the previous one doesn't have. Exec failures aren't retried, the command may still be running.
+func (md *machineDeployment) execReleaseCommand(ctx context.Context, rc appconfig.ReleaseCommand) error {
+	lm := md.releaseCommandExecMachine(rc.ProcessGroup)
+	if lm == nil {
+		return fmt.Errorf("--release-command-on-existing needs a started machine to run the release command on, there's none")
+	}
+	m := lm.Machine()
+	if !md.runsDeployedImage(m) {
+		return fmt.Errorf("--release-command-on-existing can't be used when the image changes: machine %s runs %s, not %s, "+
+			"the release command must run with the image being deployed", m.ID, m.Config.Image, md.img)
+	}
+	fmt.Fprintf(md.io.ErrOut, "Running the release command on existing machine %s\n", m.ID)
+
+	timeout := int(md.waitTimeout.Seconds())
+	out, err := md.flapsClient.Exec(ctx, m.ID, &api.MachineExecRequest{Cmd: rc.Command, Timeout: timeout})
+	if err != nil {
+		return fmt.Errorf("error running release_command on machine %s: %w", m.ID, err)
+	}
+	if out.ExitCode != 0 {
+		md.warnf("Error release_command failed running on machine %s with exit code %s.\n",
+			md.colorize.Bold(m.ID), md.colorize.Red(fmt.Sprint(out.ExitCode)))
+		for _, l := range strings.Split(strings.TrimSpace(out.StdOut+"\n"+out.StdErr), "\n") {
+			md.warnf("  %s\n", l)
+		}
+		return &ReleaseCommandError{
+			MachineID: m.ID,
+			ExitCode:  int(out.ExitCode),
+			err:       fmt.Errorf("error release_command on machine %s exited with non-zero status of %d", m.ID, out.ExitCode),
+		}
+	}
+	fmt.Fprintf(md.io.ErrOut, "  release_command on %s completed successfully\n", md.colorize.Bold(m.ID))
+	return nil
+}
+
+// runsDeployedImage tells if m runs the image being deployed, by reference or by digest
+func (md *machineDeployment) runsDeployedImage(m *api.Machine) bool {
+	if m.Config != nil && m.Config.Image == md.img {
+		return true
+	}
+	return md.imgDigest != "" && m.ImageRef.Digest == md.imgDigest
+}
+
+// releaseCommandExecMachine picks a started machine of group, the default process group when empty,
+// preferably in the primary region
+func (md *machineDeployment) releaseCommandExecMachine(group string) machine.LeasableMachine {
+	if group == "" {
+		group = md.appConfig.DefaultProcessName()
+	}
+	var picked machine.LeasableMachine
+	for _, lm := range md.machineSet.GetMachines() {
+		m := lm.Machine()
+		if m.State != api.MachineStateStarted || m.ProcessGroup() != group {
+			continue
+		}
+		if m.Region == md.primaryRegion() {
+			return lm
+		}
+		if picked == nil {
+			picked = lm
+		}
+	}
+	return picked
+}
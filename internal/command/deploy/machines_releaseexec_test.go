@@ -0,0 +1,66 @@
+package deploy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func Test_releaseCommandExecMachine(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{
+		PrimaryRegion: "ord",
+		Processes:     map[string]string{"app": "run app", "worker": "run worker"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, md.appConfig.SetMachinesPlatform())
+
+	started := func(id, group, region string) *api.Machine {
+		m := groupMachine(id, group, region)
+		m.State = api.MachineStateStarted
+		return m
+	}
+	stopped := groupMachine("m2", "app", "ord")
+	stopped.State = api.MachineStateStopped
+
+	ios, _, _, _ := iostreams.Test()
+	md.machineSet = machine.NewMachineSet(nil, ios, []*api.Machine{
+		started("m1", "app", "syd"),
+		stopped,
+		started("m3", "app", "ord"),
+		started("w1", "worker", "syd"),
+	})
+
+	assert.Equal(t, "m3", md.releaseCommandExecMachine("").Machine().ID)
+	assert.Equal(t, "w1", md.releaseCommandExecMachine("worker").Machine().ID)
+	assert.Nil(t, md.releaseCommandExecMachine("missing"))
+}
+
+func Test_execReleaseCommandRefusesNewImage(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{PrimaryRegion: "ord"})
+	require.NoError(t, err)
+	require.NoError(t, md.appConfig.SetMachinesPlatform())
+	md.img = "registry.fly.io/my-app:deployment-2"
+	m := groupMachine("m1", "app", "ord")
+	m.State = api.MachineStateStarted
+	m.Config.Image = "registry.fly.io/my-app:deployment-1"
+	ios, _, _, _ := iostreams.Test()
+	md.machineSet = machine.NewMachineSet(nil, ios, []*api.Machine{m})
+
+	err = md.execReleaseCommand(context.Background(), appconfig.ReleaseCommand{Command: "bin/migrate"})
+	assert.ErrorContains(t, err, "--release-command-on-existing can't be used when the image changes")
+	assert.False(t, retryableReleaseCommandError(context.Background(), err))
+
+	assert.False(t, md.runsDeployedImage(m))
+	m.ImageRef.Digest = "sha256:abc"
+	md.imgDigest = "sha256:abc"
+	assert.True(t, md.runsDeployedImage(m))
+	m.ImageRef.Digest = ""
+	m.Config.Image = md.img
+	assert.True(t, md.runsDeployedImage(m))
+}
@@ -0,0 +1,74 @@
+package deploy
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/terminal"
+)
+
+// releaseMetadataKeys are the metadata keys setMachineReleaseData writes
+var releaseMetadataKeys = []string{
+	api.MachineConfigMetadataKeyFlyReleaseId,
+	api.MachineConfigMetadataKeyFlyReleaseVersion,
+	api.MachineConfigMetadataKeyFlyReleaseSource,
+	api.MachineConfigMetadataKeyFlyImageDigest,
+	api.MachineConfigMetadataKeyFlyImageTag,
+	api.MachineConfigMetadataKeyFlySourceHash,
+}
+
+// releaseMetadataOf copies the release metadata of m, empty values are left out
+func releaseMetadataOf(m *api.Machine) map[string]string {
+	metadata := map[string]string{}
+	if m.Config == nil {
+		return metadata
+	}
+	for _, key := range releaseMetadataKeys {
+		if value := m.Config.Metadata[key]; value != "" {
+			metadata[key] = value
+		}
+	}
+	return metadata
+}
+
+// restoreReleaseMetadata puts back the release metadata a machine had before its update failed.
+// A failed update can still get far enough to write the new release to the machine, which
+// would then claim a release it doesn't run. It's best effort, failures are only logged.
+func (md *machineDeployment) restoreReleaseMetadata(ctx context.Context, machineID string, prior map[string]string) {
+	current, err := md.flapsClient.Get(ctx, machineID)
+	if err != nil {
+		terminal.Debugf("failed to get machine %s to restore its release metadata: %v\n", machineID, err)
+		return
+	}
+	set, unset := releaseMetadataChanges(current, prior, md.releaseVersion)
+	for key, value := range set {
+		if err := md.flapsClient.SetMetadata(ctx, machineID, key, value); err != nil {
+			terminal.Debugf("%v\n", err)
+		}
+	}
+	for _, key := range unset {
+		if err := md.flapsClient.DeleteMetadata(ctx, machineID, key); err != nil {
+			terminal.Debugf("%v\n", err)
+		}
+	}
+}
+
+// releaseMetadataChanges returns the metadata to set and to remove to bring current back to prior,
+// nothing when current doesn't claim releaseVersion
+func releaseMetadataChanges(current *api.Machine, prior map[string]string, releaseVersion int) (set map[string]string, unset []string) {
+	now := releaseMetadataOf(current)
+	if now[api.MachineConfigMetadataKeyFlyReleaseVersion] != strconv.Itoa(releaseVersion) {
+		return nil, nil
+	}
+	set = map[string]string{}
+	for _, key := range releaseMetadataKeys {
+		switch value, ok := prior[key]; {
+		case ok && now[key] != value:
+			set[key] = value
+		case !ok && now[key] != "":
+			unset = append(unset, key)
+		}
+	}
+	return set, unset
+}
@@ -0,0 +1,39 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/superfly/flyctl/api"
+)
+
+func Test_releaseMetadataChanges(t *testing.T) {
+	before := &api.Machine{ID: "m1", Config: &api.MachineConfig{Metadata: map[string]string{
+		api.MachineConfigMetadataKeyFlyReleaseId:      "rel_3",
+		api.MachineConfigMetadataKeyFlyReleaseVersion: "3",
+		api.MachineConfigMetadataKeyFlyImageDigest:    "sha256:old",
+		"user-added-me": "keep it",
+	}}}
+	prior := releaseMetadataOf(before)
+	assert.NotContains(t, prior, "user-added-me")
+
+	// The failed update wrote the new release
+	after := &api.Machine{ID: "m1", Config: &api.MachineConfig{Metadata: map[string]string{
+		api.MachineConfigMetadataKeyFlyReleaseId:      "rel_4",
+		api.MachineConfigMetadataKeyFlyReleaseVersion: "4",
+		api.MachineConfigMetadataKeyFlyImageDigest:    "sha256:old",
+		api.MachineConfigMetadataKeyFlySourceHash:     "abc",
+		"user-added-me": "keep it",
+	}}}
+	set, unset := releaseMetadataChanges(after, prior, 4)
+	assert.Equal(t, map[string]string{
+		api.MachineConfigMetadataKeyFlyReleaseId:      "rel_3",
+		api.MachineConfigMetadataKeyFlyReleaseVersion: "3",
+	}, set)
+	assert.Equal(t, []string{api.MachineConfigMetadataKeyFlySourceHash}, unset)
+
+	// The machine kept its release, nothing to undo
+	set, unset = releaseMetadataChanges(before, prior, 4)
+	assert.Empty(t, set)
+	assert.Empty(t, unset)
+}
@@ -0,0 +1,115 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/terminal"
+)
+
+const (
+	defaultReleaseReadyInterval = 5 * time.Second
+	defaultReleaseReadyTimeout  = 5 * time.Minute
+	// checkExecTimeout bounds each run of a command checking readiness, in seconds
+	checkExecTimeout = 30
+)
+
+var releaseReadyClient = &http.Client{Timeout: 30 * time.Second}
+
+// waitForReleaseReady waits for [deploy.release_ready] to confirm what the release commands
+// started is done, e.g. migrations applied asynchronously, before machines are updated.
+// The release commands exiting 0 is all a deploy knows otherwise.
+func (md *machineDeployment) waitForReleaseReady(ctx context.Context) error {
+	if md.appConfig.Deploy == nil || md.appConfig.Deploy.ReleaseReady == nil {
+		return nil
+	}
+	rr := md.appConfig.Deploy.ReleaseReady
+	interval, timeout := defaultReleaseReadyInterval, defaultReleaseReadyTimeout
+	if rr.Interval != nil && rr.Interval.Duration > 0 {
+		interval = rr.Interval.Duration
+	}
+	if rr.Timeout != nil && rr.Timeout.Duration > 0 {
+		timeout = rr.Timeout.Duration
+	}
+
+	var check func(context.Context) error
+	if rr.HTTPURL != "" {
+		fmt.Fprintf(md.io.ErrOut, "Waiting up to %s for %s to confirm the release is ready\n", timeout, rr.HTTPURL)
+		check = func(ctx context.Context) error { return probeReleaseReadyURL(ctx, rr.HTTPURL) }
+	} else {
+		lm := md.releaseReadyMachine()
+		if lm == nil {
+			return fmt.Errorf("the [deploy.release_ready] command runs on a started machine of the app, there's none")
+		}
+		fmt.Fprintf(md.io.ErrOut, "Waiting up to %s for `%s` on machine %s to confirm the release is ready\n", timeout, rr.Command, lm.FormattedMachineId())
+		check = func(ctx context.Context) error { return md.execCheckCommand(ctx, lm.Machine().ID, rr.Command) }
+	}
+
+	if err := pollReleaseReady(ctx, interval, timeout, check); err != nil {
+		return err
+	}
+	fmt.Fprintf(md.io.ErrOut, "  Release is ready\n")
+	return nil
+}
+
+// pollReleaseReady runs check every interval until it succeeds or timeout is reached
+func pollReleaseReady(ctx context.Context, interval, timeout time.Duration, check func(context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		err := check(ctx)
+		if err == nil {
+			return nil
+		}
+		terminal.Debugf("release isn't ready yet: %v\n", err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("the release wasn't ready after %s, aborting deployment: %w", timeout, err)
+		case <-time.After(interval):
+		}
+	}
+}
+
+func probeReleaseReadyURL(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := releaseReadyClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // skipcq: GO-S2307
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s answered with status %s", url, resp.Status)
+	}
+	return nil
+}
+
+// execCheckCommand runs command inside the machine, it fails unless the command exits 0
+func (md *machineDeployment) execCheckCommand(ctx context.Context, machineID, command string) error {
+	out, err := md.flapsClient.Exec(ctx, machineID, &api.MachineExecRequest{Cmd: command, Timeout: checkExecTimeout})
+	if err != nil {
+		return err
+	}
+	if out.ExitCode != 0 {
+		return fmt.Errorf("`%s` exited with code %d: %s", command, out.ExitCode, strings.TrimSpace(out.StdErr))
+	}
+	return nil
+}
+
+// releaseReadyMachine picks the machine to run the [deploy.release_ready] command on,
+// it still runs the previous release
+func (md *machineDeployment) releaseReadyMachine() machine.LeasableMachine {
+	for _, lm := range md.machineSet.GetMachines() {
+		if lm.Machine().State == api.MachineStateStarted {
+			return lm
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func Test_waitForReleaseReady_http(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	md, err := stabMachineDeployment(&appconfig.Config{
+		Deploy: &appconfig.Deploy{ReleaseReady: &appconfig.ReleaseReady{
+			HTTPURL:  server.URL,
+			Interval: &api.Duration{Duration: time.Millisecond},
+		}},
+	})
+	require.NoError(t, err)
+	md.io, _, _, _ = iostreams.Test()
+
+	require.NoError(t, md.waitForReleaseReady(context.Background()))
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func Test_pollReleaseReady_timeout(t *testing.T) {
+	notReady := errors.New("schema_migrations is behind")
+	err := pollReleaseReady(context.Background(), time.Millisecond, 20*time.Millisecond, func(context.Context) error {
+		return notReady
+	})
+	assert.ErrorIs(t, err, notReady)
+	assert.Contains(t, err.Error(), "the release wasn't ready after 20ms")
+}
+
+func Test_waitForReleaseReady_unset(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	assert.NoError(t, md.waitForReleaseReady(context.Background()))
+}
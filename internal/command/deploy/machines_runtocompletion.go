@@ -0,0 +1,32 @@
+package deploy
+
+import (
+	"github.com/superfly/flyctl/api"
+)
+
+// runsToCompletion tells whether machines with mConfig are batch jobs meant to exit, rather than
+// servers meant to stay up: they don't restart or they run on a schedule. Deploys don't wait for
+// them to pass health checks, and wait for the unscheduled ones to exit with code 0 instead.
+func runsToCompletion(mConfig *api.MachineConfig) bool {
+	if mConfig == nil {
+		return false
+	}
+	return mConfig.Restart.Policy == api.MachineRestartPolicyNo || runsOnSchedule(mConfig)
+}
+
+// runsOnSchedule tells whether machines with mConfig run on a schedule. Deploys don't wait on
+// them at all, they may not run before their next scheduled time.
+func runsOnSchedule(mConfig *api.MachineConfig) bool {
+	return mConfig != nil && mConfig.Schedule != ""
+}
+
+// latestEventTimestamp returns the timestamp of the most recent event of m, zero without events
+func latestEventTimestamp(m *api.Machine) int64 {
+	var latest int64
+	for _, e := range m.Events {
+		if e != nil && e.Timestamp > latest {
+			latest = e.Timestamp
+		}
+	}
+	return latest
+}
@@ -0,0 +1,31 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/superfly/flyctl/api"
+)
+
+func Test_runsToCompletion(t *testing.T) {
+	assert.False(t, runsToCompletion(nil))
+	assert.False(t, runsToCompletion(&api.MachineConfig{}))
+	assert.False(t, runsToCompletion(&api.MachineConfig{Restart: api.MachineRestart{Policy: api.MachineRestartPolicyOnFailure}}))
+	assert.True(t, runsToCompletion(&api.MachineConfig{Restart: api.MachineRestart{Policy: api.MachineRestartPolicyNo}}))
+	assert.True(t, runsToCompletion(&api.MachineConfig{Schedule: "daily"}))
+}
+
+func Test_runsOnSchedule(t *testing.T) {
+	assert.False(t, runsOnSchedule(nil))
+	assert.False(t, runsOnSchedule(&api.MachineConfig{Restart: api.MachineRestart{Policy: api.MachineRestartPolicyNo}}))
+	assert.True(t, runsOnSchedule(&api.MachineConfig{Schedule: "daily"}))
+}
+
+func Test_latestEventTimestamp(t *testing.T) {
+	assert.Equal(t, int64(0), latestEventTimestamp(&api.Machine{}))
+	assert.Equal(t, int64(300), latestEventTimestamp(&api.Machine{Events: []*api.MachineEvent{
+		{Type: "exit", Timestamp: 300},
+		nil,
+		{Type: "start", Timestamp: 200},
+	}}))
+}
@@ -0,0 +1,59 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// skipHealthChecksKey is what to type, followed by Enter, to stop waiting on the health checks of a machine
+const skipHealthChecksKey = "s"
+
+// watchForSkipRequests lets the health check waits read the terminal for requests to skip the
+// wait of the machine being updated, for when the app is known to be fine and the wait is overly
+// cautious. Only sessions whose input is a terminal get it. The terminal is only read while a
+// health check wait runs, prompts and the shell get their input back otherwise.
+func (md *machineDeployment) watchForSkipRequests() func() {
+	in, ok := md.io.In.(*os.File)
+	if !skipRequestsSupported || !ok || !md.io.CanPrompt() {
+		return func() {}
+	}
+	md.waitForSkip = func(ctx context.Context) bool { return readSkipRequest(ctx, in) }
+	fmt.Fprintf(md.io.ErrOut, "Type '%s' and press Enter to stop waiting on the health checks of the machine being updated\n", skipHealthChecksKey)
+	return func() {
+		md.waitForSkip = nil
+	}
+}
+
+// skippableWait runs wait with a context canceled when a skip is requested while it runs.
+// A wait cut short that way isn't an error, skipped tells it happened. The terminal isn't
+// read anymore once skippableWait returns.
+func (md *machineDeployment) skippableWait(ctx context.Context, wait func(context.Context) error) (skipped bool, err error) {
+	if md.waitForSkip == nil {
+		return false, wait(ctx)
+	}
+
+	waitCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var requested atomic.Bool
+	reading := make(chan struct{})
+	go func() {
+		defer close(reading)
+		if md.waitForSkip(waitCtx) {
+			requested.Store(true)
+			cancel()
+		}
+	}()
+
+	err = wait(waitCtx)
+	cancel()
+	<-reading
+	if err != nil {
+		if requested.Load() {
+			return true, nil
+		}
+		return false, err
+	}
+	return false, nil
+}
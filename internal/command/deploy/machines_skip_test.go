@@ -0,0 +1,44 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/internal/appconfig"
+)
+
+func Test_skippableWait(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	requests := make(chan struct{}, 1)
+	reading := false
+	md.waitForSkip = func(ctx context.Context) bool {
+		reading = true
+		defer func() { reading = false }()
+		select {
+		case <-requests:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	blocked := func(ctx context.Context) error {
+		requests <- struct{}{}
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	skipped, err := md.skippableWait(context.Background(), blocked)
+	assert.True(t, skipped)
+	assert.NoError(t, err)
+
+	// Failures that weren't requested are passed through, and the terminal isn't read anymore
+	failure := errors.New("health checks failed")
+	skipped, err = md.skippableWait(context.Background(), func(context.Context) error { return failure })
+	assert.False(t, skipped)
+	assert.ErrorIs(t, err, failure)
+	assert.False(t, reading)
+}
@@ -0,0 +1,52 @@
+//go:build !windows
+// +build !windows
+
+package deploy
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+const skipRequestsSupported = true
+
+// skipPollTimeoutMs bounds how long a skip request read keeps going once its wait is over
+const skipPollTimeoutMs = 100
+
+// readSkipRequest reads lines from the terminal in until one is skipHealthChecksKey, true, or ctx
+// is done, false. The terminal is polled rather than read in the background, so nothing typed
+// after ctx is done gets consumed. Lines typed before the call are discarded, they were meant for
+// machines already done.
+func readSkipRequest(ctx context.Context, in *os.File) bool {
+	fd := int(in.Fd())
+	buf := make([]byte, 256)
+	discarding := true
+	for ctx.Err() == nil {
+		timeout := skipPollTimeoutMs
+		if discarding {
+			timeout = 0
+		}
+		fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+		n, err := unix.Poll(fds, timeout)
+		switch {
+		case err == unix.EINTR:
+			continue
+		case err != nil || fds[0].Revents&(unix.POLLERR|unix.POLLHUP|unix.POLLNVAL) != 0:
+			return false
+		case n == 0:
+			discarding = false
+			continue
+		}
+		read, err := unix.Read(fd, buf)
+		if err != nil || read <= 0 {
+			return false
+		}
+		if !discarding && strings.TrimSpace(string(buf[:read])) == skipHealthChecksKey {
+			return true
+		}
+	}
+	return false
+}
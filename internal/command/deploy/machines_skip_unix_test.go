@@ -0,0 +1,49 @@
+//go:build !windows
+// +build !windows
+
+package deploy
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_readSkipRequest(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	// A request typed before the read started doesn't count
+	_, err = w.WriteString("s\n")
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	got := make(chan bool)
+	go func() { got <- readSkipRequest(ctx, r) }()
+	time.Sleep(50 * time.Millisecond)
+	_, err = w.WriteString("x\n")
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	_, err = w.WriteString("s\n")
+	require.NoError(t, err)
+	assert.True(t, <-got)
+
+	// Once its context is done the read stops without consuming what's typed afterwards
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() { got <- readSkipRequest(ctx, r) }()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	assert.False(t, <-got)
+	_, err = w.WriteString("y\n")
+	require.NoError(t, err)
+	buf := make([]byte, 8)
+	n, err := r.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "y\n", string(buf[:n]))
+}
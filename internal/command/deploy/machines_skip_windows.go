@@ -0,0 +1,17 @@
+//go:build windows
+// +build windows
+
+package deploy
+
+import (
+	"context"
+	"os"
+)
+
+// Windows consoles can't be polled, a background read would keep consuming input after the wait
+const skipRequestsSupported = false
+
+func readSkipRequest(ctx context.Context, _ *os.File) bool {
+	<-ctx.Done()
+	return false
+}
@@ -0,0 +1,124 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/internal/render"
+	"github.com/superfly/flyctl/terminal"
+)
+
+const (
+	machineOutcomeLaunched      = "launched"
+	machineOutcomeUpdated       = "updated"
+	machineOutcomeUpToDate      = "up to date"
+	machineOutcomeUnhealthy     = "unhealthy"
+	machineOutcomeHealthSkipped = "health checks skipped"
+	machineOutcomeFailed        = "failed"
+	machineOutcomePinned        = "pinned"
+)
+
+// deployedMachine is a machine the deploy went through and what came of it
+type deployedMachine struct {
+	lm      machine.LeasableMachine
+	outcome string
+}
+
+type machineSummaryRow struct {
+	ID             string `json:"id"`
+	Region         string `json:"region"`
+	ProcessGroup   string `json:"process_group"`
+	ReleaseVersion string `json:"release_version"`
+	State          string `json:"state"`
+	Health         string `json:"health"`
+	Outcome        string `json:"outcome"`
+}
+
+// recordMachine notes the outcome of a machine for the summary printed at the end of the deploy.
+// A failure isn't overwritten by the machine completing afterwards.
+func (md *machineDeployment) recordMachine(lm machine.LeasableMachine, outcome string) {
+	for i, d := range md.deployed {
+		if d.lm.Machine().ID != lm.Machine().ID {
+			continue
+		}
+		if d.outcome != machineOutcomeFailed {
+			md.deployed[i] = deployedMachine{lm: lm, outcome: outcome}
+		}
+		return
+	}
+	md.deployed = append(md.deployed, deployedMachine{lm: lm, outcome: outcome})
+}
+
+// deploySummaryJSON is the summary printed at the end of the deploy with --json, its shape is
+// documented in the help of fly deploy
+type deploySummaryJSON struct {
+	Machines []machineSummaryRow `json:"machines"`
+	Timings  deployTimingsJSON   `json:"timings"`
+	// Regions are the machines of each process group by region before and after the deploy
+	Regions []regionChange `json:"regions,omitempty"`
+}
+
+// printMachineSummary shows the state and health of the machines the deploy went through
+// and how long its phases took, as a table or as a JSON object with --json. The latest state
+// of the machines is fetched in a single call, the state the deploy last saw is shown when that fails.
+func (md *machineDeployment) printMachineSummary(ctx context.Context) {
+	md.timings.Total = time.Since(md.startedAt)
+
+	var current []*api.Machine
+	if md.flapsClient != nil && len(md.deployed) > 0 {
+		var err error
+		if current, err = md.flapsClient.ListActive(ctx); err != nil {
+			terminal.Debugf("failed to list machines for the deploy summary: %v\n", err)
+		}
+	}
+	rows := machineSummaryRows(md.deployed, current)
+
+	if md.jsonOutput {
+		if err := render.JSON(md.io.Out, deploySummaryJSON{Machines: rows, Timings: md.timings.json(), Regions: md.regionChanges}); err != nil {
+			terminal.Debugf("failed to render the deploy summary: %v\n", err)
+		}
+		return
+	}
+	if len(rows) > 0 {
+		table := make([][]string, 0, len(rows))
+		for _, r := range rows {
+			table = append(table, []string{r.ID, r.Region, r.ProcessGroup, r.ReleaseVersion, r.State, r.Health, r.Outcome})
+		}
+		if err := render.Table(md.io.Out, "Machines", table, "ID", "Region", "Process", "Version", "State", "Health Checks", "Outcome"); err != nil {
+			terminal.Debugf("failed to render the deploy summary: %v\n", err)
+		}
+	}
+	fmt.Fprintf(md.io.Out, "Deploy timings: %s\n", md.timings)
+}
+
+// machineSummaryRows describes deployed, preferring the state of the machines in current
+func machineSummaryRows(deployed []deployedMachine, current []*api.Machine) []machineSummaryRow {
+	byID := map[string]*api.Machine{}
+	for _, m := range current {
+		byID[m.ID] = m
+	}
+	rows := make([]machineSummaryRow, 0, len(deployed))
+	for _, d := range deployed {
+		m := d.lm.Machine()
+		if fresh, ok := byID[m.ID]; ok {
+			m = fresh
+		}
+		var version string
+		if m.Config != nil {
+			version = m.Config.Metadata[api.MachineConfigMetadataKeyFlyReleaseVersion]
+		}
+		rows = append(rows, machineSummaryRow{
+			ID:             m.ID,
+			Region:         m.Region,
+			ProcessGroup:   m.ProcessGroup(),
+			ReleaseVersion: version,
+			State:          m.State,
+			Health:         render.MachineHealthChecksSummary(m),
+			Outcome:        d.outcome,
+		})
+	}
+	return rows
+}
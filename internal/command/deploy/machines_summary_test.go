@@ -0,0 +1,49 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func Test_recordMachine_keepsFailures(t *testing.T) {
+	ios, _, _, _ := iostreams.Test()
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+
+	m1 := machine.NewLeasableMachine(nil, ios, groupMachine("m1", "app", "ord"))
+	m2 := machine.NewLeasableMachine(nil, ios, groupMachine("m2", "app", "ams"))
+	md.recordMachine(m1, machineOutcomeFailed)
+	md.recordMachine(m2, machineOutcomeUpToDate)
+	md.recordMachine(m1, machineOutcomeUpdated)
+	md.recordMachine(m2, machineOutcomeUnhealthy)
+
+	assert.Equal(t, []deployedMachine{{lm: m1, outcome: machineOutcomeFailed}, {lm: m2, outcome: machineOutcomeUnhealthy}}, md.deployed)
+}
+
+func Test_machineSummaryRows(t *testing.T) {
+	ios, _, _, _ := iostreams.Test()
+	held := groupMachine("m1", "web", "ord")
+	held.State = api.MachineStateStopped
+	held.Config.Metadata[api.MachineConfigMetadataKeyFlyReleaseVersion] = "3"
+
+	fresh := groupMachine("m1", "web", "ord")
+	fresh.State = api.MachineStateStarted
+	fresh.Config.Metadata[api.MachineConfigMetadataKeyFlyReleaseVersion] = "4"
+	fresh.Checks = []*api.MachineCheckStatus{{Name: "http", Status: "passing"}}
+
+	deployed := []deployedMachine{
+		{lm: machine.NewLeasableMachine(nil, ios, held), outcome: machineOutcomeUpdated},
+		{lm: machine.NewLeasableMachine(nil, ios, groupMachine("m2", "worker", "ams")), outcome: machineOutcomeFailed},
+	}
+	rows := machineSummaryRows(deployed, []*api.Machine{fresh})
+	assert.Equal(t, []machineSummaryRow{
+		{ID: "m1", Region: "ord", ProcessGroup: "web", ReleaseVersion: "4", State: "started", Health: "1 total, 1 passing", Outcome: "updated"},
+		{ID: "m2", Region: "ams", ProcessGroup: "worker", Outcome: "failed"},
+	}, rows)
+}
@@ -1,13 +1,18 @@
 package deploy
 
 import (
+	"context"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/superfly/flyctl/api"
 	"github.com/superfly/flyctl/internal/appconfig"
 	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/iostreams"
 )
 
 func stabMachineDeployment(appConfig *appconfig.Config) (*machineDeployment, error) {
@@ -34,7 +39,7 @@ func Test_resolveUpdatedMachineConfig_Basic(t *testing.T) {
 		},
 	})
 	require.NoError(t, err)
-	li, err := md.launchInputForLaunch("", nil)
+	li, err := md.launchInputForLaunch("", "", nil)
 	require.NoError(t, err)
 	assert.Equal(t, &api.LaunchMachineInput{
 		OrgSlug: "my-dangling-org",
@@ -97,7 +102,7 @@ func Test_resolveUpdatedMachineConfig_ReleaseCommand(t *testing.T) {
 	}
 
 	// New app machine
-	li, err := md.launchInputForLaunch("", nil)
+	li, err := md.launchInputForLaunch("", "", nil)
 	require.NoError(t, err)
 	assert.Equal(t, &api.LaunchMachineInput{
 		OrgSlug: "my-dangling-org",
@@ -169,7 +174,7 @@ func Test_resolveUpdatedMachineConfig_ReleaseCommand(t *testing.T) {
 			},
 			Guest: api.MachinePresets["shared-cpu-2x"],
 		},
-	}, md.launchInputForReleaseCommand(nil))
+	}, md.launchInputForReleaseCommand(nil, md.appConfig.ReleaseCommands()[0]))
 
 	// Update existing release command machine
 	origMachine := &api.Machine{
@@ -214,7 +219,43 @@ func Test_resolveUpdatedMachineConfig_ReleaseCommand(t *testing.T) {
 			},
 			Guest: api.MachinePresets["shared-cpu-2x"],
 		},
-	}, md.launchInputForReleaseCommand(origMachine))
+	}, md.launchInputForReleaseCommand(origMachine, md.appConfig.ReleaseCommands()[0]))
+}
+
+// Test release commands of a process group run in a machine sized like that group
+func Test_launchInputForReleaseCommand_processGroup(t *testing.T) {
+	appConfig := &appconfig.Config{
+		AppName:   "my-cool-app",
+		Processes: map[string]string{"app": "run app", "analytics": "run analytics"},
+		Deploy: &appconfig.Deploy{
+			ReleaseCommand:  "migrate",
+			ReleaseCommands: []appconfig.ReleaseCommand{{Command: "migrate-analytics", ProcessGroup: "analytics"}},
+		},
+	}
+	md, err := stabMachineDeployment(appConfig)
+	require.NoError(t, err)
+	ios, _, _, _ := iostreams.Test()
+	md.machineSet = machine.NewMachineSet(nil, ios, []*api.Machine{
+		{ID: "app1", Config: &api.MachineConfig{
+			Metadata: map[string]string{api.MachineConfigMetadataKeyFlyProcessGroup: "app"},
+			Guest:    api.MachinePresets["shared-cpu-1x"],
+		}},
+		{ID: "analytics1", Config: &api.MachineConfig{
+			Metadata: map[string]string{api.MachineConfigMetadataKeyFlyProcessGroup: "analytics"},
+			Guest:    api.MachinePresets["performance-2x"],
+		}},
+	})
+
+	cmds := appConfig.ReleaseCommands()
+	require.Len(t, cmds, 2)
+
+	li := md.launchInputForReleaseCommand(nil, cmds[0])
+	assert.Equal(t, []string{"migrate"}, li.Config.Init.Cmd)
+	assert.Equal(t, api.MachinePresets["shared-cpu-1x"], li.Config.Guest)
+
+	li = md.launchInputForReleaseCommand(nil, cmds[1])
+	assert.Equal(t, []string{"migrate-analytics"}, li.Config.Init.Cmd)
+	assert.Equal(t, api.MachinePresets["performance-2x"], li.Config.Guest)
 }
 
 // Test Mounts
@@ -231,7 +272,7 @@ func Test_resolveUpdatedMachineConfig_Mounts(t *testing.T) {
 	}
 
 	// New app machine
-	li, err := md.launchInputForLaunch("", nil)
+	li, err := md.launchInputForLaunch("", "", nil)
 	require.NoError(t, err)
 	assert.Equal(t, &api.LaunchMachineInput{
 		OrgSlug: "my-dangling-org",
@@ -366,3 +407,447 @@ func Test_resolveUpdatedMachineConfig_restartOnlyProcessGroup(t *testing.T) {
 		},
 	}, md.launchInputForRestart(origMachine))
 }
+
+func Test_formatIndex(t *testing.T) {
+	assert.Equal(t, "[0/0]", formatIndex(0, 0))
+	assert.Equal(t, "[1/1]", formatIndex(0, 1))
+	assert.Equal(t, "[03/12]", formatIndex(2, 12))
+}
+
+func Test_sameMachineConfig(t *testing.T) {
+	current := &api.MachineConfig{
+		Image: "super/balloon",
+		Metadata: map[string]string{
+			"fly_release_id":      "old",
+			"fly_release_version": "1",
+			"fly_process_group":   "app",
+		},
+	}
+	desired := &api.MachineConfig{
+		Image: "super/balloon",
+		Metadata: map[string]string{
+			"fly_release_id":      "new",
+			"fly_release_version": "2",
+			"fly_process_group":   "app",
+		},
+	}
+	assert.True(t, sameMachineConfig(current, desired))
+
+	desired.Image = "super/globe"
+	assert.False(t, sameMachineConfig(current, desired))
+}
+
+func Test_sortUpdateEntries(t *testing.T) {
+	entries := func() []*machineUpdateEntry {
+		return []*machineUpdateEntry{
+			{launchInput: &api.LaunchMachineInput{ID: "1", Region: "scl"}},
+			{launchInput: &api.LaunchMachineInput{ID: "2", Region: "ord"}},
+			{launchInput: &api.LaunchMachineInput{ID: "3", Region: "scl"}},
+			{launchInput: &api.LaunchMachineInput{ID: "4", Region: "mad"}},
+		}
+	}
+	ids := func(es []*machineUpdateEntry) (out []string) {
+		for _, e := range es {
+			out = append(out, e.launchInput.ID)
+		}
+		return
+	}
+
+	es := entries()
+	sortUpdateEntries(es, "scl", updateOrderPrimaryLast)
+	assert.Equal(t, []string{"2", "4", "1", "3"}, ids(es))
+
+	es = entries()
+	sortUpdateEntries(es, "ord", updateOrderPrimaryFirst)
+	assert.Equal(t, []string{"2", "1", "3", "4"}, ids(es))
+}
+
+func Test_imageTag(t *testing.T) {
+	assert.Equal(t, "deployment-01H5ZKD4V0", imageTag("registry.fly.io/my-app:deployment-01H5ZKD4V0"))
+	assert.Equal(t, "deployment-01H5ZKD4V0", imageTag("registry.fly.io/my-app:deployment-01H5ZKD4V0@sha256:1234"))
+	assert.Equal(t, "", imageTag("localhost:5000/my-app@sha256:1234"))
+	assert.Equal(t, "", imageTag("my-app"))
+}
+
+func Test_resolveProcessGroupChanges_expandRegions(t *testing.T) {
+	appConfig := &appconfig.Config{
+		PrimaryRegion: "scl",
+		Regions:       []string{"scl", "ord", "ams"},
+		Processes: map[string]string{
+			"web":    "run web",
+			"worker": "run worker",
+		},
+	}
+	require.NoError(t, appConfig.SetMachinesPlatform())
+	md, err := stabMachineDeployment(appConfig)
+	require.NoError(t, err)
+	ios, _, _, _ := iostreams.Test()
+	md.machineSet = machine.NewMachineSet(nil, ios, []*api.Machine{
+		{ID: "web1", Region: "scl", Config: &api.MachineConfig{Metadata: map[string]string{"fly_process_group": "web"}}},
+		{ID: "web2", Region: "ord", Config: &api.MachineConfig{Metadata: map[string]string{"fly_process_group": "web"}}},
+	})
+
+	// Regions are only expanded on demand
+	diff := md.resolveProcessGroupChanges()
+	assert.Equal(t, map[string]bool{"worker": true}, diff.groupsNeedingMachines)
+	assert.Empty(t, diff.regionsNeedingMachines)
+
+	md.expandRegions = true
+	diff = md.resolveProcessGroupChanges()
+	assert.Equal(t, map[string]bool{"worker": true}, diff.groupsNeedingMachines)
+	assert.Equal(t, map[string][]string{
+		"web":    {"ams"},
+		"worker": {"ord", "ams"},
+	}, diff.regionsNeedingMachines)
+}
+
+func Test_allowedUnhealthy(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+
+	// Strict by default
+	assert.Equal(t, 0, md.allowedUnhealthy(200))
+
+	require.NoError(t, md.setMinHealthy("95%"))
+	assert.Equal(t, 10, md.allowedUnhealthy(200))
+	assert.Equal(t, 0, md.allowedUnhealthy(10))
+
+	require.NoError(t, md.setMinHealthy("100%"))
+	assert.Equal(t, 0, md.allowedUnhealthy(200))
+
+	assert.Error(t, md.setMinHealthy("150%"))
+	assert.Error(t, md.setMinHealthy("most"))
+}
+
+func Test_setImmediateMaxErrors(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	require.NoError(t, md.setStrategy("immediate"))
+
+	require.NoError(t, md.setImmediateMaxErrors(0))
+	assert.Equal(t, 0, md.immediateMaxErrors)
+	require.NoError(t, md.setImmediateMaxErrors(3))
+	assert.Equal(t, 3, md.immediateMaxErrors)
+	assert.Error(t, md.setImmediateMaxErrors(-1))
+}
+
+func Test_setStrategy(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	require.NoError(t, md.setStrategy(""))
+	assert.Equal(t, "rolling", md.strategy)
+
+	md.appConfig.Deploy = &appconfig.Deploy{Strategy: "immediate"}
+	require.NoError(t, md.setStrategy(""))
+	assert.Equal(t, "immediate", md.strategy)
+	// The flag wins over fly.toml
+	require.NoError(t, md.setStrategy("rolling"))
+	assert.Equal(t, "rolling", md.strategy)
+
+	md.appConfig.Deploy.Strategy = "rollling"
+	assert.ErrorContains(t, md.setStrategy(""), "'rollling' from fly.toml [deploy] strategy")
+	assert.ErrorContains(t, md.setStrategy("canary"), "'canary' from --strategy")
+}
+
+func Test_waitTimeoutFor(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	md.waitTimeout = 2 * time.Minute
+	md.newMachineWaitTimeout = 4 * time.Minute
+
+	ios, _, _, _ := iostreams.Test()
+	lm := machine.NewLeasableMachine(nil, ios, &api.Machine{ID: "ab1234567890"})
+	updated := &machineUpdateEntry{leasableMachine: lm, launchInput: &api.LaunchMachineInput{ID: "ab1234567890"}}
+	replaced := &machineUpdateEntry{leasableMachine: lm, launchInput: &api.LaunchMachineInput{}}
+
+	assert.Equal(t, 2*time.Minute, md.waitTimeoutFor(updated))
+	assert.Equal(t, 4*time.Minute, md.waitTimeoutFor(replaced))
+}
+
+func Test_validateVolumeConfig_newGroupVolumeCount(t *testing.T) {
+	appConfig := &appconfig.Config{
+		AppName: "my-cool-app",
+		Mounts: []appconfig.Mount{
+			{Source: "data", Destination: "/data"},
+			{Source: "data", Destination: "/backups"},
+		},
+	}
+	require.NoError(t, appConfig.SetMachinesPlatform())
+	md, err := stabMachineDeployment(appConfig)
+	require.NoError(t, err)
+
+	md.volumes = map[string][]api.Volume{"data": {{ID: "vol_12345", Name: "data"}}}
+	assert.ErrorContains(t, md.validateVolumeConfig(), "requires 2 unattached 'data' volume(s) but found 1")
+
+	md.volumes["data"] = append(md.volumes["data"], api.Volume{ID: "vol_67890", Name: "data"})
+	assert.NoError(t, md.validateVolumeConfig())
+}
+
+func Test_setImagePullPolicy(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+
+	require.NoError(t, md.setImagePullPolicy(""))
+	assert.Equal(t, imagePullPolicyAuto, md.imagePullPolicy)
+	require.NoError(t, md.setImagePullPolicy("always"))
+	assert.Equal(t, imagePullPolicyAlways, md.imagePullPolicy)
+	assert.Error(t, md.setImagePullPolicy("never"))
+
+	// Images already pinned to a digest don't need the registry
+	md.img = "registry.fly.io/my-cool-app:latest@sha256:abcdef"
+	require.NoError(t, md.resolveImgDigest(context.Background()))
+	assert.Equal(t, "sha256:abcdef", md.imgDigest)
+}
+
+func Test_updateExistingMachines_skipHealthChecksByLabel(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	ios, _, _, _ := iostreams.Test()
+	md.io = ios
+	md.colorize = ios.ColorScheme()
+	md.strategy = "rolling"
+
+	// A started machine with failing checks would be waited on, the label skips the wait
+	m := groupMachine("m1", "app", "ord")
+	m.State = api.MachineStateStarted
+	m.Checks = []*api.MachineCheckStatus{{Name: "http", Status: "critical"}}
+	m.Config.Metadata[api.MachineConfigMetadataKeyFlySkipHealthChecks] = "true"
+	entry := &machineUpdateEntry{
+		leasableMachine: machine.NewLeasableMachine(nil, ios, m),
+		launchInput:     &api.LaunchMachineInput{ID: m.ID, Config: m.Config},
+		upToDate:        true,
+	}
+
+	require.NoError(t, md.updateExistingMachines(context.Background(), []*machineUpdateEntry{entry}))
+	assert.Equal(t, machineOutcomeUpToDate, md.deployed[0].outcome)
+}
+
+// suspendedMachine fakes a suspended machine an update leaves suspended until it's resumed
+type suspendedMachine struct {
+	machine.LeasableMachine
+	m         *api.Machine
+	calls     []string
+	resumeErr error
+	// updatedState is the state the update returns, suspended by default
+	updatedState string
+}
+
+func (s *suspendedMachine) Machine() *api.Machine      { return s.m }
+func (s *suspendedMachine) FormattedMachineId() string { return s.m.ID }
+
+func (s *suspendedMachine) Update(_ context.Context, input api.LaunchMachineInput) error {
+	s.calls = append(s.calls, "update")
+	state := s.updatedState
+	if state == "" {
+		state = api.MachineStateSuspended
+	}
+	s.m = &api.Machine{ID: s.m.ID, State: state, Config: input.Config}
+	return nil
+}
+
+func (s *suspendedMachine) Resume(context.Context) error {
+	s.calls = append(s.calls, "resume")
+	if s.resumeErr != nil {
+		return s.resumeErr
+	}
+	s.m.State = api.MachineStateStarted
+	return nil
+}
+
+func (s *suspendedMachine) WaitForState(_ context.Context, state string, _ time.Duration, _ string) error {
+	s.calls = append(s.calls, "wait "+state)
+	if s.m.State != state {
+		return fmt.Errorf("machine is %s", s.m.State)
+	}
+	return nil
+}
+
+func (s *suspendedMachine) WaitForConsecutiveHealthchecksToPass(context.Context, time.Duration, int, string) error {
+	s.calls = append(s.calls, "health checks")
+	return nil
+}
+
+func Test_updateExistingMachines_resumesSuspended(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	ios, _, _, _ := iostreams.Test()
+	md.io = ios
+	md.colorize = ios.ColorScheme()
+	md.strategy = "rolling"
+
+	m := groupMachine("m1", "app", "ord")
+	m.State = api.MachineStateSuspended
+	lm := &suspendedMachine{m: m}
+	entry := &machineUpdateEntry{leasableMachine: lm, launchInput: &api.LaunchMachineInput{ID: m.ID, Config: m.Config}}
+
+	require.NoError(t, md.updateExistingMachines(context.Background(), []*machineUpdateEntry{entry}))
+	assert.Equal(t, []string{"update", "resume", "wait started", "health checks"}, lm.calls)
+	assert.Equal(t, api.MachineStateStarted, lm.Machine().State)
+	assert.Equal(t, machineOutcomeUpdated, md.deployed[0].outcome)
+
+	// The update may answer with a transitional state, the machine is resumed all the same
+	m = groupMachine("m2", "app", "ord")
+	m.State = api.MachineStateSuspended
+	lm = &suspendedMachine{m: m, updatedState: "replacing"}
+	entry = &machineUpdateEntry{leasableMachine: lm, launchInput: &api.LaunchMachineInput{ID: m.ID, Config: m.Config}}
+	require.NoError(t, md.updateExistingMachines(context.Background(), []*machineUpdateEntry{entry}))
+	assert.Equal(t, []string{"update", "resume", "wait started", "health checks"}, lm.calls)
+}
+
+func Test_updateExistingMachines_immediateFailureOutcome(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	ios, _, _, errOut := iostreams.Test()
+	md.io = ios
+	md.colorize = ios.ColorScheme()
+	md.alertOut = ios.ErrOut
+	md.strategy = "immediate"
+
+	m := groupMachine("m1", "app", "ord")
+	m.State = api.MachineStateSuspended
+	lm := &suspendedMachine{m: m, resumeErr: fmt.Errorf("resume refused")}
+	entry := &machineUpdateEntry{leasableMachine: lm, launchInput: &api.LaunchMachineInput{ID: m.ID, Config: m.Config}}
+
+	require.NoError(t, md.updateExistingMachines(context.Background(), []*machineUpdateEntry{entry}))
+	assert.Contains(t, errOut.String(), "Continuing after error: machine m1: resume refused")
+	assert.Contains(t, errOut.String(), "Machine m1 updated but failed to resume")
+	assert.NotContains(t, errOut.String(), "Machine m1 resumed and updated")
+	assert.Equal(t, machineOutcomeFailed, md.deployed[0].outcome)
+}
+
+func Test_updateExistingMachines_scheduledNotWaited(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	ios, _, _, _ := iostreams.Test()
+	md.io = ios
+	md.colorize = ios.ColorScheme()
+	md.strategy = "rolling"
+
+	m := groupMachine("m1", "app", "ord")
+	m.State = api.MachineStateStopped
+	m.Config.Schedule = "daily"
+	lm := &suspendedMachine{m: m}
+	entry := &machineUpdateEntry{leasableMachine: lm, launchInput: &api.LaunchMachineInput{ID: m.ID, Config: m.Config}}
+
+	// Waiting for an exit would time out until the next daily run
+	require.NoError(t, md.updateExistingMachines(context.Background(), []*machineUpdateEntry{entry}))
+	assert.Equal(t, []string{"update"}, lm.calls)
+}
+
+func Test_printSkippedErrors(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	ios, _, _, errOut := iostreams.Test()
+	md.io = ios
+	md.alertOut = ios.ErrOut
+
+	ok := &machineUpdateEntry{leasableMachine: machine.NewLeasableMachine(nil, ios, groupMachine("m1", "app", "ord"))}
+	failed := &machineUpdateEntry{
+		leasableMachine: machine.NewLeasableMachine(nil, ios, groupMachine("m2", "worker", "ams")),
+		err:             fmt.Errorf("failed to update"),
+	}
+
+	md.printSkippedErrors([]*machineUpdateEntry{ok})
+	assert.Empty(t, errOut.String())
+
+	md.printSkippedErrors([]*machineUpdateEntry{ok, failed})
+	assert.Contains(t, errOut.String(), "m2 (group worker, region ams): failed to update")
+	assert.NotContains(t, errOut.String(), "m1")
+}
+
+func Test_confirmDestroy(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	md.io, _, _, _ = iostreams.Test()
+	md.confirmDestroyOver = defaultConfirmDestroyOver
+
+	assert.NoError(t, md.confirmDestroy(context.Background(), 3))
+	// Not interactive, more machines than the threshold need --auto-confirm
+	err = md.confirmDestroy(context.Background(), 4)
+	assert.ErrorContains(t, err, "would destroy 4 machines of process groups removed from fly.toml, more than --confirm-destroy-over=3")
+
+	md.autoConfirm = true
+	assert.NoError(t, md.confirmDestroy(context.Background(), 4))
+}
+
+// slowMachine fakes a machine whose update takes delay, or until the context is done
+type slowMachine struct {
+	machine.LeasableMachine
+	m     *api.Machine
+	delay time.Duration
+}
+
+func (s *slowMachine) Machine() *api.Machine      { return s.m }
+func (s *slowMachine) FormattedMachineId() string { return s.m.ID }
+
+func (s *slowMachine) Update(ctx context.Context, _ api.LaunchMachineInput) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(s.delay):
+		return nil
+	}
+}
+
+func (s *slowMachine) WaitForState(context.Context, string, time.Duration, string) error {
+	return nil
+}
+
+func Test_updateExistingMachines_deployTimeout(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	ios, _, _, _ := iostreams.Test()
+	md.io = ios
+	md.colorize = ios.ColorScheme()
+	md.strategy = "rolling"
+	md.skipHealthChecks = true
+
+	var entries []*machineUpdateEntry
+	for i, delay := range []time.Duration{0, time.Minute, 0} {
+		m := groupMachine(fmt.Sprintf("m%d", i+1), "app", "ord")
+		entries = append(entries, &machineUpdateEntry{
+			leasableMachine: &slowMachine{m: m, delay: delay},
+			launchInput:     &api.LaunchMachineInput{ID: m.ID, Config: m.Config},
+		})
+	}
+
+	// The deploy timeout expires while the second machine updates
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = md.updateExistingMachines(ctx, entries)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "1 of 3 machines were updated")
+}
+
+func Test_pinnedMachines(t *testing.T) {
+	cfg := &appconfig.Config{Processes: map[string]string{"web": "run web"}}
+	require.NoError(t, cfg.SetMachinesPlatform())
+	md, err := stabMachineDeployment(cfg)
+	require.NoError(t, err)
+	ios, _, _, errOut := iostreams.Test()
+	md.io = ios
+	md.colorize = ios.ColorScheme()
+
+	pinned := func(m *api.Machine) *api.Machine {
+		m.Config.Metadata[api.MachineConfigMetadataKeyFlyDeployPinned] = "true"
+		return m
+	}
+	md.machineSet = machine.NewMachineSet(nil, ios, []*api.Machine{
+		groupMachine("web1", "web", "ord"),
+		pinned(groupMachine("web2", "web", "ord")),
+		groupMachine("old1", "old", "ord"),
+		pinned(groupMachine("old2", "old", "ord")),
+	})
+
+	// Updates skip pinned machines and report them
+	ids := lo.Map(md.unpinnedMachines(), func(lm machine.LeasableMachine, _ int) string { return lm.Machine().ID })
+	assert.Equal(t, []string{"web1", "old1"}, ids)
+	assert.Contains(t, errOut.String(), "Skipping machine web2 [web] because it is pinned with fly_deploy_pinned=true")
+	assert.Len(t, md.deployed, 2)
+
+	// Pinned machines of a removed group stay
+	diff := md.resolveProcessGroupChanges()
+	assert.Equal(t, map[string]int{"old": 1}, diff.groupsToRemove)
+	require.Len(t, diff.machinesToRemove, 1)
+	assert.Equal(t, "old1", diff.machinesToRemove[0].Machine().ID)
+}
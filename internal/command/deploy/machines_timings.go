@@ -0,0 +1,64 @@
+package deploy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// deployTimings is how long each phase of a deploy took, zero for the phases it went without
+type deployTimings struct {
+	Build          time.Duration
+	Push           time.Duration
+	ReleaseCommand time.Duration
+	MachineUpdates time.Duration
+	Total          time.Duration
+}
+
+// deployTimingsJSON is deployTimings as shown by --json, in milliseconds
+type deployTimingsJSON struct {
+	BuildMs          int64 `json:"build_ms"`
+	PushMs           int64 `json:"push_ms"`
+	ReleaseCommandMs int64 `json:"release_command_ms"`
+	MachineUpdatesMs int64 `json:"machine_updates_ms"`
+	TotalMs          int64 `json:"total_ms"`
+}
+
+func (t deployTimings) json() deployTimingsJSON {
+	return deployTimingsJSON{
+		BuildMs:          t.Build.Milliseconds(),
+		PushMs:           t.Push.Milliseconds(),
+		ReleaseCommandMs: t.ReleaseCommand.Milliseconds(),
+		MachineUpdatesMs: t.MachineUpdates.Milliseconds(),
+		TotalMs:          t.Total.Milliseconds(),
+	}
+}
+
+// String lists the phases the deploy went through, e.g. "build 1m2s, machine updates 40.5s, total 1m43s"
+func (t deployTimings) String() string {
+	phases := []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"build", t.Build},
+		{"push", t.Push},
+		{"release command", t.ReleaseCommand},
+		{"machine updates", t.MachineUpdates},
+	}
+	var parts []string
+	for _, p := range phases {
+		if p.duration > 0 {
+			parts = append(parts, fmt.Sprintf("%s %s", p.name, p.duration.Round(100*time.Millisecond)))
+		}
+	}
+	parts = append(parts, fmt.Sprintf("total %s", t.Total.Round(100*time.Millisecond)))
+	return strings.Join(parts, ", ")
+}
+
+// timeMachineUpdates adds the time until the returned func is called to the machine updates timing
+func (md *machineDeployment) timeMachineUpdates() func() {
+	started := time.Now()
+	return func() {
+		md.timings.MachineUpdates += time.Since(started)
+	}
+}
@@ -0,0 +1,26 @@
+package deploy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_deployTimings(t *testing.T) {
+	timings := deployTimings{
+		Build:          62 * time.Second,
+		ReleaseCommand: 3210 * time.Millisecond,
+		MachineUpdates: 40*time.Second + 520*time.Millisecond,
+		Total:          2 * time.Minute,
+	}
+	assert.Equal(t, "build 1m2s, release command 3.2s, machine updates 40.5s, total 2m0s", timings.String())
+	assert.Equal(t, deployTimingsJSON{
+		BuildMs:          62000,
+		ReleaseCommandMs: 3210,
+		MachineUpdatesMs: 40520,
+		TotalMs:          120000,
+	}, timings.json())
+
+	assert.Equal(t, "total 0s", deployTimings{}.String())
+}
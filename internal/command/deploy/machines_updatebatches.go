@@ -0,0 +1,72 @@
+package deploy
+
+import (
+	"io"
+	"sync"
+)
+
+// updateBatches splits the sorted entries in the batches of machines updated at once, as indexes in
+// entries. A batch has machines of a single process group, up to its [deploy] max_concurrent_per_group,
+// taken in the update order. Machines already up to date and the ones enabling autostop, which keep
+// another machine of their group up meanwhile, go on their own.
+func (md *machineDeployment) updateBatches(entries []*machineUpdateEntry) ([][]int, error) {
+	machines := map[string]int{}
+	for _, e := range entries {
+		machines[e.launchInput.Config.ProcessGroup()]++
+	}
+	limits := map[string]int{}
+	for group, count := range machines {
+		limit, err := md.appConfig.MaxConcurrentUpdates(group, count)
+		if err != nil {
+			return nil, err
+		}
+		limits[group] = limit
+	}
+	alone := func(e *machineUpdateEntry) bool {
+		return e.upToDate || e.enablesAutostop
+	}
+
+	var batches [][]int
+	batched := make([]bool, len(entries))
+	for i, e := range entries {
+		if batched[i] {
+			continue
+		}
+		batched[i] = true
+		batch := []int{i}
+		group := e.launchInput.Config.ProcessGroup()
+		for j := i + 1; j < len(entries) && !alone(e) && len(batch) < limits[group]; j++ {
+			if !batched[j] && !alone(entries[j]) && entries[j].launchInput.Config.ProcessGroup() == group {
+				batched[j] = true
+				batch = append(batch, j)
+			}
+		}
+		batches = append(batches, batch)
+	}
+	return batches, nil
+}
+
+// lockedWriter serializes the writes of the machines of a batch updated at once, so their lines
+// don't interleave
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (w lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// serializeOutput routes the output of the deploy through a single lock until the returned
+// function restores it
+func (md *machineDeployment) serializeOutput() func() {
+	errOut, alertOut := md.io.ErrOut, md.alertOut
+	var mu sync.Mutex
+	md.io.ErrOut = lockedWriter{mu: &mu, w: errOut}
+	md.alertOut = lockedWriter{mu: &mu, w: alertOut}
+	return func() {
+		md.io.ErrOut, md.alertOut = errOut, alertOut
+	}
+}
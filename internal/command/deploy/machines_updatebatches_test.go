@@ -0,0 +1,100 @@
+package deploy
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func Test_updateBatches(t *testing.T) {
+	cfg := &appconfig.Config{
+		Processes: map[string]string{"web": "run web", "worker": "run worker"},
+		Deploy:    &appconfig.Deploy{MaxConcurrentPerGroup: map[string]string{"web": "50%"}},
+	}
+	require.NoError(t, cfg.SetMachinesPlatform())
+	md, err := stabMachineDeployment(cfg)
+	require.NoError(t, err)
+
+	entry := func(group string) *machineUpdateEntry {
+		return &machineUpdateEntry{launchInput: &api.LaunchMachineInput{Config: groupMachine("", group, "ord").Config}}
+	}
+	upToDate := entry("web")
+	upToDate.upToDate = true
+	entries := []*machineUpdateEntry{
+		entry("web"), entry("worker"), entry("web"), upToDate, entry("worker"), entry("web"), entry("web"), entry("web"),
+	}
+
+	// Half of the 6 web machines go at once, workers one at a time
+	batches, err := md.updateBatches(entries)
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{{0, 2, 5}, {1}, {3}, {4}, {6, 7}}, batches)
+
+	md.appConfig.Deploy.MaxConcurrentPerGroup["web"] = "zero"
+	_, err = md.updateBatches(entries)
+	assert.ErrorContains(t, err, "invalid max_concurrent_per_group 'zero' for group 'web'")
+}
+
+// concurrentMachine fakes a machine whose update waits for the other updates running at once
+type concurrentMachine struct {
+	machine.LeasableMachine
+	m        *api.Machine
+	inFlight *inFlightCounter
+}
+
+type inFlightCounter struct {
+	mu       sync.Mutex
+	current  int
+	maxSoFar int
+}
+
+func (c *concurrentMachine) Machine() *api.Machine      { return c.m }
+func (c *concurrentMachine) FormattedMachineId() string { return c.m.ID }
+
+func (c *concurrentMachine) Update(context.Context, api.LaunchMachineInput) error {
+	c.inFlight.mu.Lock()
+	c.inFlight.current++
+	if c.inFlight.current > c.inFlight.maxSoFar {
+		c.inFlight.maxSoFar = c.inFlight.current
+	}
+	c.inFlight.mu.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+
+	c.inFlight.mu.Lock()
+	c.inFlight.current--
+	c.inFlight.mu.Unlock()
+	return nil
+}
+
+func Test_updateExistingMachines_concurrentBatches(t *testing.T) {
+	cfg := &appconfig.Config{Deploy: &appconfig.Deploy{MaxConcurrentPerGroup: map[string]string{"app": "2"}}}
+	md, err := stabMachineDeployment(cfg)
+	require.NoError(t, err)
+	ios, _, _, errOut := iostreams.Test()
+	md.io = ios
+	md.colorize = ios.ColorScheme()
+	md.strategy = "immediate"
+
+	inFlight := &inFlightCounter{}
+	var entries []*machineUpdateEntry
+	for _, id := range []string{"m1", "m2", "m3"} {
+		m := groupMachine(id, "app", "ord")
+		entries = append(entries, &machineUpdateEntry{
+			leasableMachine: &concurrentMachine{m: m, inFlight: inFlight},
+			launchInput:     &api.LaunchMachineInput{ID: id, Config: m.Config},
+		})
+	}
+
+	require.NoError(t, md.updateExistingMachines(context.Background(), entries))
+	assert.Equal(t, 2, inFlight.maxSoFar)
+	assert.Contains(t, errOut.String(), "Updating 2 machines of group 'app' at once")
+	assert.Len(t, md.deployed, 3)
+}
@@ -0,0 +1,172 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/build/imgsrc"
+	"github.com/superfly/flyctl/terminal"
+)
+
+// validateConfigOnly runs the platform checks of a deploy against appConfig, without building
+// the image nor touching the app
+func validateConfigOnly(ctx context.Context, appConfig *appconfig.Config, appCompact *api.AppCompact) error {
+	if appCompact.PlatformVersion == appconfig.NomadPlatform {
+		return fmt.Errorf("--validate-only is only supported by apps running on machines")
+	}
+	if err := appConfig.EnsureV2Config(); err != nil {
+		return fmt.Errorf("Can't deploy an invalid v2 app config: %s", err)
+	}
+	return deployToMachines(ctx, appConfig, appCompact, &imgsrc.DeploymentImage{}, time.Now(), 0)
+}
+
+// validatePlatformConstraints checks the app config against what the platform accepts and
+// reports every problem at once, before a deploy starts changing machines.
+// Region codes are checked only when the platform regions can be listed.
+func (md *machineDeployment) validatePlatformConstraints(ctx context.Context) error {
+	var regionCodes []string
+	if md.apiClient != nil {
+		regions, _, err := md.apiClient.PlatformRegions(ctx)
+		if err != nil {
+			terminal.Debugf("failed to list the platform regions, not validating region codes: %v\n", err)
+		}
+		regionCodes = lo.Map(regions, func(r api.Region, _ int) string { return r.Code })
+	}
+	if errs := md.platformValidationErrors(regionCodes); len(errs) > 0 {
+		return &PlatformValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// platformValidationErrors lists the problems of the app config, regionCodes are the valid regions
+func (md *machineDeployment) platformValidationErrors(regionCodes []string) (errs []error) {
+	cfg := md.appConfig
+	groups := cfg.ProcessNames()
+
+	// Regions
+	regions := lo.Uniq(lo.Compact(append(append([]string{cfg.PrimaryRegion, md.primaryRegionOverride}, cfg.Regions...), md.fallbackRegions...)))
+	for _, region := range regions {
+		if len(regionCodes) > 0 && !lo.Contains(regionCodes, region) {
+			errs = append(errs, fmt.Errorf("region '%s' doesn't exist, see `fly platform regions`", region))
+		}
+	}
+
+	// Guest sizes
+	for _, group := range groups {
+		mConfig, err := cfg.ToMachineConfig(group, nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("process group '%s': %w", group, err))
+			continue
+		}
+		if md.machineGuest != nil {
+			mConfig.Guest = md.machineGuest
+		}
+		if mConfig, err = md.applyConfigOverride(mConfig); err != nil {
+			errs = append(errs, fmt.Errorf("process group '%s': %w", group, err))
+			continue
+		}
+		if err := validateGuest(mConfig.Guest); err != nil {
+			errs = append(errs, fmt.Errorf("process group '%s': %w", group, err))
+		}
+		// Fallback regions without the GPU are skipped, new machines can't land in them
+		for _, region := range lo.Uniq(append([]string{md.primaryRegion()}, cfg.Regions...)) {
+			if err := checkGPURegion(mConfig.Guest, region); err != nil {
+				errs = append(errs, fmt.Errorf("process group '%s': %w", group, err))
+			}
+		}
+	}
+
+	// Service ports
+	errs = append(errs, servicePortConflicts(cfg.AllServices())...)
+
+	// Mounts
+	destinations := map[string]map[string]bool{}
+	for _, m := range cfg.Mounts {
+		if m.Source == "" {
+			errs = append(errs, fmt.Errorf("mount for '%s' has no source volume name", m.Destination))
+		}
+		if m.Destination == "" {
+			errs = append(errs, fmt.Errorf("mount of volume '%s' has no destination", m.Source))
+		}
+		mountGroups := m.Processes
+		for _, group := range m.Processes {
+			if !lo.Contains(groups, group) {
+				errs = append(errs, fmt.Errorf("mount of volume '%s' is for process group '%s' which isn't defined in fly.toml", m.Source, group))
+			}
+		}
+		if len(mountGroups) == 0 {
+			mountGroups = groups
+		}
+		for _, group := range mountGroups {
+			if destinations[group] == nil {
+				destinations[group] = map[string]bool{}
+			}
+			if m.Destination != "" && destinations[group][m.Destination] {
+				errs = append(errs, fmt.Errorf("process group '%s' mounts more than one volume at %s", group, m.Destination))
+			}
+			destinations[group][m.Destination] = true
+		}
+	}
+	return errs
+}
+
+// validateGuest checks a guest matches one of the machine sizes and has memory the size allows
+func validateGuest(guest *api.MachineGuest) error {
+	if guest == nil || guest.CPUs == 0 {
+		return nil
+	}
+	if guest.CPUKind == "" {
+		// The machines API defaults to shared CPUs
+		guest = &api.MachineGuest{CPUKind: "shared", CPUs: guest.CPUs, MemoryMB: guest.MemoryMB}
+	}
+	size := guest.ToSize()
+	if _, ok := api.MachinePresets[size]; !ok {
+		return fmt.Errorf("%d %s CPU(s) isn't a valid guest size, see `fly platform vm-sizes`", guest.CPUs, guest.CPUKind)
+	}
+	if guest.MemoryMB == 0 {
+		return nil
+	}
+	minMemory, maxMemory := api.MIN_MEMORY_MB_PER_SHARED_CPU, api.MAX_MEMORY_MB_PER_SHARED_CPU
+	if guest.CPUKind == "performance" {
+		minMemory, maxMemory = api.MIN_MEMORY_MB_PER_CPU, api.MAX_MEMORY_MB_PER_CPU
+	}
+	if guest.MemoryMB < minMemory*guest.CPUs || guest.MemoryMB > maxMemory*guest.CPUs {
+		return fmt.Errorf("%dMB of memory isn't allowed for size %s, it takes %dMB to %dMB",
+			guest.MemoryMB, size, minMemory*guest.CPUs, maxMemory*guest.CPUs)
+	}
+	return nil
+}
+
+// servicePortConflicts reports the public ports more than one service listens on with the same protocol
+func servicePortConflicts(services []appconfig.Service) (errs []error) {
+	type portRange struct {
+		service    int
+		start, end int
+	}
+	seen := map[string][]portRange{}
+	for i, svc := range services {
+		for _, p := range svc.Ports {
+			var r portRange
+			switch {
+			case p.Port != nil:
+				r = portRange{service: i, start: *p.Port, end: *p.Port}
+			case p.StartPort != nil && p.EndPort != nil:
+				r = portRange{service: i, start: *p.StartPort, end: *p.EndPort}
+			default:
+				continue
+			}
+			for _, other := range seen[svc.Protocol] {
+				if r.start <= other.end && other.start <= r.end {
+					errs = append(errs, fmt.Errorf("%s port %d is used by more than one service (internal ports %d and %d)",
+						svc.Protocol, lo.Max([]int{r.start, other.start}), services[other.service].InternalPort, svc.InternalPort))
+				}
+			}
+			seen[svc.Protocol] = append(seen[svc.Protocol], r)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,60 @@
+package deploy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/appconfig"
+)
+
+func Test_platformValidationErrors(t *testing.T) {
+	cfg := &appconfig.Config{
+		AppName:       "my-cool-app",
+		PrimaryRegion: "ord",
+		Regions:       []string{"atlantis"},
+		Processes:     map[string]string{"app": "run", "worker": "work"},
+		Services: []appconfig.Service{
+			{Protocol: "tcp", InternalPort: 8080, Ports: []api.MachinePort{{Port: lo.ToPtr(443)}}, Processes: []string{"app"}},
+			{Protocol: "tcp", InternalPort: 9090, Ports: []api.MachinePort{{StartPort: lo.ToPtr(400), EndPort: lo.ToPtr(500)}}, Processes: []string{"worker"}},
+			{Protocol: "udp", InternalPort: 5353, Ports: []api.MachinePort{{Port: lo.ToPtr(443)}}, Processes: []string{"worker"}},
+		},
+		Mounts: []appconfig.Mount{
+			{Source: "data", Destination: "/data", Processes: []string{"app", "cron"}},
+			{Destination: "/data", Processes: []string{"app"}},
+		},
+	}
+	require.NoError(t, cfg.SetMachinesPlatform())
+	md, err := stabMachineDeployment(cfg)
+	require.NoError(t, err)
+	md.machineGuest = &api.MachineGuest{CPUKind: "shared", CPUs: 3}
+
+	errs := md.platformValidationErrors([]string{"ord", "ams"})
+	messages := lo.Map(errs, func(err error, _ int) string { return err.Error() })
+	assert.ElementsMatch(t, []string{
+		"region 'atlantis' doesn't exist, see `fly platform regions`",
+		"process group 'app': 3 shared CPU(s) isn't a valid guest size, see `fly platform vm-sizes`",
+		"process group 'worker': 3 shared CPU(s) isn't a valid guest size, see `fly platform vm-sizes`",
+		"tcp port 443 is used by more than one service (internal ports 8080 and 9090)",
+		"mount of volume 'data' is for process group 'cron' which isn't defined in fly.toml",
+		"mount for '/data' has no source volume name",
+		"process group 'app' mounts more than one volume at /data",
+	}, messages)
+
+	vErr := &PlatformValidationError{Errors: errs}
+	assert.Contains(t, vErr.Error(), "7 problem(s) found")
+	assert.True(t, errors.Is(vErr, errs[0]))
+}
+
+func Test_validateGuest(t *testing.T) {
+	assert.NoError(t, validateGuest(nil))
+	assert.NoError(t, validateGuest(&api.MachineGuest{CPUKind: "shared", CPUs: 1, MemoryMB: 512}))
+	assert.NoError(t, validateGuest(&api.MachineGuest{CPUs: 2}))
+	assert.NoError(t, validateGuest(&api.MachineGuest{CPUKind: "performance", CPUs: 2, MemoryMB: 4096}))
+	assert.EqualError(t, validateGuest(&api.MachineGuest{CPUKind: "performance", CPUs: 1, MemoryMB: 512}),
+		"512MB of memory isn't allowed for size performance-1x, it takes 2048MB to 8192MB")
+	assert.Error(t, validateGuest(&api.MachineGuest{CPUKind: "shared", CPUs: 1, MemoryMB: 4096}))
+}
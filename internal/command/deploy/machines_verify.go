@@ -0,0 +1,172 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/terminal"
+	"golang.org/x/exp/slices"
+)
+
+// machineTopology counts machines by process group and region. An empty region
+// stands for machines whose region is picked by the platform.
+type machineTopology map[string]map[string]int
+
+func (t machineTopology) add(group, region string, count int) {
+	if t[group] == nil {
+		t[group] = map[string]int{}
+	}
+	t[group][region] += count
+}
+
+// move counts a machine of group in region to instead of from
+func (t machineTopology) move(group, from, to string) {
+	if t[group][from] > 0 {
+		t.add(group, from, -1)
+	}
+	t.add(group, to, 1)
+}
+
+func (t machineTopology) total(group string) (total int) {
+	for _, count := range t[group] {
+		total += count
+	}
+	return total
+}
+
+// expectedTopology returns the machines each process group must have once the deploy
+// applied diff: the machines kept plus the ones launched for new groups and regions
+func (md *machineDeployment) expectedTopology(diff ProcessGroupsDiff) machineTopology {
+	expected := machineTopology{}
+	groups := md.enabledProcessNames()
+	for _, lm := range md.machineSet.GetMachines() {
+		m := lm.Machine()
+		if slices.Contains(groups, m.ProcessGroup()) {
+			expected.add(m.ProcessGroup(), m.Region, 1)
+		}
+	}
+	for group := range diff.groupsNeedingMachines {
+		expected.add(group, md.primaryRegion(), 1)
+	}
+	for group, regions := range diff.regionsNeedingMachines {
+		for _, region := range regions {
+			expected.add(group, region, 1)
+		}
+	}
+	return expected
+}
+
+// verifyDeployment lists the app machines once the deploy is done to check it did what was expected
+func (md *machineDeployment) verifyDeployment(ctx context.Context) error {
+	verifyImages := !md.restartOnly && md.imgDigest != ""
+	if md.expected == nil && !verifyImages {
+		return nil
+	}
+	machines, _, err := md.flapsClient.ListFlyAppsMachines(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list machines to verify the deploy: %w", err)
+	}
+	if err := md.verifyMachineTopology(machines); err != nil {
+		return err
+	}
+	if verifyImages {
+		return md.verifyMachineImages(machines)
+	}
+	return nil
+}
+
+// verifyMachineTopology reports the process groups left with fewer machines than expected,
+// e.g. after errors the immediate strategy continued past. The deploy only fails for them
+// with --fail-on-missing-machines.
+func (md *machineDeployment) verifyMachineTopology(machines []*api.Machine) error {
+	if md.expected == nil {
+		return nil
+	}
+	shortfalls := topologyShortfalls(md.expected, machines)
+	if len(shortfalls) == 0 {
+		return nil
+	}
+	if md.failOnMissingMachines {
+		for _, s := range shortfalls {
+			md.warnf("  * %s\n", s)
+		}
+		return fmt.Errorf("%d process group(s) have fewer machines than expected after the deploy", len(shortfalls))
+	}
+	terminal.Warnf("Some process groups have fewer machines than expected after the deploy:\n")
+	for _, s := range shortfalls {
+		md.warnf("  * %s\n", s)
+	}
+	return nil
+}
+
+// verifyMachineImages reports the machines of the app groups that don't run the deployed image digest,
+// like an update that looked successful but left the machine on its previous image. The deploy only
+// fails for them with --fail-on-image-mismatch.
+func (md *machineDeployment) verifyMachineImages(machines []*api.Machine) error {
+	mismatches := imageMismatches(md.imgDigest, md.appConfig.ProcessNames(), machines)
+	if len(mismatches) == 0 {
+		return nil
+	}
+	if md.failOnImageMismatch {
+		for _, m := range mismatches {
+			md.warnf("  * %s\n", m)
+		}
+		return fmt.Errorf("%d machine(s) don't run the deployed image %s", len(mismatches), md.img)
+	}
+	terminal.Warnf("Some machines don't run the deployed image %s:\n", md.img)
+	for _, m := range mismatches {
+		md.warnf("  * %s\n", m)
+	}
+	return nil
+}
+
+// imageMismatches describes the machines of groups whose running image digest isn't digest.
+// Pinned machines are left out of deploys, and machines without a known digest can't be told.
+func imageMismatches(digest string, groups []string, machines []*api.Machine) []string {
+	var mismatches []string
+	for _, m := range machines {
+		if !slices.Contains(groups, m.ProcessGroup()) || m.IsDeployPinned() {
+			continue
+		}
+		if running := m.ImageRef.Digest; running != "" && running != digest {
+			mismatches = append(mismatches, fmt.Sprintf("machine %s of group '%s' runs %s", m.ID, m.ProcessGroup(), running))
+		}
+	}
+	return mismatches
+}
+
+// topologyShortfalls describes the groups and regions with fewer machines than expected
+func topologyShortfalls(expected machineTopology, machines []*api.Machine) []string {
+	actual := machineTopology{}
+	for _, m := range machines {
+		actual.add(m.ProcessGroup(), m.Region, 1)
+	}
+
+	var shortfalls []string
+	groups := make([]string, 0, len(expected))
+	for group := range expected {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		regions := make([]string, 0, len(expected[group]))
+		for region := range expected[group] {
+			regions = append(regions, region)
+		}
+		sort.Strings(regions)
+		for _, region := range regions {
+			if region == "" {
+				continue
+			}
+			if want, got := expected[group][region], actual[group][region]; got < want {
+				shortfalls = append(shortfalls, fmt.Sprintf("group '%s' has %d of %d machines in region '%s'", group, got, want, region))
+			}
+		}
+		if want, got := expected.total(group), actual.total(group); got < want {
+			shortfalls = append(shortfalls, fmt.Sprintf("group '%s' has %d of %d machines", group, got, want))
+		}
+	}
+	return shortfalls
+}
@@ -0,0 +1,89 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func groupMachine(id, group, region string) *api.Machine {
+	return &api.Machine{
+		ID:     id,
+		Region: region,
+		Config: &api.MachineConfig{
+			Metadata: map[string]string{api.MachineConfigMetadataKeyFlyProcessGroup: group},
+		},
+	}
+}
+
+func Test_expectedTopology(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{
+		PrimaryRegion: "ord",
+		Processes:     map[string]string{"app": "run app", "worker": "run worker"},
+	})
+	require.NoError(t, err)
+	ios, _, _, _ := iostreams.Test()
+	md.machineSet = machine.NewMachineSet(nil, ios, []*api.Machine{
+		groupMachine("m1", "app", "ord"),
+		groupMachine("m2", "app", "ord"),
+		groupMachine("m3", "app", "ams"),
+		groupMachine("m4", "gone", "ord"),
+	})
+
+	expected := md.expectedTopology(ProcessGroupsDiff{
+		groupsNeedingMachines:  map[string]bool{"worker": true},
+		regionsNeedingMachines: map[string][]string{"app": {"syd"}},
+	})
+	assert.Equal(t, machineTopology{
+		"app":    {"ord": 2, "ams": 1, "syd": 1},
+		"worker": {"ord": 1},
+	}, expected)
+}
+
+func Test_topologyShortfalls(t *testing.T) {
+	expected := machineTopology{
+		"app":    {"ord": 2, "ams": 1},
+		"worker": {"": 1},
+	}
+
+	assert.Empty(t, topologyShortfalls(expected, []*api.Machine{
+		groupMachine("m1", "app", "ord"),
+		groupMachine("m2", "app", "ord"),
+		groupMachine("m3", "app", "ams"),
+		groupMachine("m4", "worker", "syd"),
+	}))
+
+	assert.Equal(t, []string{
+		"group 'app' has 1 of 2 machines in region 'ord'",
+		"group 'app' has 2 of 3 machines",
+		"group 'worker' has 0 of 1 machines",
+	}, topologyShortfalls(expected, []*api.Machine{
+		groupMachine("m1", "app", "ord"),
+		groupMachine("m3", "app", "ams"),
+	}))
+}
+
+func Test_imageMismatches(t *testing.T) {
+	machine := func(id, group, digest string) *api.Machine {
+		m := groupMachine(id, group, "ord")
+		m.ImageRef.Digest = digest
+		return m
+	}
+	pinned := machine("m4", "app", "sha256:old")
+	pinned.Config.Metadata[api.MachineConfigMetadataKeyFlyDeployPinned] = "true"
+
+	assert.Equal(t, []string{
+		"machine m2 of group 'app' runs sha256:old",
+	}, imageMismatches("sha256:new", []string{"app"}, []*api.Machine{
+		machine("m1", "app", "sha256:new"),
+		machine("m2", "app", "sha256:old"),
+		machine("m3", "app", ""),
+		pinned,
+		machine("m5", "removed", "sha256:old"),
+	}))
+}
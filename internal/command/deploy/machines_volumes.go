@@ -0,0 +1,164 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/superfly/flyctl/api"
+)
+
+// defaultVolumeSizeGb is the size of created volumes without a volume of the same name to copy, like `fly volume create`
+const defaultVolumeSizeGb = 3
+
+type volumeSlot struct {
+	name   string
+	region string
+}
+
+// missingVolumes lists, one entry per volume to create, the unattached volumes the machines
+// about to be launched for diff, and the ones replacing machines to attach a different volume,
+// need and the app doesn't have
+func (md *machineDeployment) missingVolumes(diff ProcessGroupsDiff, machines []*api.Machine) ([]volumeSlot, error) {
+	needed := map[volumeSlot]int{}
+	addGroup := func(groupName, region string) error {
+		groupConfig, err := md.appConfig.Flatten(groupName)
+		if err != nil {
+			return err
+		}
+		if region == "" {
+			region = md.primaryRegion()
+		}
+		for _, m := range groupConfig.Mounts {
+			needed[volumeSlot{name: m.Source, region: region}]++
+		}
+		return nil
+	}
+	for groupName := range diff.groupsNeedingMachines {
+		if err := addGroup(groupName, ""); err != nil {
+			return nil, err
+		}
+	}
+	for groupName, regions := range diff.regionsNeedingMachines {
+		for _, region := range regions {
+			if err := addGroup(groupName, region); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, m := range machines {
+		name, err := md.replacementVolume(m)
+		if err != nil {
+			return nil, err
+		}
+		if name != "" {
+			needed[volumeSlot{name: name, region: m.Region}]++
+		}
+	}
+
+	available := map[volumeSlot]int{}
+	for name, volumes := range md.volumes {
+		for _, v := range volumes {
+			available[volumeSlot{name: name, region: v.Region}]++
+		}
+	}
+
+	var missing []volumeSlot
+	for slot, n := range needed {
+		for i := available[slot]; i < n; i++ {
+			missing = append(missing, slot)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		if missing[i].name != missing[j].name {
+			return missing[i].name < missing[j].name
+		}
+		return missing[i].region < missing[j].region
+	})
+	return missing, nil
+}
+
+// createMissingVolumes creates the volumes new and replacement machines need before they are
+// launched, so scaling a group with mounts into a new region or adding a [mounts] section doesn't
+// stop at a missing volume
+func (md *machineDeployment) createMissingVolumes(ctx context.Context, diff ProcessGroupsDiff) error {
+	var machines []*api.Machine
+	for _, lm := range md.machineSet.GetMachines() {
+		if !lm.Machine().IsDeployPinned() {
+			machines = append(machines, lm.Machine())
+		}
+	}
+	missing, err := md.missingVolumes(diff, machines)
+	if err != nil {
+		return err
+	}
+	for _, slot := range missing {
+		sizeGb := md.volumeSizes[slot.name]
+		if sizeGb == 0 {
+			sizeGb = defaultVolumeSizeGb
+		}
+		volume, err := md.apiClient.CreateVolume(ctx, api.CreateVolumeInput{
+			AppID:             md.app.ID,
+			Name:              slot.name,
+			Region:            slot.region,
+			SizeGb:            sizeGb,
+			Encrypted:         true,
+			RequireUniqueZone: true,
+		})
+		if err != nil {
+			return fmt.Errorf("failed creating volume '%s' in region %s: %w", slot.name, slot.region, err)
+		}
+		fmt.Fprintf(md.io.ErrOut, "Created volume %s '%s' of %dGB in region %s\n", md.colorize.Bold(volume.ID), slot.name, sizeGb, slot.region)
+		if md.volumes == nil {
+			md.volumes = map[string][]api.Volume{}
+		}
+		md.volumes[slot.name] = append(md.volumes[slot.name], *volume)
+	}
+	return nil
+}
+
+// replacementVolume is the name of the volume m gets replaced to mount, the way launchInputForUpdate
+// decides it: when fly.toml adds a mount to the machine or mounts a volume of another name
+func (md *machineDeployment) replacementVolume(m *api.Machine) (string, error) {
+	m = upgradeLegacyMachine(m)
+	groupConfig, err := md.appConfig.Flatten(m.Config.ProcessGroup())
+	if err != nil {
+		return "", err
+	}
+	if len(groupConfig.Mounts) == 0 {
+		return "", nil
+	}
+	name := groupConfig.Mounts[0].Source
+	switch oMounts := m.Config.Mounts; {
+	case len(oMounts) == 0:
+		return name, nil
+	case oMounts[0].Name != "" && oMounts[0].Name != name:
+		return name, nil
+	default:
+		return "", nil
+	}
+}
+
+// missingVolumeError explains that machineDesc, in processGroup and region, has no unattached
+// volume to mount and gives the command creating one
+func (md *machineDeployment) missingVolumeError(machineDesc, processGroup string, mount api.MachineMount, region string) error {
+	sizeGb := mount.SizeGb
+	if sizeGb == 0 {
+		sizeGb = md.volumeSizes[mount.Name]
+	}
+	if sizeGb == 0 {
+		sizeGb = defaultVolumeSizeGb
+	}
+	where, create := "", "fly volumes create "+mount.Name
+	if region != "" {
+		where = " in region " + region
+		create += " --region " + region
+	}
+	create += fmt.Sprintf(" --size %d", sizeGb)
+	return fmt.Errorf(
+		"%s in group '%s' needs an unattached volume named '%s' to mount at %s%s. "+
+			"Create it with `%s` or deploy with --auto-create-volumes",
+		machineDesc, processGroup, mount.Name, mount.Path, where, create,
+	)
+}
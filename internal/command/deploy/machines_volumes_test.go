@@ -0,0 +1,81 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/appconfig"
+)
+
+func Test_missingVolumes(t *testing.T) {
+	appConfig := &appconfig.Config{
+		AppName:       "my-cool-app",
+		PrimaryRegion: "scl",
+		Mounts:        []appconfig.Mount{{Source: "data", Destination: "/data"}},
+	}
+	require.NoError(t, appConfig.SetMachinesPlatform())
+	md, err := stabMachineDeployment(appConfig)
+	require.NoError(t, err)
+	md.volumes = map[string][]api.Volume{"data": {{ID: "vol_12345", Name: "data", Region: "ord"}}}
+
+	missing, err := md.missingVolumes(ProcessGroupsDiff{
+		groupsNeedingMachines:  map[string]bool{"app": true},
+		regionsNeedingMachines: map[string][]string{"app": {"ord", "ams"}},
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []volumeSlot{{name: "data", region: "ams"}, {name: "data", region: "scl"}}, missing)
+
+	// Machines replaced to mount a volume need one too, the ones keeping theirs don't
+	withMount := func(id, region, volume string) *api.Machine {
+		m := groupMachine(id, "app", region)
+		if volume != "" {
+			m.Config.Mounts = []api.MachineMount{{Name: volume, Volume: "vol_" + id, Path: "/data"}}
+		}
+		return m
+	}
+	missing, err = md.missingVolumes(ProcessGroupsDiff{}, []*api.Machine{
+		withMount("m1", "ord", ""), withMount("m2", "ams", ""), withMount("m3", "ams", "other"), withMount("m4", "ams", "data"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []volumeSlot{{name: "data", region: "ams"}, {name: "data", region: "ams"}}, missing)
+
+	// Without enough volumes the deploy only fails when they can't be created
+	md.volumes = map[string][]api.Volume{}
+	assert.Error(t, md.validateVolumeConfig())
+	md.autoCreateVolumes = true
+	assert.NoError(t, md.validateVolumeConfig())
+}
+
+func Test_popVolume_prefersRegion(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	md.volumes = map[string][]api.Volume{"data": {
+		{ID: "vol_ord", Name: "data", Region: "ord"},
+		{ID: "vol_ams", Name: "data", Region: "ams"},
+	}}
+
+	v, ok := md.popVolume("data", "ams")
+	require.True(t, ok)
+	assert.Equal(t, "vol_ams", v.ID)
+	// Any volume does when none is in the region
+	v, ok = md.popVolume("data", "scl")
+	require.True(t, ok)
+	assert.Equal(t, "vol_ord", v.ID)
+	_, ok = md.popVolume("data", "ord")
+	assert.False(t, ok)
+}
+
+func Test_missingVolumeError(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{})
+	require.NoError(t, err)
+	md.volumeSizes = map[string]int{"data": 10}
+
+	err = md.missingVolumeError("New machine", "web", api.MachineMount{Name: "data", Path: "/data"}, "ord")
+	assert.EqualError(t, err, "New machine in group 'web' needs an unattached volume named 'data' to mount at /data in region ord. "+
+		"Create it with `fly volumes create data --region ord --size 10` or deploy with --auto-create-volumes")
+
+	err = md.missingVolumeError("Machine ab1234567890", "web", api.MachineMount{Name: "cache", Path: "/cache"}, "")
+	assert.ErrorContains(t, err, "`fly volumes create cache --size 3`")
+}
@@ -0,0 +1,78 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/superfly/flyctl/terminal"
+)
+
+const (
+	webhookEventReleaseStarted         = "release_started"
+	webhookEventReleaseCommandFinished = "release_command_finished"
+	webhookEventGroupCompleted         = "group_completed"
+	webhookEventDeployComplete         = "deploy_complete"
+	webhookEventDeployFailed           = "deploy_failed"
+)
+
+var webhookClient = &http.Client{Timeout: 5 * time.Second}
+
+// webhookPayload is the JSON body POSTed to the deploy webhook
+type webhookPayload struct {
+	Event          string    `json:"event"`
+	App            string    `json:"app"`
+	ReleaseID      string    `json:"release_id"`
+	ReleaseVersion int       `json:"release_version"`
+	Image          string    `json:"image,omitempty"`
+	Group          string    `json:"group,omitempty"`
+	Machines       int       `json:"machines"`
+	Completed      int       `json:"completed_machines"`
+	Error          string    `json:"error,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// notifyWebhook posts a deploy event to the configured webhook. It is best effort,
+// failures are only logged and never abort the deployment.
+func (md *machineDeployment) notifyWebhook(ctx context.Context, payload webhookPayload) {
+	if md.webhookURL == "" {
+		return
+	}
+	payload.App = md.app.Name
+	payload.ReleaseID = md.releaseId
+	payload.ReleaseVersion = md.releaseVersion
+	payload.Image = md.img
+	payload.Timestamp = time.Now().UTC()
+	if payload.Machines == 0 {
+		payload.Machines = len(md.machineSet.GetMachines())
+	}
+
+	if err := postWebhook(ctx, md.webhookURL, payload); err != nil {
+		terminal.Warnf("failed to send the %s event to the deploy webhook: %v\n", payload.Event, err)
+	}
+}
+
+func postWebhook(ctx context.Context, webhookURL string, payload webhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
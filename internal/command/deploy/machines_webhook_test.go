@@ -0,0 +1,49 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/internal/appconfig"
+)
+
+func Test_postWebhook(t *testing.T) {
+	var got webhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		if got.Event == webhookEventDeployFailed {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	err := postWebhook(context.Background(), server.URL, webhookPayload{Event: webhookEventGroupCompleted, Group: "web", Machines: 3, Completed: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "web", got.Group)
+	assert.Equal(t, 2, got.Completed)
+
+	err = postWebhook(context.Background(), server.URL, webhookPayload{Event: webhookEventDeployFailed})
+	assert.ErrorContains(t, err, "status 500")
+}
+
+func Test_setWebhookURL(t *testing.T) {
+	md, err := stabMachineDeployment(&appconfig.Config{
+		Deploy: &appconfig.Deploy{WebhookURL: "https://example.com/from-config"},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, md.setWebhookURL(""))
+	assert.Equal(t, "https://example.com/from-config", md.webhookURL)
+
+	require.NoError(t, md.setWebhookURL("https://example.com/from-flag"))
+	assert.Equal(t, "https://example.com/from-flag", md.webhookURL)
+
+	assert.Error(t, md.setWebhookURL("example.com/no-scheme"))
+}
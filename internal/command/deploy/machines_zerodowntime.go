@@ -0,0 +1,110 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/superfly/flyctl/api"
+	machcmd "github.com/superfly/flyctl/internal/command/machine"
+	"github.com/superfly/flyctl/internal/machine"
+)
+
+// replaceSingleMachineGroups avoids the downtime of updating the only machine of a process group
+// in place: a machine on the new release is launched next to it and, once healthy, the original
+// machine is destroyed. It returns the entries left for the regular update loop.
+func (md *machineDeployment) replaceSingleMachineGroups(ctx context.Context, entries []*machineUpdateEntry) ([]*machineUpdateEntry, error) {
+	singles := singleMachineGroupEntries(entries, md.machineSet.GetMachines())
+	if len(singles) == 0 {
+		return entries, nil
+	}
+
+	fmt.Fprintf(md.io.ErrOut, "Replacing the single machine of %d process group(s) with zero downtime\n", len(singles))
+	var remaining []*machineUpdateEntry
+	for _, e := range entries {
+		if !singles[e] {
+			remaining = append(remaining, e)
+			continue
+		}
+		if err := md.checkCanceled(); err != nil {
+			return nil, err
+		}
+		if err := md.replaceMachineWithZeroDowntime(ctx, e); err != nil {
+			return nil, err
+		}
+	}
+	return remaining, nil
+}
+
+func (md *machineDeployment) replaceMachineWithZeroDowntime(ctx context.Context, e *machineUpdateEntry) error {
+	oldMachine := e.leasableMachine.Machine()
+	launchInput := *e.launchInput
+	launchInput.ID = ""
+
+	newMachineRaw, err := md.launchOrReuse(ctx, launchInput, md.takeKeptMachine(&launchInput))
+	if err != nil {
+		return &MachineLaunchError{Group: launchInput.Config.ProcessGroup(), Region: launchInput.Region, err: err}
+	}
+	newMachine := machine.NewLeasableMachine(md.flapsClient, md.io, newMachineRaw)
+	fmt.Fprintf(md.io.ErrOut, "  Created machine %s to take over %s\n", md.colorize.Bold(newMachine.FormattedMachineId()), md.colorize.Bold(e.leasableMachine.FormattedMachineId()))
+
+	if err := md.waitForReplacement(ctx, newMachine); err != nil {
+		// The original machine is still serving, don't leave a broken one behind
+		if destroyErr := machcmd.Destroy(ctx, md.app, newMachineRaw, true); destroyErr != nil {
+			md.warnf("Failed to destroy the unhealthy machine %s: %s\n", newMachineRaw.ID, destroyErr)
+		}
+		return err
+	}
+
+	if err := md.machineSet.RemoveMachines(ctx, []machine.LeasableMachine{e.leasableMachine}); err != nil {
+		return err
+	}
+	if !md.keepForRollback(ctx, e.leasableMachine) {
+		md.drainMachine(ctx, e.leasableMachine)
+		if err := machcmd.Destroy(ctx, md.app, oldMachine, true); err != nil {
+			return fmt.Errorf("machine %s replaced %s but destroying it failed: %w", newMachineRaw.ID, oldMachine.ID, err)
+		}
+	}
+	fmt.Fprintf(md.io.ErrOut, "  Machine %s replaced by %s: %s\n",
+		md.colorize.Bold(e.leasableMachine.FormattedMachineId()),
+		md.colorize.Bold(newMachine.FormattedMachineId()),
+		md.colorize.Green("success"),
+	)
+	return nil
+}
+
+func (md *machineDeployment) waitForReplacement(ctx context.Context, lm machine.LeasableMachine) error {
+	if err := lm.WaitForState(ctx, api.MachineStateStarted, md.newMachineWaitTimeout, ""); err != nil {
+		return err
+	}
+	if md.skipHealthChecks || lm.Machine().SkipsHealthChecks() {
+		return nil
+	}
+	if err := lm.WaitForConsecutiveHealthchecksToPass(ctx, md.newMachineWaitTimeout, md.healthyPollsRequired, ""); err != nil {
+		return healthCheckError(lm.Machine().ID, err)
+	}
+	return nil
+}
+
+// singleMachineGroupEntries returns the entries updating the only machine of their process group.
+// Machines with volumes are left out since their volume can't be attached to a second machine.
+func singleMachineGroupEntries(entries []*machineUpdateEntry, machines []machine.LeasableMachine) map[*machineUpdateEntry]bool {
+	machinesByGroup := map[string]int{}
+	for _, lm := range machines {
+		machinesByGroup[lm.Machine().ProcessGroup()]++
+	}
+
+	singles := map[*machineUpdateEntry]bool{}
+	for _, e := range entries {
+		m := e.leasableMachine.Machine()
+		switch {
+		case machinesByGroup[m.ProcessGroup()] != 1:
+		case e.upToDate:
+		case e.launchInput.ID != m.ID:
+			// Already being replaced
+		case len(m.Config.Mounts) > 0:
+		default:
+			singles[e] = true
+		}
+	}
+	return singles
+}
@@ -0,0 +1,42 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func Test_singleMachineGroupEntries(t *testing.T) {
+	group := func(name string) *api.MachineConfig {
+		return &api.MachineConfig{Metadata: map[string]string{"fly_process_group": name}}
+	}
+	ios, _, _, _ := iostreams.Test()
+	ms := machine.NewMachineSet(nil, ios, []*api.Machine{
+		{ID: "web1", Config: group("web")},
+		{ID: "web2", Config: group("web")},
+		{ID: "worker1", Config: group("worker")},
+		{ID: "cron1", Config: group("cron")},
+		{ID: "db1", Config: &api.MachineConfig{
+			Metadata: map[string]string{"fly_process_group": "db"},
+			Mounts:   []api.MachineMount{{Volume: "vol_1234", Path: "/data"}},
+		}},
+	})
+
+	var entries []*machineUpdateEntry
+	for _, lm := range ms.GetMachines() {
+		entries = append(entries, &machineUpdateEntry{
+			leasableMachine: lm,
+			launchInput:     &api.LaunchMachineInput{ID: lm.Machine().ID, Config: lm.Machine().Config},
+			upToDate:        lm.Machine().ID == "cron1",
+		})
+	}
+
+	singles := singleMachineGroupEntries(entries, ms.GetMachines())
+	assert.Len(t, singles, 1)
+	for e := range singles {
+		assert.Equal(t, "worker1", e.leasableMachine.Machine().ID)
+	}
+}
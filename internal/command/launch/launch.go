@@ -28,7 +28,10 @@ func New() (cmd *cobra.Command) {
 		short = long
 	)
 
-	cmd = command.New("launch", short, long, run, command.RequireSession, command.LoadAppConfigIfPresent)
+	cmd = command.New("launch", short, long, run,
+		unlessScanOnly(command.RequireSession),
+		unlessScanOnly(command.LoadAppConfigIfPresent),
+	)
 	cmd.Args = cobra.NoArgs
 
 	flag.Add(cmd,
@@ -73,11 +76,27 @@ func New() (cmd *cobra.Command) {
 			Description: "Set internal_port for all services in the generated fly.toml",
 			Default:     -1,
 		},
+		flag.Bool{
+			Name:        "scan-only",
+			Description: "Only scan the source code and print what was detected, without creating anything",
+		},
+		flag.JSONOutput(),
 	)
 
 	return
 }
 
+// unlessScanOnly skips the preparer with --scan-only, which only reads the source code and works
+// logged out
+func unlessScanOnly(p command.Preparer) command.Preparer {
+	return func(ctx context.Context) (context.Context, error) {
+		if flag.GetBool(ctx, "scan-only") {
+			return ctx, nil
+		}
+		return p(ctx)
+	}
+}
+
 func run(ctx context.Context) (err error) {
 	io := iostreams.FromContext(ctx)
 	client := client.FromContext(ctx).API()
@@ -93,6 +112,9 @@ func run(ctx context.Context) (err error) {
 	if absDir, err := filepath.Abs(workingDir); err == nil {
 		workingDir = absDir
 	}
+	if flag.GetBool(ctx, "scan-only") {
+		return runScanOnly(ctx, workingDir)
+	}
 	configFilePath := filepath.Join(workingDir, appconfig.DefaultConfigFileName)
 	fmt.Fprintln(io.Out, "Creating app in", workingDir)
 
@@ -287,6 +309,12 @@ func run(ctx context.Context) (err error) {
 		fmt.Fprintln(io.Out, srcInfo.Notice)
 	}
 
+	// Build secrets are mounted while building only, they have to be given on every deploy
+	if len(srcInfo.BuildSecrets) > 0 {
+		fmt.Fprintf(io.Out, "The generated Dockerfile needs %s while building, pass their values to fly deploy with --build-secret NAME=VALUE\n",
+			strings.Join(srcInfo.BuildSecrets, ", "))
+	}
+
 	deployNow := false
 	promptForDeploy := true
 
@@ -308,6 +336,9 @@ func run(ctx context.Context) (err error) {
 	}
 
 	if deployNow {
+		for _, secret := range srcInfo.Secrets {
+			deployArgs.ExpectedSecrets = append(deployArgs.ExpectedSecrets, secret.Key)
+		}
 		return deploy.DeployWithConfig(ctx, appConfig, deployArgs)
 	}
 
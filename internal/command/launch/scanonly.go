@@ -0,0 +1,141 @@
+package launch
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/superfly/flyctl/internal/config"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/internal/render"
+	"github.com/superfly/flyctl/iostreams"
+	"github.com/superfly/flyctl/scanner"
+)
+
+// scanOnlySecret is a secret the scanner asks for, its value is generated or prompted at launch
+type scanOnlySecret struct {
+	Key       string `json:"key"`
+	Help      string `json:"help,omitempty"`
+	Generated bool   `json:"generated"`
+}
+
+// scanOnlyResult is the scanner SourceInfo as printed by --scan-only --json
+type scanOnlyResult struct {
+	Family           string            `json:"family"`
+	Version          string            `json:"version,omitempty"`
+	Port             int               `json:"port,omitempty"`
+	Builder          string            `json:"builder,omitempty"`
+	Buildpacks       []string          `json:"buildpacks,omitempty"`
+	DockerfilePath   string            `json:"dockerfile_path,omitempty"`
+	BuildArgs        map[string]string `json:"build_args,omitempty"`
+	BuildSecrets     []string          `json:"build_secrets,omitempty"`
+	DockerCommand    string            `json:"docker_command,omitempty"`
+	DockerEntrypoint string            `json:"docker_entrypoint,omitempty"`
+	KillSignal       string            `json:"kill_signal,omitempty"`
+	ReleaseCmd       string            `json:"release_cmd,omitempty"`
+	Env              map[string]string `json:"env,omitempty"`
+	Secrets          []scanOnlySecret  `json:"secrets,omitempty"`
+	Statics          []scanner.Static  `json:"statics,omitempty"`
+	Volumes          []scanner.Volume  `json:"volumes,omitempty"`
+	Processes        map[string]string `json:"processes,omitempty"`
+	Concurrency      map[string]int    `json:"concurrency,omitempty"`
+	HttpCheckPath    string            `json:"http_check_path,omitempty"`
+	Files            []string          `json:"files,omitempty"`
+	SkipDeploy       bool              `json:"skip_deploy"`
+	SkipDatabase     bool              `json:"skip_database"`
+	NoServices       bool              `json:"no_services"`
+	GRPC             bool              `json:"grpc"`
+	Notice           string            `json:"notice,omitempty"`
+}
+
+// newScanOnlyResult keeps what can be serialized of srcInfo, secrets only give their key and help
+// and generated files their path
+func newScanOnlyResult(srcInfo *scanner.SourceInfo) scanOnlyResult {
+	return scanOnlyResult{
+		Family:           srcInfo.Family,
+		Version:          srcInfo.Version,
+		Port:             srcInfo.Port,
+		Builder:          srcInfo.Builder,
+		Buildpacks:       srcInfo.Buildpacks,
+		DockerfilePath:   srcInfo.DockerfilePath,
+		BuildArgs:        srcInfo.BuildArgs,
+		BuildSecrets:     srcInfo.BuildSecrets,
+		DockerCommand:    srcInfo.DockerCommand,
+		DockerEntrypoint: srcInfo.DockerEntrypoint,
+		KillSignal:       srcInfo.KillSignal,
+		ReleaseCmd:       srcInfo.ReleaseCmd,
+		Env:              srcInfo.Env,
+		Secrets: lo.Map(srcInfo.Secrets, func(s scanner.Secret, _ int) scanOnlySecret {
+			return scanOnlySecret{Key: s.Key, Help: s.Help, Generated: s.Generate != nil}
+		}),
+		Statics:       srcInfo.Statics,
+		Volumes:       srcInfo.Volumes,
+		Processes:     srcInfo.Processes,
+		Concurrency:   srcInfo.Concurrency,
+		HttpCheckPath: srcInfo.HttpCheckPath,
+		Files:         lo.Map(srcInfo.Files, func(f scanner.SourceFile, _ int) string { return f.Path }),
+		SkipDeploy:    srcInfo.SkipDeploy,
+		SkipDatabase:  srcInfo.SkipDatabase,
+		NoServices:    srcInfo.NoServices,
+		GRPC:          srcInfo.GRPC,
+		Notice:        srcInfo.Notice,
+	}
+}
+
+// runScanOnly runs the scanners on workingDir and prints what they detected, nothing is created
+func runScanOnly(ctx context.Context, workingDir string) error {
+	io := iostreams.FromContext(ctx)
+
+	// Scanners don't prompt in the scan mode, prompts would end up in the output
+	scannerConfig := &scanner.ScannerConfig{
+		Mode:     "scan",
+		BuildKit: buildKitInUse(ctx),
+	}
+	if n := flag.GetInt(ctx, "internal-port"); n > 0 {
+		scannerConfig.ExistingPort = n
+	}
+	srcInfo, err := scanner.Scan(workingDir, scannerConfig)
+	if err != nil {
+		return err
+	}
+	if srcInfo == nil {
+		return fmt.Errorf("could not find a Dockerfile, nor detect a runtime or framework from source code in %s", workingDir)
+	}
+
+	result := newScanOnlyResult(srcInfo)
+	if config.FromContext(ctx).JSONOutput {
+		return render.JSON(io.Out, result)
+	}
+
+	fmt.Fprintf(io.Out, "Detected %s %s app\n", articleFor(result.Family), strings.TrimSpace(result.Family+" "+result.Version))
+	if result.Port > 0 {
+		fmt.Fprintf(io.Out, "  Port: %d\n", result.Port)
+	}
+	if len(result.Env) > 0 {
+		fmt.Fprintf(io.Out, "  Env: %s\n", strings.Join(sortedKeys(result.Env), ", "))
+	}
+	if len(result.BuildArgs) > 0 {
+		fmt.Fprintf(io.Out, "  Build args: %s\n", strings.Join(sortedKeys(result.BuildArgs), ", "))
+	}
+	if len(result.BuildSecrets) > 0 {
+		fmt.Fprintf(io.Out, "  Build secrets: %s\n", strings.Join(result.BuildSecrets, ", "))
+	}
+	if len(result.Secrets) > 0 {
+		fmt.Fprintf(io.Out, "  Secrets: %s\n", strings.Join(lo.Map(result.Secrets, func(s scanOnlySecret, _ int) string { return s.Key }), ", "))
+	}
+	if result.ReleaseCmd != "" {
+		fmt.Fprintf(io.Out, "  Release command: %s\n", result.ReleaseCmd)
+	}
+	if len(result.Files) > 0 {
+		fmt.Fprintf(io.Out, "  Files: %s\n", strings.Join(result.Files, ", "))
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := lo.Keys(m)
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,56 @@
+package launch
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/client"
+	"github.com/superfly/flyctl/internal/command"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/scanner"
+)
+
+func Test_newScanOnlyResult(t *testing.T) {
+	srcInfo := &scanner.SourceInfo{
+		Family:     "Django",
+		Port:       8000,
+		ReleaseCmd: "python manage.py migrate",
+		Env:        map[string]string{"PORT": "8000"},
+		Secrets: []scanner.Secret{{
+			Key:      "SECRET_KEY",
+			Help:     "Django needs a random, secret key.",
+			Generate: func() (string, error) { return "secret", nil },
+		}},
+		Statics: []scanner.Static{{GuestPath: "/code/static", UrlPrefix: "/static/"}},
+		Files:   []scanner.SourceFile{{Path: "Dockerfile", Contents: []byte("FROM python")}},
+	}
+
+	result := newScanOnlyResult(srcInfo)
+	assert.Equal(t, []scanOnlySecret{{Key: "SECRET_KEY", Help: "Django needs a random, secret key.", Generated: true}}, result.Secrets)
+	assert.Equal(t, []string{"Dockerfile"}, result.Files)
+
+	raw, err := json.Marshal(result)
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), `"release_cmd":"python manage.py migrate"`)
+	assert.Contains(t, string(raw), `"statics":[{"guest_path":"/code/static","url_prefix":"/static/"}]`)
+}
+
+func Test_unlessScanOnly(t *testing.T) {
+	fs := pflag.NewFlagSet("launch", pflag.ContinueOnError)
+	fs.Bool("scan-only", false, "")
+	ctx := flag.NewContext(context.Background(), fs)
+	ctx = client.NewContext(ctx, client.FromToken(""))
+
+	requireSession := unlessScanOnly(command.RequireSession)
+	_, err := requireSession(ctx)
+	assert.ErrorIs(t, err, client.ErrNoAuthToken)
+
+	// Scans only read the source code, they work logged out
+	require.NoError(t, fs.Set("scan-only", "true"))
+	_, err = requireSession(ctx)
+	assert.NoError(t, err)
+}
@@ -3,7 +3,9 @@ package launch
 import (
 	"context"
 	"fmt"
+	"os"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/cavaliergopher/grab/v3"
@@ -23,6 +25,7 @@ func determineSourceInfo(ctx context.Context, appConfig *appconfig.Config, copyC
 	scannerConfig := &scanner.ScannerConfig{
 		ExistingPort: appConfig.InternalPort(),
 		Mode:         "launch",
+		BuildKit:     buildKitInUse(ctx),
 	}
 	// Detect if --copy-config and --now flags are set. If so, limited set of
 	// fly.toml file updates. Helpful for deploying PRs when the project is
@@ -105,3 +108,12 @@ func articleFor(w string) string {
 	}
 	return article
 }
+
+// buildKitInUse tells if the app image is going to be built with BuildKit. DOCKER_BUILDKIT decides
+// when set, otherwise remote builders run BuildKit and a local Docker isn't assumed to.
+func buildKitInUse(ctx context.Context) bool {
+	if enabled, err := strconv.ParseBool(os.Getenv("DOCKER_BUILDKIT")); err == nil {
+		return enabled
+	}
+	return !flag.GetLocalOnly(ctx)
+}
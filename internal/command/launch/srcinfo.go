@@ -222,10 +222,18 @@ func setAppconfigFromSrcinfo(ctx context.Context, srcInfo *scanner.SourceInfo, a
 		return nil
 	}
 
+	if srcInfo.NoServices {
+		appConfig.RemoveServices()
+	}
+
 	if srcInfo.Port > 0 {
 		appConfig.SetInternalPort(srcInfo.Port)
 	}
 
+	if srcInfo.GRPC {
+		appConfig.SetGRPCService(srcInfo.Port)
+	}
+
 	if srcInfo.HttpCheckPath != "" {
 		appConfig.SetHttpCheck(srcInfo.HttpCheckPath)
 	}
@@ -49,6 +49,14 @@ func newUpdate() *cobra.Command {
 			Name:        "mount-point",
 			Description: "New volume mount point",
 		},
+		flag.Bool{
+			Name:        "pin",
+			Description: fmt.Sprintf("Pin the machine so `fly deploy` skips it, sets the %s=true metadata", api.MachineConfigMetadataKeyFlyDeployPinned),
+		},
+		flag.Bool{
+			Name:        "unpin",
+			Description: "Unpin the machine so `fly deploy` updates it again",
+		},
 	)
 
 	cmd.Args = cobra.RangeArgs(0, 1)
@@ -67,6 +75,10 @@ func runUpdate(ctx context.Context) (err error) {
 		dockerfile       = flag.GetString(ctx, flag.Dockerfile().Name)
 	)
 
+	if flag.GetBool(ctx, "pin") && flag.GetBool(ctx, "unpin") {
+		return fmt.Errorf("--pin and --unpin are mutually exclusive")
+	}
+
 	machineID := flag.FirstArg(ctx)
 	haveMachineID := len(flag.Args(ctx)) > 0
 	machine, ctx, err := selectOneMachine(ctx, nil, machineID, haveMachineID)
@@ -115,6 +127,16 @@ func runUpdate(ctx context.Context) (err error) {
 		machineConf.Mounts[0].Path = mp
 	}
 
+	switch {
+	case flag.GetBool(ctx, "pin"):
+		if machineConf.Metadata == nil {
+			machineConf.Metadata = map[string]string{}
+		}
+		machineConf.Metadata[api.MachineConfigMetadataKeyFlyDeployPinned] = "true"
+	case flag.GetBool(ctx, "unpin"):
+		delete(machineConf.Metadata, api.MachineConfigMetadataKeyFlyDeployPinned)
+	}
+
 	// Prompt user to confirm changes
 	if !autoConfirm {
 		confirmed, err := mach.ConfirmConfigChanges(ctx, machine, *machineConf, "")
@@ -296,6 +296,20 @@ func GetLocalOnly(ctx context.Context) bool {
 	return GetBool(ctx, localOnlyName)
 }
 
+const builderName = "builder"
+
+// Builder returns a string flag for picking the builder that produces the image
+func Builder() String {
+	return String{
+		Name:        builderName,
+		Description: "Builder to produce the image with: local or remote. The local and remote builders take precedence over --local-only and --remote-only",
+	}
+}
+
+func GetBuilder(ctx context.Context) string {
+	return GetString(ctx, builderName)
+}
+
 const detachName = "detach"
 
 // Detach returns a boolean flag for detaching during deployment
@@ -0,0 +1,35 @@
+package machine
+
+import (
+	"context"
+	"strings"
+
+	"github.com/superfly/flyctl/api"
+)
+
+type skipServiceChecksKey struct{}
+
+// WithoutServiceChecks returns a context making the health check waits run with ctx only wait
+// for the machine checks, the service checks are ignored.
+func WithoutServiceChecks(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipServiceChecksKey{}, true)
+}
+
+func isServiceCheck(name string) bool {
+	return strings.HasPrefix(name, "servicecheck-")
+}
+
+// gatingChecks returns m with only the checks a health check wait with ctx waits for
+func gatingChecks(ctx context.Context, m *api.Machine) *api.Machine {
+	if skip, _ := ctx.Value(skipServiceChecksKey{}).(bool); !skip || m == nil {
+		return m
+	}
+	filtered := *m
+	filtered.Checks = nil
+	for _, c := range m.Checks {
+		if !isServiceCheck(c.Name) {
+			filtered.Checks = append(filtered.Checks, c)
+		}
+	}
+	return &filtered
+}
@@ -0,0 +1,24 @@
+package machine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/superfly/flyctl/api"
+)
+
+func TestGatingChecks(t *testing.T) {
+	m := &api.Machine{ID: "m1", Checks: []*api.MachineCheckStatus{
+		{Name: "servicecheck-00-http-8080", Status: "critical"},
+		{Name: "db", Status: "passing"},
+	}}
+
+	assert.Same(t, m, gatingChecks(context.Background(), m))
+
+	filtered := gatingChecks(WithoutServiceChecks(context.Background()), m)
+	assert.Equal(t, "m1", filtered.ID)
+	assert.Equal(t, []*api.MachineCheckStatus{{Name: "db", Status: "passing"}}, filtered.Checks)
+	assert.True(t, filtered.HealthCheckStatus().AllPassing())
+	assert.Len(t, m.Checks, 2)
+}
@@ -0,0 +1,162 @@
+package machine
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/superfly/flyctl/api"
+)
+
+// FailingCheck is the last known state of a health check that wasn't passing
+type FailingCheck struct {
+	Name   string
+	Type   string
+	Status string
+	Output string
+	// HTTPStatus is the 4xx or 5xx status an HTTP check got, zero when its output doesn't tell
+	HTTPStatus int
+}
+
+var httpErrorStatusRegexp = regexp.MustCompile(`\b([45][0-9]{2})\b`)
+
+// HTTPErrorStatus finds the 4xx or 5xx status in the output of an HTTP check, or returns zero
+func HTTPErrorStatus(output string) int {
+	match := httpErrorStatusRegexp.FindStringSubmatch(output)
+	if match == nil {
+		return 0
+	}
+	code, _ := strconv.Atoi(match[1])
+	return code
+}
+
+// HealthChecksTimeoutError is returned when a machine health checks didn't pass in time,
+// it carries the checks that were still failing to tell TCP and HTTP checks progress apart.
+type HealthChecksTimeoutError struct {
+	MachineID     string
+	FailingChecks []FailingCheck
+	err           error
+}
+
+func newHealthChecksTimeoutError(m *api.Machine, err error) *HealthChecksTimeoutError {
+	e := &HealthChecksTimeoutError{MachineID: m.ID, err: err}
+	for _, c := range m.Checks {
+		if c == nil || c.Status == "passing" {
+			continue
+		}
+		failing := FailingCheck{
+			Name:   c.Name,
+			Type:   CheckType(m, c.Name),
+			Status: c.Status,
+			Output: c.Output,
+		}
+		if failing.Type == "http" {
+			failing.HTTPStatus = HTTPErrorStatus(c.Output)
+		}
+		e.FailingChecks = append(e.FailingChecks, failing)
+	}
+	return e
+}
+
+func (e *HealthChecksTimeoutError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "timeout reached waiting for healthchecks to pass for machine %s", e.MachineID)
+	if len(e.FailingChecks) > 0 {
+		b.WriteString(", failing checks:")
+	}
+	for _, c := range e.FailingChecks {
+		fmt.Fprintf(&b, "\n  * %s (%s) %s", c.Name, c.Type, c.Status)
+		if c.HTTPStatus != 0 {
+			fmt.Fprintf(&b, ", got HTTP %d", c.HTTPStatus)
+		}
+		// Only the first line, check outputs can be full HTTP responses
+		if output, _, _ := strings.Cut(strings.TrimSpace(c.Output), "\n"); output != "" {
+			fmt.Fprintf(&b, ": %s", output)
+		}
+		if c.HTTPStatus == 401 || c.HTTPStatus == 403 {
+			b.WriteString("\n    the endpoint rejected the check, set the headers it expects (e.g. Authorization) in the check 'headers' in fly.toml")
+		}
+	}
+	return b.String()
+}
+
+func (e *HealthChecksTimeoutError) Unwrap() error {
+	return e.err
+}
+
+// CheckType infers the check type from its definition in the machine config,
+// service checks aren't listed there but their names look like servicecheck-00-http-8080
+func CheckType(m *api.Machine, name string) string {
+	if m.Config != nil {
+		if def, ok := m.Config.Checks[name]; ok && def.Type != nil {
+			return *def.Type
+		}
+	}
+	if parts := strings.Split(name, "-"); len(parts) == 4 && parts[0] == "servicecheck" {
+		return parts[2]
+	}
+	return "unknown"
+}
+
+// MachineFailedError is returned when a machine being waited on to start reached a
+// terminal state instead, e.g. its process exited right after boot.
+type MachineFailedError struct {
+	MachineID string
+	State     string
+	ExitEvent *api.MachineExitEvent
+}
+
+func newMachineFailedError(m *api.Machine) *MachineFailedError {
+	return &MachineFailedError{MachineID: m.ID, State: m.State, ExitEvent: latestExitEvent(m)}
+}
+
+func (e *MachineFailedError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "machine %s reached the %s state instead of starting", e.MachineID, e.State)
+	if e.ExitEvent != nil {
+		fmt.Fprintf(&b, ", exit code: %d", e.ExitEvent.ExitCode)
+		if e.ExitEvent.OOMKilled {
+			b.WriteString(", it ran out of memory")
+		}
+	}
+	b.WriteString(". Check its logs with 'fly logs -i " + e.MachineID + "'")
+	return b.String()
+}
+
+// MachineExitError is returned when a machine that runs to completion exited with a nonzero code
+type MachineExitError struct {
+	MachineID string
+	ExitCode  int
+}
+
+func (e *MachineExitError) Error() string {
+	return fmt.Sprintf("machine %s exited with code %d. Check its logs with 'fly logs -i %s'", e.MachineID, e.ExitCode, e.MachineID)
+}
+
+// isTerminalState tells whether a machine won't start unless something restarts it
+func isTerminalState(m *api.Machine) bool {
+	switch m.State {
+	case "failed", api.MachineStateDestroyed, api.MachineStateDestroying:
+		return true
+	case api.MachineStateStopped:
+		event := latestExitEvent(m)
+		return event != nil && !event.Restarting
+	default:
+		return false
+	}
+}
+
+func latestExitEvent(m *api.Machine) *api.MachineExitEvent {
+	// Events are ordered from the most recent one
+	for _, e := range m.Events {
+		if e == nil || e.Type != "exit" || e.Request == nil {
+			continue
+		}
+		if e.Request.MonitorEvent != nil && e.Request.MonitorEvent.ExitEvent != nil {
+			return e.Request.MonitorEvent.ExitEvent
+		}
+		return e.Request.ExitEvent
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package machine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/superfly/flyctl/api"
+)
+
+func TestHealthChecksTimeoutError(t *testing.T) {
+	m := &api.Machine{
+		ID: "ab1234567890",
+		Config: &api.MachineConfig{
+			Checks: map[string]api.MachineCheck{
+				"alive": {Type: api.Pointer("tcp")},
+			},
+		},
+		Checks: []*api.MachineCheckStatus{
+			{Name: "alive", Status: "critical", Output: "connection refused\nmore details"},
+			{Name: "servicecheck-00-http-8080", Status: "warning", Output: "500 Internal Server Error"},
+			{Name: "servicecheck-01-tcp-8080", Status: "passing"},
+			{Name: "servicecheck-02-http-8080", Status: "critical", Output: "401 Unauthorized"},
+		},
+	}
+
+	err := newHealthChecksTimeoutError(m, nil)
+	assert.Equal(t, []FailingCheck{
+		{Name: "alive", Type: "tcp", Status: "critical", Output: "connection refused\nmore details"},
+		{Name: "servicecheck-00-http-8080", Type: "http", Status: "warning", Output: "500 Internal Server Error", HTTPStatus: 500},
+		{Name: "servicecheck-02-http-8080", Type: "http", Status: "critical", Output: "401 Unauthorized", HTTPStatus: 401},
+	}, err.FailingChecks)
+	assert.Equal(t, "timeout reached waiting for healthchecks to pass for machine ab1234567890, failing checks:\n"+
+		"  * alive (tcp) critical: connection refused\n"+
+		"  * servicecheck-00-http-8080 (http) warning, got HTTP 500: 500 Internal Server Error\n"+
+		"  * servicecheck-02-http-8080 (http) critical, got HTTP 401: 401 Unauthorized\n"+
+		"    the endpoint rejected the check, set the headers it expects (e.g. Authorization) in the check 'headers' in fly.toml", err.Error())
+}
+
+func TestHTTPErrorStatus(t *testing.T) {
+	assert.Equal(t, 403, HTTPErrorStatus("GET /health: 403 Forbidden"))
+	assert.Equal(t, 0, HTTPErrorStatus(`Get "http://172.19.3.2:8080/health": dial tcp 172.19.3.2:8080: connect: connection refused`))
+	assert.Equal(t, 0, HTTPErrorStatus("200 OK"))
+}
+
+func TestMachineFailedError(t *testing.T) {
+	exited := func(state string, restarting bool) *api.Machine {
+		return &api.Machine{
+			ID:    "ab1234567890",
+			State: state,
+			Events: []*api.MachineEvent{
+				{Type: "exit", Request: &api.MachineRequest{
+					MonitorEvent: &api.MachineMonitorEvent{ExitEvent: &api.MachineExitEvent{ExitCode: 137, OOMKilled: true, Restarting: restarting}},
+				}},
+				{Type: "start"},
+			},
+		}
+	}
+
+	assert.True(t, isTerminalState(exited(api.MachineStateStopped, false)))
+	assert.False(t, isTerminalState(exited(api.MachineStateStopped, true)))
+	assert.True(t, isTerminalState(&api.Machine{State: "failed"}))
+	assert.False(t, isTerminalState(&api.Machine{State: "starting"}))
+
+	err := newMachineFailedError(exited(api.MachineStateStopped, false))
+	assert.Equal(t, "machine ab1234567890 reached the stopped state instead of starting, exit code: 137, it ran out of memory. Check its logs with 'fly logs -i ab1234567890'", err.Error())
+}
+
+func TestExitEventSince(t *testing.T) {
+	exit := func(ts int64, code int) *api.MachineEvent {
+		return &api.MachineEvent{Type: "exit", Timestamp: ts, Request: &api.MachineRequest{
+			ExitEvent: &api.MachineExitEvent{ExitCode: code},
+		}}
+	}
+	m := &api.Machine{Events: []*api.MachineEvent{
+		exit(400, 1),
+		{Type: "start", Timestamp: 300},
+		{Type: "update", Timestamp: 250},
+		exit(200, 0),
+	}}
+
+	assert.Equal(t, m.Events[0], exitEventSince(m, 250))
+	assert.Nil(t, exitEventSince(m, 400))
+	assert.Equal(t, m.Events[0], exitEventSince(m, 0))
+
+	// The exit from before the update doesn't count
+	m.Events = m.Events[1:]
+	assert.Nil(t, exitEventSince(m, 250))
+
+	err := &MachineExitError{MachineID: "ab1234567890", ExitCode: 1}
+	assert.Equal(t, "machine ab1234567890 exited with code 1. Check its logs with 'fly logs -i ab1234567890'", err.Error())
+}
@@ -25,10 +25,16 @@ type LeasableMachine interface {
 	StartBackgroundLeaseRefresh(context.Context, time.Duration, time.Duration)
 	Update(context.Context, api.LaunchMachineInput) error
 	Start(context.Context) error
+	Resume(context.Context) error
+	Stop(context.Context, time.Duration) error
 	Destroy(context.Context, bool) error
+	Cordon(context.Context) error
+	Uncordon(context.Context) error
 	WaitForState(context.Context, string, time.Duration, string) error
 	WaitForHealthchecksToPass(context.Context, time.Duration, string) error
+	WaitForConsecutiveHealthchecksToPass(context.Context, time.Duration, int, string) error
 	WaitForEventTypeAfterType(context.Context, string, string, time.Duration) (*api.MachineEvent, error)
+	WaitForExit(context.Context, int64, time.Duration, string) error
 	FormattedMachineId() string
 }
 
@@ -94,15 +100,20 @@ func (lm *leasableMachine) FormattedMachineId() string {
 	return fmt.Sprintf("%s [%s]", res, procGroup)
 }
 
-func (lm *leasableMachine) logClearLinesAbove(count int) {
-	if lm.io.IsInteractive() {
+func (lm *leasableMachine) logClearLinesAbove(ctx context.Context, count int) {
+	if showsProgress(ctx, lm.io) {
 		builder := aec.EmptyBuilder
 		str := builder.Up(uint(count)).EraseLine(aec.EraseModes.All).ANSI
 		fmt.Fprint(lm.io.ErrOut, str.String())
 	}
 }
 
-func (lm *leasableMachine) logStatusWaiting(desired, prefix string) {
+// The status lines below are progress meant to be overwritten in place, they are skipped
+// in non-interactive sessions where they would pile up. Callers log the outcome instead.
+func (lm *leasableMachine) logStatusWaiting(ctx context.Context, desired, prefix string) {
+	if !showsProgress(ctx, lm.io) {
+		return
+	}
 	if prefix != "" {
 		prefix += " "
 	}
@@ -113,15 +124,18 @@ func (lm *leasableMachine) logStatusWaiting(desired, prefix string) {
 	)
 }
 
-func (lm *leasableMachine) logStatusFinished(current string) {
+func (lm *leasableMachine) logStatusFinished(ctx context.Context, current string) {
+	if !showsProgress(ctx, lm.io) {
+		return
+	}
 	fmt.Fprintf(lm.io.ErrOut, "  Machine %s has state: %s\n",
 		lm.colorize.Bold(lm.FormattedMachineId()),
 		lm.colorize.Green(current),
 	)
 }
 
-func (lm *leasableMachine) logHealthCheckStatus(status *api.HealthCheckStatus, prefix string) {
-	if status == nil {
+func (lm *leasableMachine) logHealthCheckStatus(ctx context.Context, status *api.HealthCheckStatus, prefix string) {
+	if status == nil || !showsProgress(ctx, lm.io) {
 		return
 	}
 	resColor := lm.colorize.Green
@@ -145,27 +159,74 @@ func (lm *leasableMachine) Start(ctx context.Context) error {
 	if lm.HasLease() {
 		return fmt.Errorf("error cannot start machine %s because it has a lease", lm.machine.ID)
 	}
-	lm.logStatusWaiting(api.MachineStateStarted, "")
-	_, err := lm.flapsClient.Start(ctx, lm.machine.ID)
+	lm.logStatusWaiting(ctx, api.MachineStateStarted, "")
+	_, err := lm.flapsClient.Start(ctx, lm.machine.ID, "")
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// Cordon takes the machine out of the proxy routing, under the lease held on it if any
+func (lm *leasableMachine) Cordon(ctx context.Context) error {
+	if lm.IsDestroyed() {
+		return fmt.Errorf("error cannot cordon machine %s that was already destroyed", lm.machine.ID)
+	}
+	return lm.flapsClient.Cordon(ctx, lm.machine.ID, lm.leaseNonce)
+}
+
+// Uncordon puts a cordoned machine back in the proxy routing, under the lease held on it if any
+func (lm *leasableMachine) Uncordon(ctx context.Context) error {
+	if lm.IsDestroyed() {
+		return fmt.Errorf("error cannot uncordon machine %s that was already destroyed", lm.machine.ID)
+	}
+	return lm.flapsClient.Uncordon(ctx, lm.machine.ID, lm.leaseNonce)
+}
+
+// Resume starts a suspended machine under the lease held on it, like one an update left suspended
+func (lm *leasableMachine) Resume(ctx context.Context) error {
+	if lm.IsDestroyed() {
+		return fmt.Errorf("error cannot resume machine %s that was already destroyed", lm.machine.ID)
+	}
+	if !lm.HasLease() {
+		return fmt.Errorf("no current lease for machine %s", lm.machine.ID)
+	}
+	if _, err := lm.flapsClient.Start(ctx, lm.machine.ID, lm.leaseNonce); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Stop sends the configured kill signal and gives the machine up to timeout to exit on its own
+func (lm *leasableMachine) Stop(ctx context.Context, timeout time.Duration) error {
+	if lm.IsDestroyed() {
+		return fmt.Errorf("error cannot stop machine %s that was already destroyed", lm.machine.ID)
+	}
+	input := api.StopMachineInput{
+		ID:      lm.machine.ID,
+		Timeout: api.Duration{Duration: timeout},
+	}
+	// Send the kill_signal the app expects for a graceful shutdown
+	if lm.machine.Config != nil && lm.machine.Config.StopConfig != nil && lm.machine.Config.StopConfig.Signal != nil {
+		input.Signal = *lm.machine.Config.StopConfig.Signal
+	}
+	return lm.flapsClient.Stop(ctx, input, lm.leaseNonce)
+}
+
 func (lm *leasableMachine) WaitForState(ctx context.Context, desiredState string, timeout time.Duration, logPrefix string) error {
 	waitCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
-	b := &backoff.Backoff{
-		Min:    500 * time.Millisecond,
-		Max:    2 * time.Second,
-		Factor: 2,
-		Jitter: true,
-	}
-	lm.logClearLinesAbove(1)
-	lm.logStatusWaiting(desiredState, logPrefix)
+	b := newPollBackoff(ctx)
+	lm.logClearLinesAbove(ctx, 1)
+	lm.logStatusWaiting(ctx, desiredState, logPrefix)
 	for {
-		err := lm.flapsClient.Wait(waitCtx, lm.Machine(), desiredState, timeout)
+		// Wait in short rounds so a machine that crashed on boot is noticed
+		// between them instead of at the end of the full timeout
+		round := waitForStateRound
+		if timeout < round {
+			round = timeout
+		}
+		err := lm.flapsClient.Wait(waitCtx, lm.Machine(), desiredState, round)
 		notFoundResponse := false
 		if err != nil {
 			var flapsErr *flaps.FlapsError
@@ -181,17 +242,48 @@ func (lm *leasableMachine) WaitForState(ctx context.Context, desiredState string
 		case notFoundResponse && desiredState != api.MachineStateDestroyed:
 			return err
 		case !notFoundResponse && err != nil:
-			time.Sleep(b.Duration())
+			if failedErr := lm.checkTerminalState(waitCtx, desiredState); failedErr != nil {
+				return failedErr
+			}
+			pause(waitCtx, b.Duration())
 			continue
 		}
-		lm.logClearLinesAbove(1)
-		lm.logStatusFinished(desiredState)
+		lm.logClearLinesAbove(ctx, 1)
+		lm.logStatusFinished(ctx, desiredState)
 		return nil
 	}
 }
 
+// checkTerminalState returns a MachineFailedError when the machine ended up in a state
+// it won't leave on its own to reach the desired one, like failing to boot
+func (lm *leasableMachine) checkTerminalState(ctx context.Context, desiredState string) error {
+	if desiredState != api.MachineStateStarted {
+		return nil
+	}
+	m, err := lm.flapsClient.Get(ctx, lm.Machine().ID)
+	if err != nil {
+		return nil
+	}
+	if isTerminalState(m) {
+		return newMachineFailedError(m)
+	}
+	return nil
+}
+
+const waitForStateRound = 15 * time.Second
+
+// defaultCheckInterval is how often the machines API runs a check without an interval
+const defaultCheckInterval = 15 * time.Second
+
 func (lm *leasableMachine) WaitForHealthchecksToPass(ctx context.Context, timeout time.Duration, logPrefix string) error {
-	if len(lm.Machine().Checks) == 0 {
+	return lm.WaitForConsecutiveHealthchecksToPass(ctx, timeout, 1, logPrefix)
+}
+
+// WaitForConsecutiveHealthchecksToPass waits until all health checks are seen passing in
+// requiredPolls polls in a row, so a single lucky poll of a flapping machine isn't enough.
+// The machine isn't evaluated again once it returns.
+func (lm *leasableMachine) WaitForConsecutiveHealthchecksToPass(ctx context.Context, timeout time.Duration, requiredPolls int, logPrefix string) error {
+	if len(gatingChecks(ctx, lm.Machine()).Checks) == 0 {
 		return nil
 	}
 	waitCtx, cancel := context.WithTimeout(ctx, timeout)
@@ -201,44 +293,116 @@ func (lm *leasableMachine) WaitForHealthchecksToPass(ctx context.Context, timeou
 	for _, s := range lm.Machine().Config.Services {
 		checkDefs = append(checkDefs, s.Checks...)
 	}
-	shortestInterval := 120 * time.Second
+	var shortestInterval time.Duration
 	for _, c := range checkDefs {
-		if c.Interval != nil && c.Interval.Duration < shortestInterval {
+		if c.Interval != nil && c.Interval.Duration > 0 && (shortestInterval == 0 || c.Interval.Duration < shortestInterval) {
 			shortestInterval = c.Interval.Duration
 		}
 	}
+	if shortestInterval == 0 {
+		shortestInterval = defaultCheckInterval
+	}
 	b := &backoff.Backoff{
 		Min:    shortestInterval / 2,
 		Max:    2 * shortestInterval,
 		Factor: 2,
 		Jitter: true,
 	}
+	// Sleeps end with the wait, so a canceled deploy or a timeout isn't held up by them
+	sleep := func(d time.Duration) {
+		select {
+		case <-waitCtx.Done():
+		case <-time.After(d):
+		}
+	}
 
-	printedFirst := false
+	passingPolls := 0
+	var lastSeen *api.Machine
 	for {
 		updateMachine, err := lm.flapsClient.Get(waitCtx, lm.Machine().ID)
+		updateMachine = gatingChecks(ctx, updateMachine)
 		switch {
 		case errors.Is(waitCtx.Err(), context.Canceled):
 			return err
+		case errors.Is(waitCtx.Err(), context.DeadlineExceeded) && lastSeen != nil:
+			return newHealthChecksTimeoutError(lastSeen, err)
 		case errors.Is(waitCtx.Err(), context.DeadlineExceeded):
 			return fmt.Errorf("timeout reached waiting for healthchecks to pass for machine %s %w", lm.Machine().ID, err)
 		case err != nil:
 			return fmt.Errorf("error getting machine %s from api: %w", lm.Machine().ID, err)
 		case !updateMachine.HealthCheckStatus().AllPassing():
-			if !printedFirst || lm.io.IsInteractive() {
-				lm.logClearLinesAbove(1)
-				lm.logHealthCheckStatus(updateMachine.HealthCheckStatus(), logPrefix)
-				printedFirst = true
-			}
-			time.Sleep(b.Duration())
+			lastSeen = updateMachine
+			passingPolls = 0
+			lm.logClearLinesAbove(ctx, 1)
+			lm.logHealthCheckStatus(ctx, updateMachine.HealthCheckStatus(), logPrefix)
+			sleep(b.Duration())
+			continue
+		case passingPolls+1 < requiredPolls:
+			lastSeen = updateMachine
+			passingPolls++
+			sleep(shortestInterval)
+			continue
+		}
+		lm.logClearLinesAbove(ctx, 1)
+		lm.logHealthCheckStatus(ctx, updateMachine.HealthCheckStatus(), logPrefix)
+		return nil
+	}
+}
+
+// WaitForExit waits for a machine that runs to completion, like a batch job, to exit. Only exit
+// events newer than since, a machine event timestamp, count so an exit from before an update is
+// ignored. An exit with a nonzero code is returned as a MachineExitError.
+func (lm *leasableMachine) WaitForExit(ctx context.Context, since int64, timeout time.Duration, logPrefix string) error {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	b := newPollBackoff(ctx)
+	lm.logClearLinesAbove(ctx, 1)
+	lm.logStatusWaiting(ctx, api.MachineStateStopped, logPrefix)
+	for {
+		updateMachine, err := lm.flapsClient.Get(waitCtx, lm.Machine().ID)
+		switch {
+		case errors.Is(waitCtx.Err(), context.Canceled):
+			return err
+		case errors.Is(waitCtx.Err(), context.DeadlineExceeded):
+			return fmt.Errorf("timeout reached waiting for machine %s to exit %w", lm.Machine().ID, err)
+		case err != nil:
+			return fmt.Errorf("error getting machine %s from api: %w", lm.Machine().ID, err)
+		}
+		exitEvent := exitEventSince(updateMachine, since)
+		if exitEvent == nil {
+			pause(waitCtx, b.Duration())
 			continue
 		}
-		lm.logClearLinesAbove(1)
-		lm.logHealthCheckStatus(updateMachine.HealthCheckStatus(), logPrefix)
+		exitCode, err := exitEvent.Request.GetExitCode()
+		if err != nil {
+			return fmt.Errorf("error getting machine %s exit code: %w", lm.Machine().ID, err)
+		}
+		if exitCode != 0 {
+			return &MachineExitError{MachineID: lm.Machine().ID, ExitCode: exitCode}
+		}
+		lm.logClearLinesAbove(ctx, 1)
+		lm.logStatusFinished(ctx, api.MachineStateStopped)
 		return nil
 	}
 }
 
+// exitEventSince returns the latest exit event of m newer than since, if any
+func exitEventSince(m *api.Machine, since int64) *api.MachineEvent {
+	// Events are ordered from the most recent one
+	for _, e := range m.Events {
+		if e == nil {
+			continue
+		}
+		if e.Timestamp <= since {
+			return nil
+		}
+		if e.Type == "exit" && e.Request != nil {
+			return e
+		}
+	}
+	return nil
+}
+
 // waits for an eventType1 type event to show up after we see a eventType2 event, and returns it
 func (lm *leasableMachine) WaitForEventTypeAfterType(ctx context.Context, eventType1, eventType2 string, timeout time.Duration) (*api.MachineEvent, error) {
 	waitCtx, cancel := context.WithTimeout(ctx, timeout)
@@ -249,7 +413,7 @@ func (lm *leasableMachine) WaitForEventTypeAfterType(ctx context.Context, eventT
 		Factor: 2,
 		Jitter: true,
 	}
-	lm.logClearLinesAbove(1)
+	lm.logClearLinesAbove(ctx, 1)
 	fmt.Fprintf(lm.io.ErrOut, "  Waiting for %s to get %s event\n",
 		lm.colorize.Bold(lm.FormattedMachineId()),
 		lm.colorize.Yellow(eventType1),
@@ -321,6 +485,10 @@ func (lm *leasableMachine) RefreshLease(ctx context.Context, duration time.Durat
 }
 
 func (lm *leasableMachine) StartBackgroundLeaseRefresh(ctx context.Context, leaseDuration time.Duration, delayBetween time.Duration) {
+	// Only one refresh loop per lease
+	if lm.leaseRefreshCancelFunc != nil {
+		lm.leaseRefreshCancelFunc()
+	}
 	ctx, lm.leaseRefreshCancelFunc = context.WithCancel(ctx)
 	go lm.refreshLeaseUntilCanceled(ctx, leaseDuration, delayBetween)
 }
@@ -337,12 +505,18 @@ func (lm *leasableMachine) refreshLeaseUntilCanceled(ctx context.Context, durati
 	for {
 		err = lm.RefreshLease(ctx, duration)
 		switch {
-		case errors.Is(err, context.Canceled):
+		case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
 			return
 		case err != nil:
 			terminal.Warnf("error refreshing lease for machine %s: %v\n", lm.machine.ID, err)
+		default:
+			terminal.Debugf("Refreshed lease on machine %s for %s\n", lm.machine.ID, duration)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(b.Duration()):
 		}
-		time.Sleep(b.Duration())
 	}
 }
 
@@ -0,0 +1,67 @@
+package machine
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/flaps"
+	"github.com/superfly/flyctl/internal/logger"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func TestStatusLogsOnlyWhenInteractive(t *testing.T) {
+	ios, _, _, errOut := iostreams.Test()
+	lm := NewLeasableMachine(nil, ios, &api.Machine{ID: "ab1234567890", Config: &api.MachineConfig{}}).(*leasableMachine)
+
+	ctx := context.Background()
+
+	// Non-interactive sessions don't get progress lines that would pile up
+	lm.logStatusWaiting(ctx, api.MachineStateStarted, "[1/2]")
+	lm.logStatusFinished(ctx, api.MachineStateStarted)
+	lm.logHealthCheckStatus(ctx, &api.HealthCheckStatus{Total: 1}, "[1/2]")
+	assert.Empty(t, errOut.String())
+
+	ios.SetStdinTTY(true)
+	ios.SetStdoutTTY(true)
+	// Neither do waits on several machines at once, their lines would overwrite each other
+	lm.logStatusWaiting(WithoutProgress(ctx), api.MachineStateStarted, "[1/2]")
+	assert.Empty(t, errOut.String())
+	lm.logStatusWaiting(ctx, api.MachineStateStarted, "[1/2]")
+	assert.Contains(t, errOut.String(), "[1/2] Waiting for ab1234567890")
+}
+
+func TestWaitForConsecutiveHealthchecksToPassStopsWithContext(t *testing.T) {
+	m := &api.Machine{
+		ID:     "ab1234567890",
+		Config: &api.MachineConfig{Checks: map[string]api.MachineCheck{"web": {Type: api.Pointer("http")}}},
+		Checks: []*api.MachineCheckStatus{{Name: "web", Status: "passing"}},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewEncoder(w).Encode(m))
+	}))
+	defer server.Close()
+	t.Setenv("FLY_FLAPS_BASE_URL", server.URL)
+	t.Setenv("FLY_ACCESS_TOKEN", "token")
+	ios, _, _, _ := iostreams.Test()
+	ctx := logger.NewContext(context.Background(), logger.FromEnv(ios.ErrOut))
+	flapsClient, err := flaps.NewFromAppName(ctx, "my-app")
+	require.NoError(t, err)
+
+	lm := NewLeasableMachine(flapsClient, ios, m)
+
+	// Without a check interval the polls are the default check interval apart,
+	// the wait still ends as soon as the context does
+	ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = lm.WaitForConsecutiveHealthchecksToPass(ctx, time.Minute, 2, "")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
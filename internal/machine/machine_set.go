@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -36,6 +38,13 @@ func NewMachineSet(flapsClient *flaps.Client, io *iostreams.IOStreams, machines
 	}
 }
 
+// NewMachineSetOf groups machines already created as leasable ones, keeping their leases
+func NewMachineSetOf(machines []LeasableMachine) MachineSet {
+	return &machineSet{
+		machines: machines,
+	}
+}
+
 func (ms *machineSet) IsEmpty() bool {
 	return len(ms.machines) == 0
 }
@@ -49,12 +58,19 @@ func (ms *machineSet) AcquireLeases(ctx context.Context, duration time.Duration)
 		return nil
 	}
 
+	startedAt := time.Now()
+	terminal.Debugf("Acquiring leases on %d machines\n", len(ms.machines))
+	pending := newPendingMachines(ms.machines)
+	stopLogging := pending.logPeriodically(leaseWaitLogInterval)
+	defer stopLogging()
+
 	results := make(chan error, len(ms.machines))
 	var wg sync.WaitGroup
 	for _, m := range ms.machines {
 		wg.Add(1)
 		go func(m LeasableMachine) {
 			defer wg.Done()
+			defer pending.done(m.Machine().ID)
 			results <- m.AcquireLease(ctx, duration)
 		}(m)
 	}
@@ -62,6 +78,9 @@ func (ms *machineSet) AcquireLeases(ctx context.Context, duration time.Duration)
 		wg.Wait()
 		close(results)
 	}()
+	defer func() {
+		terminal.Debugf("Finished acquiring leases on %d machines in %s\n", len(ms.machines), time.Since(startedAt).Round(time.Millisecond))
+	}()
 	hadError := false
 	for err := range results {
 		if err != nil {
@@ -108,8 +127,8 @@ func (ms *machineSet) ReleaseLeases(ctx context.Context) error {
 		return nil
 	}
 
-	// when context is canceled, take 500ms to attempt to release the leases
-	contextWasAlreadyCanceled := errors.Is(ctx.Err(), context.Canceled)
+	// when context is canceled or its deadline expired, take 500ms to attempt to release the leases
+	contextWasAlreadyCanceled := errors.Is(ctx.Err(), context.Canceled) || errors.Is(ctx.Err(), context.DeadlineExceeded)
 	if contextWasAlreadyCanceled {
 		var cancel context.CancelFunc
 		cancelTimeout := 500 * time.Millisecond
@@ -118,6 +137,12 @@ func (ms *machineSet) ReleaseLeases(ctx context.Context) error {
 		defer cancel()
 	}
 
+	startedAt := time.Now()
+	terminal.Debugf("Releasing leases on %d machines\n", len(ms.machines))
+	defer func() {
+		terminal.Debugf("Finished releasing leases on %d machines in %s\n", len(ms.machines), time.Since(startedAt).Round(time.Millisecond))
+	}()
+
 	results := make(chan error, len(ms.machines))
 	var wg sync.WaitGroup
 	for _, m := range ms.machines {
@@ -150,3 +175,57 @@ func (ms *machineSet) StartBackgroundLeaseRefresh(ctx context.Context, leaseDura
 		m.StartBackgroundLeaseRefresh(ctx, leaseDuration, delayBetween)
 	}
 }
+
+// leaseWaitLogInterval is how often machines still holding up lease acquisition are logged
+const leaseWaitLogInterval = 5 * time.Second
+
+// pendingMachines tracks the machines a lease operation is still waiting on
+type pendingMachines struct {
+	mu  sync.Mutex
+	ids map[string]bool
+}
+
+func newPendingMachines(machines []LeasableMachine) *pendingMachines {
+	p := &pendingMachines{ids: map[string]bool{}}
+	for _, m := range machines {
+		p.ids[m.Machine().ID] = true
+	}
+	return p
+}
+
+func (p *pendingMachines) done(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.ids, id)
+}
+
+func (p *pendingMachines) list() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ids := make([]string, 0, len(p.ids))
+	for id := range p.ids {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// logPeriodically logs the pending machines until the returned func is called
+func (p *pendingMachines) logPeriodically(interval time.Duration) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if ids := p.list(); len(ids) > 0 {
+					terminal.Debugf("Still waiting for leases on machines: %s\n", strings.Join(ids, ", "))
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
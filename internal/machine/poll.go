@@ -0,0 +1,67 @@
+package machine
+
+import (
+	"context"
+	"time"
+
+	"github.com/jpillora/backoff"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+// PollBackoff bounds the pause between two polls of the machines API while waiting on a machine.
+// The pause starts at Min and doubles up to Max, so slow starting machines cost fewer API calls.
+type PollBackoff struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+// DefaultPollBackoff is used by waits whose context carries no PollBackoff
+var DefaultPollBackoff = PollBackoff{Min: time.Second, Max: 5 * time.Second}
+
+type pollBackoffKey struct{}
+
+// WithPollBackoff returns a context making the machine waits run with ctx poll with b
+func WithPollBackoff(ctx context.Context, b PollBackoff) context.Context {
+	return context.WithValue(ctx, pollBackoffKey{}, b)
+}
+
+type noProgressKey struct{}
+
+// WithoutProgress returns a context making the machine waits run with ctx skip their status lines
+// overwritten in place, for callers waiting on several machines at once
+func WithoutProgress(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noProgressKey{}, true)
+}
+
+// showsProgress tells whether waits with ctx show their progress on io
+func showsProgress(ctx context.Context, io *iostreams.IOStreams) bool {
+	skip, _ := ctx.Value(noProgressKey{}).(bool)
+	return !skip && io.IsInteractive()
+}
+
+// newPollBackoff returns a fresh backoff for a single wait, so every machine starts polling at Min
+func newPollBackoff(ctx context.Context) *backoff.Backoff {
+	b, ok := ctx.Value(pollBackoffKey{}).(PollBackoff)
+	if !ok {
+		b = DefaultPollBackoff
+	}
+	if b.Min > b.Max {
+		b.Min = b.Max
+	}
+	return &backoff.Backoff{
+		Min:    b.Min,
+		Max:    b.Max,
+		Factor: 2,
+		Jitter: true,
+	}
+}
+
+// pause sleeps for d, cut short when ctx is done so a wait never outlives its timeout
+func pause(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
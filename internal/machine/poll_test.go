@@ -0,0 +1,35 @@
+package machine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPollBackoff(t *testing.T) {
+	b := newPollBackoff(context.Background())
+	assert.Equal(t, DefaultPollBackoff.Min, b.Min)
+	assert.Equal(t, DefaultPollBackoff.Max, b.Max)
+
+	ctx := WithPollBackoff(context.Background(), PollBackoff{Min: time.Second, Max: 10 * time.Second})
+	b = newPollBackoff(ctx)
+	assert.Equal(t, 10*time.Second, b.Max)
+	b.Duration()
+	b.Duration()
+	// Every wait starts over at the shortest pause
+	assert.Zero(t, newPollBackoff(ctx).Attempt())
+
+	// A maximum below the minimum caps both
+	b = newPollBackoff(WithPollBackoff(context.Background(), PollBackoff{Min: time.Second, Max: 500 * time.Millisecond}))
+	assert.Equal(t, 500*time.Millisecond, b.Min)
+}
+
+func TestPauseRespectsContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	pause(ctx, time.Minute)
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -157,6 +157,22 @@ func (s *IOStreams) IsInteractive() bool {
 	return s.IsStdinTTY() && s.IsStdoutTTY()
 }
 
+// Quiet returns a copy of s discarding everything written to Out and ErrOut.
+// It's never interactive, so it doesn't prompt nor show progress indicators.
+func (s *IOStreams) Quiet() *IOStreams {
+	return &IOStreams{
+		In:                s.In,
+		Out:               ioutil.Discard,
+		ErrOut:            ioutil.Discard,
+		originalOut:       ioutil.Discard,
+		terminalTheme:     "none",
+		stdinTTYOverride:  true,
+		stdoutTTYOverride: true,
+		stderrTTYOverride: true,
+		neverPrompt:       true,
+	}
+}
+
 func (s *IOStreams) SetPager(cmd string) {
 	s.pagerCommand = cmd
 }
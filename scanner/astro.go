@@ -0,0 +1,47 @@
+package scanner
+
+func configureAstro(sourceDir string, config *ScannerConfig) (*SourceInfo, error) {
+	if !checksPass(sourceDir, dirContains("package.json", `"astro"`)) {
+		return nil, nil
+	}
+
+	s := &SourceInfo{
+		Family: "Astro",
+		Port:   8080,
+	}
+
+	packager, lockfile := nodePackager(sourceDir)
+	install, prune := nodeInstallCommands(packager)
+	vars := map[string]interface{}{
+		"packager": packager,
+		"lockfile": lockfile,
+		"install":  install,
+		"prune":    prune,
+	}
+
+	// Server side rendering needs the node adapter, anything else is built as a static site
+	if checksPass(sourceDir, dirContains("astro.config.*", "@astrojs/node")) {
+		s.Files = templatesExecute("templates/astro", vars)
+		s.Env = map[string]string{
+			"HOST": "0.0.0.0",
+			"PORT": "8080",
+		}
+		return s, nil
+	}
+
+	s.Files = templatesExecute("templates/astro_static", vars)
+	s.Statics = []Static{
+		{
+			GuestPath: "/srv/http",
+			UrlPrefix: "/",
+		},
+	}
+	s.DeployDocs = `
+Your Astro site is ready to deploy!
+
+It was detected as a static site, so it is served from a small static file server.
+Static sites don't need a VM of their own: a CDN or static host may be cheaper if
+you don't plan to add server side rendering.
+`
+	return s, nil
+}
@@ -0,0 +1,38 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAstroScanner(t *testing.T) {
+	dir := t.TempDir()
+	pkg := `{"dependencies": {"astro": "^2.3.0", "@astrojs/node": "^5.1.0"}}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "package.json"), []byte(pkg), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "yarn.lock"), []byte{}, 0644))
+
+	// Static output by default
+	si, err := configureAstro(dir, &ScannerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, si)
+	assert.Equal(t, "Astro", si.Family)
+	assert.Equal(t, []Static{{GuestPath: "/srv/http", UrlPrefix: "/"}}, si.Statics)
+	assert.Contains(t, findSourceFile(t, si, "Dockerfile"), "COPY --from=build /app/dist /srv/http/")
+	assert.Contains(t, findSourceFile(t, si, "Dockerfile"), "RUN yarn run build")
+
+	// Server side rendering with the node adapter
+	config := `import node from "@astrojs/node";
+export default defineConfig({ output: "server", adapter: node({ mode: "standalone" }) });`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "astro.config.mjs"), []byte(config), 0644))
+	si, err = configureAstro(dir, &ScannerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, si)
+	assert.Empty(t, si.Statics)
+	assert.Equal(t, "8080", si.Env["PORT"])
+	assert.Contains(t, findSourceFile(t, si, "Dockerfile"), `CMD ["node", "./dist/server/entry.mjs"]`)
+	assert.Contains(t, findSourceFile(t, si, "Dockerfile"), "ADD package.json yarn.lock ./")
+}
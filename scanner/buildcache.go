@@ -0,0 +1,26 @@
+package scanner
+
+// packageCacheDirs are the download caches of the package managers generated Dockerfiles run
+var packageCacheDirs = map[string]string{
+	"pip":     "/root/.cache/pip",
+	"pipenv":  "/root/.cache/pipenv",
+	"poetry":  "/root/.cache/pypoetry",
+	"npm":     "/root/.npm",
+	"yarn":    "/usr/local/share/.cache/yarn",
+	"bundler": "/usr/local/bundle/cache",
+}
+
+// usesBuildKit tells if the generated Dockerfile is built with BuildKit, which RUN --mount needs
+func usesBuildKit(config *ScannerConfig) bool {
+	return config != nil && config.BuildKit
+}
+
+// cacheMount returns the RUN flag keeping the cache of a package manager across builds, so
+// rebuilds don't download every package again. It's empty without BuildKit.
+func cacheMount(config *ScannerConfig, manager string) string {
+	dir, ok := packageCacheDirs[manager]
+	if !ok || !usesBuildKit(config) {
+		return ""
+	}
+	return "--mount=type=cache,target=" + dir + " "
+}
@@ -0,0 +1,38 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDjangoCacheMounts(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "requirements.txt"), []byte("Django==4.1\n"), 0o644))
+	dockerfile := func(config *ScannerConfig) string {
+		si, err := configureDjango(dir, config)
+		require.NoError(t, err)
+		require.NotNil(t, si)
+		return findSourceFile(t, si, "Dockerfile")
+	}
+
+	withBuildKit := dockerfile(&ScannerConfig{BuildKit: true})
+	assert.Contains(t, withBuildKit, "# syntax=docker/dockerfile:1\nARG PYTHON_VERSION")
+	assert.Contains(t, withBuildKit, "RUN --mount=type=cache,target=/root/.cache/pip set -ex")
+	assert.NotContains(t, withBuildKit, "rm -rf /root/.cache/")
+
+	withoutBuildKit := dockerfile(&ScannerConfig{})
+	assert.NotContains(t, withoutBuildKit, "--mount")
+	assert.NotContains(t, withoutBuildKit, "# syntax")
+	assert.Contains(t, withoutBuildKit, "pip install -r /tmp/requirements.txt && \\\n    rm -rf /root/.cache/\n")
+}
+
+func TestCacheMount(t *testing.T) {
+	assert.Equal(t, "--mount=type=cache,target=/root/.npm ", cacheMount(&ScannerConfig{BuildKit: true}, "npm"))
+	assert.Empty(t, cacheMount(&ScannerConfig{}, "npm"))
+	assert.Empty(t, cacheMount(nil, "npm"))
+	assert.Empty(t, cacheMount(&ScannerConfig{BuildKit: true}, "cargo"))
+}
@@ -0,0 +1,41 @@
+package scanner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// buildArgLines declares the build args of a source as ARG instructions. Their values are
+// defaults only: fly.toml's [build.args] overrides them, and they never reach the runtime
+// environment of the machines.
+func buildArgLines(args map[string]string) string {
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "ARG %s=%q\n", name, args[name])
+	}
+	return b.String()
+}
+
+// secretRun returns the RUN prefix exposing build secrets to a single command, mounted with
+// BuildKit so their values aren't stored in any image layer. It's empty without BuildKit.
+// Secrets not passed with --build-secret are left unset rather than empty, so the defaults
+// the app falls back to still apply.
+func secretRun(config *ScannerConfig, secrets []string) string {
+	if !usesBuildKit(config) || len(secrets) == 0 {
+		return ""
+	}
+
+	var mounts, env strings.Builder
+	for _, name := range secrets {
+		fmt.Fprintf(&mounts, "--mount=type=secret,id=%s ", name)
+		fmt.Fprintf(&env, "[ -f /run/secrets/%s ] && export %s=\"$(cat /run/secrets/%s)\"; ", name, name, name)
+	}
+	return mounts.String() + env.String()
+}
@@ -0,0 +1,55 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildArgLines(t *testing.T) {
+	assert.Equal(t, "ARG A=\"1\"\nARG B=\"two words\"\n", buildArgLines(map[string]string{"B": "two words", "A": "1"}))
+	assert.Empty(t, buildArgLines(nil))
+}
+
+func TestSecretRun(t *testing.T) {
+	assert.Equal(t, `--mount=type=secret,id=SECRET_KEY [ -f /run/secrets/SECRET_KEY ] && export SECRET_KEY="$(cat /run/secrets/SECRET_KEY)"; `, secretRun(&ScannerConfig{BuildKit: true}, []string{"SECRET_KEY"}))
+	assert.Empty(t, secretRun(&ScannerConfig{}, []string{"SECRET_KEY"}))
+	assert.Empty(t, secretRun(&ScannerConfig{BuildKit: true}, nil))
+}
+
+func TestNextJsBuildEnv(t *testing.T) {
+	dir := t.TempDir()
+	pkg := `{"dependencies": {"next": "^13.3.0", "@prisma/client": "^4.12.0"}}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "package.json"), []byte(pkg), 0o644))
+	si, err := configureNextJs(dir, &ScannerConfig{BuildKit: true})
+	require.NoError(t, err)
+	require.NotNil(t, si)
+	assert.Equal(t, []string{"DATABASE_URL"}, si.BuildSecrets)
+	assert.NotContains(t, si.Env, "NEXT_PUBLIC_EXAMPLE")
+	assert.Contains(t, findSourceFile(t, si, "Dockerfile"), "ARG NEXT_PUBLIC_EXAMPLE=\"Value goes here\"\n")
+	assert.Contains(t, findSourceFile(t, si, "Dockerfile"), `RUN --mount=type=secret,id=DATABASE_URL [ -f /run/secrets/DATABASE_URL ] && export DATABASE_URL="$(cat /run/secrets/DATABASE_URL)"; yarn build`)
+
+	si, err = configureNextJs(dir, &ScannerConfig{})
+	require.NoError(t, err)
+	assert.Empty(t, si.BuildSecrets)
+	assert.Contains(t, findSourceFile(t, si, "Dockerfile"), "\nRUN yarn build\n")
+	assert.NotContains(t, findSourceFile(t, si, "Dockerfile"), "--mount")
+}
+
+func TestDjangoBuildSecrets(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "requirements.txt"), []byte("Django==4.1\n"), 0o644))
+	dockerfile := func(config *ScannerConfig) string {
+		si, err := configureDjango(dir, config)
+		require.NoError(t, err)
+		require.NotNil(t, si)
+		return findSourceFile(t, si, "Dockerfile")
+	}
+
+	// Without --build-secret SECRET_KEY stays unset, for the settings default to apply
+	assert.Contains(t, dockerfile(&ScannerConfig{BuildKit: true}), `RUN --mount=type=secret,id=SECRET_KEY [ -f /run/secrets/SECRET_KEY ] && export SECRET_KEY="$(cat /run/secrets/SECRET_KEY)"; python manage.py collectstatic`)
+	assert.Contains(t, dockerfile(&ScannerConfig{}), "\nRUN python manage.py collectstatic --noinput\n")
+}
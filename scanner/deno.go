@@ -1,21 +1,85 @@
 package scanner
 
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// entrypoints commonly used by Deno projects, in order of precedence
+var denoEntrypoints = []string{"main.ts", "mod.ts", "server.ts", "app.ts", "index.ts", "src/main.ts"}
+
+var (
+	denoPortRegex         = regexp.MustCompile(`\bport\s*[:=]\s*(\d{2,5})\b`)
+	denoJSONCommentsRegex = regexp.MustCompile(`(?m)^\s*//.*$|/\*(?s:.*?)\*/`)
+)
+
 func configureDeno(sourceDir string, config *ScannerConfig) (*SourceInfo, error) {
-	if !checksPass(sourceDir, dirContains("*.ts", "denopkg")) {
+	if !checksPass(sourceDir, fileExists("deno.json", "deno.jsonc", "deps.ts"), dirContains("*.ts", "denopkg")) {
 		return nil, nil
 	}
 
+	tasks := denoTasks(sourceDir)
+	_, startTask := tasks["start"]
+	_, buildTask := tasks["build"]
+	// Fresh is imported through the import map, either inline or in its own file
+	fresh := checksPass(sourceDir, dirContains("deno.json*", `\$fresh/`), dirContains("import_map.json", `\$fresh/`))
+
+	entrypoint, found := "main.ts", false
+	for _, candidate := range denoEntrypoints {
+		if checksPass(sourceDir, fileExists(candidate)) {
+			entrypoint, found = candidate, true
+			break
+		}
+	}
+
+	// Deno's std/http serves on 8000 by default, as does Fresh
+	port := 8000
+	if contents, err := os.ReadFile(filepath.Join(sourceDir, entrypoint)); err == nil && !fresh {
+		if m := denoPortRegex.FindSubmatch(contents); m != nil {
+			if p, err := strconv.Atoi(string(m[1])); err == nil {
+				port = p
+			}
+		}
+	}
+
+	vars := map[string]interface{}{
+		"entrypoint": entrypoint,
+		// Only an entrypoint that exists can be cached, deno cache fails on a missing file
+		"cache":     found,
+		"startTask": startTask && !fresh,
+		"fresh":     fresh,
+		"build":     fresh && buildTask,
+		"port":      port,
+	}
+
 	s := &SourceInfo{
-		Files:  templates("templates/deno"),
+		Files:  templatesExecute("templates/deno", vars),
 		Family: "Deno",
-		Port:   8080,
-		Processes: map[string]string{
-			"app": "run --allow-net ./example.ts",
-		},
+		Port:   port,
 		Env: map[string]string{
-			"PORT": "8080",
+			"PORT": strconv.Itoa(port),
 		},
 	}
-
 	return s, nil
 }
+
+// denoTasks returns the tasks defined in deno.json or deno.jsonc
+func denoTasks(sourceDir string) map[string]string {
+	for _, name := range []string{"deno.json", "deno.jsonc"} {
+		contents, err := os.ReadFile(filepath.Join(sourceDir, name))
+		if err != nil {
+			continue
+		}
+		var config struct {
+			Tasks map[string]string `json:"tasks"`
+		}
+		if err := json.Unmarshal(denoJSONCommentsRegex.ReplaceAll(contents, nil), &config); err != nil {
+			continue
+		}
+		return config.Tasks
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDenoScanner(t *testing.T) {
+	t.Run("plain app", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "deps.ts"), []byte(`export * from "https://deno.land/std/http/server.ts";`), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "server.ts"), []byte(`serve(handler, { port: 3000 });`), 0644))
+
+		si, err := configureDeno(dir, &ScannerConfig{})
+		require.NoError(t, err)
+		require.NotNil(t, si)
+		assert.Equal(t, "Deno", si.Family)
+		assert.Equal(t, 3000, si.Port)
+		assert.Contains(t, findSourceFile(t, si, "Dockerfile"), `CMD ["deno", "run", "--allow-net", "--allow-env", "server.ts"]`)
+		assert.NotContains(t, findSourceFile(t, si, "Dockerfile"), "deno task build")
+		assert.Contains(t, findSourceFile(t, si, "Dockerfile"), "RUN deno cache server.ts\n")
+	})
+
+	t.Run("start task", func(t *testing.T) {
+		dir := t.TempDir()
+		config := `{
+  // comments are allowed in deno.jsonc
+  "tasks": {"start": "deno run --allow-net main.ts"}
+}`
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "deno.jsonc"), []byte(config), 0644))
+
+		si, err := configureDeno(dir, &ScannerConfig{})
+		require.NoError(t, err)
+		require.NotNil(t, si)
+		assert.Equal(t, 8000, si.Port)
+		assert.Contains(t, findSourceFile(t, si, "Dockerfile"), `CMD ["deno", "task", "start"]`)
+		// There's no main.ts to cache
+		assert.NotContains(t, findSourceFile(t, si, "Dockerfile"), "deno cache")
+	})
+
+	t.Run("fresh", func(t *testing.T) {
+		dir := t.TempDir()
+		config := `{
+  "tasks": {"start": "deno run -A --watch=static/,routes/ dev.ts", "build": "deno run -A dev.ts build"},
+  "imports": {"$fresh/": "https://deno.land/x/fresh@1.2.0/"}
+}`
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "deno.json"), []byte(config), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "main.ts"), []byte(`await start(manifest);`), 0644))
+
+		si, err := configureDeno(dir, &ScannerConfig{})
+		require.NoError(t, err)
+		require.NotNil(t, si)
+		assert.Equal(t, 8000, si.Port)
+		assert.Contains(t, findSourceFile(t, si, "Dockerfile"), "RUN deno task build")
+		assert.Contains(t, findSourceFile(t, si, "Dockerfile"), `CMD ["deno", "run", "-A", "main.ts"]`)
+	})
+}
@@ -44,6 +44,17 @@ func configureDjango(sourceDir string, config *ScannerConfig) (*SourceInfo, erro
 	    vars["venv"] = true
 	}
 
+	vars["buildkit"] = usesBuildKit(config)
+	vars["pipCacheMount"] = cacheMount(config, "pip")
+	vars["pipenvCacheMount"] = cacheMount(config, "pipenv")
+	vars["poetryCacheMount"] = cacheMount(config, "poetry")
+
+	// collectstatic loads the settings, which usually read SECRET_KEY
+	if usesBuildKit(config) {
+		s.BuildSecrets = []string{"SECRET_KEY"}
+	}
+	vars["buildSecrets"] = secretRun(config, s.BuildSecrets)
+
 	s.Files = templatesExecute("templates/django", vars)
 
 	// check if project has a postgres dependency
@@ -67,5 +78,7 @@ For detailed documentation, see https://fly.dev/docs/django/
 		}
 	}
 
+	configureRedis(sourceDir, s)
+
 	return s, nil
 }
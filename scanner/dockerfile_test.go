@@ -2,6 +2,7 @@ package scanner
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -65,3 +66,31 @@ func TestDockerfileScanner(t *testing.T) {
 		})
 	}
 }
+
+func TestScanKeepsExistingDockerfile(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "redwood.toml"), []byte{}, 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM node:18"), 0644))
+
+	si, err := Scan(dir, &ScannerConfig{})
+	assert.NoError(t, err)
+	assert.Equal(t, "RedwoodJS", si.Family)
+	assert.Equal(t, 8910, si.Port)
+	assert.Equal(t, ".fly/release.sh", si.ReleaseCmd)
+	assert.Equal(t, filepath.Join(dir, "Dockerfile"), si.DockerfilePath)
+	assert.NotEmpty(t, si.Files)
+	for _, f := range si.Files {
+		assert.NotEqual(t, "Dockerfile", f.Path)
+	}
+}
+
+func TestScanDockerfileBeforeGenericScanners(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/app\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM golang:1.20\nEXPOSE 3000\n"), 0644))
+
+	si, err := Scan(dir, &ScannerConfig{})
+	assert.NoError(t, err)
+	assert.Equal(t, "Dockerfile", si.Family)
+	assert.Equal(t, 3000, si.Port)
+}
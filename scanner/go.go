@@ -1,19 +1,139 @@
 package scanner
 
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"golang.org/x/mod/modfile"
+)
+
+const defaultGoVersion = "1.20"
+
+// ports used by the examples of popular frameworks, in order of precedence
+var goFrameworkPorts = []struct {
+	module string
+	port   int
+}{
+	{"github.com/gin-gonic/gin", 8080},
+	{"github.com/labstack/echo", 1323},
+	{"github.com/gofiber/fiber", 3000},
+}
+
 func configureGo(sourceDir string, config *ScannerConfig) (*SourceInfo, error) {
 	if !checksPass(sourceDir, fileExists("go.mod", "Gopkg.lock")) {
 		return nil, nil
 	}
 
+	// Projects still on dep don't have a go.mod to build from, let buildpacks deal with them
+	if !checksPass(sourceDir, fileExists("go.mod")) {
+		s := &SourceInfo{
+			Builder:    "paketobuildpacks/builder:base",
+			Buildpacks: []string{"gcr.io/paketo-buildpacks/go"},
+			Family:     "Go",
+			Port:       8080,
+			Env: map[string]string{
+				"PORT": "8080",
+			},
+		}
+		return s, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(sourceDir, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+	gomod, err := modfile.ParseLax("go.mod", data, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	version := defaultGoVersion
+	if gomod.Go != nil && gomod.Go.Version != "" {
+		version = gomod.Go.Version
+	}
+
+	port := 8080
+	for _, fw := range goFrameworkPorts {
+		if goModRequires(gomod, fw.module) {
+			port = fw.port
+			break
+		}
+	}
+	grpc := goModRequires(gomod, "google.golang.org/grpc") && hasProtoFiles(sourceDir)
+
+	vars := map[string]interface{}{
+		"goVersion":   version,
+		"mainPackage": goMainPackage(sourceDir, config),
+	}
+
 	s := &SourceInfo{
-		Builder:    "paketobuildpacks/builder:base",
-		Buildpacks: []string{"gcr.io/paketo-buildpacks/go"},
-		Family:     "Go",
-		Port:       8080,
+		Files:   templatesExecute("templates/go", vars),
+		Family:  "Go",
+		Version: version,
+		Port:    port,
 		Env: map[string]string{
-			"PORT": "8080",
+			"PORT": strconv.Itoa(port),
 		},
 	}
+	if grpc {
+		configureGRPC(s, grpcDefaultPort)
+	}
 
 	return s, nil
 }
+
+// goModRequires reports if the module, or any of its major versions, is required by go.mod
+func goModRequires(gomod *modfile.File, module string) bool {
+	for _, r := range gomod.Require {
+		if r.Mod.Path == module || strings.HasPrefix(r.Mod.Path, module+"/v") {
+			return true
+		}
+	}
+	return false
+}
+
+// goMainPackage returns the package path to build, looking for a main package
+// at the module root first and then under cmd/. The user picks one if there are many,
+// scans that only report what they detect keep the first one.
+func goMainPackage(sourceDir string, config *ScannerConfig) string {
+	if isGoMainPackage(sourceDir) {
+		return "."
+	}
+
+	var candidates []string
+	entries, _ := os.ReadDir(filepath.Join(sourceDir, "cmd"))
+	for _, e := range entries {
+		if e.IsDir() && isGoMainPackage(filepath.Join(sourceDir, "cmd", e.Name())) {
+			candidates = append(candidates, "./cmd/"+e.Name())
+		}
+	}
+	sort.Strings(candidates)
+
+	switch len(candidates) {
+	case 0:
+		return "."
+	case 1:
+		return candidates[0]
+	}
+
+	selected := candidates[0]
+	if config.Mode == "scan" {
+		return selected
+	}
+	prompt := &survey.Select{
+		Message: "Found multiple binaries, which one should be deployed?",
+		Options: candidates,
+	}
+	// Non interactive sessions keep the first binary found
+	_ = survey.AskOne(prompt, &selected)
+	return selected
+}
+
+func isGoMainPackage(dir string) bool {
+	return checksPass(dir, dirContains("*.go", `^package main\b`)) &&
+		checksPass(dir, dirContains("*.go", `^func main\(\)`))
+}
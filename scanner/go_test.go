@@ -0,0 +1,43 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoScanner(t *testing.T) {
+	dir := t.TempDir()
+	gomod := "module example.com/app\n\ngo 1.19\n\nrequire github.com/gofiber/fiber/v2 v2.40.0\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "cmd", "server"), 0755))
+	main := "package main\n\nfunc main() {}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cmd", "server", "main.go"), []byte(main), 0644))
+
+	si, err := configureGo(dir, &ScannerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, si)
+	assert.Equal(t, "Go", si.Family)
+	assert.Equal(t, "1.19", si.Version)
+	assert.Equal(t, 3000, si.Port)
+	assert.Equal(t, "3000", si.Env["PORT"])
+	require.Len(t, si.Files, 1)
+	assert.Contains(t, string(si.Files[0].Contents), "ARG GO_VERSION=1.19")
+	assert.Contains(t, string(si.Files[0].Contents), "go build -v -o /run-app ./cmd/server")
+}
+
+func TestGoScanner_scanModeKeepsFirstBinary(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/app\n\ngo 1.20\n"), 0644))
+	main := "package main\n\nfunc main() {}\n"
+	for _, name := range []string{"worker", "api"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "cmd", name), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "cmd", name, "main.go"), []byte(main), 0644))
+	}
+
+	// Many binaries would prompt, scans pick the first one instead
+	assert.Equal(t, "./cmd/api", goMainPackage(dir, &ScannerConfig{Mode: "scan"}))
+}
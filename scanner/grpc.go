@@ -0,0 +1,52 @@
+package scanner
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strconv"
+)
+
+// grpcDefaultPort is the port gRPC servers conventionally listen on
+const grpcDefaultPort = 50051
+
+// pythonGRPCDeps matches the gRPC server libraries in requirements.txt, poetry.lock and Pipfile
+const pythonGRPCDeps = `(?i)^\s*(name\s*=\s*)?"?(grpcio|grpclib)\b`
+
+// hasProtoFiles tells whether the project has protobuf definitions, outside of its dependencies
+func hasProtoFiles(sourceDir string) bool {
+	found := false
+	_ = filepath.WalkDir(sourceDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			switch d.Name() {
+			case ".git", "node_modules", "vendor", ".venv", "venv":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) == ".proto" {
+			found = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found
+}
+
+// configureGRPC sets s up for a gRPC server on port. Launch then generates an HTTP/2 service,
+// with TLS terminated by the proxy, instead of the HTTP one health checked with GET requests.
+func configureGRPC(s *SourceInfo, port int) {
+	s.GRPC = true
+	s.Port = port
+	if s.Env == nil {
+		s.Env = map[string]string{}
+	}
+	s.Env["PORT"] = strconv.Itoa(port)
+	s.Notice += `
+This app was detected as a gRPC server: it's served over HTTP/2 with TLS terminated by Fly on port 443.
+Fly health checks can't speak the grpc.health.v1 protocol, a TCP check makes sure the server accepts
+connections. Make it listen on 0.0.0.0:` + strconv.Itoa(port) + ` or on the port set in PORT.
+`
+}
@@ -0,0 +1,53 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoScannerGRPC(t *testing.T) {
+	dir := t.TempDir()
+	gomod := "module example.com/app\n\ngo 1.20\n\nrequire google.golang.org/grpc v1.54.0\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644))
+
+	// The library alone isn't enough, gRPC clients use it too
+	si, err := configureGo(dir, &ScannerConfig{})
+	require.NoError(t, err)
+	assert.False(t, si.GRPC)
+	assert.Equal(t, 8080, si.Port)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "proto"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "proto", "greeter.proto"), []byte(`syntax = "proto3";`), 0644))
+	si, err = configureGo(dir, &ScannerConfig{})
+	require.NoError(t, err)
+	assert.True(t, si.GRPC)
+	assert.Equal(t, grpcDefaultPort, si.Port)
+	assert.Equal(t, "50051", si.Env["PORT"])
+}
+
+func TestPythonScannerGRPC(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "requirements.txt"), []byte("grpcio==1.54.0\ngrpcio-health-checking\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "service.proto"), []byte(`syntax = "proto3";`), 0644))
+
+	si, err := configurePython(dir, &ScannerConfig{})
+	require.NoError(t, err)
+	assert.True(t, si.GRPC)
+	assert.False(t, si.NoServices)
+	assert.Equal(t, grpcDefaultPort, si.Port)
+}
+
+func TestHasProtoFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "node_modules", "dep"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "node_modules", "dep", "dep.proto"), []byte{}, 0644))
+	assert.False(t, hasProtoFiles(dir))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "api.proto"), []byte{}, 0644))
+	assert.True(t, hasProtoFiles(dir))
+}
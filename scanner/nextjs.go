@@ -16,11 +16,23 @@ func configureNextJs(sourceDir string, config *ScannerConfig) (*SourceInfo, erro
 		Env:          env,
 	}
 
-	s.Files = templates("templates/nextjs")
-
+	// NEXT_PUBLIC_ variables are inlined into the bundle by `next build`, so they're build args
 	s.BuildArgs = map[string]string{
 		"NEXT_PUBLIC_EXAMPLE": "Value goes here",
 	}
 
+	vars := map[string]interface{}{}
+	configurePrisma(sourceDir, s, vars)
+
+	// Pages statically generated by Prisma queries need the database while building
+	if vars["prisma"] == true && usesBuildKit(config) {
+		s.BuildSecrets = []string{"DATABASE_URL"}
+	}
+
+	vars["buildkit"] = usesBuildKit(config)
+	vars["buildArgs"] = buildArgLines(s.BuildArgs)
+	vars["buildSecrets"] = secretRun(config, s.BuildSecrets)
+	s.Files = templatesExecute("templates/nextjs", vars)
+
 	return s, nil
 }
@@ -72,6 +72,9 @@ func configureNode(sourceDir string, config *ScannerConfig) (*SourceInfo, error)
 	}
 
 	vars["build"] = scripts["build"] != nil
+	configurePrisma(sourceDir, s, vars)
+	vars["buildkit"] = usesBuildKit(config)
+	vars["cacheMount"] = cacheMount(config, vars["packager"].(string))
 
 	vars["nodeVersion"] = nodeVersion
 	vars["yarnVersion"] = yarnVersion
@@ -94,3 +97,31 @@ Now: run 'fly deploy' to deploy your Node app.
 
 	return s, nil
 }
+
+// nodePackager detects the package manager of a node project from its lockfile,
+// returning the package manager and the lockfile, which is empty for npm projects without one.
+func nodePackager(sourceDir string) (packager, lockfile string) {
+	switch {
+	case checksPass(sourceDir, fileExists("pnpm-lock.yaml")):
+		return "pnpm", "pnpm-lock.yaml"
+	case checksPass(sourceDir, fileExists("yarn.lock")):
+		return "yarn", "yarn.lock"
+	case checksPass(sourceDir, fileExists("package-lock.json")):
+		return "npm", "package-lock.json"
+	default:
+		return "npm", ""
+	}
+}
+
+// nodeInstallCommands returns the commands to install all the dependencies of a
+// project and to prune the ones only needed to build it
+func nodeInstallCommands(packager string) (install, prune string) {
+	switch packager {
+	case "pnpm":
+		return "pnpm install --frozen-lockfile --prod=false", "pnpm prune --prod"
+	case "yarn":
+		return "yarn install --frozen-lockfile --production=false", "yarn install --production=true --ignore-scripts --prefer-offline"
+	default:
+		return "npm install --production=false", "npm prune --production"
+	}
+}
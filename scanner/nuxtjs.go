@@ -1,22 +1,104 @@
 package scanner
 
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+var (
+	nuxtConfigFiles = []string{"nuxt.config.ts", "nuxt.config.js", "nuxt.config.mjs"}
+	nitroPresetRe   = regexp.MustCompile(`preset\s*:\s*['"]([\w-]+)['"]`)
+)
+
+// nitroNodePresets are the Nitro presets building a node server Fly can run
+var nitroNodePresets = map[string]bool{
+	"node-server":  true,
+	"node":         true,
+	"node-cluster": true,
+}
+
 func configureNuxt(sourceDir string, config *ScannerConfig) (*SourceInfo, error) {
-	if !checksPass(sourceDir, fileExists("nuxt.config.js")) {
+	if !checksPass(sourceDir, dirContains("package.json", `"nuxt"`), fileExists(nuxtConfigFiles...)) {
 		return nil, nil
 	}
 
-	env := map[string]string{
-		"PORT": "8080",
+	if !isNuxt3(sourceDir) {
+		return configureNuxt2(sourceDir)
 	}
 
+	s := &SourceInfo{
+		Family:       "Nuxt",
+		Port:         8080,
+		SkipDatabase: true,
+		Env: map[string]string{
+			"PORT":       "8080",
+			"NITRO_PORT": "8080",
+		},
+	}
+
+	packager, lockfile := nodePackager(sourceDir)
+	install, _ := nodeInstallCommands(packager)
+	vars := map[string]interface{}{
+		"packager": packager,
+		"lockfile": lockfile,
+		"install":  install,
+	}
+	configurePrisma(sourceDir, s, vars)
+	// The release command needs the Prisma CLI and schema, the final image only has them when
+	// the schema is where the CLI looks by default
+	vars["prismaSchema"] = vars["prisma"] == true && checksPass(sourceDir, fileExists("prisma/schema.prisma"))
+	if vars["prismaSchema"] != true {
+		s.ReleaseCmd = ""
+	}
+	s.Files = templatesExecute("templates/nuxt", vars)
+
+	if preset := nitroPreset(sourceDir); preset != "" && !nitroNodePresets[preset] {
+		s.DeployDocs = `
+Your Nuxt app is configured with the '` + preset + `' Nitro preset in nuxt.config.
+
+Fly runs the node server Nitro builds with its default 'node-server' preset. Remove the
+nitro.preset setting from nuxt.config, or set it to 'node-server', before deploying.
+`
+	}
+
+	return s, nil
+}
+
+// configureNuxt2 keeps supporting apps built before Nitro
+func configureNuxt2(sourceDir string) (*SourceInfo, error) {
 	s := &SourceInfo{
 		Family:       "NuxtJS",
 		Port:         8080,
 		SkipDatabase: true,
-		Env:          env,
+		Env: map[string]string{
+			"PORT": "8080",
+		},
 	}
 
 	s.Files = templates("templates/nuxtjs")
 
 	return s, nil
 }
+
+func isNuxt3(sourceDir string) bool {
+	return checksPass(sourceDir,
+		dirContains("package.json", `"nuxt"\s*:\s*"[\^~>=]*3`),
+		fileExists("nuxt.config.ts"),
+		dirContains("nuxt.config.*", `defineNuxtConfig`),
+	)
+}
+
+// nitroPreset returns the Nitro preset set in nuxt.config, if any
+func nitroPreset(sourceDir string) string {
+	for _, name := range nuxtConfigFiles {
+		data, err := os.ReadFile(filepath.Join(sourceDir, name))
+		if err != nil {
+			continue
+		}
+		if m := nitroPresetRe.FindSubmatch(data); m != nil {
+			return string(m[1])
+		}
+	}
+	return ""
+}
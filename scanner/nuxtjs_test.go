@@ -0,0 +1,70 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNuxtScanner(t *testing.T) {
+	t.Run("nuxt 3", func(t *testing.T) {
+		dir := t.TempDir()
+		pkg := `{"devDependencies": {"nuxt": "^3.5.0"}}`
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "package.json"), []byte(pkg), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "pnpm-lock.yaml"), []byte{}, 0644))
+
+		si, err := configureNuxt(dir, &ScannerConfig{})
+		require.NoError(t, err)
+		require.NotNil(t, si)
+		assert.Equal(t, "Nuxt", si.Family)
+		assert.Equal(t, map[string]string{"PORT": "8080", "NITRO_PORT": "8080"}, si.Env)
+		assert.Empty(t, si.DeployDocs)
+		assert.Contains(t, findSourceFile(t, si, "Dockerfile"), "RUN corepack enable")
+		assert.Contains(t, findSourceFile(t, si, "Dockerfile"), "ADD package.json pnpm-lock.yaml ./")
+		assert.Contains(t, findSourceFile(t, si, "Dockerfile"), "RUN pnpm run build")
+		assert.Contains(t, findSourceFile(t, si, "Dockerfile"), `CMD ["node", ".output/server/index.mjs"]`)
+	})
+
+	t.Run("non node nitro preset", func(t *testing.T) {
+		dir := t.TempDir()
+		config := `export default defineNuxtConfig({
+  nitro: {
+    preset: 'cloudflare',
+  },
+})
+`
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "nuxt.config.js"), []byte(config), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{}`), 0644))
+
+		si, err := configureNuxt(dir, &ScannerConfig{})
+		require.NoError(t, err)
+		require.NotNil(t, si)
+		assert.Equal(t, "Nuxt", si.Family)
+		assert.Contains(t, si.DeployDocs, "'cloudflare' Nitro preset")
+		assert.Contains(t, findSourceFile(t, si, "Dockerfile"), "RUN npm run build")
+	})
+
+	t.Run("nuxt 2", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "nuxt.config.js"), []byte(`export default {}`), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"dependencies": {"nuxt": "^2.15.8"}}`), 0644))
+
+		si, err := configureNuxt(dir, &ScannerConfig{})
+		require.NoError(t, err)
+		require.NotNil(t, si)
+		assert.Equal(t, "NuxtJS", si.Family)
+		assert.Contains(t, findSourceFile(t, si, "Dockerfile"), `CMD [ "yarn", "start" ]`)
+	})
+
+	t.Run("not nuxt", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"dependencies": {"nuxt-icon": "1.0.0"}}`), 0644))
+
+		si, err := configureNuxt(dir, &ScannerConfig{})
+		require.NoError(t, err)
+		assert.Nil(t, si)
+	})
+}
@@ -103,5 +103,7 @@ a Postgresql database.
 		s.ReleaseCmd = "/app/bin/migrate"
 	}
 
+	configureRedis(sourceDir, s)
+
 	return s, nil
 }
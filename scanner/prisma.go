@@ -0,0 +1,18 @@
+package scanner
+
+// usesPrisma tells whether a node project uses Prisma, from its dependencies or its schema
+func usesPrisma(sourceDir string) bool {
+	return checksPass(sourceDir,
+		dirContains("package.json", `"prisma"`, `"@prisma/client"`),
+		fileExists("prisma/schema.prisma"),
+	)
+}
+
+// configurePrisma opts a JS framework scanner into Prisma support: migrations are applied by the
+// release command and the "prisma" template var has the Dockerfile generate the client at build time
+func configurePrisma(sourceDir string, s *SourceInfo, vars map[string]interface{}) {
+	vars["prisma"] = usesPrisma(sourceDir)
+	if vars["prisma"] == true {
+		s.ReleaseCmd = "npx prisma migrate deploy"
+	}
+}
@@ -0,0 +1,75 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsesPrisma(t *testing.T) {
+	dir := t.TempDir()
+	assert.False(t, usesPrisma(dir))
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "prisma"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "prisma", "schema.prisma"), []byte{}, 0644))
+	assert.True(t, usesPrisma(dir))
+
+	dir = t.TempDir()
+	pkg := `{"dependencies": {"@prisma/client": "^4.12.0"}}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "package.json"), []byte(pkg), 0644))
+	assert.True(t, usesPrisma(dir))
+}
+
+func TestNextJsScannerPrisma(t *testing.T) {
+	dir := t.TempDir()
+	pkg := `{"dependencies": {"next": "^13.3.0", "@prisma/client": "^4.12.0"}, "devDependencies": {"prisma": "^4.12.0"}}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "package.json"), []byte(pkg), 0644))
+
+	si, err := configureNextJs(dir, &ScannerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, si)
+	assert.Equal(t, "npx prisma migrate deploy", si.ReleaseCmd)
+
+	dockerfile := findSourceFile(t, si, "Dockerfile")
+	assert.Contains(t, dockerfile, "RUN npx prisma generate")
+}
+
+func TestRemixScannerPrisma(t *testing.T) {
+	dir := t.TempDir()
+	pkg := `{"dependencies": {"@remix-run/node": "^1.15.0", "@prisma/client": "^4.12.0"}}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "package.json"), []byte(pkg), 0644))
+
+	si, err := configureRemix(dir, &ScannerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, si)
+	assert.Equal(t, "npx prisma migrate deploy", si.ReleaseCmd)
+
+	dockerfile := findSourceFile(t, si, "Dockerfile")
+	assert.Contains(t, dockerfile, "RUN npx prisma generate")
+	assert.Contains(t, dockerfile, "COPY --from=build /app/node_modules/.prisma /app/node_modules/.prisma")
+}
+
+func TestNuxtScannerPrisma(t *testing.T) {
+	dir := t.TempDir()
+	pkg := `{"devDependencies": {"nuxt": "^3.5.0", "prisma": "^4.12.0"}, "dependencies": {"@prisma/client": "^4.12.0"}}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "package.json"), []byte(pkg), 0644))
+
+	// Without a schema in prisma/ there's nothing for the release command to run
+	si, err := configureNuxt(dir, &ScannerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, si)
+	assert.Empty(t, si.ReleaseCmd)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "prisma"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "prisma", "schema.prisma"), []byte{}, 0644))
+	si, err = configureNuxt(dir, &ScannerConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, "npx prisma migrate deploy", si.ReleaseCmd)
+
+	dockerfile := findSourceFile(t, si, "Dockerfile")
+	assert.Contains(t, dockerfile, "RUN npx prisma generate")
+	assert.Contains(t, dockerfile, "COPY --from=build /app/node_modules /app/node_modules\nCOPY --from=build /app/prisma /app/prisma\n")
+}
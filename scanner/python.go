@@ -1,11 +1,29 @@
 package scanner
 
+import (
+	"fmt"
+)
+
+// pythonWebDeps matches the dependencies of Python apps serving HTTP in requirements.txt,
+// environment.yml, poetry.lock and Pipfile. They make an app a web app whatever else it uses.
+const pythonWebDeps = `(?i)^\s*(-\s*|name\s*=\s*)?"?(gunicorn|uvicorn|hypercorn|daphne|waitress|flask|fastapi|django|starlette|aiohttp|tornado|sanic|bottle|pyramid|quart|falcon|streamlit)\b`
+
+// pythonWorkerDeps matches the dependencies of Python background workers: task queues, message
+// consumers, schedulers and chat bots. Apps are only workers with one of them, or a worker.py,
+// and none of pythonWebDeps, web frameworks missing from that list still get a web service.
+const pythonWorkerDeps = `(?i)^\s*(-\s*|name\s*=\s*)?"?(celery|rq|dramatiq|huey|arq|apscheduler|schedule|pika|kombu|aio-pika|kafka-python|confluent-kafka|python-telegram-bot|aiogram|discord\.py)\b`
+
 func configurePython(sourceDir string, config *ScannerConfig) (*SourceInfo, error) {
 	// using 'poetry.lock' as an indicator instead of 'pyproject.toml', as Paketo doesn't support PEP-517 implementations
 	if !checksPass(sourceDir, fileExists("requirements.txt", "environment.yml", "poetry.lock", "Pipfile")) {
 		return nil, nil
 	}
 
+	grpc := isPythonGRPCServer(sourceDir)
+	if !grpc && isPythonWorker(sourceDir) {
+		return configurePythonWorker(sourceDir)
+	}
+
 	s := &SourceInfo{
 		Files:   templates("templates/python"),
 		Builder: "paketobuildpacks/builder:base",
@@ -17,6 +35,61 @@ func configurePython(sourceDir string, config *ScannerConfig) (*SourceInfo, erro
 		SkipDeploy: true,
 		DeployDocs: `We have generated a simple Procfile for you. Modify it to fit your needs and run "fly deploy" to deploy your application.`,
 	}
+	if grpc {
+		configureGRPC(s, grpcDefaultPort)
+	}
+
+	return s, nil
+}
+
+// configurePythonWorker configures projects without any web server, e.g. a queue consumer loop.
+// They get no services nor HTTP checks, a deploy would otherwise wait on checks that never pass.
+func configurePythonWorker(sourceDir string) (*SourceInfo, error) {
+	entrypoint := "main.py"
+	for _, name := range []string{"worker.py", "main.py", "app.py"} {
+		if checksPass(sourceDir, fileExists(name)) {
+			entrypoint = name
+			break
+		}
+	}
+
+	s := &SourceInfo{
+		Files: templatesExecute("templates/python-worker", map[string]interface{}{
+			"entrypoint": entrypoint,
+		}),
+		Builder:      "paketobuildpacks/builder:base",
+		Family:       "Python",
+		NoServices:   true,
+		SkipDatabase: true,
+		SkipDeploy:   true,
+		DeployDocs: fmt.Sprintf(`Your app looks like a background worker without a web server, it is configured without services or HTTP checks.
+We have generated a Procfile running %s for you. Modify it to fit your needs and run "fly deploy" to deploy your application.`, entrypoint),
+	}
 
 	return s, nil
 }
+
+func isPythonGRPCServer(sourceDir string) bool {
+	return hasProtoFiles(sourceDir) && checksPass(sourceDir,
+		dirContains("requirements.txt", pythonGRPCDeps),
+		dirContains("poetry.lock", pythonGRPCDeps),
+		dirContains("Pipfile", pythonGRPCDeps),
+	)
+}
+
+// isPythonWorker tells whether the project only runs in the background. Projects are web apps
+// by default, as a web app configured without services isn't reachable at all.
+func isPythonWorker(sourceDir string) bool {
+	depsMatch := func(deps string) bool {
+		return checksPass(sourceDir,
+			dirContains("requirements.txt", deps),
+			dirContains("environment.yml", deps),
+			dirContains("poetry.lock", deps),
+			dirContains("Pipfile", deps),
+		)
+	}
+	if depsMatch(pythonWebDeps) {
+		return false
+	}
+	return depsMatch(pythonWorkerDeps) || checksPass(sourceDir, fileExists("worker.py"))
+}
@@ -0,0 +1,77 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPythonScanner(t *testing.T) {
+	t.Run("web app", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "requirements.txt"), []byte("requests==2.31.0\nFlask==2.3.2\n"), 0644))
+
+		si, err := configurePython(dir, &ScannerConfig{})
+		require.NoError(t, err)
+		require.NotNil(t, si)
+		assert.False(t, si.NoServices)
+		assert.Equal(t, 8080, si.Port)
+	})
+
+	t.Run("poetry web app", func(t *testing.T) {
+		dir := t.TempDir()
+		lock := "[[package]]\nname = \"fastapi\"\nversion = \"0.100.0\"\n"
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "poetry.lock"), []byte(lock), 0644))
+
+		si, err := configurePython(dir, &ScannerConfig{})
+		require.NoError(t, err)
+		require.NotNil(t, si)
+		assert.False(t, si.NoServices)
+	})
+
+	t.Run("unlisted web framework", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "requirements.txt"), []byte("litestar==2.0.0\nrequests==2.31.0\n"), 0644))
+
+		si, err := configurePython(dir, &ScannerConfig{})
+		require.NoError(t, err)
+		require.NotNil(t, si)
+		assert.False(t, si.NoServices)
+		assert.Equal(t, 8080, si.Port)
+	})
+
+	t.Run("task queue worker", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "requirements.txt"), []byte("celery==5.3.1\nredis==4.6.0\n"), 0644))
+
+		si, err := configurePython(dir, &ScannerConfig{})
+		require.NoError(t, err)
+		require.NotNil(t, si)
+		assert.True(t, si.NoServices)
+		assert.Contains(t, findSourceFile(t, si, "Procfile"), "web: python main.py")
+
+		// A web framework next to the task queue serves HTTP
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "requirements.txt"), []byte("celery==5.3.1\nDjango==4.2\n"), 0644))
+		si, err = configurePython(dir, &ScannerConfig{})
+		require.NoError(t, err)
+		assert.False(t, si.NoServices)
+	})
+
+	t.Run("worker", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "requirements.txt"), []byte("pika==1.3.2\nredis==4.6.0\n"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "worker.py"), []byte("while True: pass\n"), 0644))
+
+		si, err := configurePython(dir, &ScannerConfig{})
+		require.NoError(t, err)
+		require.NotNil(t, si)
+		assert.True(t, si.NoServices)
+		assert.Zero(t, si.Port)
+		assert.Empty(t, si.Statics)
+		assert.Empty(t, si.HttpCheckPath)
+		assert.Contains(t, findSourceFile(t, si, "Procfile"), "web: python worker.py")
+	})
+}
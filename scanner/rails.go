@@ -73,6 +73,8 @@ https://fly.io/docs/rails/getting-started/dockerfiles/.
 Once ready: run 'fly deploy' to deploy your Rails app.
 `
 
+	configureRedis(sourceDir, s)
+
 	// fetch healthcheck route in a separate thread
 	go func() {
 		out, err := exec.Command("ruby", "./bin/rails", "runner",
@@ -0,0 +1,33 @@
+package scanner
+
+// redis dependencies as they appear in the manifests of supported frameworks
+var redisChecks = []checkFn{
+	dirContains("requirements.txt", `(?i)^\s*redis\b`, `(?i)^\s*celery\[.*redis`),
+	dirContains("Pipfile", `(?i)^\s*"?redis\b`, `(?i)^\s*"?celery\b.*redis`),
+	dirContains("pyproject.toml", `(?i)^\s*"?redis\b`, `(?i)^\s*"?celery\b.*redis`),
+	dirContains("Gemfile", `^\s*gem\s+['"](redis|sidekiq)['"]`),
+	dirContains("mix.exs", `\{:redix,`),
+}
+
+// usesRedis reports if the project depends on a redis client
+func usesRedis(sourceDir string) bool {
+	return checksPass(sourceDir, redisChecks...)
+}
+
+// configureRedis flags the REDIS_URL secret as needed and explains how to provision
+// a redis instance when the project depends on redis. No value is generated for the
+// secret, it has to point to a database provisioned by the user.
+func configureRedis(sourceDir string, s *SourceInfo) {
+	if !usesRedis(sourceDir) {
+		return
+	}
+
+	s.Secrets = append(s.Secrets, Secret{
+		Key:  "REDIS_URL",
+		Help: "Your app depends on Redis. Provision one with 'fly redis create' and paste its connection URL, or leave it empty to set it later.",
+	})
+	s.DeployDocs += `
+Your app depends on Redis. Provision an Upstash Redis database with 'fly redis create'
+and set its connection URL with 'fly secrets set REDIS_URL=<url>' before deploying.
+`
+}
@@ -0,0 +1,29 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigureRedis(t *testing.T) {
+	dir := t.TempDir()
+	requirements := filepath.Join(dir, "requirements.txt")
+
+	require.NoError(t, os.WriteFile(requirements, []byte("Django==4.1\ncelery==5.2\n"), 0644))
+	s := &SourceInfo{}
+	configureRedis(dir, s)
+	assert.Empty(t, s.Secrets)
+	assert.Empty(t, s.DeployDocs)
+
+	require.NoError(t, os.WriteFile(requirements, []byte("Django==4.1\ncelery[redis]==5.2\n"), 0644))
+	configureRedis(dir, s)
+	require.Len(t, s.Secrets, 1)
+	assert.Equal(t, "REDIS_URL", s.Secrets[0].Key)
+	assert.Empty(t, s.Secrets[0].Value)
+	assert.Nil(t, s.Secrets[0].Generate)
+	assert.Contains(t, s.DeployDocs, "fly redis create")
+}
@@ -1,7 +1,11 @@
 package scanner
 
+import (
+	"github.com/superfly/flyctl/helpers"
+)
+
 func configureRemix(sourceDir string, config *ScannerConfig) (*SourceInfo, error) {
-	if !checksPass(sourceDir, fileExists("remix.config.js")) {
+	if !checksPass(sourceDir, fileExists("remix.config.js"), dirContains("package.json", `"@remix-run/node"`)) {
 		return nil, nil
 	}
 
@@ -12,11 +16,38 @@ func configureRemix(sourceDir string, config *ScannerConfig) (*SourceInfo, error
 	s := &SourceInfo{
 		Family: "Remix",
 		Port:   8080,
+		Secrets: []Secret{
+			{
+				Key:  "SESSION_SECRET",
+				Help: "Remix signs session cookies with a secret. Use the random default we've generated, or generate your own.",
+				Generate: func() (string, error) {
+					return helpers.RandString(64)
+				},
+			},
+		},
+		DeployDocs: `
+Your Remix app is ready to deploy!
+
+A random SESSION_SECRET secret was set to sign session cookies. Read it from
+process.env.SESSION_SECRET when creating your session storage, and rotate it
+with 'fly secrets set SESSION_SECRET=<value>'.
+`,
+	}
+
+	packager, lockfile := nodePackager(sourceDir)
+	install, prune := nodeInstallCommands(packager)
+	vars := map[string]interface{}{
+		"packager": packager,
+		"lockfile": lockfile,
+		"install":  install,
+		"prune":    prune,
+		// remix-serve runs the build directly, custom servers (like express) come with a start script
+		"remixServe": checksPass(sourceDir, dirContains("package.json", `"@remix-run/serve"`)),
 	}
 
 	if checksPass(sourceDir+"/prisma", dirContains("*.prisma", "sqlite")) {
 		env["DATABASE_URL"] = "file:/data/sqlite.db"
-		s.Files = templates("templates/remix_prisma")
+		s.Files = templatesExecute("templates/remix_prisma", vars)
 		s.DockerCommand = "start_with_migrations.sh"
 		s.DockerEntrypoint = "sh"
 		s.SkipDatabase = true
@@ -28,7 +59,8 @@ func configureRemix(sourceDir string, config *ScannerConfig) (*SourceInfo, error
 		}
 		s.Notice = "\nThis launch configuration uses SQLite on a single, dedicated volume. It will not scale beyond a single VM. Look into 'fly postgres' for a more robust production database. \n"
 	} else {
-		s.Files = templates("templates/remix")
+		configurePrisma(sourceDir, s, vars)
+		s.Files = templatesExecute("templates/remix", vars)
 	}
 
 	s.Env = env
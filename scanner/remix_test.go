@@ -0,0 +1,31 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemixScanner(t *testing.T) {
+	dir := t.TempDir()
+	pkg := `{"dependencies": {"@remix-run/node": "^1.15.0", "@remix-run/serve": "^1.15.0"}}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "package.json"), []byte(pkg), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pnpm-lock.yaml"), []byte{}, 0644))
+
+	si, err := configureRemix(dir, &ScannerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, si)
+	assert.Equal(t, "Remix", si.Family)
+	require.Len(t, si.Secrets, 1)
+	assert.Equal(t, "SESSION_SECRET", si.Secrets[0].Key)
+	assert.NotNil(t, si.Secrets[0].Generate)
+
+	dockerfile := findSourceFile(t, si, "Dockerfile")
+	assert.Contains(t, dockerfile, "RUN corepack enable")
+	assert.Contains(t, dockerfile, "ADD package.json pnpm-lock.yaml ./")
+	assert.Contains(t, dockerfile, "RUN pnpm run build")
+	assert.Contains(t, dockerfile, `CMD ["npx", "remix-serve", "build"]`)
+}
@@ -39,6 +39,8 @@ func configureRuby(sourceDir string, config *ScannerConfig) (*SourceInfo, error)
 
 	vars := make(map[string]interface{})
 	vars["rubyVersion"] = rubyVersion
+	vars["buildkit"] = usesBuildKit(config)
+	vars["cacheMount"] = cacheMount(config, "bundler")
 	s.Files = templatesExecute("templates/ruby", vars)
 
 	s.SkipDeploy = true
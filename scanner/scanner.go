@@ -32,6 +32,7 @@ type SourceInfo struct {
 	Version                      string
 	DockerfilePath               string
 	BuildArgs                    map[string]string
+	BuildSecrets                 []string
 	Builder                      string
 	ReleaseCmd                   string
 	DockerCommand                string
@@ -56,6 +57,9 @@ type SourceInfo struct {
 	Concurrency                  map[string]int
 	Callback                     func(srcInfo *SourceInfo, options map[string]bool) error
 	HttpCheckPath                string
+	NoServices                   bool
+	// GRPC is set for gRPC servers listening on Port, they are served over HTTP/2
+	GRPC bool
 }
 
 type SourceFile struct {
@@ -73,32 +77,53 @@ type Volume struct {
 type ScannerConfig struct {
 	Mode         string
 	ExistingPort int
+	// BuildKit is set when the image will be built with BuildKit, generated Dockerfiles then cache package downloads
+	BuildKit bool
 }
 
 func Scan(sourceDir string, config *ScannerConfig) (*SourceInfo, error) {
-	scanners := []sourceScanner{
+	/* framework scanners are placed before the Dockerfile scanner, since they
+	   detect defaults like the port or the release command a Dockerfile doesn't
+	   tell. An existing Dockerfile still wins over the one they generate. */
+	frameworkScanners := []sourceScanner{
 		configureDjango,
 		configureLaravel,
 		configurePhoenix,
 		configureRails,
 		configureRedwood,
-		/* frameworks scanners are placed before generic scanners,
-		   since they might mix languages or have a Dockerfile that
-			 doesn't work with Fly */
+		configureHugo,
+		configureJekyll,
+	}
+	for _, scanner := range frameworkScanners {
+		si, err := scanner(sourceDir, config)
+		if err != nil {
+			return nil, err
+		}
+		if si != nil {
+			useExistingDockerfile(sourceDir, si)
+			return si, nil
+		}
+	}
+
+	/* generic scanners only generate a Dockerfile or pick a builder,
+	   they never run when there's already a Dockerfile */
+	scanners := []sourceScanner{
 		configureDockerfile,
 		configureLucky,
 		configureRuby,
 		configureGo,
+		configureSpringBoot,
 		configureElixir,
 		configurePython,
 		configureDeno,
 		configureRemix,
+		configureAstro,
 		configureNuxt,
 		configureNextJs,
+		configureVite,
 		configureNode,
 		configureStatic,
 	}
-
 	for _, scanner := range scanners {
 		si, err := scanner(sourceDir, config)
 		if err != nil {
@@ -112,6 +137,29 @@ func Scan(sourceDir string, config *ScannerConfig) (*SourceInfo, error) {
 	return nil, nil
 }
 
+// useExistingDockerfile keeps a hand written Dockerfile from being clobbered by the one a
+// framework scanner generates, while keeping the defaults it detected like port or release command
+func useExistingDockerfile(sourceDir string, si *SourceInfo) {
+	if !checksPass(sourceDir, fileExists("Dockerfile")) {
+		return
+	}
+
+	files := si.Files[:0]
+	for _, f := range si.Files {
+		if f.Path != "Dockerfile" {
+			files = append(files, f)
+		}
+	}
+	si.Files = files
+	si.DockerfilePath = filepath.Join(sourceDir, "Dockerfile")
+	si.Builder = ""
+	si.Buildpacks = nil
+	// These are tailored to the generated Dockerfile
+	si.DockerfileAppendix = nil
+	si.DockerCommand = ""
+	si.DockerEntrypoint = ""
+}
+
 type sourceScanner func(sourceDir string, config *ScannerConfig) (*SourceInfo, error)
 
 // templates recursively returns files from the templates directory within the named directory
@@ -0,0 +1,15 @@
+package scanner
+
+import "testing"
+
+// findSourceFile returns the contents of the file at path the scanner generated
+func findSourceFile(t *testing.T, si *SourceInfo, path string) string {
+	t.Helper()
+	for _, f := range si.Files {
+		if f.Path == path {
+			return string(f.Contents)
+		}
+	}
+	t.Fatalf("no %s in the scanned files", path)
+	return ""
+}
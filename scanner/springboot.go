@@ -0,0 +1,88 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+const defaultJavaVersion = "17"
+
+// patterns matching the Java version declared in Maven and Gradle manifests
+var javaVersionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`<java\.version>\s*(?:1\.)?(\d+)\s*</java\.version>`),
+	regexp.MustCompile(`<maven\.compiler\.(?:source|target|release)>\s*(?:1\.)?(\d+)\s*</maven\.compiler`),
+	regexp.MustCompile(`JavaLanguageVersion\.of\(\s*(\d+)\s*\)`),
+	regexp.MustCompile(`JavaVersion\.VERSION_(?:1_)?(\d+)`),
+	regexp.MustCompile(`sourceCompatibility\s*=\s*['"]?(?:1\.)?(\d+)`),
+}
+
+// dependencies hinting the app needs a database
+var springBootDatasources = []string{
+	"spring-boot-starter-data-jpa",
+	"spring-boot-starter-jdbc",
+	"spring-boot-starter-data-jdbc",
+	"spring-boot-starter-data-r2dbc",
+}
+
+func configureSpringBoot(sourceDir string, config *ScannerConfig) (*SourceInfo, error) {
+	var manifest string
+	switch {
+	case checksPass(sourceDir, dirContains("pom.xml", "spring-boot-starter")):
+		manifest = "pom.xml"
+	case checksPass(sourceDir, dirContains("build.gradle", `org\.springframework\.boot`)):
+		manifest = "build.gradle"
+	case checksPass(sourceDir, dirContains("build.gradle.kts", `org\.springframework\.boot`)):
+		manifest = "build.gradle.kts"
+	default:
+		return nil, nil
+	}
+
+	buildTool := "gradle"
+	if manifest == "pom.xml" {
+		buildTool = "maven"
+	}
+	version := javaVersion(filepath.Join(sourceDir, manifest))
+
+	vars := map[string]interface{}{
+		"buildTool":   buildTool,
+		"javaVersion": version,
+	}
+
+	s := &SourceInfo{
+		Files:   templatesExecute("templates/springboot", vars),
+		Family:  "Spring Boot",
+		Version: version,
+		Port:    8080,
+		Env: map[string]string{
+			"SERVER_PORT": "8080",
+		},
+	}
+
+	if checksPass(sourceDir, dirContains(manifest, springBootDatasources...)) {
+		s.DeployDocs = `
+Your Spring Boot app declares a datasource.
+
+Create a database with 'fly postgres create', attach it with 'fly postgres attach' and point
+Spring to it, e.g. with the SPRING_DATASOURCE_URL, SPRING_DATASOURCE_USERNAME and
+SPRING_DATASOURCE_PASSWORD secrets. Note the JDBC URL format differs from the DATABASE_URL
+set by 'fly postgres attach'.
+`
+	}
+
+	return s, nil
+}
+
+// javaVersion returns the major Java version declared in a Maven or Gradle manifest
+func javaVersion(manifest string) string {
+	data, err := os.ReadFile(manifest)
+	if err != nil {
+		return defaultJavaVersion
+	}
+	for _, re := range javaVersionPatterns {
+		if m := re.FindSubmatch(data); m != nil {
+			return string(m[1])
+		}
+	}
+	return defaultJavaVersion
+}
@@ -0,0 +1,94 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpringBootScannerMaven(t *testing.T) {
+	dir := t.TempDir()
+	pom := `<project>
+  <properties>
+    <java.version>21</java.version>
+  </properties>
+  <dependencies>
+    <dependency>
+      <groupId>org.springframework.boot</groupId>
+      <artifactId>spring-boot-starter-web</artifactId>
+    </dependency>
+    <dependency>
+      <groupId>org.springframework.boot</groupId>
+      <artifactId>spring-boot-starter-data-jpa</artifactId>
+    </dependency>
+  </dependencies>
+</project>
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pom.xml"), []byte(pom), 0644))
+
+	si, err := configureSpringBoot(dir, &ScannerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, si)
+	assert.Equal(t, "Spring Boot", si.Family)
+	assert.Equal(t, "21", si.Version)
+	assert.Equal(t, 8080, si.Port)
+	assert.Equal(t, "8080", si.Env["SERVER_PORT"])
+	assert.Contains(t, si.DeployDocs, "datasource")
+
+	dockerfile := findSourceFile(t, si, "Dockerfile")
+	assert.Contains(t, dockerfile, "ARG JAVA_VERSION=21")
+	assert.Contains(t, dockerfile, "FROM maven:3-eclipse-temurin-${JAVA_VERSION} as builder")
+	assert.NotContains(t, dockerfile, "gradle")
+	assert.Contains(t, dockerfile, `CMD ["java", "-jar", "app.jar"]`)
+}
+
+func TestSpringBootScannerGradle(t *testing.T) {
+	dir := t.TempDir()
+	gradle := `plugins {
+	id 'java'
+	id 'org.springframework.boot' version '3.1.0'
+}
+
+java {
+	sourceCompatibility = '11'
+}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "build.gradle"), []byte(gradle), 0644))
+
+	si, err := configureSpringBoot(dir, &ScannerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, si)
+	assert.Equal(t, "11", si.Version)
+	assert.Empty(t, si.DeployDocs)
+
+	dockerfile := findSourceFile(t, si, "Dockerfile")
+	assert.Contains(t, dockerfile, "FROM gradle:8-jdk${JAVA_VERSION} as builder")
+	assert.NotContains(t, dockerfile, "mvn")
+}
+
+func TestSpringBootScannerNoMatch(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pom.xml"), []byte("<project></project>"), 0644))
+
+	si, err := configureSpringBoot(dir, &ScannerConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, si)
+}
+
+func TestJavaVersion(t *testing.T) {
+	dir := t.TempDir()
+	cases := map[string]string{
+		"<maven.compiler.source>1.8</maven.compiler.source>":         "8",
+		"toolchain { languageVersion = JavaLanguageVersion.of(17) }": "17",
+		"sourceCompatibility = JavaVersion.VERSION_11":               "11",
+		"nothing about java here":                                    defaultJavaVersion,
+	}
+	for content, want := range cases {
+		path := filepath.Join(dir, "manifest")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		assert.Equal(t, want, javaVersion(path), content)
+	}
+}
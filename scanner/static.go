@@ -6,6 +6,48 @@ import (
 	"github.com/superfly/flyctl/helpers"
 )
 
+// staticSiteStatics serves the whole site from the static file server of the static templates
+func staticSiteStatics() []Static {
+	return []Static{
+		{
+			GuestPath: "/srv/http",
+			UrlPrefix: "/",
+		},
+	}
+}
+
+func configureHugo(sourceDir string, config *ScannerConfig) (*SourceInfo, error) {
+	if !checksPass(sourceDir, fileExists("hugo.toml", "config.toml")) || !helpers.DirectoryExists(filepath.Join(sourceDir, "content")) {
+		return nil, nil
+	}
+
+	s := &SourceInfo{
+		Family:        "Hugo",
+		Port:          8080,
+		Files:         templates("templates/hugo"),
+		Statics:       staticSiteStatics(),
+		HttpCheckPath: "/",
+	}
+
+	return s, nil
+}
+
+func configureJekyll(sourceDir string, config *ScannerConfig) (*SourceInfo, error) {
+	if !checksPass(sourceDir, fileExists("_config.yml")) || !checksPass(sourceDir, dirContains("Gemfile", `jekyll`)) {
+		return nil, nil
+	}
+
+	s := &SourceInfo{
+		Family:        "Jekyll",
+		Port:          8080,
+		Files:         templates("templates/jekyll"),
+		Statics:       staticSiteStatics(),
+		HttpCheckPath: "/",
+	}
+
+	return s, nil
+}
+
 func configureStatic(sourceDir string, config *ScannerConfig) (*SourceInfo, error) {
 	// No index.html detected, move on
 	if !helpers.FileExists(filepath.Join(sourceDir, "index.html")) {
@@ -13,9 +55,11 @@ func configureStatic(sourceDir string, config *ScannerConfig) (*SourceInfo, erro
 	}
 
 	s := &SourceInfo{
-		Family: "Static",
-		Port:   8080,
-		Files:  templates("templates/static"),
+		Family:        "Static",
+		Port:          8080,
+		Files:         templates("templates/static"),
+		Statics:       staticSiteStatics(),
+		HttpCheckPath: "/",
 	}
 
 	return s, nil
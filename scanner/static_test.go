@@ -0,0 +1,68 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticSiteScanners(t *testing.T) {
+	write := func(dir, name, content string) {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	t.Run("hugo", func(t *testing.T) {
+		dir := t.TempDir()
+		write(dir, "hugo.toml", "baseURL = 'https://example.org/'")
+		si, err := configureHugo(dir, &ScannerConfig{})
+		require.NoError(t, err)
+		assert.Nil(t, si, "a config without content isn't a Hugo site")
+
+		write(dir, "content/_index.md", "# Hello")
+		si, err = Scan(dir, &ScannerConfig{})
+		require.NoError(t, err)
+		require.NotNil(t, si)
+		assert.Equal(t, "Hugo", si.Family)
+		assert.Equal(t, []Static{{GuestPath: "/srv/http", UrlPrefix: "/"}}, si.Statics)
+		assert.Equal(t, "/", si.HttpCheckPath)
+		assert.Empty(t, si.ReleaseCmd)
+		assert.Contains(t, findSourceFile(t, si, "Dockerfile"), "COPY --from=build /src/public /srv/http/")
+	})
+
+	t.Run("jekyll", func(t *testing.T) {
+		dir := t.TempDir()
+		write(dir, "_config.yml", "title: My blog")
+		write(dir, "Gemfile", "source 'https://rubygems.org'\ngem \"jekyll\", \"~> 4.3\"")
+		si, err := Scan(dir, &ScannerConfig{})
+		require.NoError(t, err)
+		require.NotNil(t, si)
+		assert.Equal(t, "Jekyll", si.Family)
+		assert.Equal(t, "/", si.HttpCheckPath)
+		assert.Contains(t, findSourceFile(t, si, "Dockerfile"), "RUN JEKYLL_ENV=production bundle exec jekyll build")
+		assert.Contains(t, findSourceFile(t, si, "Dockerfile"), "COPY --from=build /src/_site /srv/http/")
+	})
+
+	t.Run("plain ruby isn't jekyll", func(t *testing.T) {
+		dir := t.TempDir()
+		write(dir, "_config.yml", "title: My app")
+		write(dir, "Gemfile", "gem 'sinatra'")
+		si, err := configureJekyll(dir, &ScannerConfig{})
+		require.NoError(t, err)
+		assert.Nil(t, si)
+	})
+
+	t.Run("plain html", func(t *testing.T) {
+		dir := t.TempDir()
+		write(dir, "index.html", "<h1>Hello</h1>")
+		si, err := Scan(dir, &ScannerConfig{})
+		require.NoError(t, err)
+		require.NotNil(t, si)
+		assert.Equal(t, "Static", si.Family)
+		assert.Equal(t, []Static{{GuestPath: "/srv/http", UrlPrefix: "/"}}, si.Statics)
+		assert.Equal(t, "/", si.HttpCheckPath)
+	})
+}
@@ -2,4 +2,4 @@
 
 set -ex
 npx prisma migrate deploy
-npm run start
+{{ if .remixServe }}npx remix-serve build{{ else }}{{ .packager }} run start{{ end }}
@@ -0,0 +1,57 @@
+package scanner
+
+// viteSSRDependencies are the packages of frameworks rendering Vite apps on a server,
+// those need a node runtime and aren't served as static files
+var viteSSRDependencies = []string{
+	`"@sveltejs/kit"`,
+	`"@remix-run/`,
+	`"nuxt"`,
+	`"astro"`,
+	`"vite-plugin-ssr"`,
+	`"vike"`,
+	`"@analogjs/`,
+	`"@solidjs/start"`,
+	`"@qwik-city`,
+}
+
+// configureVite builds single page apps made with Vite, e.g. React or Vue without server side
+// rendering, and serves their dist/ output with nginx falling back to index.html for client routes
+func configureVite(sourceDir string, config *ScannerConfig) (*SourceInfo, error) {
+	if !checksPass(sourceDir, dirContains("package.json", `"vite"`)) {
+		return nil, nil
+	}
+	if checksPass(sourceDir, dirContains("package.json", viteSSRDependencies...)) {
+		return nil, nil
+	}
+
+	packager, lockfile := nodePackager(sourceDir)
+	install, _ := nodeInstallCommands(packager)
+	vars := map[string]interface{}{
+		"packager":   packager,
+		"lockfile":   lockfile,
+		"install":    install,
+		"buildkit":   usesBuildKit(config),
+		"cacheMount": cacheMount(config, packager),
+	}
+
+	s := &SourceInfo{
+		Family: "Vite",
+		Port:   8080,
+		Files:  templatesExecute("templates/vite", vars),
+		Statics: []Static{
+			{
+				GuestPath: "/usr/share/nginx/html",
+				UrlPrefix: "/",
+			},
+		},
+		HttpCheckPath: "/",
+		DeployDocs: `
+Your Vite app is ready to deploy!
+
+It was built as a single page app served by nginx, routes unknown to the server get index.html
+and are resolved in the browser. Set VITE_* variables as build arguments, they are embedded in
+the build and not read at runtime.
+`,
+	}
+	return s, nil
+}
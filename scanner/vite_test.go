@@ -0,0 +1,36 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestViteScanner(t *testing.T) {
+	dir := t.TempDir()
+	pkg := `{"dependencies": {"react": "^18.2.0"}, "devDependencies": {"vite": "^4.4.5"}}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "package.json"), []byte(pkg), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pnpm-lock.yaml"), []byte{}, 0644))
+
+	si, err := configureVite(dir, &ScannerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, si)
+	assert.Equal(t, "Vite", si.Family)
+	assert.Equal(t, 8080, si.Port)
+	assert.Equal(t, "/", si.HttpCheckPath)
+	assert.Equal(t, []Static{{GuestPath: "/usr/share/nginx/html", UrlPrefix: "/"}}, si.Statics)
+	assert.Contains(t, findSourceFile(t, si, "Dockerfile"), "RUN corepack enable")
+	assert.Contains(t, findSourceFile(t, si, "Dockerfile"), "ADD package.json pnpm-lock.yaml ./")
+	assert.Contains(t, findSourceFile(t, si, "Dockerfile"), "COPY --from=build /app/dist /usr/share/nginx/html")
+	assert.Contains(t, findSourceFile(t, si, "nginx.conf"), "try_files $uri $uri/ /index.html;")
+
+	// Apps rendered on a server are left to the node scanners
+	pkg = `{"devDependencies": {"vite": "^4.4.5", "@sveltejs/kit": "^1.20.4"}}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "package.json"), []byte(pkg), 0644))
+	si, err = configureVite(dir, &ScannerConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, si)
+}